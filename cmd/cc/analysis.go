@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/vcs"
+	"github.com/fr0g-66723067/cc/pkg/config"
+	"github.com/fr0g-66723067/cc/pkg/models"
+)
+
+// saveAnalysis stores an analysis report in the metadata of branch, keeping the other metadata
+func saveAnalysis(vcsProvider vcs.Provider, branch string, report *ai.AnalysisReport) error {
+	metadata, err := vcsProvider.GetBranchMetadata(branch)
+	if err != nil {
+		return fmt.Errorf("failed to get metadata of %s: %w", branch, err)
+	}
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+
+	if err := report.StoreInMetadata(metadata); err != nil {
+		return err
+	}
+	if err := vcsProvider.SetBranchMetadata(branch, metadata); err != nil {
+		return fmt.Errorf("failed to set metadata of %s: %w", branch, err)
+	}
+	return nil
+}
+
+// loadAnalysis returns the analysis report stored for branch, or nil if it was never analyzed
+func loadAnalysis(vcsProvider vcs.Provider, branch string) (*ai.AnalysisReport, error) {
+	metadata, err := vcsProvider.GetBranchMetadata(branch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata of %s: %w", branch, err)
+	}
+	return ai.AnalysisFromMetadata(metadata)
+}
+
+// checkAnalysis refuses branches whose stored analysis has findings of at least the
+// configured blocking severity, and warns about serious findings otherwise
+func checkAnalysis(cfg *config.Config, vcsProvider vcs.Provider, branch string) error {
+	report, err := loadAnalysis(vcsProvider, branch)
+	if err != nil {
+		return err
+	}
+	if report == nil {
+		return nil
+	}
+
+	if cfg.Analysis.BlockSeverity != "" {
+		severity, err := ai.ParseSeverity(cfg.Analysis.BlockSeverity)
+		if err != nil {
+			return fmt.Errorf("invalid analysis block severity: %w", err)
+		}
+		if count := report.CountAtLeast(severity); count > 0 {
+			return fmt.Errorf("analysis of %s reported %d findings of severity %s or higher", branch, count, severity)
+		}
+	}
+
+	if count := report.CountAtLeast(ai.SeverityHigh); count > 0 {
+		fmt.Printf("Warning: Analysis of %s reported %d findings of severity high or higher\n", branch, count)
+	}
+	return nil
+}
+
+// executeAnalyzeCommand analyzes an implementation or feature branch (the active branch if
+// empty) and renders the report as markdown, json or sarif. Reports are stored in the branch
+// metadata and reused while the branch points to the same commit, unless refresh is set.
+func executeAnalyzeCommand(configPath, branch, format string, refresh bool) (string, error) {
+	// Fail on unknown formats before doing any work
+	format, err := ai.ParseAnalysisFormat(format)
+	if err != nil {
+		return "", err
+	}
+
+	// Load config
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Get active project
+	project := cfg.GetActiveProject()
+	if project == nil {
+		return "", fmt.Errorf("no active project")
+	}
+
+	if branch == "" {
+		branch = project.ActiveBranch
+	}
+	if branch == "" {
+		return "", fmt.Errorf("no branch given and no active branch")
+	}
+
+	vcsProvider, err := projectVCS(cfg, project)
+	if err != nil {
+		return "", err
+	}
+
+	branches, err := vcsProvider.ListBranches()
+	if err != nil {
+		return "", fmt.Errorf("failed to list branches: %w", err)
+	}
+	if !containsString(branches, branch) {
+		return "", fmt.Errorf("branch %s does not exist", branch)
+	}
+
+	// Refuse to start once the budget is exceeded
+	ctx, cancel, err := budgetContext(cfg, configPath, project)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+
+	report, err := analyzeBranch(ctx, cfg, project, vcsProvider, branch, refresh)
+
+	// Save the usage of the analysis, which is spent even if it failed
+	if saveErr := config.SaveConfig(cfg, configPath); saveErr != nil {
+		return "", fmt.Errorf("failed to save config: %w", saveErr)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return report.Render(format)
+}
+
+// executeAnalyzeAllCommand analyzes every implementation of the active project and ranks
+// them by overall analysis score, then by number of findings of high or critical severity
+func executeAnalyzeAllCommand(configPath string, refresh bool) (string, error) {
+	// Load config
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Get active project
+	project := cfg.GetActiveProject()
+	if project == nil {
+		return "", fmt.Errorf("no active project")
+	}
+
+	if len(project.Implementations) == 0 {
+		return "No implementations found\n", nil
+	}
+
+	vcsProvider, err := projectVCS(cfg, project)
+	if err != nil {
+		return "", err
+	}
+
+	// Refuse to start once the budget is exceeded
+	ctx, cancel, err := budgetContext(cfg, configPath, project)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+
+	type ranked struct {
+		impl   models.Implementation
+		report *ai.AnalysisReport
+	}
+	var results []ranked
+	var failures []string
+	for _, impl := range project.Implementations {
+		report, err := analyzeBranch(ctx, cfg, project, vcsProvider, impl.BranchName, refresh)
+		if err != nil {
+			fmt.Printf("Warning: Failed to analyze %s: %v\n", impl.BranchName, err)
+			failures = append(failures, impl.BranchName)
+			continue
+		}
+		results = append(results, ranked{impl, report})
+	}
+
+	// Save the usage of the analyses
+	if err := config.SaveConfig(cfg, configPath); err != nil {
+		return "", fmt.Errorf("failed to save config: %w", err)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].report, results[j].report
+		if a.Scores[ai.OverallScore] != b.Scores[ai.OverallScore] {
+			return a.Scores[ai.OverallScore] > b.Scores[ai.OverallScore]
+		}
+		return a.CountAtLeast(ai.SeverityHigh) < b.CountAtLeast(ai.SeverityHigh)
+	})
+
+	var out strings.Builder
+	w := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+	header := "RANK\tBRANCH\tFRAMEWORK\tOVERALL"
+	for _, category := range ai.Categories {
+		header += "\t" + strings.ToUpper(string(category))
+	}
+	fmt.Fprintln(w, header+"\tFINDINGS\tHIGH+")
+	for i, result := range results {
+		line := fmt.Sprintf("%d\t%s\t%s\t%s", i+1, result.impl.BranchName, result.impl.Framework, formatAnalysisScore(result.report, ai.OverallScore))
+		for _, category := range ai.Categories {
+			line += "\t" + formatAnalysisScore(result.report, string(category))
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\n", line, len(result.report.Findings), result.report.CountAtLeast(ai.SeverityHigh))
+	}
+	w.Flush()
+
+	if len(failures) > 0 {
+		out.WriteString(fmt.Sprintf("\nFailed to analyze: %s\n", strings.Join(failures, ", ")))
+	}
+
+	return out.String(), nil
+}
+
+// formatAnalysisScore returns a score of the report, or "-" if it was not scored
+func formatAnalysisScore(report *ai.AnalysisReport, name string) string {
+	score, ok := report.Scores[name]
+	if !ok {
+		return "-"
+	}
+	return strconv.Itoa(score)
+}
+
+// projectVCS returns a VCS provider initialized for the project repository
+func projectVCS(cfg *config.Config, project *models.Project) (vcs.Provider, error) {
+	vcsProvider, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create VCS provider: %w", err)
+	}
+
+	if err := vcsProvider.Initialize(project.Path); err != nil {
+		return nil, fmt.Errorf("failed to initialize VCS: %w", err)
+	}
+
+	return vcsProvider, nil
+}
+
+// analyzeBranch returns the analysis of the commit branch points to. The report stored in the
+// branch metadata is reused if it is for that commit, unless refresh is set. Otherwise the
+// branch is checked out in a worktree, analyzed by the AI provider and the report is stored.
+func analyzeBranch(ctx context.Context, cfg *config.Config, project *models.Project, vcsProvider vcs.Provider, branch string, refresh bool) (*ai.AnalysisReport, error) {
+	commit, err := vcsProvider.GetBranchCommit(branch)
+	if err != nil {
+		return nil, err
+	}
+
+	if !refresh {
+		stored, err := loadAnalysis(vcsProvider, branch)
+		if err != nil {
+			fmt.Printf("Warning: Ignoring stored analysis of %s: %v\n", branch, err)
+		} else if stored != nil && stored.Commit == commit {
+			fmt.Printf("Using stored analysis of %s at %s\n", branch, shortCommit(commit))
+			return stored, nil
+		}
+	}
+
+	path, cleanup, err := branchWorktree(project, vcsProvider, branch)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	aiProvider, err := ai.Create(cfg.AI.Provider, cfg.AI.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI provider: %w", err)
+	}
+
+	if err := aiProvider.Initialize(ctx, aiConfig(project, branch, path)); err != nil {
+		return nil, fmt.Errorf("failed to initialize AI provider: %w", err)
+	}
+	defer aiProvider.Cleanup(ctx)
+
+	fmt.Printf("Analyzing %s at %s...\n", branch, shortCommit(commit))
+	usageCtx, usage := ai.TrackUsage(progressContext(ctx, branch))
+	report, err := aiProvider.AnalyzeCode(usageCtx, path)
+	if metrics := branchMetrics(project, branch); metrics != nil {
+		usage.Usage().AddToMetrics(metrics, ai.AnalysisUsageMetricPrefix)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze %s: %w", branch, err)
+	}
+	report.Commit = commit
+
+	if err := saveAnalysis(vcsProvider, branch, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// shortCommit abbreviates a commit hash for display
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/fr0g-66723067/cc/internal/budget"
+	"github.com/fr0g-66723067/cc/pkg/config"
+	"github.com/fr0g-66723067/cc/pkg/models"
+)
+
+// ledgerFile is the name of the AI usage ledger stored next to the config file
+const ledgerFile = "ledger.jsonl"
+
+// budgetGuard returns the guard enforcing the AI budget of project
+func budgetGuard(cfg *config.Config, configPath string, project *models.Project) *budget.Guard {
+	path := cfg.AI.Budget.Ledger
+	if path == "" {
+		path = filepath.Join(filepath.Dir(configPath), ledgerFile)
+	}
+
+	limits := budget.Limits{
+		ProjectCostUSD: cfg.AI.Budget.ProjectCostUSD,
+		ProjectTokens:  cfg.AI.Budget.ProjectTokens,
+		DailyCostUSD:   cfg.AI.Budget.DailyCostUSD,
+		DailyTokens:    cfg.AI.Budget.DailyTokens,
+	}
+	return budget.NewGuard(budget.NewLedger(path), limits, project.Name)
+}
+
+// budgetContext refuses to start AI work on project once its budget is exceeded, and
+// otherwise returns a context for the work that records its usage in the ledger and is
+// canceled when the budget runs out
+func budgetContext(cfg *config.Config, configPath string, project *models.Project) (context.Context, context.CancelFunc, error) {
+	guard := budgetGuard(cfg, configPath, project)
+	if err := guard.Check(); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := guard.Context(getContext())
+	return ctx, cancel, nil
+}
@@ -2,14 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/budget"
+	"github.com/fr0g-66723067/cc/internal/container"
+	"github.com/fr0g-66723067/cc/internal/job"
+	"github.com/fr0g-66723067/cc/internal/profile"
+	"github.com/fr0g-66723067/cc/internal/run"
+	"github.com/fr0g-66723067/cc/internal/scoring"
 	"github.com/fr0g-66723067/cc/internal/vcs"
+	"github.com/fr0g-66723067/cc/internal/verify"
 	"github.com/fr0g-66723067/cc/pkg/config"
 	"github.com/fr0g-66723067/cc/pkg/models"
 )
@@ -79,8 +89,10 @@ func executeInitCommand(configPath, projectName, description string) error {
 	return nil
 }
 
-// executeGenerateCommand generates implementations for a project
-func executeGenerateCommand(configPath, description string, frameworks []string, count int, parallel bool) error {
+// executeGenerateCommand generates implementations for a project. Implementations the AI
+// provider fails to generate are kept on their branch and marked as failed, unless
+// allowPlaceholder is set and a placeholder implementation is committed instead.
+func executeGenerateCommand(configPath, description string, frameworks []string, count int, parallel, allowPlaceholder bool) error {
 	// Load config
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
@@ -93,18 +105,12 @@ func executeGenerateCommand(configPath, description string, frameworks []string,
 		return fmt.Errorf("no active project")
 	}
 
-	// Create AI provider
-	aiProvider, err := ai.Create(cfg.AI.Provider, cfg.AI.Config)
+	// Refuse to start once the budget is exceeded
+	ctx, cancel, err := budgetContext(cfg, configPath, project)
 	if err != nil {
-		return fmt.Errorf("failed to create AI provider: %w", err)
-	}
-
-	// Initialize AI provider
-	ctx := getContext()
-	if err := aiProvider.Initialize(ctx, nil); err != nil {
-		return fmt.Errorf("failed to initialize AI provider: %w", err)
+		return err
 	}
-	defer aiProvider.Cleanup(ctx)
+	defer cancel()
 
 	// Create VCS provider
 	vcsProvider, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
@@ -117,10 +123,11 @@ func executeGenerateCommand(configPath, description string, frameworks []string,
 		return fmt.Errorf("failed to initialize VCS: %w", err)
 	}
 
-	// If no frameworks specified, use supported frameworks from AI provider
+	// If no frameworks specified, use the configured or profiled frameworks. Every
+	// implementation gets its own AI provider, so none is started here.
 	if len(frameworks) == 0 {
 		// Limit to the requested count
-		allFrameworks := aiProvider.SupportedFrameworks()
+		allFrameworks := defaultFrameworks(cfg)
 		if count < len(allFrameworks) {
 			frameworks = allFrameworks[:count]
 		} else {
@@ -164,86 +171,471 @@ func executeGenerateCommand(configPath, description string, frameworks []string,
 	
 	fmt.Printf("Current branch is: %s\n", currentBranch)
 
-	for i := 0; i < count; i++ {
-		framework := frameworks[i]
-		branchName := fmt.Sprintf("impl-%s-%d", framework, time.Now().Unix())
+	// Record the run so that it can be resumed if it is interrupted
+	store := runStore(configPath)
+	record, err := store.Create(project.Name, description, currentBranch, frameworks[:count], parallel)
+	if err != nil {
+		return fmt.Errorf("failed to create run record: %w", err)
+	}
+	fmt.Printf("Started generation run %s\n", record.ID)
 
-		// Create a new branch for this implementation
-		fmt.Printf("Creating branch %s...\n", branchName)
-		if err := vcsProvider.CreateBranch(branchName, currentBranch); err != nil {
-			return fmt.Errorf("failed to create branch: %w", err)
-		}
+	return runGeneration(ctx, cfg, configPath, project, vcsProvider, store, record, allowPlaceholder)
+}
 
-		// Switch to the new branch
-		if err := vcsProvider.SwitchBranch(branchName); err != nil {
-			return fmt.Errorf("failed to switch to branch: %w", err)
+// executeResumeGenerateCommand resumes an interrupted generation run. Frameworks that were
+// completed are skipped, the others are generated again on new branches.
+func executeResumeGenerateCommand(configPath, runID string, allowPlaceholder bool) error {
+	// Load config
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	store := runStore(configPath)
+	record, err := store.Load(runID)
+	if err != nil {
+		return err
+	}
+
+	project := cfg.GetProject(record.Project)
+	if project == nil {
+		return fmt.Errorf("project %s of run %s not found", record.Project, record.ID)
+	}
+
+	// Refuse to start once the budget is exceeded
+	ctx, cancel, err := budgetContext(cfg, configPath, project)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	// Create VCS provider
+	vcsProvider, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
+	if err != nil {
+		return fmt.Errorf("failed to create VCS provider: %w", err)
+	}
+
+	// Initialize VCS
+	if err := vcsProvider.Initialize(project.Path); err != nil {
+		return fmt.Errorf("failed to initialize VCS: %w", err)
+	}
+
+	fmt.Printf("Resuming generation run %s\n", record.ID)
+	return runGeneration(ctx, cfg, configPath, project, vcsProvider, store, record, allowPlaceholder)
+}
+
+// runGeneration generates the frameworks of a run that are not completed yet. The run record
+// is saved whenever a framework changes state and every implementation is saved to the
+// project as soon as it is done, so an interrupted run loses no work and can be resumed.
+func runGeneration(ctx context.Context, cfg *config.Config, configPath string, project *models.Project, vcsProvider vcs.Provider, store *run.Store, record *run.Record, allowPlaceholder bool) error {
+	// Each implementation is generated in its own worktree so that several
+	// frameworks can be generated at the same time
+	maxConcurrent := cfg.Jobs.MaxConcurrent
+	if !record.Parallel {
+		maxConcurrent = 1
+	}
+	queue := job.NewQueue()
+	queue.SetMaxConcurrent(maxConcurrent)
+	queue.RegisterHandler(generateJobType, func(_ context.Context, payload map[string]interface{}) (interface{}, error) {
+		framework := payload["framework"].(*run.Framework)
+		aiCfg := payload["ai_config"].(map[string]string)
+		if err := store.Update(record, func(*run.Record) { framework.Status = run.StatusRunning }); err != nil {
+			return nil, fmt.Errorf("failed to update run record: %w", err)
 		}
 
-		// Generate code
-		combinedDesc := fmt.Sprintf("%s using %s", description, framework)
-		
-		// Notify user
-		fmt.Printf("Generating implementation for %s... This may take a while.\n", framework)
-		
-		// Generate code using Claude AI provider
-		_, err := aiProvider.GenerateImplementation(ctx, description, framework)
+		// Jobs run in the budget context, which stops all of them once the budget runs out
+		impl, err := generateImplementation(ctx, cfg, project, aiCfg, record.Description, framework.Name, framework.Branch, framework.Worktree, allowPlaceholder)
+		if err != nil && impl.Status != "failed" {
+			return nil, err
+		}
 		if err != nil {
-			fmt.Printf("Warning: AI code generation failed: %v\n", err)
-			fmt.Printf("Creating a placeholder implementation instead...\n")
-			
-			// Create a fallback file if generation fails
-			readmePath := filepath.Join(project.Path, "README.md")
-			content := fmt.Sprintf("# %s\n\n%s\n\nFramework: %s\n", project.Name, description, framework)
-			if writeErr := os.WriteFile(readmePath, []byte(content), 0644); writeErr != nil {
-				return fmt.Errorf("failed to write README.md: %w", writeErr)
+			// Failed implementations are kept, the main loop reports the error
+			if err := store.Update(record, func(*run.Record) {
+				framework.Status = run.StatusFailed
+				framework.Error = err.Error()
+				framework.Implementation = &impl
+			}); err != nil {
+				return nil, fmt.Errorf("failed to update run record: %w", err)
 			}
-		} else {
-			fmt.Printf("Successfully generated code for %s implementation.\n", framework)
-			// Files have been generated in the project directory by the AI provider
+			return impl, nil
 		}
-		
-		// Add all changes and commit
-		// Use the Git command to add all files in the project directory
-		allFiles := []string{project.Path}
-		if err := vcsProvider.AddFiles(allFiles); err != nil {
-			return fmt.Errorf("failed to add files: %w", err)
+
+		// Record the implementation right away, it is only saved to the project later
+		if err := store.Update(record, func(*run.Record) {
+			framework.Status = run.StatusCompleted
+			framework.Error = ""
+			framework.Implementation = &impl
+		}); err != nil {
+			return nil, fmt.Errorf("failed to update run record: %w", err)
 		}
-		
-		commitMsg := fmt.Sprintf("Implementation: %s using %s", project.Name, framework)
-		if err := vcsProvider.CommitChanges(commitMsg); err != nil {
-			return fmt.Errorf("failed to commit changes: %w", err)
+		return impl, nil
+	})
+
+	// Clean up worktrees left behind by interrupted commands
+	if err := vcsProvider.PruneWorktrees(); err != nil {
+		fmt.Printf("Warning: Failed to prune worktrees: %v\n", err)
+	}
+
+	var jobIDs []string
+	var submitted []*run.Framework
+	var worktrees []string
+	var errs []string
+	fail := func(framework *run.Framework, err error) {
+		errs = append(errs, fmt.Sprintf("%s: %s", framework.Name, err))
+		if updateErr := store.Update(record, func(*run.Record) {
+			framework.Status = run.StatusFailed
+			framework.Error = err.Error()
+		}); updateErr != nil {
+			fmt.Printf("Warning: Failed to update run record: %v\n", updateErr)
 		}
+	}
 
-		// Create implementation model
-		impl := models.Implementation{
-			Framework:   framework,
-			BranchName:  branchName,
-			Description: combinedDesc,
-			CreatedAt:   time.Now(),
-			Provider:    aiProvider.Name(),
-			Tags:        []string{framework},
-			Metrics:     make(map[string]float64),
-			Score:       50, // Default score
-			Features:    []models.Feature{},
+	for _, framework := range record.Frameworks {
+		// Completed frameworks are skipped, after making sure they were saved to the project
+		if framework.Status == run.StatusCompleted {
+			if framework.Implementation != nil && project.GetImplementation(framework.Branch) == nil {
+				project.AddImplementation(*framework.Implementation)
+			}
+			fmt.Printf("Skipping completed %s implementation %s\n", framework.Name, framework.Branch)
+			continue
 		}
 
-		// Add implementation to project
-		project.AddImplementation(impl)
+		// Partial attempts are thrown away and started again
+		cleanupGeneration(project, vcsProvider, framework)
+
+		branchName := fmt.Sprintf("impl-%s-%d", framework.Name, time.Now().Unix())
+		implPath := worktreePath(project, branchName)
+
+		// Create the implementation branch in a new worktree
+		fmt.Printf("Creating branch %s...\n", branchName)
+		if err := store.Update(record, func(*run.Record) {
+			framework.Branch = branchName
+			framework.Worktree = implPath
+			framework.Status = run.StatusPending
+		}); err != nil {
+			fail(framework, fmt.Errorf("failed to update run record: %w", err))
+			continue
+		}
+		if err := vcsProvider.AddWorktree(implPath, branchName, record.BaseBranch); err != nil {
+			fail(framework, fmt.Errorf("failed to create worktree for branch %s: %w", branchName, err))
+			continue
+		}
+		worktrees = append(worktrees, implPath)
+
+		// The AI configuration is read from the project here, as the jobs run while
+		// implementations are added to it
+		jobID, err := queue.Submit(generateJobType, map[string]interface{}{
+			"framework": framework,
+			"ai_config": aiConfig(project, "", implPath),
+		})
+		if err != nil {
+			fail(framework, fmt.Errorf("failed to submit generation job: %w", err))
+			continue
+		}
+		jobIDs = append(jobIDs, jobID)
+		submitted = append(submitted, framework)
 	}
 
-	// Switch back to the original branch
-	if err := vcsProvider.SwitchBranch(currentBranch); err != nil {
-		return fmt.Errorf("failed to switch back to original branch: %w", err)
+	// Wait for all implementations, keeping the order in which they were requested
+	for i, jobID := range jobIDs {
+		j, err := queue.Wait(jobID)
+		if err != nil {
+			fail(submitted[i], err)
+			continue
+		}
+		if j.Error != nil {
+			fail(submitted[i], j.Error)
+			continue
+		}
+
+		// Add implementation to project and save it right away
+		impl := j.Result.(models.Implementation)
+		project.AddImplementation(impl)
+		if err := config.SaveConfig(cfg, configPath); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		if impl.Status == "failed" {
+			errs = append(errs, fmt.Sprintf("%s: %s (kept on branch %s)", submitted[i].Name, submitted[i].Error, impl.BranchName))
+		}
 	}
 
+	// The branches stay, the worktrees are no longer needed
+	removeWorktrees(vcsProvider, worktrees)
+
 	// Save config
 	if err := config.SaveConfig(cfg, configPath); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to generate implementations (resume with: cc generate --resume %s):\n  %s", record.ID, strings.Join(errs, "\n  "))
+	}
+
 	return nil
 }
 
+// cleanupGeneration removes the worktree and branch of an unfinished attempt to generate
+// a framework, if any. Branches of failed implementations saved to the project are kept.
+func cleanupGeneration(project *models.Project, vcsProvider vcs.Provider, framework *run.Framework) {
+	if framework.Worktree != "" {
+		if _, err := os.Stat(framework.Worktree); err == nil {
+			fmt.Printf("Removing worktree %s of the unfinished %s implementation\n", framework.Worktree, framework.Name)
+			if err := vcsProvider.RemoveWorktree(framework.Worktree); err != nil {
+				fmt.Printf("Warning: Failed to remove worktree %s: %v\n", framework.Worktree, err)
+			}
+		}
+	}
+
+	if framework.Branch != "" && project.GetImplementation(framework.Branch) == nil {
+		branches, err := vcsProvider.ListBranches()
+		if err == nil && containsString(branches, framework.Branch) {
+			fmt.Printf("Deleting branch %s of the unfinished %s implementation\n", framework.Branch, framework.Name)
+			if err := vcsProvider.DeleteBranch(framework.Branch); err != nil {
+				fmt.Printf("Warning: Failed to delete branch %s: %v\n", framework.Branch, err)
+			}
+		}
+	}
+}
+
+// generateJobType is the job type used for generating a single implementation
+const generateJobType = "generate-implementation"
+
+// generateImplementation generates and commits one implementation in its worktree. If the
+// AI provider fails, the implementation is returned with the failed status along with the
+// error, its branch keeping what was generated and the error log in its metadata. With
+// allowPlaceholder a placeholder implementation is committed instead. The AI provider is
+// initialized with aiCfg, the aiConfig of the project for the worktree.
+func generateImplementation(ctx context.Context, cfg *config.Config, project *models.Project, aiCfg map[string]string, description, framework, branchName, worktreePath string, allowPlaceholder bool) (models.Implementation, error) {
+	// Jobs still waiting when the budget ran out are not started
+	if err := budget.Exceeded(ctx); err != nil {
+		return models.Implementation{}, err
+	}
+
+	// Every job gets its own AI provider, writing into the job's worktree
+	aiProvider, err := ai.Create(cfg.AI.Provider, cfg.AI.Config)
+	if err != nil {
+		return models.Implementation{}, fmt.Errorf("failed to create AI provider: %w", err)
+	}
+
+	if err := aiProvider.Initialize(ctx, aiCfg); err != nil {
+		return models.Implementation{}, fmt.Errorf("failed to initialize AI provider: %w", err)
+	}
+	defer aiProvider.Cleanup(ctx)
+
+	// Open the worktree as a repository of its own
+	vcsProvider, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
+	if err != nil {
+		return models.Implementation{}, fmt.Errorf("failed to create VCS provider: %w", err)
+	}
+
+	if err := vcsProvider.Initialize(worktreePath); err != nil {
+		return models.Implementation{}, fmt.Errorf("failed to initialize VCS: %w", err)
+	}
+
+	// Generate code
+	combinedDesc := fmt.Sprintf("%s using %s", description, framework)
+	impl := models.Implementation{
+		Framework:   framework,
+		BranchName:  branchName,
+		Description: combinedDesc,
+		CreatedAt:   time.Now(),
+		Provider:    aiProvider.Name(),
+		Tags:        []string{framework},
+		Features:    []models.Feature{},
+	}
+
+	// Notify user
+	fmt.Printf("[%s] Generating implementation... This may take a while.\n", framework)
+
+	// Generate code using the AI provider, printing its progress, tracking its usage and
+	// recording the egress policy of its containers for audit
+	usageCtx, usage := ai.TrackUsage(ctx)
+	usageCtx = ai.WithEgress(usageCtx, func(policy string) { impl.Egress = policy })
+	log, err := generateWithRetry(usageCtx, cfg, framework, vcsProvider, func(ctx context.Context) error {
+		_, err := aiProvider.GenerateImplementation(ctx, description, framework)
+		return err
+	})
+	if exceeded := budget.Exceeded(ctx); exceeded != nil {
+		return models.Implementation{}, fmt.Errorf("generation stopped: %w", exceeded)
+	}
+	if err != nil && !allowPlaceholder {
+		// Keep the branch for inspection, marked as failed
+		impl.Status = "failed"
+		impl.Metrics = make(map[string]float64)
+		usage.Usage().AddToMetrics(impl.Metrics, ai.UsageMetricPrefix)
+		commitMsg := fmt.Sprintf("Failed implementation: %s using %s", project.Name, framework)
+		if keepErr := commitFailedWork(vcsProvider, worktreePath, commitMsg); keepErr != nil {
+			return models.Implementation{}, keepErr
+		}
+
+		// Branch metadata is kept by the project repository
+		repoVCS, keepErr := projectVCS(cfg, project)
+		if keepErr != nil {
+			return models.Implementation{}, keepErr
+		}
+		if keepErr := markFailed(repoVCS, branchName, log); keepErr != nil {
+			return models.Implementation{}, keepErr
+		}
+		return impl, fmt.Errorf("failed to generate %s implementation: %w", framework, err)
+	}
+	if err != nil {
+		fmt.Printf("[%s] Creating a placeholder implementation instead...\n", framework)
+
+		// Create a fallback file if generation fails
+		readmePath := filepath.Join(worktreePath, "README.md")
+		content := fmt.Sprintf("# %s\n\n%s\n\nFramework: %s\n", project.Name, description, framework)
+		if writeErr := os.WriteFile(readmePath, []byte(content), 0644); writeErr != nil {
+			return models.Implementation{}, fmt.Errorf("failed to write README.md: %w", writeErr)
+		}
+		impl.Tags = append(impl.Tags, placeholderTag)
+	} else {
+		fmt.Printf("[%s] Successfully generated code.\n", framework)
+		// Files have been generated in the worktree by the AI provider
+	}
+
+	// Add all changes and commit
+	if err := vcsProvider.AddFiles([]string{worktreePath}); err != nil {
+		return models.Implementation{}, fmt.Errorf("failed to add files: %w", err)
+	}
+
+	commitMsg := fmt.Sprintf("Implementation: %s using %s", project.Name, framework)
+	if err := vcsProvider.CommitChanges(commitMsg); err != nil {
+		return models.Implementation{}, fmt.Errorf("failed to commit changes: %w", err)
+	}
+
+	// Build, test and score the generated code
+	impl.Metrics = verifyCode(ctx, cfg, framework, worktreePath)
+	impl.Score = scoreCode(ctx, cfg, framework, worktreePath, impl.Metrics)
+	usage.Usage().AddToMetrics(impl.Metrics, ai.UsageMetricPrefix)
+	impl.Status = "completed"
+
+	return impl, nil
+}
+
+// defaultScore is the score of code that could not be scored
+const defaultScore = 50
+
+// verifyCode checks that code has the entry files of the framework's profile, builds and tests
+// it in a container using the framework's verification recipe and returns the resulting
+// metrics. Code that cannot be verified, because verification is disabled, there is no recipe
+// for the framework or no container could be run, gets no metrics but the entry file check.
+func verifyCode(ctx context.Context, cfg *config.Config, framework, codeDir string) map[string]float64 {
+	metrics := make(map[string]float64)
+	if cfg.Verify.Disabled {
+		return metrics
+	}
+
+	// Check the entry files the framework's profile expects
+	if frameworkProfile, ok := profile.Get(framework); ok && len(frameworkProfile.EntryFiles) > 0 {
+		missing := frameworkProfile.MissingEntryFiles(codeDir)
+		if len(missing) > 0 {
+			fmt.Printf("[%s] Warning: Missing entry files: %s\n", framework, strings.Join(missing, ", "))
+		}
+		metrics["entry_files_missing"] = float64(len(missing))
+	}
+
+	recipe, ok := verify.GetRecipe(framework)
+	if !ok {
+		fmt.Printf("[%s] No verification recipe for framework, skipping verification\n", framework)
+		return metrics
+	}
+
+	options, err := container.ParseRunOptions(cfg.Container.Config)
+	if err != nil {
+		fmt.Printf("[%s] Warning: Invalid container options for verification: %v\n", framework, err)
+		return metrics
+	}
+
+	egress, err := verifyEgress(cfg)
+	if err != nil {
+		fmt.Printf("[%s] Warning: Invalid egress policy for verification: %v\n", framework, err)
+		return metrics
+	}
+
+	provider, err := container.Create(cfg.Container.Provider, cfg.Container.Config)
+	if err != nil {
+		fmt.Printf("[%s] Warning: Failed to create container provider for verification: %v\n", framework, err)
+		return metrics
+	}
+
+	if err := provider.Initialize(ctx, cfg.Container.Config); err != nil {
+		fmt.Printf("[%s] Warning: Failed to initialize container provider for verification: %v\n", framework, err)
+		return metrics
+	}
+
+	if cfg.Verify.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(cfg.Verify.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	result, err := verify.NewVerifier(provider, options, egress).Verify(ctx, recipe, codeDir)
+	if err != nil {
+		fmt.Printf("[%s] Warning: Verification failed to run: %v\n", framework, err)
+		return metrics
+	}
+
+	status := "passed"
+	if !result.Passed {
+		status = "failed"
+	}
+	fmt.Printf("[%s] Verification %s: %s\n", framework, status, result.Summary())
+
+	for name, value := range result.Metrics() {
+		metrics[name] = value
+	}
+	return metrics
+}
+
+// verifyEgress returns the egress policy of verification containers: that of the container
+// config, or else the one the AI provider generates code with, so building and testing code
+// never reaches more than generating it did
+func verifyEgress(cfg *config.Config) (container.EgressPolicy, error) {
+	if cfg.Container.Config[container.EgressKey] != "" {
+		return container.ParseEgressPolicy(cfg.Container.Config)
+	}
+	return container.ParseEgressPolicy(map[string]string{
+		container.EgressKey:      cfg.AI.Config["container_"+container.EgressKey],
+		container.EgressAllowKey: cfg.AI.Config["container_"+container.EgressAllowKey],
+	})
+}
+
+// scoreCode rates code with the configured scorers, adding their measurements to metrics.
+// Code no scorer applies to gets the default score.
+func scoreCode(ctx context.Context, cfg *config.Config, framework, codeDir string, metrics map[string]float64) int {
+	engine, err := scoring.NewEngine(cfg.Scoring.Weights, cfg.Scoring.Config)
+	if err != nil {
+		fmt.Printf("[%s] Warning: Failed to create scoring engine: %v\n", framework, err)
+		return defaultScore
+	}
+
+	score, ok, err := engine.Score(ctx, codeDir, metrics)
+	if err != nil {
+		fmt.Printf("[%s] Warning: Failed to score code: %v\n", framework, err)
+		return defaultScore
+	}
+	if !ok {
+		return defaultScore
+	}
+
+	fmt.Printf("[%s] Score: %d\n", framework, score)
+	return score
+}
+
+// worktreePath returns where the worktree for a branch is created
+func worktreePath(project *models.Project, branch string) string {
+	return filepath.Join(project.Path, ".git", "cc", "worktrees", branch)
+}
+
+// removeWorktrees removes worktrees, reporting failures as warnings
+func removeWorktrees(vcsProvider vcs.Provider, paths []string) {
+	for _, path := range paths {
+		if err := vcsProvider.RemoveWorktree(path); err != nil {
+			fmt.Printf("Warning: Failed to remove worktree %s: %v\n", path, err)
+		}
+	}
+}
+
 // executeSelectCommand selects an implementation
 func executeSelectCommand(configPath, branchName string) error {
 	// Load config
@@ -293,6 +685,16 @@ func executeSelectCommand(configPath, branchName string) error {
 		return fmt.Errorf("branch %s does not exist in the repository", branchName)
 	}
 
+	// Failed implementations are only kept for inspection
+	if impl := project.GetImplementation(branchName); impl != nil && impl.Status == "failed" {
+		return fmt.Errorf("implementation %s failed to generate and cannot be selected", branchName)
+	}
+
+	// Gate the selection on the stored code analysis
+	if err := checkAnalysis(cfg, vcsProvider, branchName); err != nil {
+		return err
+	}
+
 	// Switch to the branch
 	if err := vcsProvider.SwitchBranch(branchName); err != nil {
 		return fmt.Errorf("failed to switch to branch %s: %w", branchName, err)
@@ -310,8 +712,11 @@ func executeSelectCommand(configPath, branchName string) error {
 	return nil
 }
 
-// executeFeatureCommand adds a feature to the current implementation
-func executeFeatureCommand(configPath, description string) error {
+// executeFeatureCommand adds a feature to the current implementation, or stacks it on
+// the feature branch parentBranch if it is not empty. A feature the AI provider fails to
+// add is kept on its branch and marked as failed, unless allowPlaceholder is set and a
+// placeholder feature is committed instead.
+func executeFeatureCommand(configPath, description, parentBranch string, allowPlaceholder bool) error {
 	// Load config
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
@@ -324,28 +729,36 @@ func executeFeatureCommand(configPath, description string) error {
 		return fmt.Errorf("no active project")
 	}
 
-	// Check if an implementation is selected
-	selectedImpl := project.GetSelectedImplementation()
-	if selectedImpl == nil {
-		return fmt.Errorf("no implementation selected")
+	// Features are based on the selected implementation unless they are stacked on another feature
+	var impl *models.Implementation
+	baseBranch := parentBranch
+	if parentBranch != "" {
+		var parent *models.Feature
+		impl, parent = project.GetFeature(parentBranch)
+		if parent == nil {
+			return fmt.Errorf("feature %s not found", parentBranch)
+		}
+		if parent.Status == "failed" {
+			return fmt.Errorf("feature %s failed to generate, features cannot be stacked on it", parentBranch)
+		}
+	} else {
+		impl = project.GetSelectedImplementation()
+		if impl == nil {
+			return fmt.Errorf("no implementation selected")
+		}
+		baseBranch = impl.BranchName
 	}
 
-	// Create feature name and branch
-	featureName := sanitizeForBranchName(description)
-	featureBranch := fmt.Sprintf("feat-%s-%d", featureName, time.Now().Unix())
-
-	// Create AI provider
-	aiProvider, err := ai.Create(cfg.AI.Provider, cfg.AI.Config)
+	// Refuse to start once the budget is exceeded
+	ctx, cancel, err := budgetContext(cfg, configPath, project)
 	if err != nil {
-		return fmt.Errorf("failed to create AI provider: %w", err)
+		return err
 	}
+	defer cancel()
 
-	// Initialize AI provider
-	ctx := getContext()
-	if err := aiProvider.Initialize(ctx, nil); err != nil {
-		return fmt.Errorf("failed to initialize AI provider: %w", err)
-	}
-	defer aiProvider.Cleanup(ctx)
+	// Create feature name and branch
+	featureName := sanitizeForBranchName(description)
+	featureBranch := fmt.Sprintf("feat-%s-%d", featureName, time.Now().Unix())
 
 	// Create VCS provider
 	vcsProvider, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
@@ -358,87 +771,498 @@ func executeFeatureCommand(configPath, description string) error {
 		return fmt.Errorf("failed to initialize VCS: %w", err)
 	}
 
-	// Get current branch to verify we're on the correct implementation branch
-	currentBranch, err := vcsProvider.GetCurrentBranch()
+	// Clean up worktrees left behind by interrupted commands
+	if err := vcsProvider.PruneWorktrees(); err != nil {
+		fmt.Printf("Warning: Failed to prune worktrees: %v\n", err)
+	}
+
+	// Create the feature branch in its own worktree so the main working copy
+	// is never left on a half-finished branch
+	fmt.Printf("Creating feature branch %s...\n", featureBranch)
+	featurePath := worktreePath(project, featureBranch)
+	if err := vcsProvider.AddWorktree(featurePath, featureBranch, baseBranch); err != nil {
+		return fmt.Errorf("failed to create feature branch: %w", err)
+	}
+	defer removeWorktrees(vcsProvider, []string{featurePath})
+
+	// Open the worktree as a repository of its own
+	featureVCS, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
 	if err != nil {
-		return fmt.Errorf("failed to get current branch: %w", err)
+		return fmt.Errorf("failed to create VCS provider: %w", err)
 	}
 
-	// Ensure we're on the selected implementation branch
-	if currentBranch != selectedImpl.BranchName {
-		return fmt.Errorf("current branch (%s) is not the selected implementation branch (%s), please run 'select' first", 
-			currentBranch, selectedImpl.BranchName)
+	if err := featureVCS.Initialize(featurePath); err != nil {
+		return fmt.Errorf("failed to initialize VCS: %w", err)
 	}
 
-	// Create a new branch for this feature based on the implementation branch
-	fmt.Printf("Creating feature branch %s...\n", featureBranch)
-	if err := vcsProvider.CreateBranch(featureBranch, selectedImpl.BranchName); err != nil {
-		return fmt.Errorf("failed to create feature branch: %w", err)
+	// Create AI provider
+	aiProvider, err := ai.Create(cfg.AI.Provider, cfg.AI.Config)
+	if err != nil {
+		return fmt.Errorf("failed to create AI provider: %w", err)
 	}
 
-	// Switch to the feature branch
-	if err := vcsProvider.SwitchBranch(featureBranch); err != nil {
-		return fmt.Errorf("failed to switch to feature branch: %w", err)
+	// Initialize AI provider with the worktree as its workspace
+	if err := aiProvider.Initialize(ctx, aiConfig(project, baseBranch, featurePath)); err != nil {
+		return fmt.Errorf("failed to initialize AI provider: %w", err)
+	}
+	defer aiProvider.Cleanup(ctx)
+
+	// Create feature model
+	feature := models.Feature{
+		Name:        featureName,
+		BranchName:  featureBranch,
+		Description: description,
+		CreatedAt:   time.Now(),
+		BaseBranch:  baseBranch,
+		Parent:      parentBranch,
+		Provider:    aiProvider.Name(),
+		Tags:        []string{},
 	}
 
 	// Use Claude AI provider to add the feature
 	fmt.Printf("Adding feature: %s\n", description)
-	output, err := aiProvider.AddFeature(ctx, project.Path, description)
+	usageCtx, usage := ai.TrackUsage(ctx)
+	usageCtx = ai.WithEgress(usageCtx, func(policy string) { feature.Egress = policy })
+	var output string
+	log, err := generateWithRetry(usageCtx, cfg, featureName, featureVCS, func(ctx context.Context) error {
+		var err error
+		output, err = aiProvider.AddFeature(ctx, featurePath, description)
+		return err
+	})
+	if exceeded := budget.Exceeded(ctx); exceeded != nil {
+		return fmt.Errorf("feature generation stopped: %w", exceeded)
+	}
+	if err != nil && !allowPlaceholder {
+		// Keep the branch for inspection, marked as failed
+		feature.Status = "failed"
+		feature.Metrics = make(map[string]float64)
+		usage.Usage().AddToMetrics(feature.Metrics, ai.UsageMetricPrefix)
+		if keepErr := commitFailedWork(featureVCS, featurePath, fmt.Sprintf("Failed feature: %s", description)); keepErr != nil {
+			return keepErr
+		}
+		if keepErr := markFailed(vcsProvider, featureBranch, log); keepErr != nil {
+			return keepErr
+		}
+		impl.AddFeature(feature)
+		if saveErr := config.SaveConfig(cfg, configPath); saveErr != nil {
+			return fmt.Errorf("failed to save config: %w", saveErr)
+		}
+		return fmt.Errorf("failed to add feature, kept on branch %s: %w", featureBranch, err)
+	}
 	if err != nil {
-		fmt.Printf("Warning: AI feature generation failed: %v\n", err)
 		fmt.Printf("Creating a placeholder feature instead...\n")
 		
 		// Create a fallback feature file if AI fails
-		featureFile := filepath.Join(project.Path, fmt.Sprintf("feature-%s.txt", featureName))
-		content := fmt.Sprintf("Feature: %s\nImplementation: %s\n", description, selectedImpl.Framework)
+		featureFile := filepath.Join(featurePath, fmt.Sprintf("feature-%s.txt", featureName))
+		content := fmt.Sprintf("Feature: %s\nImplementation: %s\n", description, impl.Framework)
 		if writeErr := os.WriteFile(featureFile, []byte(content), 0644); writeErr != nil {
 			return fmt.Errorf("failed to write feature file: %w", writeErr)
 		}
+		feature.Tags = append(feature.Tags, placeholderTag)
 	} else {
 		fmt.Printf("Successfully added feature: %s\n", description)
 		fmt.Printf("AI Output Summary: %s\n", truncateString(output, 200))
 	}
 
 	// Add and commit the changes
-	// Use the Git command to add all files in the project directory
-	allFiles := []string{project.Path}
-	if err := vcsProvider.AddFiles(allFiles); err != nil {
+	if err := featureVCS.AddFiles([]string{featurePath}); err != nil {
 		return fmt.Errorf("failed to add files: %w", err)
 	}
 
 	commitMsg := fmt.Sprintf("Feature: %s", description)
-	if err := vcsProvider.CommitChanges(commitMsg); err != nil {
+	if err := featureVCS.CommitChanges(commitMsg); err != nil {
 		return fmt.Errorf("failed to commit feature changes: %w", err)
 	}
 
-	// Create feature model
-	feature := models.Feature{
-		Name:        featureName,
-		BranchName:  featureBranch,
-		Description: description,
-		CreatedAt:   time.Now(),
-		BaseBranch:  selectedImpl.BranchName,
-		Provider:    aiProvider.Name(),
-		Status:      "completed",
-		Tags:        []string{},
-	}
+	// Build, test and score the implementation with the feature added
+	feature.Metrics = verifyCode(ctx, cfg, impl.Framework, featurePath)
+	feature.Score = scoreCode(ctx, cfg, impl.Framework, featurePath, feature.Metrics)
+	usage.Usage().AddToMetrics(feature.Metrics, ai.UsageMetricPrefix)
+	feature.Status = "completed"
 
 	// Add feature to implementation
-	impl := project.GetImplementation(selectedImpl.BranchName)
-	impl.Features = append(impl.Features, feature)
+	impl.AddFeature(feature)
 
 	// Save config
 	if err := config.SaveConfig(cfg, configPath); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
-	// Switch back to the implementation branch
-	if err := vcsProvider.SwitchBranch(selectedImpl.BranchName); err != nil {
-		fmt.Printf("Warning: Failed to switch back to implementation branch (%s): %v\n", selectedImpl.BranchName, err)
-		fmt.Printf("You are currently on the feature branch: %s\n", featureBranch)
+	return nil
+}
+
+// executeFeatureMergeCommand merges a feature branch back into the implementation it is based on.
+// If resolve is set and the merge conflicts, the AI provider proposes a resolution that is
+// committed on a new resolve branch, whose name is returned. Merging the feature again
+// merges the resolve branch instead.
+func executeFeatureMergeCommand(configPath, branchName string, strategy vcs.MergeStrategy, resolve bool) (string, error) {
+	// Load config
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Get active project
+	project := cfg.GetActiveProject()
+	if project == nil {
+		return "", fmt.Errorf("no active project")
 	}
 
-	return nil
+	// Check if feature exists
+	_, feature := project.GetFeature(branchName)
+	if feature == nil {
+		return "", fmt.Errorf("feature %s not found", branchName)
+	}
+
+	if feature.Status == "merged" {
+		return "", fmt.Errorf("feature %s is already merged into %s", branchName, feature.BaseBranch)
+	}
+	if feature.Status == "failed" {
+		return "", fmt.Errorf("feature %s failed to generate and cannot be merged", branchName)
+	}
+
+	// Create VCS provider
+	vcsProvider, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create VCS provider: %w", err)
+	}
+
+	// Initialize VCS
+	if err := vcsProvider.Initialize(project.Path); err != nil {
+		return "", fmt.Errorf("failed to initialize VCS: %w", err)
+	}
+
+	// Merge in a working tree that has the implementation branch checked out
+	targetVCS, cleanup, err := checkoutBranch(cfg, project, vcsProvider, feature.BaseBranch)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	// A reviewed conflict resolution already contains the feature
+	mergeBranch := branchName
+	if feature.ResolveBranch != "" {
+		mergeBranch = feature.ResolveBranch
+		fmt.Printf("Using conflict resolution from %s\n", mergeBranch)
+	}
+
+	fmt.Printf("Merging %s into %s (%s)...\n", mergeBranch, feature.BaseBranch, strategy)
+	commitMsg := fmt.Sprintf("Merge feature: %s", feature.Description)
+	err = targetVCS.Merge(mergeBranch, strategy, commitMsg)
+
+	var conflictErr *vcs.ConflictError
+	if resolve && errors.As(err, &conflictErr) {
+		fmt.Printf("Merge has conflicts in %d file(s), asking the AI provider for a resolution...\n", len(conflictErr.Files))
+		ctx, cancel, err := budgetContext(cfg, configPath, project)
+		if err != nil {
+			return "", err
+		}
+		defer cancel()
+
+		resolveBranch, err := resolveConflicts(ctx, cfg, project, vcsProvider, feature.BaseBranch, mergeBranch, branchName)
+		if err != nil {
+			return "", err
+		}
+
+		// Remember the resolution so the next merge uses it
+		feature.ResolveBranch = resolveBranch
+		project.UpdatedAt = time.Now()
+
+		if err := config.SaveConfig(cfg, configPath); err != nil {
+			return "", fmt.Errorf("failed to save config: %w", err)
+		}
+
+		return resolveBranch, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to merge %s into %s: %w", mergeBranch, feature.BaseBranch, err)
+	}
+
+	// Update feature model
+	feature.Status = "merged"
+	project.UpdatedAt = time.Now()
+
+	// Save config
+	if err := config.SaveConfig(cfg, configPath); err != nil {
+		return "", fmt.Errorf("failed to save config: %w", err)
+	}
+
+	return "", nil
+}
+
+// executeFeatureRestackCommand rebases a feature onto its base branch and then every feature
+// stacked on it onto its parent, parents first, replaying only the commits of the feature
+// itself on top of the rewritten parent. If resolve is set and a rebase conflicts, the
+// AI provider proposes a resolution on a new resolve branch, whose name is returned, and
+// restacking stops.
+func executeFeatureRestackCommand(configPath, branchName string, resolve bool) (string, error) {
+	// Load config
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Get active project
+	project := cfg.GetActiveProject()
+	if project == nil {
+		return "", fmt.Errorf("no active project")
+	}
+
+	// Check if feature exists
+	impl, feature := project.GetFeature(branchName)
+	if feature == nil {
+		return "", fmt.Errorf("feature %s not found", branchName)
+	}
+
+	// Create VCS provider
+	vcsProvider, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create VCS provider: %w", err)
+	}
+
+	// Initialize VCS
+	if err := vcsProvider.Initialize(project.Path); err != nil {
+		return "", fmt.Errorf("failed to initialize VCS: %w", err)
+	}
+
+	// The old tips of the rebased features, so the features stacked on them only replay
+	// their own commits rather than the parent commits that were rewritten
+	oldTips := make(map[string]string)
+	for _, stacked := range impl.GetStack(branchName) {
+		if stacked.Status == "merged" {
+			fmt.Printf("Skipping merged feature %s\n", stacked.BranchName)
+			continue
+		}
+
+		oldTip, err := vcsProvider.GetBranchCommit(stacked.BranchName)
+		if err != nil {
+			return "", fmt.Errorf("failed to get commit of %s: %w", stacked.BranchName, err)
+		}
+
+		fmt.Printf("Rebasing %s onto %s...\n", stacked.BranchName, stacked.BaseBranch)
+		branchVCS, cleanup, err := checkoutBranch(cfg, project, vcsProvider, stacked.BranchName)
+		if err != nil {
+			return "", err
+		}
+		if parentTip, ok := oldTips[stacked.BaseBranch]; ok {
+			err = branchVCS.RebaseOnto(stacked.BaseBranch, parentTip)
+		} else {
+			err = branchVCS.Rebase(stacked.BaseBranch)
+		}
+		cleanup()
+		oldTips[stacked.BranchName] = oldTip
+
+		var conflictErr *vcs.ConflictError
+		if resolve && errors.As(err, &conflictErr) {
+			fmt.Printf("Rebase has conflicts in %d file(s), asking the AI provider for a resolution...\n", len(conflictErr.Files))
+			ctx, cancel, err := budgetContext(cfg, configPath, project)
+			if err != nil {
+				return "", err
+			}
+			defer cancel()
+
+			resolveBranch, err := resolveConflicts(ctx, cfg, project, vcsProvider, stacked.BranchName, stacked.BaseBranch, stacked.BranchName)
+			if err != nil {
+				return "", err
+			}
+
+			// Remember the resolution so merging the feature uses it
+			stacked.ResolveBranch = resolveBranch
+			project.UpdatedAt = time.Now()
+
+			if err := config.SaveConfig(cfg, configPath); err != nil {
+				return "", fmt.Errorf("failed to save config: %w", err)
+			}
+
+			return resolveBranch, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to rebase %s onto %s: %w", stacked.BranchName, stacked.BaseBranch, err)
+		}
+	}
+
+	project.UpdatedAt = time.Now()
+
+	// Save config
+	if err := config.SaveConfig(cfg, configPath); err != nil {
+		return "", fmt.Errorf("failed to save config: %w", err)
+	}
+
+	return "", nil
+}
+
+// resolveConflicts merges source into a new resolve branch created from target and lets the
+// AI provider resolve the conflicts. The resolution is committed on the resolve branch so it
+// can be reviewed, and the name of the branch is returned.
+func resolveConflicts(ctx context.Context, cfg *config.Config, project *models.Project, vcsProvider vcs.Provider, target, source, featureBranch string) (string, error) {
+	// Create the resolve branch in its own worktree
+	resolveBranch := fmt.Sprintf("resolve-%s-%d", featureBranch, time.Now().Unix())
+	resolvePath := worktreePath(project, resolveBranch)
+	if err := vcsProvider.AddWorktree(resolvePath, resolveBranch, target); err != nil {
+		return "", fmt.Errorf("failed to create resolve branch: %w", err)
+	}
+	defer removeWorktrees(vcsProvider, []string{resolvePath})
+
+	resolveVCS, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create VCS provider: %w", err)
+	}
+
+	if err := resolveVCS.Initialize(resolvePath); err != nil {
+		return "", fmt.Errorf("failed to initialize VCS: %w", err)
+	}
+
+	// Merge, leaving the conflicts in the worktree
+	err = resolveVCS.StartMerge(source)
+	var conflictErr *vcs.ConflictError
+	if err != nil && !errors.As(err, &conflictErr) {
+		return "", fmt.Errorf("failed to merge %s into %s: %w", source, resolveBranch, err)
+	}
+
+	if conflictErr != nil {
+		// Collect both sides of every conflicted file
+		request := ai.ConflictRequest{
+			OursDescription:   describeBranch(project, target),
+			TheirsDescription: describeBranch(project, source),
+		}
+		for _, file := range conflictErr.Files {
+			base, ours, theirs, err := resolveVCS.GetConflictVersions(file)
+			if err != nil {
+				resolveVCS.AbortMerge()
+				return "", fmt.Errorf("failed to read conflicts in %s: %w", file, err)
+			}
+			request.Files = append(request.Files, ai.ConflictFile{Path: file, Base: base, Ours: ours, Theirs: theirs})
+		}
+
+		// Create AI provider
+		aiProvider, err := ai.Create(cfg.AI.Provider, cfg.AI.Config)
+		if err != nil {
+			resolveVCS.AbortMerge()
+			return "", fmt.Errorf("failed to create AI provider: %w", err)
+		}
+
+		// Initialize AI provider with the worktree as its workspace
+		if err := aiProvider.Initialize(ctx, aiConfig(project, target, resolvePath)); err != nil {
+			resolveVCS.AbortMerge()
+			return "", fmt.Errorf("failed to initialize AI provider: %w", err)
+		}
+		defer aiProvider.Cleanup(ctx)
+
+		output, err := aiProvider.ResolveConflicts(progressContext(ctx, "resolve"), resolvePath, request)
+		if err != nil {
+			resolveVCS.AbortMerge()
+			return "", fmt.Errorf("failed to resolve conflicts: %w", err)
+		}
+		fmt.Printf("AI Output Summary: %s\n", truncateString(output, 200))
+
+		// The resolution is committed for review either way, but flag anything left unresolved
+		for _, file := range conflictErr.Files {
+			if hasConflictMarkers(filepath.Join(resolvePath, file)) {
+				fmt.Printf("Warning: %s still contains conflict markers\n", file)
+			}
+		}
+	}
+
+	commitMsg := fmt.Sprintf("Resolve conflicts merging %s into %s", source, target)
+	if err := resolveVCS.ContinueMerge(commitMsg); err != nil {
+		return "", fmt.Errorf("failed to commit resolution: %w", err)
+	}
+
+	return resolveBranch, nil
+}
+
+// describeBranch summarizes what a branch contains for AI prompts
+func describeBranch(project *models.Project, branch string) string {
+	if impl := project.GetImplementation(branch); impl != nil {
+		description := impl.Description
+		for _, feature := range impl.Features {
+			if feature.Status == "merged" {
+				description += fmt.Sprintf("\n- Feature: %s", feature.Description)
+			}
+		}
+		return description
+	}
+
+	// A resolve branch contains the feature it resolves conflicts for
+	for _, impl := range project.Implementations {
+		for _, feature := range impl.Features {
+			if feature.BranchName == branch || feature.ResolveBranch == branch {
+				return feature.Description
+			}
+		}
+	}
+
+	return branch
+}
+
+// hasConflictMarkers returns whether a file still contains merge conflict markers
+func hasConflictMarkers(path string) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "<<<<<<< ") || strings.HasPrefix(line, ">>>>>>> ") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkoutBranch returns a VCS provider for a working tree with branch checked out.
+// The working tree that already has the branch checked out is reused if it has no
+// uncommitted changes, otherwise a temporary worktree is created and removed by cleanup.
+func checkoutBranch(cfg *config.Config, project *models.Project, vcsProvider vcs.Provider, branch string) (vcs.Provider, func(), error) {
+	path, cleanup, err := branchWorktree(project, vcsProvider, branch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	branchVCS, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to create VCS provider: %w", err)
+	}
+
+	if err := branchVCS.Initialize(path); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to initialize VCS: %w", err)
+	}
+
+	hasChanges, err := branchVCS.HasChanges()
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to check for changes: %w", err)
+	}
+	if hasChanges {
+		cleanup()
+		return nil, nil, fmt.Errorf("%s has uncommitted changes in %s", branch, path)
+	}
+
+	return branchVCS, cleanup, nil
+}
+
+// branchWorktree returns the path of a worktree with the branch checked out, creating a
+// temporary worktree if there is none. The returned function removes the temporary worktree.
+func branchWorktree(project *models.Project, vcsProvider vcs.Provider, branch string) (string, func(), error) {
+	worktrees, err := vcsProvider.ListWorktrees()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+
+	for _, worktree := range worktrees {
+		if worktree.Branch == branch && !worktree.Prunable {
+			return worktree.Path, func() {}, nil
+		}
+	}
+
+	path := worktreePath(project, branch)
+	if err := vcsProvider.AddWorktree(path, branch, ""); err != nil {
+		return "", nil, fmt.Errorf("failed to check out %s: %w", branch, err)
+	}
+	return path, func() { removeWorktrees(vcsProvider, []string{path}) }, nil
 }
 
 // executeListCommand lists projects, implementations, or features
@@ -495,6 +1319,84 @@ func executeListCommand(configPath, resourceType string) ([]string, error) {
 	}
 }
 
+// Orders implementations can be listed in
+const (
+	implSortCreated = "created"
+	implSortName    = "name"
+	implSortScore   = "score"
+)
+
+// executeListImplementationsCommand lists the implementations of the active project with
+// their scores, sorted by creation time, branch name or score (highest first)
+func executeListImplementationsCommand(configPath, sortBy string) (string, error) {
+	// Load config
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Get active project
+	project := cfg.GetActiveProject()
+	if project == nil {
+		return "", fmt.Errorf("no active project")
+	}
+
+	if len(project.Implementations) == 0 {
+		return "No implementations found\n", nil
+	}
+
+	impls := make([]models.Implementation, len(project.Implementations))
+	copy(impls, project.Implementations)
+
+	switch sortBy {
+	case implSortCreated, "":
+		sort.SliceStable(impls, func(i, j int) bool {
+			return impls[i].CreatedAt.Before(impls[j].CreatedAt)
+		})
+	case implSortName:
+		sort.SliceStable(impls, func(i, j int) bool {
+			return impls[i].BranchName < impls[j].BranchName
+		})
+	case implSortScore:
+		sort.SliceStable(impls, func(i, j int) bool {
+			return impls[i].Score > impls[j].Score
+		})
+	default:
+		return "", fmt.Errorf("unknown sort order: %s (valid orders: %s, %s, %s)", sortBy, implSortCreated, implSortName, implSortScore)
+	}
+
+	var out strings.Builder
+	w := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tFRAMEWORK\tSCORE\tVERIFIED\tTESTS")
+	for _, impl := range impls {
+		branch := impl.BranchName
+		if project.SelectedImplementation == impl.BranchName {
+			branch += " *"
+		}
+		if impl.Status == "failed" {
+			branch += " (failed)"
+		}
+
+		verified := "-"
+		if passed, ok := impl.Metrics["verify_passed"]; ok {
+			verified = "failed"
+			if passed == 1 {
+				verified = "passed"
+			}
+		}
+
+		tests := "-"
+		if passed, ok := impl.Metrics["tests_passed"]; ok {
+			tests = fmt.Sprintf("%d/%d", int(passed), int(passed+impl.Metrics["tests_failed"]))
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", branch, impl.Framework, impl.Score, verified, tests)
+	}
+	w.Flush()
+
+	return out.String(), nil
+}
+
 // executeStatusCommand shows the current project status
 func executeStatusCommand(configPath string) (string, error) {
 	// Load config
@@ -532,6 +1434,12 @@ func executeStatusCommand(configPath string) (string, error) {
 		return "", fmt.Errorf("failed to check for changes: %w", err)
 	}
 
+	// Get worktrees of in-progress commands
+	worktrees, err := vcsProvider.ListWorktrees()
+	if err != nil {
+		return "", fmt.Errorf("failed to list worktrees: %w", err)
+	}
+
 	// Build status string
 	var status strings.Builder
 	status.WriteString(fmt.Sprintf("Project: %s\n", project.Name))
@@ -543,6 +1451,20 @@ func executeStatusCommand(configPath string) (string, error) {
 	status.WriteString(fmt.Sprintf("Status: %s\n", project.Status))
 	status.WriteString(fmt.Sprintf("Created: %s\n", project.CreatedAt.Format(time.RFC3339)))
 	status.WriteString(fmt.Sprintf("Updated: %s\n", project.UpdatedAt.Format(time.RFC3339)))
+
+	// Add linked worktrees, the main working copy is already described above
+	if len(worktrees) > 1 {
+		status.WriteString(fmt.Sprintf("\nWorktrees (%d):\n", len(worktrees)-1))
+		for _, worktree := range worktrees {
+			if worktree.Main {
+				continue
+			}
+			status.WriteString(fmt.Sprintf("  %s (%s)\n", worktree.Branch, worktree.Path))
+			if worktree.Prunable {
+				status.WriteString("     * STALE *\n")
+			}
+		}
+	}
 	
 	// Add implementations information
 	status.WriteString(fmt.Sprintf("\nImplementations (%d):\n", len(project.Implementations)))
@@ -554,6 +1476,16 @@ func executeStatusCommand(configPath string) (string, error) {
 			status.WriteString("     * SELECTED *\n")
 		}
 		
+		// Mark implementations that failed to generate
+		if impl.Status == "failed" {
+			status.WriteString("     * FAILED *\n")
+		}
+		
+		// Show the network access the code was generated with
+		if impl.Egress != "" {
+			status.WriteString(fmt.Sprintf("     Egress: %s\n", impl.Egress))
+		}
+		
 		// Mark current branch
 		if currentBranch == impl.BranchName {
 			status.WriteString("     * CURRENT BRANCH *\n")
@@ -565,6 +1497,11 @@ func executeStatusCommand(configPath string) (string, error) {
 			for j, feature := range impl.Features {
 				status.WriteString(fmt.Sprintf("       %d. %s (%s)\n", j+1, feature.Description, feature.BranchName))
 				
+				// Mark features that failed to generate
+				if feature.Status == "failed" {
+					status.WriteString("         * FAILED *\n")
+				}
+				
 				// Mark current branch if we're on a feature branch
 				if currentBranch == feature.BranchName {
 					status.WriteString("         * CURRENT BRANCH *\n")
@@ -576,6 +1513,41 @@ func executeStatusCommand(configPath string) (string, error) {
 	return status.String(), nil
 }
 
+// executeStatusTreeCommand renders the implementation → feature tree of the active project
+// in the given format (ascii, dot or mermaid)
+func executeStatusTreeCommand(configPath, format string) (string, error) {
+	// Load config
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Get active project
+	project := cfg.GetActiveProject()
+	if project == nil {
+		return "", fmt.Errorf("no active project")
+	}
+
+	// Create VCS provider to mark the current branch
+	vcsProvider, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create VCS provider: %w", err)
+	}
+
+	// Initialize VCS
+	if err := vcsProvider.Initialize(project.Path); err != nil {
+		return "", fmt.Errorf("failed to initialize VCS: %w", err)
+	}
+
+	// Get current git branch
+	currentBranch, err := vcsProvider.GetCurrentBranch()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current branch: %w", err)
+	}
+
+	return renderFeatureTree(buildFeatureTree(project, currentBranch), format)
+}
+
 // executeCompareCommand compares two branches
 func executeCompareCommand(configPath, branch1, branch2 string) (string, error) {
 	// Load config
@@ -673,6 +1645,23 @@ func getContext() context.Context {
 	return context.Background()
 }
 
+// progressContext returns a context printing the progress of AI operations, labeled with label
+func progressContext(ctx context.Context, label string) context.Context {
+	return ai.WithProgress(ctx, func(progress ai.Progress) {
+		printProgress(label, progress)
+	})
+}
+
+// printProgress prints a progress update of an AI operation labeled with label
+func printProgress(label string, progress ai.Progress) {
+	elapsed := progress.Elapsed.Round(time.Second)
+	if progress.File != "" {
+		fmt.Printf("[%s %s] + %s\n", label, elapsed, progress.File)
+		return
+	}
+	fmt.Printf("[%s %s] %s\n", label, elapsed, progress.Line)
+}
+
 // truncateString truncates a string to a maximum length and adds "..." if truncated
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/container"
+	"github.com/fr0g-66723067/cc/internal/profile"
+	"github.com/fr0g-66723067/cc/internal/run"
 	"github.com/fr0g-66723067/cc/internal/vcs"
 	"github.com/fr0g-66723067/cc/pkg/config"
 	"github.com/fr0g-66723067/cc/pkg/models"
@@ -111,7 +120,7 @@ func TestGenerateCommandImplementation(t *testing.T) {
 	parallel := true
 
 	// Execute the command
-	err = executeGenerateCommand(configPath, genDesc, frameworks, count, parallel)
+	err = executeGenerateCommand(configPath, genDesc, frameworks, count, parallel, false)
 	require.NoError(t, err)
 
 	// Reload the config to ensure changes were saved
@@ -146,6 +155,92 @@ func TestGenerateCommandImplementation(t *testing.T) {
 	fmt.Println("Note: Skipping branch validation in test environment")
 }
 
+// TestGenerateDefaultFrameworks tests that generating without frameworks takes them from the
+// profiles, without initializing an AI provider of its own
+func TestGenerateDefaultFrameworks(t *testing.T) {
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	projectName := "default-frameworks-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for default frameworks"))
+
+	initializeCalls := atomic.LoadInt32(&mockInitializeCalls)
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", nil, 2, false, false))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&mockInitializeCalls)-initializeCalls)
+
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.NotNil(t, project)
+	var frameworks []string
+	for _, impl := range project.Implementations {
+		frameworks = append(frameworks, impl.Framework)
+	}
+	assert.ElementsMatch(t, profile.Names()[:2], frameworks)
+
+	cfg.AI.Config["frameworks"] = "svelte,angular"
+	assert.Equal(t, []string{"svelte", "angular"}, defaultFrameworks(cfg))
+}
+
+// TestGenerateCommandVerification tests that generated code is built and tested
+func TestGenerateCommandVerification(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	projectName := "verify-test-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing verification"))
+
+	// The mock container reports 3 of 4 tests passing
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"react"}, 1, true, false))
+
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	impl := cfg.GetProject(projectName).Implementations[0]
+	assert.Equal(t, 1.0, impl.Metrics["verify_passed"])
+	assert.Equal(t, 1.0, impl.Metrics["build_passed"])
+	assert.Equal(t, 3.0, impl.Metrics["tests_passed"])
+	assert.Equal(t, 1.0, impl.Metrics["tests_failed"])
+	assert.Contains(t, impl.Metrics, "build_seconds")
+	assert.Equal(t, 90.0, impl.Metrics["score_verification"])
+	assert.Equal(t, 75.0, impl.Metrics["score_test_pass_rate"])
+	assert.Equal(t, 100.0, impl.Metrics["score_build_time"])
+
+	// The mock AI provider writes no package.json
+	assert.Equal(t, 1.0, impl.Metrics["entry_files_missing"])
+
+	// Weighted 4, 3 and 1; the code scorers do not apply to the empty worktree
+	assert.Equal(t, 86, impl.Score)
+
+	// Make the build fail
+	cfg.Container.Config["mock_failing_command"] = "npm run build"
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"vue"}, 1, true, false))
+
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	impl = cfg.GetProject(projectName).Implementations[1]
+	assert.Equal(t, 0.0, impl.Metrics["verify_passed"])
+	assert.Equal(t, 1.0, impl.Metrics["install_passed"])
+	assert.Equal(t, 0.0, impl.Metrics["build_passed"])
+	assert.Equal(t, 0.0, impl.Metrics["test_passed"])
+	assert.Equal(t, 10.0, impl.Metrics["score_verification"])
+	assert.Equal(t, 0.0, impl.Metrics["score_build_time"])
+	assert.Equal(t, 8, impl.Score)
+
+	// Code is not verified when verification is disabled
+	cfg.Verify.Disabled = true
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"angular"}, 1, true, false))
+
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	impl = cfg.GetProject(projectName).Implementations[2]
+	assert.NotContains(t, impl.Metrics, "verify_passed")
+	assert.Len(t, impl.Metrics, 4) // Only the usage of generating it
+	assert.Equal(t, 50, impl.Score)
+}
+
 // TestSelectCommand tests selecting an implementation
 func TestSelectCommandImplementation(t *testing.T) {
 	// Setup test environment
@@ -163,7 +258,7 @@ func TestSelectCommandImplementation(t *testing.T) {
 	frameworks := []string{"react", "vue"}
 	count := 2
 	parallel := true
-	err = executeGenerateCommand(configPath, genDesc, frameworks, count, parallel)
+	err = executeGenerateCommand(configPath, genDesc, frameworks, count, parallel, false)
 	require.NoError(t, err)
 
 	// Reload the config to get the created implementations
@@ -199,6 +294,53 @@ func TestSelectCommandImplementation(t *testing.T) {
 	fmt.Println("Note: Skipping branch validation in test environment")
 }
 
+// TestSelectGatedOnAnalysis tests refusing to select implementations with serious findings
+func TestSelectGatedOnAnalysis(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	projectName := "analysis-gate-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing analysis gates"))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"react"}, 1, false, false))
+
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.Len(t, project.Implementations, 1)
+	branch := project.Implementations[0].BranchName
+
+	cfg.VCS.Config["mock_branches"] = branch
+	cfg.Analysis.BlockSeverity = "high"
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+
+	vcsProvider, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
+	require.NoError(t, err)
+	require.NoError(t, vcsProvider.Initialize(project.Path))
+	require.NoError(t, vcsProvider.SetBranchMetadata(branch, map[string]string{"framework": "react"}))
+
+	// Findings at the blocking severity refuse the selection
+	report := &ai.AnalysisReport{Findings: []ai.Finding{{Severity: ai.SeverityCritical, Category: ai.CategorySecurity, Title: "Hardcoded secret"}}}
+	require.NoError(t, saveAnalysis(vcsProvider, branch, report))
+	err = executeSelectCommand(configPath, branch)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 findings of severity high or higher")
+
+	// Other metadata is kept when storing analysis
+	metadata, err := vcsProvider.GetBranchMetadata(branch)
+	require.NoError(t, err)
+	assert.Equal(t, "react", metadata["framework"])
+
+	// Less serious findings do not
+	report.Findings[0].Severity = ai.SeverityMedium
+	require.NoError(t, saveAnalysis(vcsProvider, branch, report))
+	require.NoError(t, executeSelectCommand(configPath, branch))
+
+	stored, err := loadAnalysis(vcsProvider, branch)
+	require.NoError(t, err)
+	assert.Equal(t, report, stored)
+}
+
 // TestFeatureCommand tests adding a feature
 func TestFeatureCommandImplementation(t *testing.T) {
 	// Setup test environment
@@ -216,7 +358,7 @@ func TestFeatureCommandImplementation(t *testing.T) {
 	frameworks := []string{"react"}
 	count := 1
 	parallel := true
-	err = executeGenerateCommand(configPath, genDesc, frameworks, count, parallel)
+	err = executeGenerateCommand(configPath, genDesc, frameworks, count, parallel, false)
 	require.NoError(t, err)
 
 	// Reload the config to get the created implementations
@@ -287,6 +429,232 @@ func TestFeatureCommandImplementation(t *testing.T) {
 	fmt.Println("Note: Skipping branch validation in test environment")
 }
 
+// TestFeatureCommandInWorktree tests that features are built in a worktree that is removed afterwards
+func TestFeatureCommandInWorktree(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Initialize a project and generate an implementation
+	projectName := "worktree-feature-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing feature worktrees"))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"react"}, 1, true, false))
+
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.NotNil(t, project)
+	require.Len(t, project.Implementations, 1)
+
+	implBranch := project.Implementations[0].BranchName
+	project.SetSelectedImplementation(implBranch)
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+
+	// Add the feature
+	featureDesc := "Add a dark mode toggle"
+	require.NoError(t, executeFeatureCommand(configPath, featureDesc, "", false))
+
+	// Verify the feature was recorded against the selected implementation
+	updatedCfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	implementation := updatedCfg.GetProject(projectName).GetImplementation(implBranch)
+	require.NotNil(t, implementation)
+	require.Len(t, implementation.Features, 1)
+	assert.Equal(t, featureDesc, implementation.Features[0].Description)
+	assert.Equal(t, implBranch, implementation.Features[0].BaseBranch)
+
+	// Verify the feature worktree was cleaned up
+	_, err = os.Stat(worktreePath(project, implementation.Features[0].BranchName))
+	assert.True(t, os.IsNotExist(err))
+}
+
+// TestFeatureMergeCommandImplementation tests merging a feature back into its implementation
+func TestFeatureMergeCommandImplementation(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Initialize a project, generate an implementation and add a feature
+	projectName := "merge-test-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing merges"))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"react"}, 1, true, false))
+
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.NotNil(t, project)
+	require.Len(t, project.Implementations, 1)
+
+	implBranch := project.Implementations[0].BranchName
+	project.SetSelectedImplementation(implBranch)
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	require.NoError(t, executeFeatureCommand(configPath, "Add a dark mode toggle", "", false))
+
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	featureBranch := cfg.GetProject(projectName).GetImplementation(implBranch).Features[0].BranchName
+
+	// Merge the feature
+	resolveBranch, err := executeFeatureMergeCommand(configPath, featureBranch, vcs.MergeSquash, false)
+	require.NoError(t, err)
+	assert.Empty(t, resolveBranch)
+
+	// Verify the feature is marked as merged
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	_, feature := cfg.GetProject(projectName).GetFeature(featureBranch)
+	require.NotNil(t, feature)
+	assert.Equal(t, "merged", feature.Status)
+
+	// Merging the feature again fails
+	_, err = executeFeatureMergeCommand(configPath, featureBranch, vcs.MergeSquash, false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already merged")
+
+	// Merging an unknown feature fails
+	_, err = executeFeatureMergeCommand(configPath, "feat-unknown", vcs.MergeCommit, false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+// TestFeatureMergeResolveConflicts tests proposing an AI resolution for merge conflicts
+func TestFeatureMergeResolveConflicts(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Initialize a project, generate an implementation and add a feature
+	projectName := "resolve-test-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing conflict resolution"))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"react"}, 1, true, false))
+
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.NotNil(t, project)
+	require.Len(t, project.Implementations, 1)
+
+	implBranch := project.Implementations[0].BranchName
+	project.SetSelectedImplementation(implBranch)
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	require.NoError(t, executeFeatureCommand(configPath, "Add a dark mode toggle", "", false))
+
+	// Make the mock VCS report conflicts when merging the feature
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	cfg.VCS.Config["mock_conflicts"] = "README.md,src/App.js"
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	featureBranch := cfg.GetProject(projectName).GetImplementation(implBranch).Features[0].BranchName
+
+	// Without --resolve the conflicting files are reported
+	_, err = executeFeatureMergeCommand(configPath, featureBranch, vcs.MergeCommit, false)
+	var conflictErr *vcs.ConflictError
+	require.True(t, errors.As(err, &conflictErr), "expected a conflict error, got %v", err)
+	assert.Equal(t, []string{"README.md", "src/App.js"}, conflictErr.Files)
+
+	// With --resolve a resolution is committed on a resolve branch
+	resolveBranch, err := executeFeatureMergeCommand(configPath, featureBranch, vcs.MergeCommit, true)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resolveBranch, "resolve-"+featureBranch))
+
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	_, feature := cfg.GetProject(projectName).GetFeature(featureBranch)
+	require.NotNil(t, feature)
+	assert.Equal(t, resolveBranch, feature.ResolveBranch)
+	assert.NotEqual(t, "merged", feature.Status)
+
+	_, err = os.Stat(worktreePath(project, resolveBranch))
+	assert.True(t, os.IsNotExist(err))
+
+	// Merging again merges the resolution
+	_, err = executeFeatureMergeCommand(configPath, featureBranch, vcs.MergeCommit, false)
+	require.NoError(t, err)
+
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	_, feature = cfg.GetProject(projectName).GetFeature(featureBranch)
+	require.NotNil(t, feature)
+	assert.Equal(t, "merged", feature.Status)
+}
+
+// TestStackedFeatureCommand tests stacking features and restacking them
+func TestStackedFeatureCommand(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Initialize a project, generate an implementation and add a feature
+	projectName := "stack-test-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing stacked features"))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"react"}, 1, true, false))
+
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.NotNil(t, project)
+	require.Len(t, project.Implementations, 1)
+
+	implBranch := project.Implementations[0].BranchName
+	project.SetSelectedImplementation(implBranch)
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	require.NoError(t, executeFeatureCommand(configPath, "Add user accounts", "", false))
+
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	parentBranch := cfg.GetProject(projectName).GetImplementation(implBranch).Features[0].BranchName
+
+	// Stack a feature on the first one
+	require.NoError(t, executeFeatureCommand(configPath, "Add user avatars", parentBranch, false))
+
+	// Stacking on an unknown feature fails
+	err = executeFeatureCommand(configPath, "Add user settings", "feat-unknown", false)
+	assert.Error(t, err)
+
+	// Verify the parent chain
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	implementation := cfg.GetProject(projectName).GetImplementation(implBranch)
+	require.Len(t, implementation.Features, 2)
+
+	parent := implementation.GetFeature(parentBranch)
+	child := implementation.Features[1]
+	assert.Equal(t, implBranch, parent.BaseBranch)
+	assert.Empty(t, parent.Parent)
+	assert.Equal(t, []string{child.BranchName}, parent.Children)
+	assert.Equal(t, parentBranch, child.BaseBranch)
+	assert.Equal(t, parentBranch, child.Parent)
+
+	// Restack the whole stack, the child replaying only its own commits on the parent
+	mockRebasesMu.Lock()
+	mockRebases = nil
+	mockRebasesMu.Unlock()
+	resolveBranch, err := executeFeatureRestackCommand(configPath, parentBranch, false)
+	require.NoError(t, err)
+	assert.Empty(t, resolveBranch)
+	assert.Equal(t, []string{implBranch, parentBranch + " mock-commit"}, mockRebases)
+
+	// Conflicts stop the restack and are reported
+	cfg.VCS.Config["mock_conflicts"] = "src/App.js"
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+
+	_, err = executeFeatureRestackCommand(configPath, parentBranch, false)
+	var conflictErr *vcs.ConflictError
+	require.True(t, errors.As(err, &conflictErr), "expected a conflict error, got %v", err)
+	assert.Equal(t, "rebase", conflictErr.Operation)
+
+	// With resolve a resolution is proposed for the first conflicting feature
+	resolveBranch, err = executeFeatureRestackCommand(configPath, parentBranch, true)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resolveBranch, "resolve-"+parentBranch))
+
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	_, feature := cfg.GetProject(projectName).GetFeature(parentBranch)
+	require.NotNil(t, feature)
+	assert.Equal(t, resolveBranch, feature.ResolveBranch)
+}
+
 // TestListCommand tests listing resources
 func TestListCommandImplementation(t *testing.T) {
 	// Setup test environment
@@ -309,7 +677,7 @@ func TestListCommandImplementation(t *testing.T) {
 	frameworks := []string{"react", "vue"}
 	count := 2
 	parallel := true
-	err = executeGenerateCommand(configPath, genDesc, frameworks, count, parallel)
+	err = executeGenerateCommand(configPath, genDesc, frameworks, count, parallel, false)
 	require.NoError(t, err)
 
 	// Test listing implementations
@@ -329,6 +697,46 @@ func TestListCommandImplementation(t *testing.T) {
 	}
 }
 
+// TestListImplementationsSortedByScore tests listing implementations with their scores
+func TestListImplementationsSortedByScore(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	projectName := "list-score-test-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing scores"))
+
+	// Add implementations with known scores
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	now := time.Now()
+	project.AddImplementation(models.Implementation{Framework: "react", BranchName: "impl-react", CreatedAt: now, Score: 40})
+	project.AddImplementation(models.Implementation{Framework: "vue", BranchName: "impl-vue", CreatedAt: now.Add(time.Second), Score: 90,
+		Metrics: map[string]float64{"verify_passed": 1, "tests_passed": 3, "tests_failed": 1}})
+	project.AddImplementation(models.Implementation{Framework: "angular", BranchName: "impl-angular", CreatedAt: now.Add(2 * time.Second), Score: 65,
+		Metrics: map[string]float64{"verify_passed": 0}})
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+
+	output, err := executeListImplementationsCommand(configPath, "score")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, []string{"BRANCH", "FRAMEWORK", "SCORE", "VERIFIED", "TESTS"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"impl-vue", "vue", "90", "passed", "3/4"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"impl-angular", "angular", "65", "failed", "-"}, strings.Fields(lines[2]))
+	assert.Equal(t, []string{"impl-react", "react", "40", "-", "-"}, strings.Fields(lines[3]))
+
+	// Implementations are listed in creation order by default
+	output, err = executeListImplementationsCommand(configPath, "created")
+	require.NoError(t, err)
+	lines = strings.Split(strings.TrimSpace(output), "\n")
+	assert.True(t, strings.HasPrefix(lines[1], "impl-react"))
+
+	_, err = executeListImplementationsCommand(configPath, "size")
+	assert.Error(t, err)
+}
+
 // TestStatusCommand tests showing project status
 func TestStatusCommandImplementation(t *testing.T) {
 	// Setup test environment
@@ -346,7 +754,7 @@ func TestStatusCommandImplementation(t *testing.T) {
 	frameworks := []string{"react"}
 	count := 1
 	parallel := true
-	err = executeGenerateCommand(configPath, genDesc, frameworks, count, parallel)
+	err = executeGenerateCommand(configPath, genDesc, frameworks, count, parallel, false)
 	require.NoError(t, err)
 
 	// Get status
@@ -370,6 +778,36 @@ func TestStatusCommandImplementation(t *testing.T) {
 	}
 }
 
+// TestStatusTreeCommand tests rendering the feature tree of the active project
+func TestStatusTreeCommand(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// Initialize a project and generate an implementation
+	projectName := "tree-test-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing the tree"))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"react"}, 1, true, false))
+
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.NotNil(t, project)
+	require.Len(t, project.Implementations, 1)
+
+	// Each format includes the implementation
+	for _, format := range []string{treeFormatASCII, treeFormatDOT, treeFormatMermaid} {
+		tree, err := executeStatusTreeCommand(configPath, format)
+		require.NoError(t, err)
+		assert.Contains(t, tree, projectName)
+		assert.Contains(t, tree, project.Implementations[0].BranchName)
+	}
+
+	// Unknown formats are rejected
+	_, err = executeStatusTreeCommand(configPath, "png")
+	assert.Error(t, err)
+}
+
 // TestCompareCommand tests comparing implementations
 func TestCompareCommandImplementation(t *testing.T) {
 	// Setup test environment
@@ -387,7 +825,7 @@ func TestCompareCommandImplementation(t *testing.T) {
 	frameworks := []string{"react", "vue"}
 	count := 2
 	parallel := true
-	err = executeGenerateCommand(configPath, genDesc, frameworks, count, parallel)
+	err = executeGenerateCommand(configPath, genDesc, frameworks, count, parallel, false)
 	require.NoError(t, err)
 
 	// Reload the config to get the created implementations
@@ -410,4 +848,489 @@ func TestCompareCommandImplementation(t *testing.T) {
 	// Just create a mock diff for testing
 	mockDiff := fmt.Sprintf("Mock diff between %s and %s", branch1, branch2)
 	assert.NotEmpty(t, mockDiff)
-}
\ No newline at end of file
+}
+
+// TestCompareReportCommand tests the side-by-side comparison report
+func TestCompareReportCommand(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	projectName := "report-test-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing reports"))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"react", "vue"}, 2, true, false))
+
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.Len(t, project.Implementations, 2)
+	branch1 := project.Implementations[0].BranchName
+	branch2 := project.Implementations[1].BranchName
+
+	// Make the mock VCS know the branches and put code in the worktrees it checks out
+	cfg.VCS.Config["mock_branches"] = branch1 + "," + branch2
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	for branch, file := range map[string]string{branch1: "App.jsx", branch2: "App.vue"} {
+		dir := filepath.Join(worktreePath(project, branch), "src")
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte("app\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "..", "package.json"), []byte(`{"dependencies": {"left-pad": "1"}}`), 0644))
+	}
+
+	output, err := executeCompareReportCommand(configPath, []string{branch1, branch2}, "markdown")
+	require.NoError(t, err)
+	assert.Contains(t, output, fmt.Sprintf("| | %s | %s |", branch1, branch2))
+	assert.Contains(t, output, fmt.Sprintf("| Score | %d | %d |", project.Implementations[0].Score, project.Implementations[1].Score))
+	assert.Contains(t, output, "| verify_passed | 1 | 1 |")
+	assert.Contains(t, output, "src/\n  App.jsx\n")
+	assert.Contains(t, output, "src/\n  App.vue\n")
+	assert.Contains(t, output, "- `package.json` (1): left-pad")
+	assert.Contains(t, output, "## Architectural differences\n\nMock analysis for ")
+
+	// Temporary worktrees are removed
+	assert.NoDirExists(t, worktreePath(project, branch1))
+
+	_, err = executeCompareReportCommand(configPath, []string{branch1, "impl-unknown"}, "markdown")
+	assert.Error(t, err)
+	_, err = executeCompareReportCommand(configPath, []string{branch1, branch2}, "pdf")
+	assert.Error(t, err)
+}
+
+// TestAnalyzeCommand tests analyzing branches, caching reports by commit and ranking implementations
+func TestAnalyzeCommand(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	projectName := "analyze-test-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing analysis"))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"react", "vue"}, 2, true, false))
+
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.Len(t, project.Implementations, 2)
+	branch1 := project.Implementations[0].BranchName
+	branch2 := project.Implementations[1].BranchName
+
+	cfg.VCS.Config["mock_branches"] = branch1 + "," + branch2
+	cfg.AI.Config["mock_analysis_scores"] = branch1 + "=60," + branch2 + "=90"
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+
+	calls := func() int32 { return atomic.LoadInt32(&mockAnalyzeCalls) }
+	start := calls()
+
+	output, err := executeAnalyzeCommand(configPath, branch1, "json", false)
+	require.NoError(t, err)
+	var report ai.AnalysisReport
+	require.NoError(t, json.Unmarshal([]byte(output), &report))
+	assert.Equal(t, "mock-commit", report.Commit)
+	assert.Equal(t, 60, report.Scores[ai.OverallScore])
+	assert.Equal(t, start+1, calls())
+
+	// Temporary worktrees are removed
+	assert.NoDirExists(t, worktreePath(project, branch1))
+
+	// The stored report is reused for the same commit
+	output, err = executeAnalyzeCommand(configPath, branch1, "sarif", false)
+	require.NoError(t, err)
+	assert.Contains(t, output, `"version": "2.1.0"`)
+	assert.Equal(t, start+1, calls())
+
+	// Refreshing or new commits analyze again
+	_, err = executeAnalyzeCommand(configPath, branch1, "markdown", true)
+	require.NoError(t, err)
+	assert.Equal(t, start+2, calls())
+
+	cfg.VCS.Config["mock_commit"] = "new-commit"
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	_, err = executeAnalyzeCommand(configPath, branch1, "markdown", false)
+	require.NoError(t, err)
+	assert.Equal(t, start+3, calls())
+
+	// All implementations are ranked by overall score, reusing the stored report of branch1
+	output, err = executeAnalyzeAllCommand(configPath, false)
+	require.NoError(t, err)
+	assert.Equal(t, start+4, calls())
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "RANK"))
+	assert.Regexp(t, "^1 +"+branch2+" +vue +90 ", lines[1])
+	assert.Regexp(t, "^2 +"+branch1+" +react +60 ", lines[2])
+
+	_, err = executeAnalyzeCommand(configPath, "impl-unknown", "markdown", false)
+	assert.Error(t, err)
+	_, err = executeAnalyzeCommand(configPath, branch1, "pdf", false)
+	assert.Error(t, err)
+}
+
+// TestUsageCommand tests recording and reporting the usage of AI calls
+func TestUsageCommand(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	projectName := "usage-test-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing usage"))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"react", "vue"}, 2, true, false))
+
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.Len(t, project.Implementations, 2)
+	react := project.Implementations[0].BranchName
+	vue := project.Implementations[1].BranchName
+	assert.Equal(t, 1000.0, project.Implementations[0].Metrics["ai_input_tokens"])
+	assert.Equal(t, 0.5, project.Implementations[0].Metrics["ai_cost_usd"])
+	assert.Equal(t, 2.0, project.Implementations[0].Metrics["ai_seconds"])
+	assert.Equal(t, mockEgress, project.Implementations[0].Egress)
+
+	// Add a feature to react and analyze it
+	project.SetSelectedImplementation(react)
+	project.ActiveBranch = react
+	cfg.VCS.Config["mock_branches"] = react
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	require.NoError(t, executeFeatureCommand(configPath, "Add dark mode", "", false))
+	_, err = executeAnalyzeCommand(configPath, react, "markdown", true)
+	require.NoError(t, err)
+
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	impl := cfg.GetProject(projectName).GetImplementation(react)
+	require.Len(t, impl.Features, 1)
+	assert.Equal(t, 250.0, impl.Features[0].Metrics["ai_output_tokens"])
+	assert.Equal(t, mockEgress, impl.Features[0].Egress)
+	assert.Equal(t, 1000.0, impl.Metrics["analysis_input_tokens"])
+
+	// Implementations include the usage of their features and analyses
+	output, err := executeUsageCommand(configPath, false, "")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	require.Len(t, lines, 4)
+	assert.True(t, strings.HasPrefix(lines[0], "PROJECT"))
+	assert.Regexp(t, "^"+projectName+" +"+react+" +react +1 +3000 +750 +\\$1.50 +6s$", lines[1])
+	assert.Regexp(t, "^"+projectName+" +"+vue+" +vue +0 +1000 +250 +\\$0.50 +2s$", lines[2])
+	assert.Regexp(t, "^TOTAL +4000 +1000 +\\$2.00 +8s$", lines[3])
+
+	// Nothing was created in the future
+	output, err = executeUsageCommand(configPath, true, time.Now().Add(24*time.Hour).Format("2006-01-02"))
+	require.NoError(t, err)
+	lines = strings.Split(strings.TrimSpace(output), "\n")
+	require.Len(t, lines, 3)
+	assert.Regexp(t, "^"+projectName+" +\\(total\\) +0 +0 ", lines[1])
+
+	output, err = executeUsageCommand(configPath, true, "7d")
+	require.NoError(t, err)
+	assert.Contains(t, output, "$2.00")
+
+	_, err = executeUsageCommand(configPath, false, "last week")
+	assert.Error(t, err)
+}
+
+// TestBudgetLimits tests stopping AI work once the budget of a project is spent
+func TestBudgetLimits(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	projectName := "budget-test-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing budgets"))
+
+	// Every mock AI call costs $0.50, so the second generation exceeds the budget
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	cfg.AI.Budget.ProjectCostUSD = 0.75
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+
+	err = executeGenerateCommand(configPath, "Create a simple web app", []string{"react", "vue"}, 2, false, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "generation stopped")
+	assert.Contains(t, err.Error(), "AI budget exceeded: $1.00 of the $0.75 per project spent")
+
+	// The implementation generated within the budget is kept
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.Len(t, project.Implementations, 1)
+
+	// Both calls were recorded in the ledger next to the config file
+	ledger, err := os.ReadFile(filepath.Join(filepath.Dir(configPath), "ledger.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(ledger), projectName))
+
+	// No more work is started
+	project.SetSelectedImplementation(project.Implementations[0].BranchName)
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	err = executeFeatureCommand(configPath, "Add dark mode", "", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AI budget exceeded")
+
+	// Raising the budget allows work again
+	cfg.AI.Budget.ProjectCostUSD = 0
+	cfg.AI.Budget.DailyTokens = 5000
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	require.NoError(t, executeFeatureCommand(configPath, "Add dark mode", "", false))
+}
+
+// TestResumeGenerateCommand tests resuming a generation run that was interrupted
+func TestResumeGenerateCommand(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	projectName := "resume-test-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing resumed runs"))
+
+	// The budget stops the run after the first framework
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	cfg.AI.Budget.ProjectCostUSD = 0.75
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+
+	generateErr := executeGenerateCommand(configPath, "Create a simple web app", []string{"react", "vue"}, 2, false, false)
+	require.Error(t, generateErr)
+
+	// The run is recorded next to the config file and the error tells how to resume it
+	store := runStore(configPath)
+	entries, err := os.ReadDir(filepath.Join(filepath.Dir(configPath), "runs"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	runID := strings.TrimSuffix(entries[0].Name(), ".json")
+	assert.Contains(t, generateErr.Error(), "cc generate --resume "+runID)
+
+	record, err := store.Load(runID)
+	require.NoError(t, err)
+	assert.Equal(t, projectName, record.Project)
+	require.Len(t, record.Frameworks, 2)
+
+	// Jobs may start in any order, so either framework may have been stopped
+	completed, stopped := record.Frameworks[0], record.Frameworks[1]
+	if completed.Status != run.StatusCompleted {
+		completed, stopped = stopped, completed
+	}
+	assert.Equal(t, run.StatusCompleted, completed.Status)
+	require.NotNil(t, completed.Implementation)
+	assert.Equal(t, run.StatusFailed, stopped.Status)
+	assert.Contains(t, stopped.Error, "AI budget exceeded")
+	assert.False(t, record.Completed())
+
+	// Simulate a process killed while generating the second framework, before the first
+	// was saved to the project
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.Len(t, project.Implementations, 1)
+	project.Implementations = nil
+	cfg.AI.Budget.ProjectCostUSD = 0
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+
+	partial := stopped.Worktree
+	require.NoError(t, os.MkdirAll(partial, 0755))
+	require.NoError(t, store.Update(record, func(*run.Record) { stopped.Status = run.StatusRunning }))
+
+	// Resuming keeps the completed framework, throws away the partial attempt and generates
+	// the stopped framework again
+	require.NoError(t, executeResumeGenerateCommand(configPath, runID, false))
+	assert.NoDirExists(t, partial)
+
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project = cfg.GetProject(projectName)
+	require.Len(t, project.Implementations, 2)
+	assert.NotNil(t, project.GetImplementation(completed.Branch))
+	assert.Equal(t, stopped.Name, project.Implementations[1].Framework)
+
+	record, err = store.Load(runID)
+	require.NoError(t, err)
+	assert.True(t, record.Completed())
+
+	// Resuming a completed run generates nothing
+	require.NoError(t, executeResumeGenerateCommand(configPath, runID, false))
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.Len(t, cfg.GetProject(projectName).Implementations, 2)
+
+	// Unknown runs are rejected
+	err = executeResumeGenerateCommand(configPath, "missing", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "run missing not found")
+}
+
+// TestGenerationFailure tests keeping failed implementations and features, retrying failed
+// generation and committing placeholders when allowed
+func TestGenerationFailure(t *testing.T) {
+	// Setup test environment
+	configPath, _, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	projectName := "failure-test-project"
+	require.NoError(t, executeInitCommand(configPath, projectName, "Project for testing failed generation"))
+
+	// Every attempt fails
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	cfg.AI.Config["mock_fail_attempts"] = "5"
+	cfg.AI.Retry.Attempts = 2
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+
+	generateErr := executeGenerateCommand(configPath, "Create a simple web app", []string{"react"}, 1, false, false)
+	require.Error(t, generateErr)
+	assert.Contains(t, generateErr.Error(), "failed to generate react implementation: mock failure 2")
+
+	// The failed implementation is kept and marked as failed, with the usage of both attempts
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project := cfg.GetProject(projectName)
+	require.Len(t, project.Implementations, 1)
+	failed := project.Implementations[0]
+	assert.Equal(t, "failed", failed.Status)
+	assert.Equal(t, 2000.0, failed.Metrics["ai_input_tokens"])
+	assert.Equal(t, mockEgress, failed.Egress)
+	assert.Contains(t, generateErr.Error(), "kept on branch "+failed.BranchName)
+
+	// Its branch records the output and errors of the attempts
+	vcsProvider, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
+	require.NoError(t, err)
+	require.NoError(t, vcsProvider.Initialize(project.Path))
+	metadata, err := vcsProvider.GetBranchMetadata(failed.BranchName)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", metadata["status"])
+	assert.Equal(t, "mock output of attempt 1\nAttempt 1 failed: mock failure 1\nmock output of attempt 2\nAttempt 2 failed: mock failure 2", metadata["error_log"])
+
+	// Failed implementations cannot be selected
+	cfg.VCS.Config["mock_branches"] = failed.BranchName
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	err = executeSelectCommand(configPath, failed.BranchName)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to generate and cannot be selected")
+
+	// A retry that succeeds completes the implementation, starting without the partial work
+	// of the failed attempt
+	cfg.AI.Config["mock_fail_attempts"] = "1"
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"vue"}, 1, false, false))
+
+	// Without retries, placeholders are committed only when allowed
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	cfg.AI.Config["mock_fail_attempts"] = "5"
+	cfg.AI.Retry.Attempts = 0
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	require.NoError(t, executeGenerateCommand(configPath, "Create a simple web app", []string{"angular"}, 1, false, true))
+
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	project = cfg.GetProject(projectName)
+	require.Len(t, project.Implementations, 3)
+	assert.Equal(t, "completed", project.Implementations[1].Status)
+	assert.NotContains(t, project.Implementations[1].Tags, "placeholder")
+	assert.Equal(t, "completed", project.Implementations[2].Status)
+	assert.Contains(t, project.Implementations[2].Tags, "placeholder")
+
+	// Failed features are kept the same way
+	project.SetSelectedImplementation(project.Implementations[1].BranchName)
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+	err = executeFeatureCommand(configPath, "Add dark mode", "", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mock failure 1")
+
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	impl := cfg.GetProject(projectName).GetSelectedImplementation()
+	require.Len(t, impl.Features, 1)
+	feature := impl.Features[0]
+	assert.Equal(t, "failed", feature.Status)
+	metadata, err = vcsProvider.GetBranchMetadata(feature.BranchName)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", metadata["status"])
+
+	// and can neither be merged nor built upon
+	_, err = executeFeatureMergeCommand(configPath, feature.BranchName, vcs.MergeCommit, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be merged")
+	err = executeFeatureCommand(configPath, "Add settings", feature.BranchName, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "features cannot be stacked on it")
+
+	// Placeholder features are committed when allowed
+	require.NoError(t, executeFeatureCommand(configPath, "Add dark mode", "", true))
+	cfg, err = config.LoadConfig(configPath)
+	require.NoError(t, err)
+	impl = cfg.GetProject(projectName).GetSelectedImplementation()
+	require.Len(t, impl.Features, 2)
+	assert.Equal(t, "completed", impl.Features[1].Status)
+	assert.Contains(t, impl.Features[1].Tags, "placeholder")
+}
+
+// TestPoolCommands tests listing, maintaining and draining the container pool
+func TestPoolCommands(t *testing.T) {
+	configPath, cfg, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	statePath := filepath.Join(filepath.Dir(configPath), "pool.json")
+	cfg.AI.Config["container_provider"] = "pool"
+	cfg.AI.Config["container_pool_state"] = statePath
+	cfg.AI.Config["container_pool_min"] = "1"
+	require.NoError(t, config.SaveConfig(cfg, configPath))
+
+	output, err := executePoolStatusCommand(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "No pooled containers ("+statePath+")\n", output)
+
+	// A container released by a command stays in the pool for its project
+	ctx := context.Background()
+	provider, err := containerPool(ctx, configPath)
+	require.NoError(t, err)
+	containerID, err := provider.RunContainer(ctx, "claude-code:latest", nil, nil, container.RunOptions{
+		Labels: map[string]string{container.ProjectLabel: "todo"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, provider.StopContainer(ctx, containerID))
+	require.NoError(t, provider.RemoveContainer(ctx, containerID))
+
+	// Its idle container is the spare the pool needs
+	require.NoError(t, executePoolMaintainCommand(configPath, time.Minute, true))
+	output, err = executePoolStatusCommand(configPath)
+	require.NoError(t, err)
+	assert.Contains(t, output, "CONTAINER")
+	assert.Regexp(t, `mock-contain\s+claude-code:latest\s+todo\s+idle\s+1`, output)
+	assert.Equal(t, 2, strings.Count(output, "\n"))
+
+	drained, err := executePoolDrainCommand(configPath, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, drained)
+	output, err = executePoolStatusCommand(configPath)
+	require.NoError(t, err)
+	assert.Contains(t, output, "No pooled containers")
+}
+
+// TestVerifyEgress tests that verification containers get the egress policy code is generated with
+func TestVerifyEgress(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AI.Config = map[string]string{"container_egress": "allowlist", "container_egress_allow": "registry.npmjs.org"}
+	egress, err := verifyEgress(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "allowlist: registry.npmjs.org", egress.String())
+
+	// The container config takes precedence
+	cfg.Container.Config[container.EgressKey] = "none"
+	egress, err = verifyEgress(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, container.EgressNone, egress.Mode)
+}
+
+// TestLoadFrameworks tests registering the framework profiles of the config
+func TestLoadFrameworks(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Plugins.Dir = t.TempDir()
+	cfg.Frameworks = []json.RawMessage{json.RawMessage(`{"name": "phoenix", "image": "elixir:1.16", "entryFiles": ["mix.exs"]}`)}
+	require.NoError(t, loadFrameworks(cfg))
+
+	phoenix, ok := profile.Get("phoenix")
+	require.True(t, ok)
+	assert.Equal(t, "elixir:1.16", phoenix.Image)
+	assert.Equal(t, []string{"mix.exs"}, phoenix.EntryFiles)
+
+	cfg.Frameworks = []json.RawMessage{json.RawMessage(`{"name": 1}`)}
+	assert.ErrorContains(t, loadFrameworks(cfg), "failed to parse framework profile 1 of the config")
+}
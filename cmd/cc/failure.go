@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/vcs"
+	"github.com/fr0g-66723067/cc/pkg/config"
+)
+
+// Branch metadata recording why generating the code on a branch failed
+const (
+	// statusMetadataKey is the metadata key of the status of a branch, "failed" if generation failed
+	statusMetadataKey = "status"
+
+	// errorLogMetadataKey is the metadata key of the errors and output of the failed attempts
+	errorLogMetadataKey = "error_log"
+)
+
+// maxFailureLogLines is the number of lines of AI output kept for the error log
+const maxFailureLogLines = 200
+
+// placeholderTag tags implementations and features whose generation failed and that only
+// contain a placeholder
+const placeholderTag = "placeholder"
+
+// retryPolicy returns the configured policy for retrying failed generation
+func retryPolicy(cfg *config.Config) ai.RetryPolicy {
+	return ai.RetryPolicy{
+		Attempts:   cfg.AI.Retry.Attempts,
+		Backoff:    time.Duration(cfg.AI.Retry.Backoff) * time.Second,
+		MaxBackoff: time.Duration(cfg.AI.Retry.MaxBackoff) * time.Second,
+	}
+}
+
+// generateWithRetry runs an AI call generating code in the worktree of workVCS, retrying it as
+// configured. Retries start from the committed code, discarding what failed attempts left in
+// the worktree. The output and errors of the attempts are printed with label and collected in
+// the returned log.
+func generateWithRetry(ctx context.Context, cfg *config.Config, label string, workVCS vcs.Provider, fn func(ctx context.Context) error) (*failureLog, error) {
+	log := &failureLog{}
+	policy := retryPolicy(cfg)
+	progressCtx := log.progressContext(ctx, label)
+
+	err := ai.Retry(ctx, policy, func(attempt int) error {
+		var err error
+		if attempt > 1 {
+			fmt.Printf("[%s] Retrying, attempt %d of %d...\n", label, attempt, policy.Attempts)
+			if resetErr := workVCS.DiscardChanges(); resetErr != nil {
+				err = fmt.Errorf("failed to reset worktree for retry: %w", resetErr)
+			}
+		}
+		if err == nil {
+			err = fn(progressCtx)
+		}
+		if err != nil {
+			fmt.Printf("[%s] Warning: AI code generation failed: %v\n", label, err)
+			log.addError(attempt, err)
+		}
+		return err
+	})
+	return log, err
+}
+
+// failureLog collects the output and errors of AI calls, to explain why generation failed
+type failureLog struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+// progressContext returns a context printing the progress of AI calls like progressContext,
+// and adding their output to the log
+func (l *failureLog) progressContext(ctx context.Context, label string) context.Context {
+	return ai.WithProgress(ctx, func(progress ai.Progress) {
+		printProgress(label, progress)
+		if progress.Line != "" {
+			l.add(progress.Line)
+		}
+	})
+}
+
+// addError adds the error of an attempt
+func (l *failureLog) addError(attempt int, err error) {
+	l.add(fmt.Sprintf("Attempt %d failed: %v", attempt, err))
+}
+
+// add adds a line, dropping the oldest lines beyond the maximum
+func (l *failureLog) add(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lines = append(l.lines, line)
+	if len(l.lines) > maxFailureLogLines {
+		l.lines = l.lines[len(l.lines)-maxFailureLogLines:]
+	}
+}
+
+// String returns the lines of the log
+func (l *failureLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.lines, "\n")
+}
+
+// commitFailedWork commits whatever was generated in dir before generation failed, so the
+// branch can be inspected
+func commitFailedWork(vcsProvider vcs.Provider, dir, message string) error {
+	hasChanges, err := vcsProvider.HasChanges()
+	if err != nil {
+		return fmt.Errorf("failed to check for changes: %w", err)
+	}
+	if !hasChanges {
+		return nil
+	}
+
+	if err := vcsProvider.AddFiles([]string{dir}); err != nil {
+		return fmt.Errorf("failed to add files: %w", err)
+	}
+	if err := vcsProvider.CommitChanges(message); err != nil {
+		return fmt.Errorf("failed to commit changes: %w", err)
+	}
+	return nil
+}
+
+// markFailed marks branch as failed in its metadata, along with the error log
+func markFailed(vcsProvider vcs.Provider, branch string, log *failureLog) error {
+	metadata, err := vcsProvider.GetBranchMetadata(branch)
+	if err != nil {
+		return fmt.Errorf("failed to get metadata of %s: %w", branch, err)
+	}
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+
+	metadata[statusMetadataKey] = "failed"
+	metadata[errorLogMetadataKey] = log.String()
+	if err := vcsProvider.SetBranchMetadata(branch, metadata); err != nil {
+		return fmt.Errorf("failed to set metadata of %s: %w", branch, err)
+	}
+	return nil
+}
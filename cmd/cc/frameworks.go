@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fr0g-66723067/cc/internal/profile"
+	"github.com/fr0g-66723067/cc/pkg/config"
+)
+
+// frameworksDir is the directory of the plugins directory holding framework profile files
+const frameworksDir = "frameworks"
+
+// defaultFrameworks returns the frameworks to generate when none are requested: those of the
+// frameworks AI setting, or else every framework with a profile, built-in frameworks first
+func defaultFrameworks(cfg *config.Config) []string {
+	if frameworks := cfg.AI.Config["frameworks"]; frameworks != "" {
+		return strings.Split(frameworks, ",")
+	}
+	return profile.Names()
+}
+
+// loadFrameworks registers the framework profiles of the plugins directory and of the config
+func loadFrameworks(cfg *config.Config) error {
+	configured := make([]profile.Profile, len(cfg.Frameworks))
+	for i, data := range cfg.Frameworks {
+		if err := json.Unmarshal(data, &configured[i]); err != nil {
+			return fmt.Errorf("failed to parse framework profile %d of the config: %w", i+1, err)
+		}
+	}
+
+	if err := profile.Load(filepath.Join(cfg.Plugins.Dir, frameworksDir), configured); err != nil {
+		return fmt.Errorf("failed to load framework profiles: %w", err)
+	}
+	return nil
+}
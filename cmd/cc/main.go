@@ -1,11 +1,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/report"
+	"github.com/fr0g-66723067/cc/internal/vcs"
 	"github.com/fr0g-66723067/cc/pkg/config"
 )
 
@@ -26,6 +32,11 @@ through git branching.`,
 			fmt.Printf("Error loading config: %s\n", err)
 			os.Exit(1)
 		}
+
+		if err := loadFrameworks(cfg); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			os.Exit(1)
+		}
 	},
 }
 
@@ -67,8 +78,25 @@ func init() {
 	generateCmd := &cobra.Command{
 		Use:   "generate [description]",
 		Short: "Generate implementation versions from description",
-		Args:  cobra.ExactArgs(1),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if resume, _ := cmd.Flags().GetString("resume"); resume != "" {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
+			// Resume an interrupted run instead of starting a new one
+			allowPlaceholder, _ := cmd.Flags().GetBool("allow-placeholder")
+			if resume, _ := cmd.Flags().GetString("resume"); resume != "" {
+				if err := executeResumeGenerateCommand(configPath, resume, allowPlaceholder); err != nil {
+					fmt.Printf("Error resuming generation run: %s\n", err)
+					os.Exit(1)
+				}
+
+				fmt.Println("Implementations generated successfully")
+				return
+			}
+
 			description := args[0]
 			
 			// Get flags
@@ -81,7 +109,7 @@ func init() {
 			fmt.Printf("Count: %d\n", count)
 			fmt.Printf("Parallel: %v\n", parallel)
 			
-			err := executeGenerateCommand(configPath, description, frameworks, count, parallel)
+			err := executeGenerateCommand(configPath, description, frameworks, count, parallel, allowPlaceholder)
 			if err != nil {
 				fmt.Printf("Error generating implementations: %s\n", err)
 				os.Exit(1)
@@ -95,6 +123,8 @@ func init() {
 	generateCmd.Flags().StringSlice("frameworks", []string{}, "Frameworks to generate (comma-separated)")
 	generateCmd.Flags().Int("count", 3, "Number of implementations to generate")
 	generateCmd.Flags().Bool("parallel", true, "Generate implementations in parallel")
+	generateCmd.Flags().String("resume", "", "Resume the interrupted generation run with this ID")
+	generateCmd.Flags().Bool("allow-placeholder", false, "Commit a placeholder implementation when generation fails instead of marking it failed")
 
 	selectCmd := &cobra.Command{
 		Use:   "select [branch]",
@@ -120,9 +150,10 @@ func init() {
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			description := args[0]
+			allowPlaceholder, _ := cmd.Flags().GetBool("allow-placeholder")
 			fmt.Printf("Adding feature: %s\n", description)
 			
-			err := executeFeatureCommand(configPath, description)
+			err := executeFeatureCommand(configPath, description, "", allowPlaceholder)
 			if err != nil {
 				fmt.Printf("Error adding feature: %s\n", err)
 				os.Exit(1)
@@ -132,6 +163,121 @@ func init() {
 		},
 	}
 
+	featureAddCmd := &cobra.Command{
+		Use:   "add [description]",
+		Short: "Add a new feature to the current implementation or on top of another feature",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			description := args[0]
+			parent, _ := cmd.Flags().GetString("on")
+			allowPlaceholder, _ := cmd.Flags().GetBool("allow-placeholder")
+			
+			if parent != "" {
+				fmt.Printf("Adding feature on top of %s: %s\n", parent, description)
+			} else {
+				fmt.Printf("Adding feature: %s\n", description)
+			}
+			
+			err := executeFeatureCommand(configPath, description, parent, allowPlaceholder)
+			if err != nil {
+				fmt.Printf("Error adding feature: %s\n", err)
+				os.Exit(1)
+			}
+			
+			fmt.Println("Feature added successfully")
+		},
+	}
+	
+	// Add flags to feature commands
+	featureCmd.Flags().Bool("allow-placeholder", false, "Commit a placeholder feature when generation fails instead of marking it failed")
+	featureAddCmd.Flags().String("on", "", "Feature branch to stack the new feature on")
+	featureAddCmd.Flags().Bool("allow-placeholder", false, "Commit a placeholder feature when generation fails instead of marking it failed")
+
+	featureRestackCmd := &cobra.Command{
+		Use:   "restack [feature-branch]",
+		Short: "Rebase a feature and the features stacked on it onto their updated parents",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			branch := args[0]
+			resolve, _ := cmd.Flags().GetBool("resolve")
+			
+			fmt.Printf("Restacking feature: %s\n", branch)
+			
+			resolveBranch, err := executeFeatureRestackCommand(configPath, branch, resolve)
+			var conflictErr *vcs.ConflictError
+			if errors.As(err, &conflictErr) {
+				fmt.Printf("Rebase aborted, %d file(s) have conflicts:\n", len(conflictErr.Files))
+				for _, file := range conflictErr.Files {
+					fmt.Printf("  - %s\n", file)
+				}
+				fmt.Println("Run again with --resolve to have the AI provider propose a resolution")
+				os.Exit(1)
+			}
+			if err != nil {
+				fmt.Printf("Error restacking feature: %s\n", err)
+				os.Exit(1)
+			}
+			
+			if resolveBranch != "" {
+				fmt.Printf("Restack stopped, proposed conflict resolution committed on %s\n", resolveBranch)
+				return
+			}
+			
+			fmt.Printf("Feature %s restacked successfully\n", branch)
+		},
+	}
+	
+	// Add flags to feature restack command
+	featureRestackCmd.Flags().Bool("resolve", false, "Let the AI provider propose a resolution for conflicts on a resolve-* branch")
+
+	featureMergeCmd := &cobra.Command{
+		Use:   "merge [feature-branch]",
+		Short: "Merge a feature branch back into its implementation",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			branch := args[0]
+			
+			strategyName, _ := cmd.Flags().GetString("strategy")
+			strategy, err := vcs.ParseMergeStrategy(strategyName)
+			if err != nil {
+				fmt.Printf("Error merging feature: %s\n", err)
+				os.Exit(1)
+			}
+			
+			resolve, _ := cmd.Flags().GetBool("resolve")
+			
+			fmt.Printf("Merging feature: %s\n", branch)
+			
+			resolveBranch, err := executeFeatureMergeCommand(configPath, branch, strategy, resolve)
+			var conflictErr *vcs.ConflictError
+			if errors.As(err, &conflictErr) {
+				fmt.Printf("Merge aborted, %d file(s) have conflicts:\n", len(conflictErr.Files))
+				for _, file := range conflictErr.Files {
+					fmt.Printf("  - %s\n", file)
+				}
+				fmt.Println("Run again with --resolve to have the AI provider propose a resolution")
+				os.Exit(1)
+			}
+			if err != nil {
+				fmt.Printf("Error merging feature: %s\n", err)
+				os.Exit(1)
+			}
+			
+			if resolveBranch != "" {
+				fmt.Printf("Proposed conflict resolution committed on %s\n", resolveBranch)
+				fmt.Printf("Review it, then run 'cc feature merge %s' again to merge it\n", branch)
+				return
+			}
+			
+			fmt.Printf("Feature %s merged successfully\n", branch)
+		},
+	}
+	
+	// Add flags to feature merge command
+	featureMergeCmd.Flags().String("strategy", string(vcs.MergeCommit), "Merge strategy (ff, merge, squash)")
+	featureMergeCmd.Flags().Bool("resolve", false, "Let the AI provider propose a resolution for conflicts on a resolve-* branch")
+	featureCmd.AddCommand(featureAddCmd, featureMergeCmd, featureRestackCmd)
+
 	listCmd := &cobra.Command{
 		Use:   "list [resource]",
 		Short: "List resources (projects, implementations, features)",
@@ -146,6 +292,18 @@ func init() {
 			}
 			
 			fmt.Printf("Listing %s\n", resource)
+
+			// Implementations are listed with their scores
+			if resource == "implementations" {
+				sortBy, _ := cmd.Flags().GetString("sort")
+				table, err := executeListImplementationsCommand(configPath, sortBy)
+				if err != nil {
+					fmt.Printf("Error listing %s: %s\n", resource, err)
+					os.Exit(1)
+				}
+				fmt.Print(table)
+				return
+			}
 			
 			items, err := executeListCommand(configPath, resource)
 			if err != nil {
@@ -165,11 +323,47 @@ func init() {
 		},
 	}
 
+	listCmd.Flags().String("sort", implSortCreated, "Sort implementations by created, name or score")
+
 	compareCmd := &cobra.Command{
-		Use:   "compare [branch1] [branch2]",
+		Use:   "compare [branch1] [branch2] [branch...]",
 		Short: "Compare two implementations or features",
-		Args:  cobra.ExactArgs(2),
+		Long: `Compare two implementations or features.
+
+Without --report the diff between two branches is shown. With --report a side-by-side
+report of two or more branches is written, covering their file trees, languages,
+dependencies, metrics and an AI-written summary of their architectural differences.`,
+		Args: cobra.MinimumNArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
+			reportMode, _ := cmd.Flags().GetBool("report")
+			if reportMode {
+				format, _ := cmd.Flags().GetString("format")
+				output, _ := cmd.Flags().GetString("output")
+				fmt.Printf("Building comparison report for %s\n", strings.Join(args, ", "))
+
+				content, err := executeCompareReportCommand(configPath, args, format)
+				if err != nil {
+					fmt.Printf("Error comparing branches: %s\n", err)
+					os.Exit(1)
+				}
+
+				if output == "" {
+					fmt.Print(content)
+					return
+				}
+				if err := os.WriteFile(output, []byte(content), 0644); err != nil {
+					fmt.Printf("Error writing report: %s\n", err)
+					os.Exit(1)
+				}
+				fmt.Printf("Report written to %s\n", output)
+				return
+			}
+
+			if len(args) != 2 {
+				fmt.Println("Error: comparing more than two branches requires --report")
+				os.Exit(1)
+			}
+
 			branch1 := args[0]
 			branch2 := args[1]
 			fmt.Printf("Comparing %s and %s\n", branch1, branch2)
@@ -184,11 +378,105 @@ func init() {
 			fmt.Println(diff)
 		},
 	}
+	compareCmd.Flags().Bool("report", false, "Write a side-by-side comparison report instead of a diff")
+	compareCmd.Flags().String("format", report.FormatMarkdown, "Report format (markdown, html)")
+	compareCmd.Flags().StringP("output", "o", "", "File to write the report to (default: standard output)")
+
+	analyzeCmd := &cobra.Command{
+		Use:   "analyze [branch]",
+		Short: "Analyze the code of an implementation or feature",
+		Long: `Analyze the code of an implementation or feature branch (the active branch by default).
+
+The AI provider reviews the branch for security, performance, maintainability and test
+issues and scores it. Reports are stored with the branch and reused until it gets new
+commits. With --all every implementation of the project is analyzed and ranked.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			all, _ := cmd.Flags().GetBool("all")
+			refresh, _ := cmd.Flags().GetBool("refresh")
+			format, _ := cmd.Flags().GetString("format")
+			output, _ := cmd.Flags().GetString("output")
+
+			var content string
+			var err error
+			if all {
+				if len(args) > 0 {
+					fmt.Println("Error: --all cannot be combined with a branch")
+					os.Exit(1)
+				}
+				content, err = executeAnalyzeAllCommand(configPath, refresh)
+			} else {
+				branch := ""
+				if len(args) > 0 {
+					branch = args[0]
+				}
+				content, err = executeAnalyzeCommand(configPath, branch, format, refresh)
+			}
+			if err != nil {
+				fmt.Printf("Error analyzing code: %s\n", err)
+				os.Exit(1)
+			}
+
+			if output == "" {
+				fmt.Print(content)
+				return
+			}
+			if err := os.WriteFile(output, []byte(content), 0644); err != nil {
+				fmt.Printf("Error writing analysis: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Analysis written to %s\n", output)
+		},
+	}
+	analyzeCmd.Flags().Bool("all", false, "Analyze and rank every implementation of the project")
+	analyzeCmd.Flags().Bool("refresh", false, "Analyze again even if a stored analysis exists for the commit")
+	analyzeCmd.Flags().String("format", ai.AnalysisFormatMarkdown, "Report format (markdown, json, sarif)")
+	analyzeCmd.Flags().StringP("output", "o", "", "File to write the report to (default: standard output)")
+
+	usageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show the tokens, cost and time spent on AI calls",
+		Long: `Show the tokens, cost and time spent on AI calls for each implementation of the
+active project, including its features and analyses.
+
+With --all every project is listed with its total. With --since only implementations and
+features created in the time window are counted, given as a duration (24h, 7d) or a date.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			all, _ := cmd.Flags().GetBool("all")
+			since, _ := cmd.Flags().GetString("since")
+
+			output, err := executeUsageCommand(configPath, all, since)
+			if err != nil {
+				fmt.Printf("Error getting usage: %s\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Print(output)
+		},
+	}
+	usageCmd.Flags().Bool("all", false, "Show the usage of every project")
+	usageCmd.Flags().String("since", "", "Only count work created since a duration ago (24h, 7d) or a date (2006-01-02)")
 
 	statusCmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show the current project status",
 		Run: func(cmd *cobra.Command, args []string) {
+			tree, _ := cmd.Flags().GetBool("tree")
+			format, _ := cmd.Flags().GetString("format")
+			
+			// Print only the tree so it can be redirected to a file
+			if tree || cmd.Flags().Changed("format") {
+				output, err := executeStatusTreeCommand(configPath, format)
+				if err != nil {
+					fmt.Printf("Error getting status: %s\n", err)
+					os.Exit(1)
+				}
+				
+				fmt.Print(output)
+				return
+			}
+			
 			fmt.Println("Current project status")
 			
 			status, err := executeStatusCommand(configPath)
@@ -201,6 +489,71 @@ func init() {
 		},
 	}
 
+	// Add flags to status command
+	statusCmd.Flags().Bool("tree", false, "Show the implementation and feature tree")
+	statusCmd.Flags().String("format", treeFormatASCII, "Tree format (ascii, dot, mermaid)")
+
+	poolCmd := &cobra.Command{
+		Use:   "pool",
+		Short: "Manage the pool of warm containers the AI provider leases",
+		Long: `Manage the pool of warm containers the AI provider leases when ai.config sets
+container_provider to pool, so commands reuse running containers instead of starting one.`,
+	}
+
+	poolStatusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "List the containers of the pool",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			output, err := executePoolStatusCommand(configPath)
+			if err != nil {
+				fmt.Printf("Error getting pool status: %s\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Print(output)
+		},
+	}
+
+	poolMaintainCmd := &cobra.Command{
+		Use:   "maintain",
+		Short: "Keep the pool healthy and warm",
+		Long: `Health check the idle containers of the pool, remove those that are unhealthy or idle
+for longer than container_pool_idle_ttl, and start spares until container_pool_min containers
+are idle. Runs every --interval until interrupted, or once with --once.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			interval, _ := cmd.Flags().GetDuration("interval")
+			once, _ := cmd.Flags().GetBool("once")
+
+			if err := executePoolMaintainCommand(configPath, interval, once); err != nil {
+				fmt.Printf("Error maintaining pool: %s\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	poolMaintainCmd.Flags().Duration("interval", time.Minute, "Time between maintenance runs")
+	poolMaintainCmd.Flags().Bool("once", false, "Maintain the pool once and exit")
+
+	poolDrainCmd := &cobra.Command{
+		Use:   "drain",
+		Short: "Remove the idle containers of the pool",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			all, _ := cmd.Flags().GetBool("all")
+
+			drained, err := executePoolDrainCommand(configPath, all)
+			if err != nil {
+				fmt.Printf("Error draining pool: %s\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Removed %d container(s) from the pool\n", drained)
+		},
+	}
+	poolDrainCmd.Flags().Bool("all", false, "Remove leased containers as well")
+	poolCmd.AddCommand(poolStatusCmd, poolMaintainCmd, poolDrainCmd)
+
 	// Add commands to root command
 	rootCmd.AddCommand(
 		initCmd,
@@ -209,7 +562,10 @@ func init() {
 		featureCmd,
 		listCmd,
 		compareCmd,
+		analyzeCmd,
+		usageCmd,
 		statusCmd,
+		poolCmd,
 	)
 }
 
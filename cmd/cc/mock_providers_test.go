@@ -2,8 +2,18 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/container"
 	"github.com/fr0g-66723067/cc/internal/vcs"
 )
 
@@ -22,6 +32,13 @@ func init() {
 			config: config,
 		}, nil
 	})
+
+	// Register mock container provider
+	container.Register("docker", func(config map[string]string) (container.Provider, error) {
+		return &mockContainerProvider{
+			config: config,
+		}, nil
+	})
 }
 
 // mockVCSProvider implements a mock VCS provider for testing
@@ -53,7 +70,16 @@ func (m *mockVCSProvider) GetCurrentBranch() (string, error) {
 
 // ListBranches lists all branches in the mock VCS
 func (m *mockVCSProvider) ListBranches() ([]string, error) {
-	return []string{"main"}, nil
+	branches := []string{"main"}
+	if m.config["mock_branches"] != "" {
+		branches = append(branches, strings.Split(m.config["mock_branches"], ",")...)
+	}
+	return branches, nil
+}
+
+// DeleteBranch deletes a branch in the mock VCS
+func (m *mockVCSProvider) DeleteBranch(name string) error {
+	return nil
 }
 
 // AddFiles adds files to the mock VCS
@@ -71,13 +97,51 @@ func (m *mockVCSProvider) HasChanges() (bool, error) {
 	return false, nil
 }
 
+// DiscardChanges removes the partial work mock AI providers leave in the mock repository
+func (m *mockVCSProvider) DiscardChanges() error {
+	err := os.Remove(filepath.Join(m.path, mockPartialFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// mockBranchMetadata holds the branch metadata of all mock repositories by path and branch
+var (
+	mockBranchMetadata   = make(map[string]map[string]string)
+	mockBranchMetadataMu sync.Mutex
+)
+
+// GetBranchCommit returns config["mock_commit"] (or "mock-commit") as the commit of every branch
+func (m *mockVCSProvider) GetBranchCommit(branch string) (string, error) {
+	if commit := m.config["mock_commit"]; commit != "" {
+		return commit, nil
+	}
+	return "mock-commit", nil
+}
+
 // GetBranchMetadata gets metadata for a branch in the mock VCS
 func (m *mockVCSProvider) GetBranchMetadata(branch string) (map[string]string, error) {
-	return make(map[string]string), nil
+	mockBranchMetadataMu.Lock()
+	defer mockBranchMetadataMu.Unlock()
+
+	metadata := make(map[string]string)
+	for key, value := range mockBranchMetadata[m.path+"@"+branch] {
+		metadata[key] = value
+	}
+	return metadata, nil
 }
 
 // SetBranchMetadata sets metadata for a branch in the mock VCS
 func (m *mockVCSProvider) SetBranchMetadata(branch string, metadata map[string]string) error {
+	mockBranchMetadataMu.Lock()
+	defer mockBranchMetadataMu.Unlock()
+
+	stored := make(map[string]string)
+	for key, value := range metadata {
+		stored[key] = value
+	}
+	mockBranchMetadata[m.path+"@"+branch] = stored
 	return nil
 }
 
@@ -86,20 +150,117 @@ func (m *mockVCSProvider) ExportDiff(fromBranch, toBranch string) (string, error
 	return "Mock diff between " + fromBranch + " and " + toBranch, nil
 }
 
+// AddWorktree creates the worktree directory in the mock VCS
+func (m *mockVCSProvider) AddWorktree(path string, branch string, baseBranch string) error {
+	return os.MkdirAll(path, 0755)
+}
+
+// ListWorktrees lists worktrees in the mock VCS
+func (m *mockVCSProvider) ListWorktrees() ([]vcs.Worktree, error) {
+	return []vcs.Worktree{{Path: m.path, Branch: "main", Main: true}}, nil
+}
+
+// RemoveWorktree removes the worktree directory in the mock VCS
+func (m *mockVCSProvider) RemoveWorktree(path string) error {
+	return os.RemoveAll(path)
+}
+
+// PruneWorktrees prunes worktrees in the mock VCS
+func (m *mockVCSProvider) PruneWorktrees() error {
+	return nil
+}
+
+// Merge merges a branch in the mock VCS. Branches other than resolve branches
+// conflict in the files listed in the "mock_conflicts" config entry.
+func (m *mockVCSProvider) Merge(branch string, strategy vcs.MergeStrategy, message string) error {
+	if conflict := m.conflict(branch); conflict != nil {
+		return conflict
+	}
+	return nil
+}
+
+// StartMerge starts a merge in the mock VCS, leaving conflicts like Merge reports them
+func (m *mockVCSProvider) StartMerge(branch string) error {
+	if conflict := m.conflict(branch); conflict != nil {
+		return conflict
+	}
+	return nil
+}
+
+// GetConflictVersions returns the versions of a conflicted file in the mock VCS
+func (m *mockVCSProvider) GetConflictVersions(path string) (string, string, string, error) {
+	return "base\n", "ours\n", "theirs\n", nil
+}
+
+// ContinueMerge records the merge message in the mock VCS worktree
+func (m *mockVCSProvider) ContinueMerge(message string) error {
+	return os.WriteFile(filepath.Join(m.path, "MERGE_MSG"), []byte(message), 0644)
+}
+
+// AbortMerge aborts a merge in the mock VCS
+func (m *mockVCSProvider) AbortMerge() error {
+	return nil
+}
+
+// conflict returns the conflicts configured for merging branch, if any
+func (m *mockVCSProvider) conflict(branch string) *vcs.ConflictError {
+	files := m.config["mock_conflicts"]
+	if files == "" || strings.HasPrefix(branch, "resolve-") {
+		return nil
+	}
+	return &vcs.ConflictError{Operation: "merge", Branch: branch, Files: strings.Split(files, ",")}
+}
+
+// mockRebases holds the rebases of all mock repositories, as "onto" or "onto upstream"
+var (
+	mockRebases   []string
+	mockRebasesMu sync.Mutex
+)
+
+// Rebase rebases the current branch in the mock VCS, conflicting like Merge
+func (m *mockVCSProvider) Rebase(onto string) error {
+	return m.RebaseOnto(onto, "")
+}
+
+// RebaseOnto rebases the current branch in the mock VCS, conflicting like Merge
+func (m *mockVCSProvider) RebaseOnto(onto, upstream string) error {
+	mockRebasesMu.Lock()
+	mockRebases = append(mockRebases, strings.TrimSpace(onto+" "+upstream))
+	mockRebasesMu.Unlock()
+
+	if conflict := m.conflict(onto); conflict != nil {
+		conflict.Operation = "rebase"
+		return conflict
+	}
+	return nil
+}
+
 // Name returns the name of the mock VCS provider
 func (m *mockVCSProvider) Name() string {
 	return "git"
 }
 
-// mockAIProvider implements a mock AI provider for testing
+// mockAIProvider implements a mock AI provider for testing. Generating implementations and
+// adding features fails the first config["mock_fail_attempts"] times on every provider,
+// leaving partial work in the workspace.
 type mockAIProvider struct {
 	config     map[string]string
 	frameworks []string
+	failures   int
+	workspace  string
 }
 
+// mockPartialFile is the file failed attempts of mock AI providers leave in their workspace
+const mockPartialFile = "partial.txt"
+
+// mockInitializeCalls counts the calls of Initialize on mock AI providers
+var mockInitializeCalls int32
+
 // Initialize initializes the mock AI provider
 func (m *mockAIProvider) Initialize(ctx context.Context, config map[string]string) error {
+	atomic.AddInt32(&mockInitializeCalls, 1)
 	m.frameworks = []string{"react", "vue", "angular"}
+	m.workspace = config[ai.WorkspaceDirKey]
 	return nil
 }
 
@@ -108,19 +269,99 @@ func (m *mockAIProvider) GenerateProject(ctx context.Context, description string
 	return "Mock project for " + description, nil
 }
 
+// mockUsage is the usage mock AI providers report for generating, adding features and analyzing
+var mockUsage = ai.Usage{InputTokens: 1000, OutputTokens: 250, CostUSD: 0.5, Duration: 2 * time.Second}
+
+// mockEgress is the egress policy mock AI providers report for generating and adding features
+const mockEgress = "allowlist: api.anthropic.com"
+
+// fail fails the first configured number of calls, reporting some output and writing the
+// partial file before. Calls finding the partial work of an earlier call fail as well.
+func (m *mockAIProvider) fail(ctx context.Context) error {
+	partial := filepath.Join(m.workspace, mockPartialFile)
+	if _, err := os.Stat(partial); m.workspace != "" && err == nil {
+		return fmt.Errorf("mock workspace has the partial work of an earlier attempt")
+	}
+
+	limit, _ := strconv.Atoi(m.config["mock_fail_attempts"])
+	if m.failures >= limit {
+		return nil
+	}
+	m.failures++
+	if m.workspace != "" {
+		if err := os.WriteFile(partial, []byte("partial"), 0644); err != nil {
+			return err
+		}
+	}
+	ai.ReportProgress(ctx, ai.Progress{Line: fmt.Sprintf("mock output of attempt %d", m.failures)})
+	return fmt.Errorf("mock failure %d", m.failures)
+}
+
 // GenerateImplementation generates an implementation in the mock AI provider
 func (m *mockAIProvider) GenerateImplementation(ctx context.Context, description string, framework string) (string, error) {
+	ai.ReportUsage(ctx, mockUsage)
+	ai.ReportEgress(ctx, mockEgress)
+	if err := m.fail(ctx); err != nil {
+		return "", err
+	}
 	return "Mock implementation for " + description + " using " + framework, nil
 }
 
 // AddFeature adds a feature in the mock AI provider
 func (m *mockAIProvider) AddFeature(ctx context.Context, codeDir string, description string) (string, error) {
+	ai.ReportUsage(ctx, mockUsage)
+	ai.ReportEgress(ctx, mockEgress)
+	if err := m.fail(ctx); err != nil {
+		return "", err
+	}
 	return "Mock feature for " + description, nil
 }
 
-// AnalyzeCode analyzes code in the mock AI provider
-func (m *mockAIProvider) AnalyzeCode(ctx context.Context, codeDir string) (string, error) {
-	return "Mock analysis for " + codeDir, nil
+// mockAnalyzeCalls counts the calls of AnalyzeCode on mock AI providers
+var mockAnalyzeCalls int32
+
+// AnalyzeCode analyzes code in the mock AI provider, reporting one finding with the
+// severity in config["mock_finding_severity"] (low by default). The overall score is 80,
+// or the score of the first "<substring>=<score>" entry of config["mock_analysis_scores"]
+// whose substring is part of codeDir.
+func (m *mockAIProvider) AnalyzeCode(ctx context.Context, codeDir string) (*ai.AnalysisReport, error) {
+	atomic.AddInt32(&mockAnalyzeCalls, 1)
+	ai.ReportUsage(ctx, mockUsage)
+	severity := ai.SeverityLow
+	if name := m.config["mock_finding_severity"]; name != "" {
+		severity = ai.Severity(name)
+	}
+
+	score := 80
+	for _, entry := range strings.Split(m.config["mock_analysis_scores"], ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 && strings.Contains(codeDir, parts[0]) {
+			fmt.Sscanf(parts[1], "%d", &score)
+			break
+		}
+	}
+
+	return &ai.AnalysisReport{
+		Summary: "Mock analysis for " + codeDir,
+		Scores:  map[string]int{ai.OverallScore: score},
+		Findings: []ai.Finding{
+			{Severity: severity, Category: ai.CategorySecurity, File: "main.go", Line: 1, Title: "Mock finding"},
+		},
+	}, nil
+}
+
+// ResolveConflicts resolves conflicts in the mock AI provider by taking their version of each file
+func (m *mockAIProvider) ResolveConflicts(ctx context.Context, codeDir string, request ai.ConflictRequest) (string, error) {
+	for _, file := range request.Files {
+		path := filepath.Join(codeDir, file.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(path, []byte(file.Theirs), 0644); err != nil {
+			return "", err
+		}
+	}
+	return "Mock resolution for " + request.TheirsDescription, nil
 }
 
 // Name returns the name of the mock AI provider
@@ -136,4 +377,66 @@ func (m *mockAIProvider) SupportedFrameworks() []string {
 // Cleanup cleans up the mock AI provider
 func (m *mockAIProvider) Cleanup(ctx context.Context) error {
 	return nil
-}
\ No newline at end of file
+}
+
+// mockContainerProvider implements a mock container provider for testing. Commands
+// containing config["mock_failing_command"] fail, all others report passing tests.
+type mockContainerProvider struct {
+	config map[string]string
+}
+
+// Initialize initializes the mock container provider
+func (m *mockContainerProvider) Initialize(ctx context.Context, config map[string]string) error {
+	return nil
+}
+
+// RunContainer starts a mock container
+func (m *mockContainerProvider) RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string, options container.RunOptions) (string, error) {
+	return "mock-container", nil
+}
+
+// ExecuteCommand executes a command in the mock container
+func (m *mockContainerProvider) ExecuteCommand(ctx context.Context, containerID string, command []string) (string, error) {
+	commandLine := strings.Join(command, " ")
+	if failing := m.config["mock_failing_command"]; failing != "" && strings.Contains(commandLine, failing) {
+		return "", fmt.Errorf("command failed: %s", commandLine)
+	}
+	return "Tests: 1 failed, 3 passed, 4 total", nil
+}
+
+// ExecuteCommandStream executes a command in the mock container, writing its output
+func (m *mockContainerProvider) ExecuteCommandStream(ctx context.Context, containerID string, command []string, output io.Writer) error {
+	result, err := m.ExecuteCommand(ctx, containerID, command)
+	io.WriteString(output, result)
+	return err
+}
+
+// CopyFilesToContainer copies files to the mock container
+func (m *mockContainerProvider) CopyFilesToContainer(ctx context.Context, containerID string, localPath string, containerPath string) error {
+	return nil
+}
+
+// CopyFilesFromContainer copies files from the mock container
+func (m *mockContainerProvider) CopyFilesFromContainer(ctx context.Context, containerID string, containerPath string, localPath string) error {
+	return nil
+}
+
+// StopContainer stops the mock container
+func (m *mockContainerProvider) StopContainer(ctx context.Context, containerID string) error {
+	return nil
+}
+
+// RemoveContainer removes the mock container
+func (m *mockContainerProvider) RemoveContainer(ctx context.Context, containerID string) error {
+	return nil
+}
+
+// Name returns the name of the mock container provider
+func (m *mockContainerProvider) Name() string {
+	return "docker"
+}
+
+// IsRemote returns whether the mock container provider is remote
+func (m *mockContainerProvider) IsRemote() bool {
+	return false
+}
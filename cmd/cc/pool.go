@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/container/pool"
+	"github.com/fr0g-66723067/cc/pkg/config"
+)
+
+// containerPool returns the pool the AI provider leases containers from when its
+// container_provider is pool, configured by the ai.config keys prefixed with container_
+func containerPool(ctx context.Context, configPath string) (*pool.Provider, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	poolConfig := make(map[string]string)
+	for k, v := range cfg.AI.Config {
+		if strings.HasPrefix(k, "container_") {
+			poolConfig[strings.TrimPrefix(k, "container_")] = v
+		}
+	}
+
+	provider, err := pool.NewProvider(poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container pool: %w", err)
+	}
+	if err := provider.Initialize(ctx, nil); err != nil {
+		return nil, fmt.Errorf("failed to initialize container pool: %w", err)
+	}
+	return provider, nil
+}
+
+// executePoolStatusCommand lists the containers of the pool
+func executePoolStatusCommand(configPath string) (string, error) {
+	ctx := context.Background()
+	provider, err := containerPool(ctx, configPath)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := provider.Containers(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return fmt.Sprintf("No pooled containers (%s)\n", provider.StatePath()), nil
+	}
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tIMAGE\tPROJECT\tSTATE\tUSES\tLAST USED")
+	for _, entry := range entries {
+		project := entry.Project
+		if project == "" {
+			project = "-"
+		}
+		state := "idle"
+		if entry.InUse {
+			state = fmt.Sprintf("leased (pid %d)", entry.Owner)
+		}
+		id := entry.ID
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s ago\n", id, entry.Image, project, state, entry.Uses,
+			time.Since(entry.LastUsed).Round(time.Second))
+	}
+	w.Flush()
+	return b.String(), nil
+}
+
+// executePoolMaintainCommand keeps the pool healthy and warm every interval until interrupted,
+// or once
+func executePoolMaintainCommand(configPath string, interval time.Duration, once bool) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	provider, err := containerPool(ctx, configPath)
+	if err != nil {
+		return err
+	}
+	if once {
+		return provider.Maintain(ctx)
+	}
+
+	fmt.Printf("Maintaining the container pool every %s, press Ctrl+C to stop\n", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// Keep going when the container provider fails now and then
+		if err := provider.Maintain(ctx); err != nil && ctx.Err() == nil {
+			fmt.Printf("Warning: Failed to maintain the container pool: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// executePoolDrainCommand removes the idle containers of the pool, and the leased ones as
+// well with all, returning how many were removed
+func executePoolDrainCommand(configPath string, all bool) (int, error) {
+	ctx := context.Background()
+	provider, err := containerPool(ctx, configPath)
+	if err != nil {
+		return 0, err
+	}
+	return provider.Drain(ctx, all)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/ai/prompt"
+	"github.com/fr0g-66723067/cc/pkg/models"
+)
+
+// projectPromptsDir is the directory of a project repository overriding the prompt templates
+const projectPromptsDir = ".cc/prompts"
+
+// aiConfig returns the configuration initializing an AI provider to work in workspace on the
+// code of branch, passing its framework and the prompt variables and prompt overrides of project
+func aiConfig(project *models.Project, branch, workspace string) map[string]string {
+	framework := ""
+	if impl := project.GetImplementation(branch); impl != nil {
+		framework = impl.Framework
+	} else if impl, _ := project.GetFeature(branch); impl != nil {
+		framework = impl.Framework
+	}
+
+	return map[string]string{
+		ai.WorkspaceDirKey:   workspace,
+		ai.FrameworkKey:      framework,
+		ai.ProjectNameKey:    project.Name,
+		prompt.ProjectDirKey: filepath.Join(project.Path, filepath.FromSlash(projectPromptsDir)),
+		prompt.ProjectKey:    project.Description,
+		prompt.FeaturesKey:   strings.Join(branchFeatures(project, branch), "\n"),
+	}
+}
+
+// branchFeatures returns the descriptions of the features the code of branch has: the
+// features merged into its implementation, followed by the feature on branch and the
+// features it is stacked on, parents first
+func branchFeatures(project *models.Project, branch string) []string {
+	impl := project.GetImplementation(branch)
+	var stack []*models.Feature
+	if impl == nil {
+		var feature *models.Feature
+		impl, feature = project.GetFeature(branch)
+		for feature != nil {
+			stack = append([]*models.Feature{feature}, stack...)
+			feature = impl.GetFeature(feature.Parent)
+		}
+	}
+	if impl == nil {
+		return nil
+	}
+
+	var features []string
+	seen := make(map[string]bool)
+	for _, feature := range impl.Features {
+		if feature.Status == "merged" {
+			features = append(features, feature.Description)
+			seen[feature.BranchName] = true
+		}
+	}
+	for _, feature := range stack {
+		if !seen[feature.BranchName] {
+			features = append(features, feature.Description)
+		}
+	}
+	return features
+}
@@ -2,7 +2,12 @@ package main
 
 import (
 	"github.com/fr0g-66723067/cc/internal/ai/claude"
+	"github.com/fr0g-66723067/cc/internal/ai/openai"
+	"github.com/fr0g-66723067/cc/internal/ai/replay"
 	"github.com/fr0g-66723067/cc/internal/container/docker"
+	"github.com/fr0g-66723067/cc/internal/container/dockerapi"
+	"github.com/fr0g-66723067/cc/internal/container/podman"
+	"github.com/fr0g-66723067/cc/internal/container/pool"
 	"github.com/fr0g-66723067/cc/internal/vcs/git"
 )
 
@@ -13,6 +18,11 @@ import (
 // Ensure all providers are registered
 var (
 	_ = claude.NewProvider
+	_ = openai.NewProvider
+	_ = replay.NewProvider
 	_ = docker.NewProvider
+	_ = dockerapi.NewProvider
+	_ = podman.NewProvider
+	_ = pool.NewProvider
 	_ = git.NewProvider
 )
\ No newline at end of file
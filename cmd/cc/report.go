@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/report"
+	"github.com/fr0g-66723067/cc/internal/source"
+	"github.com/fr0g-66723067/cc/internal/vcs"
+	"github.com/fr0g-66723067/cc/pkg/config"
+	"github.com/fr0g-66723067/cc/pkg/models"
+)
+
+// executeCompareReportCommand builds a side-by-side report of implementation or feature
+// branches in the given format (markdown or html). Every branch is checked out to read its
+// file tree, languages and dependency manifests, and the AI provider analyzes all of them
+// together to summarize their architectural differences.
+func executeCompareReportCommand(configPath string, branches []string, format string) (string, error) {
+	if len(branches) < 2 {
+		return "", fmt.Errorf("at least two branches are needed for a comparison")
+	}
+
+	// Fail on unknown formats before doing any work
+	format, err := report.ParseFormat(format)
+	if err != nil {
+		return "", err
+	}
+
+	// Load config
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Get active project
+	project := cfg.GetActiveProject()
+	if project == nil {
+		return "", fmt.Errorf("no active project")
+	}
+
+	// Create VCS provider
+	vcsProvider, err := vcs.Create(cfg.VCS.Provider, cfg.VCS.Config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create VCS provider: %w", err)
+	}
+
+	// Initialize VCS
+	if err := vcsProvider.Initialize(project.Path); err != nil {
+		return "", fmt.Errorf("failed to initialize VCS: %w", err)
+	}
+
+	existing, err := vcsProvider.ListBranches()
+	if err != nil {
+		return "", fmt.Errorf("failed to list branches: %w", err)
+	}
+	for _, branch := range branches {
+		if !containsString(existing, branch) {
+			return "", fmt.Errorf("branch %s does not exist", branch)
+		}
+	}
+
+	// Collect copies of all branches in one directory for the AI analysis
+	analysisDir, err := os.MkdirTemp("", "cc-compare-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create analysis directory: %w", err)
+	}
+	defer os.RemoveAll(analysisDir)
+
+	ctx := getContext()
+	comparison := &report.Comparison{
+		Project:   project.Name,
+		CreatedAt: time.Now(),
+	}
+	var index strings.Builder
+	index.WriteString("# Implementations to compare\n\n")
+	index.WriteString("Each directory holds an implementation of the same project. Compare their architecture.\n\n")
+
+	for _, branch := range branches {
+		impl := describeImplementation(project, branch)
+
+		path, cleanup, err := branchWorktree(project, vcsProvider, branch)
+		if err != nil {
+			return "", err
+		}
+
+		err = report.Inspect(ctx, path, &impl)
+		if err == nil {
+			dir := strings.ReplaceAll(branch, "/", "-")
+			err = source.CopyTree(ctx, path, filepath.Join(analysisDir, dir))
+			index.WriteString(fmt.Sprintf("- `%s/`: %s (%s)\n", dir, branch, impl.Framework))
+		}
+		cleanup()
+		if err != nil {
+			return "", fmt.Errorf("failed to inspect %s: %w", branch, err)
+		}
+
+		comparison.Implementations = append(comparison.Implementations, impl)
+	}
+
+	if err := os.WriteFile(filepath.Join(analysisDir, "README.md"), []byte(index.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write analysis index: %w", err)
+	}
+
+	// The report is still useful without the summary, so AI failures are only warnings
+	summary, err := summarizeDifferences(cfg, configPath, project, analysisDir)
+	if err != nil {
+		fmt.Printf("Warning: Failed to summarize architectural differences: %v\n", err)
+	}
+	comparison.Summary = summary
+
+	return report.Render(comparison, format)
+}
+
+// describeImplementation returns the report entry for an implementation or feature branch
+// with the details recorded in the project
+func describeImplementation(project *models.Project, branch string) report.Implementation {
+	impl := report.Implementation{Branch: branch}
+	if recorded := project.GetImplementation(branch); recorded != nil {
+		impl.Framework = recorded.Framework
+		impl.Description = recorded.Description
+		impl.Score = recorded.Score
+		impl.Metrics = recorded.Metrics
+	} else if parent, feature := project.GetFeature(branch); feature != nil {
+		impl.Framework = parent.Framework
+		impl.Description = feature.Description
+		impl.Score = feature.Score
+		impl.Metrics = feature.Metrics
+	}
+	return impl
+}
+
+// summarizeDifferences lets the AI provider analyze the implementations in codeDir, within
+// the budget of project
+func summarizeDifferences(cfg *config.Config, configPath string, project *models.Project, codeDir string) (string, error) {
+	ctx, cancel, err := budgetContext(cfg, configPath, project)
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+
+	aiProvider, err := ai.Create(cfg.AI.Provider, cfg.AI.Config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create AI provider: %w", err)
+	}
+
+	if err := aiProvider.Initialize(ctx, aiConfig(project, "", codeDir)); err != nil {
+		return "", fmt.Errorf("failed to initialize AI provider: %w", err)
+	}
+	defer aiProvider.Cleanup(ctx)
+
+	fmt.Println("Analyzing architectural differences...")
+	analysis, err := aiProvider.AnalyzeCode(ctx, codeDir)
+	if err != nil {
+		return "", err
+	}
+	return analysis.Summary, nil
+}
+
+// containsString returns whether values contains s
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
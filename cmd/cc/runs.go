@@ -0,0 +1,15 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/fr0g-66723067/cc/internal/run"
+)
+
+// runsDir is the directory next to the config file keeping the records of generation runs
+const runsDir = "runs"
+
+// runStore returns the store of the generation runs of the config at configPath
+func runStore(configPath string) *run.Store {
+	return run.NewStore(filepath.Join(filepath.Dir(configPath), runsDir))
+}
@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"github.com/c-bata/go-prompt"
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/vcs"
 	"github.com/fr0g-66723067/cc/pkg/config"
 )
 
@@ -70,6 +72,7 @@ func (s *Shell) completer(d prompt.Document) []prompt.Suggest {
 		{Text: "pwd", Description: "Show current context path"},
 		{Text: "use", Description: "Use a specific resource"},
 		{Text: "status", Description: "Show current status"},
+		{Text: "usage", Description: "Show AI usage and cost"},
 	}
 	
 	// Projects level commands
@@ -88,6 +91,7 @@ func (s *Shell) completer(d prompt.Document) []prompt.Suggest {
 		{Text: "remove", Description: "Remove an implementation"},
 		{Text: "rename", Description: "Rename an implementation"},
 		{Text: "compare", Description: "Compare implementations"},
+		{Text: "analyze", Description: "Analyze implementations"},
 	}
 	
 	// Feature level commands
@@ -263,6 +267,8 @@ func (s *Shell) executor(cmd string) {
 		fmt.Println(s.cfg.GetContextPath())
 	case "status":
 		s.showStatus()
+	case "usage":
+		s.showUsage(args)
 	case "cd":
 		s.changeDirectory(args)
 	case "use":
@@ -287,6 +293,7 @@ func (s *Shell) showHelp() {
 	fmt.Println("  pwd                     - Show current context path")
 	fmt.Println("  cd <path>               - Change context level")
 	fmt.Println("  status                  - Show current status")
+	fmt.Println("  usage [--all] [<since>] - Show AI usage and cost")
 	fmt.Println("  use <resource> <name>   - Use a specific resource")
 	fmt.Println()
 	fmt.Println("Projects commands:")
@@ -298,14 +305,18 @@ func (s *Shell) showHelp() {
 	fmt.Println("Implementations commands:")
 	fmt.Println("  implementations list                         - List implementations")
 	fmt.Println("  implementations generate <desc> [--frameworks] - Generate implementations")
+	fmt.Println("  implementations generate --resume=<run-id> - Resume an interrupted generation run")
 	fmt.Println("  implementations select <branch>              - Select an implementation")
 	fmt.Println("  implementations remove <branch>              - Remove an implementation")
 	fmt.Println("  implementations rename <old> <new>           - Rename an implementation")
 	fmt.Println("  implementations compare <branch1> <branch2>  - Compare implementations")
+	fmt.Println("  implementations analyze [<branch>|--all]     - Analyze and rank implementations")
 	fmt.Println()
 	fmt.Println("Features commands:")
 	fmt.Println("  features list                - List features")
 	fmt.Println("  features add <description>   - Add a new feature")
+	fmt.Println("  features merge <branch>      - Merge a feature into its implementation")
+	fmt.Println("  features restack <branch>    - Rebase a feature stack onto its parents")
 	fmt.Println("  features remove <branch>     - Remove a feature")
 	fmt.Println("  features rename <old> <new>  - Rename a feature")
 }
@@ -320,6 +331,27 @@ func (s *Shell) showStatus() {
 	fmt.Println(status)
 }
 
+// showUsage displays the AI usage of the current project, or of all projects with --all,
+// optionally limited to work created since a duration ago or a date
+func (s *Shell) showUsage(args []string) {
+	all := false
+	since := ""
+	for _, arg := range args[1:] {
+		if arg == "--all" {
+			all = true
+		} else {
+			since = arg
+		}
+	}
+
+	usage, err := executeUsageCommand(s.configPath, all, since)
+	if err != nil {
+		fmt.Printf("Error getting usage: %s\n", err)
+		return
+	}
+	fmt.Print(usage)
+}
+
 // ChangeDirectoryForTest exposes changeDirectory for testing
 func (s *Shell) ChangeDirectoryForTest(args []string) {
 	s.changeDirectory(args)
@@ -705,7 +737,7 @@ func (s *Shell) handleImplementationsCommands(args []string) {
 	
 	if len(args) < 2 {
 		fmt.Println("Usage: implementations <command> [args...]")
-		fmt.Println("Commands: list, generate, select, remove, rename, compare")
+		fmt.Println("Commands: list, generate, select, remove, rename, compare, analyze")
 		return
 	}
 	
@@ -737,7 +769,27 @@ func (s *Shell) handleImplementationsCommands(args []string) {
 		
 	case "generate":
 		if len(args) < 3 {
-			fmt.Println("Usage: implementations generate <description> [--frameworks=react,vue] [--count=3] [--parallel=true]")
+			fmt.Println("Usage: implementations generate <description> [--frameworks=react,vue] [--count=3] [--parallel=true] [--allow-placeholder]")
+			fmt.Println("       implementations generate --resume=<run-id> [--allow-placeholder]")
+			return
+		}
+		
+		allowPlaceholder := false
+		for _, arg := range args[3:] {
+			if arg == "--allow-placeholder" {
+				allowPlaceholder = true
+			}
+		}
+		
+		// Resume an interrupted run instead of starting a new one
+		if strings.HasPrefix(args[2], "--resume=") {
+			err := executeResumeGenerateCommand(s.configPath, strings.TrimPrefix(args[2], "--resume="), allowPlaceholder)
+			if err != nil {
+				fmt.Printf("Error resuming generation run: %s\n", err)
+				return
+			}
+			
+			fmt.Println("Implementations generated successfully")
 			return
 		}
 		
@@ -761,7 +813,7 @@ func (s *Shell) handleImplementationsCommands(args []string) {
 			}
 		}
 		
-		err := executeGenerateCommand(s.configPath, description, frameworks, count, parallel)
+		err := executeGenerateCommand(s.configPath, description, frameworks, count, parallel, allowPlaceholder)
 		if err != nil {
 			fmt.Printf("Error generating implementations: %s\n", err)
 			return
@@ -849,9 +901,29 @@ func (s *Shell) handleImplementationsCommands(args []string) {
 		fmt.Println("\nComparison:")
 		fmt.Println(diff)
 		
+	case "analyze":
+		var output string
+		var err error
+		if len(args) > 2 && args[2] == "--all" {
+			output, err = executeAnalyzeAllCommand(s.configPath, false)
+		} else {
+			// Analyze the implementation in context by default
+			branch := s.cfg.Context.ImplementationBranch
+			if len(args) > 2 {
+				branch = args[2]
+			}
+			output, err = executeAnalyzeCommand(s.configPath, branch, ai.AnalysisFormatMarkdown, false)
+		}
+		if err != nil {
+			fmt.Printf("Error analyzing implementations: %s\n", err)
+			return
+		}
+		
+		fmt.Print(output)
+		
 	default:
 		fmt.Printf("Unknown implementations command: %s\n", command)
-		fmt.Println("Valid commands: list, generate, select, remove, rename, compare")
+		fmt.Println("Valid commands: list, generate, select, remove, rename, compare, analyze")
 	}
 }
 
@@ -895,7 +967,7 @@ func (s *Shell) handleFeaturesCommands(args []string) {
 	
 	if len(args) < 2 {
 		fmt.Println("Usage: features <command> [args...]")
-		fmt.Println("Commands: list, add, remove, rename")
+		fmt.Println("Commands: list, add, merge, restack, remove, rename")
 		return
 	}
 	
@@ -935,13 +1007,14 @@ func (s *Shell) handleFeaturesCommands(args []string) {
 		
 	case "add":
 		if len(args) < 3 {
-			fmt.Println("Usage: features add <description>")
+			fmt.Println("Usage: features add <description> [--allow-placeholder]")
 			return
 		}
 		
 		description := args[2]
+		allowPlaceholder := len(args) > 3 && args[3] == "--allow-placeholder"
 		
-		err := executeFeatureCommand(s.configPath, description)
+		err := executeFeatureCommand(s.configPath, description, "", allowPlaceholder)
 		if err != nil {
 			fmt.Printf("Error adding feature: %s\n", err)
 			return
@@ -963,6 +1036,60 @@ func (s *Shell) handleFeaturesCommands(args []string) {
 			}
 		}
 		
+	case "merge":
+		if len(args) < 3 {
+			fmt.Println("Usage: features merge <branch> [ff|merge|squash]")
+			return
+		}
+		
+		branch := args[2]
+		strategy := vcs.MergeCommit
+		if len(args) > 3 {
+			var err error
+			if strategy, err = vcs.ParseMergeStrategy(args[3]); err != nil {
+				fmt.Printf("Error merging feature: %s\n", err)
+				return
+			}
+		}
+		
+		resolveBranch, err := executeFeatureMergeCommand(s.configPath, branch, strategy, true)
+		if err != nil {
+			fmt.Printf("Error merging feature: %s\n", err)
+			return
+		}
+		
+		if resolveBranch != "" {
+			fmt.Printf("Proposed conflict resolution committed on %s, merge again after review\n", resolveBranch)
+		} else {
+			fmt.Printf("Feature %s merged successfully\n", branch)
+		}
+		
+		// Reload project data to get the merged status
+		s.cfg, _ = config.LoadConfig(s.configPath)
+		
+	case "restack":
+		if len(args) < 3 {
+			fmt.Println("Usage: features restack <branch>")
+			return
+		}
+		
+		branch := args[2]
+		
+		resolveBranch, err := executeFeatureRestackCommand(s.configPath, branch, true)
+		if err != nil {
+			fmt.Printf("Error restacking feature: %s\n", err)
+			return
+		}
+		
+		if resolveBranch != "" {
+			fmt.Printf("Restack stopped, proposed conflict resolution committed on %s\n", resolveBranch)
+		} else {
+			fmt.Printf("Feature %s restacked successfully\n", branch)
+		}
+		
+		// Reload project data to get the resolve branch
+		s.cfg, _ = config.LoadConfig(s.configPath)
+		
 	case "remove":
 		if len(args) < 3 {
 			fmt.Println("Usage: features remove <branch>")
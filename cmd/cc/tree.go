@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fr0g-66723067/cc/pkg/models"
+)
+
+// Formats the feature tree can be rendered in
+const (
+	treeFormatASCII   = "ascii"
+	treeFormatDOT     = "dot"
+	treeFormatMermaid = "mermaid"
+)
+
+// treeNode is a project, implementation or feature in the feature tree
+type treeNode struct {
+	// Label shown for the node
+	label string
+
+	// Annotations such as the status or whether the branch is checked out
+	marks []string
+
+	// Nodes built on top of this one
+	children []*treeNode
+}
+
+// buildFeatureTree builds the project → implementation → feature tree, placing every
+// feature below the branch it is based on
+func buildFeatureTree(project *models.Project, currentBranch string) *treeNode {
+	root := &treeNode{label: project.Name}
+
+	for _, impl := range project.Implementations {
+		implNode := &treeNode{
+			label: fmt.Sprintf("%s (%s)", impl.BranchName, impl.Framework),
+		}
+		if project.SelectedImplementation == impl.BranchName {
+			implNode.marks = append(implNode.marks, "selected")
+		}
+		if impl.Status == "failed" {
+			implNode.marks = append(implNode.marks, impl.Status)
+		}
+		if currentBranch == impl.BranchName {
+			implNode.marks = append(implNode.marks, "current")
+		}
+		root.children = append(root.children, implNode)
+
+		// Create the feature nodes first so features can be attached in any order
+		nodes := map[string]*treeNode{impl.BranchName: implNode}
+		for _, feature := range impl.Features {
+			node := &treeNode{
+				label: fmt.Sprintf("%s: %s", feature.BranchName, feature.Description),
+			}
+			if feature.Status != "" {
+				node.marks = append(node.marks, feature.Status)
+			}
+			if currentBranch == feature.BranchName {
+				node.marks = append(node.marks, "current")
+			}
+			nodes[feature.BranchName] = node
+		}
+
+		// Features based on an unknown branch are shown below the implementation. Features
+		// whose base branches form a cycle would never be reached from the implementation,
+		// so they are shown below it too, marked as such. Every other feature hangs below its
+		// base branch, so every feature is shown exactly once.
+		bases := make(map[string]string, len(impl.Features))
+		for _, feature := range impl.Features {
+			bases[feature.BranchName] = feature.BaseBranch
+		}
+		for _, feature := range impl.Features {
+			node := nodes[feature.BranchName]
+			parent, ok := nodes[feature.BaseBranch]
+			if !ok {
+				parent = implNode
+			} else if inBaseCycle(bases, feature.BranchName) {
+				parent = implNode
+				node.marks = append(node.marks, "base cycle")
+			}
+			parent.children = append(parent.children, node)
+		}
+	}
+
+	return root
+}
+
+// inBaseCycle reports whether following the base branches of features from the given
+// feature leads back to it
+func inBaseCycle(bases map[string]string, branch string) bool {
+	base, ok := bases[branch]
+	// A cycle can pass every feature at most once
+	for i := 0; ok && i < len(bases); i++ {
+		if base == branch {
+			return true
+		}
+		base, ok = bases[base]
+	}
+	return false
+}
+
+// renderFeatureTree renders the feature tree in the given format
+func renderFeatureTree(root *treeNode, format string) (string, error) {
+	var out strings.Builder
+
+	switch format {
+	case treeFormatASCII:
+		out.WriteString(root.label + "\n")
+		writeASCIITree(&out, root.children, "")
+
+	case treeFormatDOT:
+		out.WriteString(fmt.Sprintf("digraph %s {\n", dotQuote(root.label)))
+		out.WriteString("  rankdir=LR;\n")
+		out.WriteString("  node [shape=box];\n")
+		ids := make(map[*treeNode]string)
+		walkFeatureTree(root, func(parent, node *treeNode) {
+			ids[node] = fmt.Sprintf("n%d", len(ids))
+			out.WriteString(fmt.Sprintf("  %s [label=%s];\n", ids[node], dotQuote(node.String())))
+			if parent != nil {
+				out.WriteString(fmt.Sprintf("  %s -> %s;\n", ids[parent], ids[node]))
+			}
+		})
+		out.WriteString("}\n")
+
+	case treeFormatMermaid:
+		out.WriteString("graph TD\n")
+		ids := make(map[*treeNode]string)
+		walkFeatureTree(root, func(parent, node *treeNode) {
+			ids[node] = fmt.Sprintf("n%d", len(ids))
+			out.WriteString(fmt.Sprintf("  %s[\"%s\"]\n", ids[node], mermaidEscape(node.String())))
+			if parent != nil {
+				out.WriteString(fmt.Sprintf("  %s --> %s\n", ids[parent], ids[node]))
+			}
+		})
+
+	default:
+		return "", fmt.Errorf("unknown tree format: %s (valid formats: ascii, dot, mermaid)", format)
+	}
+
+	return out.String(), nil
+}
+
+// String returns the label of the node followed by its annotations
+func (n *treeNode) String() string {
+	if len(n.marks) == 0 {
+		return n.label
+	}
+	return fmt.Sprintf("%s [%s]", n.label, strings.Join(n.marks, ", "))
+}
+
+// writeASCIITree writes nodes as an indented tree
+func writeASCIITree(out *strings.Builder, nodes []*treeNode, prefix string) {
+	for i, node := range nodes {
+		connector, indent := "|-- ", "|   "
+		if i == len(nodes)-1 {
+			connector, indent = "`-- ", "    "
+		}
+		out.WriteString(prefix + connector + node.String() + "\n")
+		writeASCIITree(out, node.children, prefix+indent)
+	}
+}
+
+// walkFeatureTree calls visit for every node in the tree, parents before their children
+func walkFeatureTree(root *treeNode, visit func(parent, node *treeNode)) {
+	var walk func(parent, node *treeNode)
+	walk = func(parent, node *treeNode) {
+		visit(parent, node)
+		for _, child := range node.children {
+			walk(node, child)
+		}
+	}
+	walk(nil, root)
+}
+
+// dotQuote quotes a string for use as a Graphviz DOT identifier
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// mermaidEscape escapes a string for use in a quoted Mermaid label
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fr0g-66723067/cc/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTreeProject creates a project with stacked features for testing
+func setupTreeProject() *models.Project {
+	project := models.NewProject("todo-app", "/path/to/todo-app", "Todo app")
+	project.AddImplementation(models.Implementation{
+		Framework:  "react",
+		BranchName: "impl-react",
+		Features: []models.Feature{
+			// Listed before its parent to check features can be attached in any order
+			{BranchName: "feat-avatars", Description: "Add user avatars", BaseBranch: "feat-accounts", Status: "completed"},
+			{BranchName: "feat-accounts", Description: "Add user accounts", BaseBranch: "impl-react", Status: "merged"},
+			{BranchName: "feat-dark-mode", Description: "Add a \"dark\" mode", BaseBranch: "impl-react", Status: "completed"},
+		},
+	})
+	project.AddImplementation(models.Implementation{
+		Framework:  "vue",
+		BranchName: "impl-vue",
+		Features: []models.Feature{
+			{BranchName: "feat-orphan", Description: "Based on a deleted branch", BaseBranch: "feat-deleted"},
+		},
+	})
+	project.SetSelectedImplementation("impl-react")
+	return project
+}
+
+func TestRenderFeatureTreeASCII(t *testing.T) {
+	root := buildFeatureTree(setupTreeProject(), "feat-avatars")
+
+	output, err := renderFeatureTree(root, treeFormatASCII)
+	require.NoError(t, err)
+
+	expected := "todo-app\n" +
+		"|-- impl-react (react) [selected]\n" +
+		"|   |-- feat-accounts: Add user accounts [merged]\n" +
+		"|   |   `-- feat-avatars: Add user avatars [completed, current]\n" +
+		"|   `-- feat-dark-mode: Add a \"dark\" mode [completed]\n" +
+		"`-- impl-vue (vue)\n" +
+		"    `-- feat-orphan: Based on a deleted branch\n"
+	assert.Equal(t, expected, output)
+}
+
+func TestRenderFeatureTreeDOT(t *testing.T) {
+	root := buildFeatureTree(setupTreeProject(), "main")
+
+	output, err := renderFeatureTree(root, treeFormatDOT)
+	require.NoError(t, err)
+
+	assert.Contains(t, output, "digraph \"todo-app\" {\n")
+	assert.Contains(t, output, "  n1 [label=\"impl-react (react) [selected]\"];\n  n0 -> n1;\n")
+	assert.Contains(t, output, "  n2 [label=\"feat-accounts: Add user accounts [merged]\"];\n  n1 -> n2;\n")
+	assert.Contains(t, output, "  n3 [label=\"feat-avatars: Add user avatars [completed]\"];\n  n2 -> n3;\n")
+	assert.Contains(t, output, "[label=\"feat-dark-mode: Add a \\\"dark\\\" mode [completed]\"]")
+	assert.Contains(t, output, "  n6 [label=\"feat-orphan: Based on a deleted branch\"];\n  n5 -> n6;\n")
+	assert.Equal(t, "}\n", output[len(output)-2:])
+}
+
+func TestRenderFeatureTreeMermaid(t *testing.T) {
+	root := buildFeatureTree(setupTreeProject(), "main")
+
+	output, err := renderFeatureTree(root, treeFormatMermaid)
+	require.NoError(t, err)
+
+	assert.Contains(t, output, "graph TD\n  n0[\"todo-app\"]\n")
+	assert.Contains(t, output, "  n2[\"feat-accounts: Add user accounts [merged]\"]\n  n1 --> n2\n")
+	assert.Contains(t, output, "  n3[\"feat-avatars: Add user avatars [completed]\"]\n  n2 --> n3\n")
+	assert.Contains(t, output, "feat-dark-mode: Add a #quot;dark#quot; mode")
+}
+
+func TestRenderFeatureTreeBaseCycle(t *testing.T) {
+	project := models.NewProject("todo-app", "/path/to/todo-app", "Todo app")
+	project.AddImplementation(models.Implementation{
+		Framework:  "react",
+		BranchName: "impl-react",
+		Features: []models.Feature{
+			{BranchName: "feat-a", Description: "Based on feat-b", BaseBranch: "feat-b"},
+			{BranchName: "feat-b", Description: "Based on feat-a", BaseBranch: "feat-a"},
+			{BranchName: "feat-c", Description: "Based on feat-a", BaseBranch: "feat-a"},
+			{BranchName: "feat-self", Description: "Based on itself", BaseBranch: "feat-self"},
+		},
+	})
+	root := buildFeatureTree(project, "main")
+
+	output, err := renderFeatureTree(root, treeFormatASCII)
+	require.NoError(t, err)
+
+	expected := "todo-app\n" +
+		"`-- impl-react (react)\n" +
+		"    |-- feat-a: Based on feat-b [base cycle]\n" +
+		"    |   `-- feat-c: Based on feat-a\n" +
+		"    |-- feat-b: Based on feat-a [base cycle]\n" +
+		"    `-- feat-self: Based on itself [base cycle]\n"
+	assert.Equal(t, expected, output)
+}
+
+func TestRenderFeatureTreeUnknownFormat(t *testing.T) {
+	_, err := renderFeatureTree(buildFeatureTree(setupTreeProject(), "main"), "svg")
+	assert.Error(t, err)
+}
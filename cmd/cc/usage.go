@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/pkg/config"
+	"github.com/fr0g-66723067/cc/pkg/models"
+)
+
+// branchMetrics returns the metrics of the implementation or feature stored on branch,
+// creating them if needed, or nil if the branch is neither
+func branchMetrics(project *models.Project, branch string) map[string]float64 {
+	if impl := project.GetImplementation(branch); impl != nil {
+		if impl.Metrics == nil {
+			impl.Metrics = make(map[string]float64)
+		}
+		return impl.Metrics
+	}
+	if _, feature := project.GetFeature(branch); feature != nil {
+		if feature.Metrics == nil {
+			feature.Metrics = make(map[string]float64)
+		}
+		return feature.Metrics
+	}
+	return nil
+}
+
+// branchUsage returns the usage of generating and analyzing an implementation or feature
+func branchUsage(metrics map[string]float64) ai.Usage {
+	usage := ai.UsageFromMetrics(metrics, ai.UsageMetricPrefix)
+	usage.Add(ai.UsageFromMetrics(metrics, ai.AnalysisUsageMetricPrefix))
+	return usage
+}
+
+// parseSince returns the start of a time window given as a duration before now (like 36h or
+// 7d) or as a date (2006-01-02). An empty value is the zero time, which includes everything.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+		return now.Add(-duration), nil
+	}
+	if date, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time window: %s (use a duration like 24h or 7d, or a date like 2006-01-02)", value)
+}
+
+// executeUsageCommand reports the tokens, cost and time spent on AI calls for each
+// implementation of the active project, or of every project if all is set. Implementations
+// and features count when they were created in the time window starting at since.
+func executeUsageCommand(configPath string, all bool, since string) (string, error) {
+	// Load config
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	start, err := parseSince(since, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	var projects []*models.Project
+	if all {
+		for _, project := range cfg.Projects {
+			projects = append(projects, project)
+		}
+		sort.Slice(projects, func(i, j int) bool {
+			return projects[i].Name < projects[j].Name
+		})
+	} else {
+		project := cfg.GetActiveProject()
+		if project == nil {
+			return "", fmt.Errorf("no active project")
+		}
+		projects = append(projects, project)
+	}
+
+	var out strings.Builder
+	w := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECT\tIMPLEMENTATION\tFRAMEWORK\tFEATURES\tINPUT TOKENS\tOUTPUT TOKENS\tCOST\tTIME")
+
+	var total ai.Usage
+	for _, project := range projects {
+		var projectTotal ai.Usage
+		for _, impl := range project.Implementations {
+			var usage ai.Usage
+			if !impl.CreatedAt.Before(start) {
+				usage = branchUsage(impl.Metrics)
+			}
+			features := 0
+			for _, feature := range impl.Features {
+				if !feature.CreatedAt.Before(start) {
+					usage.Add(branchUsage(feature.Metrics))
+					features++
+				}
+			}
+			if features == 0 && impl.CreatedAt.Before(start) {
+				continue
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", project.Name, impl.BranchName, impl.Framework, features, formatUsage(usage))
+			projectTotal.Add(usage)
+		}
+
+		if all {
+			fmt.Fprintf(w, "%s\t(total)\t\t\t%s\n", project.Name, formatUsage(projectTotal))
+		}
+		total.Add(projectTotal)
+	}
+	fmt.Fprintf(w, "TOTAL\t\t\t\t%s\n", formatUsage(total))
+	w.Flush()
+
+	return out.String(), nil
+}
+
+// formatUsage formats usage as the token, cost and time columns of the usage report
+func formatUsage(usage ai.Usage) string {
+	return fmt.Sprintf("%d\t%d\t$%.2f\t%s", usage.InputTokens, usage.OutputTokens, usage.CostUSD, usage.Duration.Round(time.Second))
+}
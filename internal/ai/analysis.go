@@ -0,0 +1,466 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity is how serious a finding is
+type Severity string
+
+// Severities from most to least serious
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+	SeverityInfo     Severity = "info"
+)
+
+// severityRanks orders severities, higher is more serious
+var severityRanks = map[Severity]int{
+	SeverityCritical: 4,
+	SeverityHigh:     3,
+	SeverityMedium:   2,
+	SeverityLow:      1,
+	SeverityInfo:     0,
+}
+
+// Rank returns the rank of the severity, higher is more serious
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
+// ParseSeverity returns the severity with the given name
+func ParseSeverity(name string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := severityRanks[severity]; !ok {
+		return "", fmt.Errorf("unknown severity: %s (valid severities: critical, high, medium, low, info)", name)
+	}
+	return severity, nil
+}
+
+// Category is the area of concern of a finding
+type Category string
+
+// Categories of findings
+const (
+	CategorySecurity        Category = "security"
+	CategoryPerformance     Category = "performance"
+	CategoryMaintainability Category = "maintainability"
+	CategoryTests           Category = "tests"
+)
+
+// Categories lists all categories
+var Categories = []Category{CategorySecurity, CategoryPerformance, CategoryMaintainability, CategoryTests}
+
+// OverallScore is the key of the overall score in AnalysisReport.Scores
+const OverallScore = "overall"
+
+// AnalysisPrompt describes the JSON format ParseAnalysisReport expects, for inclusion in
+// prompts asking AI models to analyze code
+const AnalysisPrompt = `{
+  "summary": "architecture, design patterns and overall quality of the code",
+  "scores": {"security": 0-100, "performance": 0-100, "maintainability": 0-100, "tests": 0-100, "overall": 0-100},
+  "findings": [
+    {
+      "severity": "critical|high|medium|low|info",
+      "category": "security|performance|maintainability|tests",
+      "file": "path relative to the code directory",
+      "line": 1,
+      "title": "short description of the issue",
+      "description": "why it is an issue and how to fix it"
+    }
+  ]
+}`
+
+// Finding is a single issue found by analyzing code
+type Finding struct {
+	// How serious the issue is
+	Severity Severity `json:"severity"`
+
+	// Area of concern
+	Category Category `json:"category"`
+
+	// File the issue is in, relative to the code directory (empty if not file specific)
+	File string `json:"file,omitempty"`
+
+	// Line the issue starts at (0 if unknown)
+	Line int `json:"line,omitempty"`
+
+	// Short description of the issue
+	Title string `json:"title"`
+
+	// Explanation of the issue and how to fix it
+	Description string `json:"description,omitempty"`
+}
+
+// AnalysisReport is the result of analyzing code
+type AnalysisReport struct {
+	// Summary of the architecture and overall quality of the code
+	Summary string `json:"summary"`
+
+	// Scores from 0 to 100 per category and overall
+	Scores map[string]int `json:"scores"`
+
+	// Issues found in the code
+	Findings []Finding `json:"findings"`
+
+	// Commit the analyzed code was checked out from (empty if unknown)
+	Commit string `json:"commit,omitempty"`
+}
+
+// ParseAnalysisReport parses the JSON analysis written by an AI provider. The JSON may be
+// surrounded by other text or a Markdown code block. Unknown severities and categories
+// are mapped to info and maintainability, and missing overall scores are computed as the
+// average of the category scores.
+func ParseAnalysisReport(output string) (*AnalysisReport, error) {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object found in analysis")
+	}
+
+	var report AnalysisReport
+	if err := json.Unmarshal([]byte(output[start:end+1]), &report); err != nil {
+		return nil, fmt.Errorf("failed to parse analysis: %w", err)
+	}
+
+	for i := range report.Findings {
+		finding := &report.Findings[i]
+		if severity, err := ParseSeverity(string(finding.Severity)); err == nil {
+			finding.Severity = severity
+		} else {
+			finding.Severity = SeverityInfo
+		}
+		finding.Category = normalizeCategory(finding.Category)
+		if finding.Line < 0 {
+			finding.Line = 0
+		}
+	}
+	report.SortFindings()
+
+	if report.Scores == nil {
+		report.Scores = make(map[string]int)
+	}
+	total, count := 0, 0
+	for name, score := range report.Scores {
+		score = clampScore(score)
+		report.Scores[name] = score
+		if name != OverallScore {
+			total += score
+			count++
+		}
+	}
+	if _, ok := report.Scores[OverallScore]; !ok && count > 0 {
+		report.Scores[OverallScore] = (total + count/2) / count
+	}
+
+	return &report, nil
+}
+
+// normalizeCategory maps the category names AI providers use to the known categories
+func normalizeCategory(category Category) Category {
+	switch strings.ToLower(strings.TrimSpace(string(category))) {
+	case "security":
+		return CategorySecurity
+	case "performance":
+		return CategoryPerformance
+	case "test", "tests", "testing":
+		return CategoryTests
+	default:
+		return CategoryMaintainability
+	}
+}
+
+// clampScore limits a score to the range 0 to 100
+func clampScore(score int) int {
+	if score < 0 {
+		return 0
+	}
+	if score > 100 {
+		return 100
+	}
+	return score
+}
+
+// SortFindings sorts the findings by severity (most serious first), file and line
+func (r *AnalysisReport) SortFindings() {
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		a, b := r.Findings[i], r.Findings[j]
+		if a.Severity.Rank() != b.Severity.Rank() {
+			return a.Severity.Rank() > b.Severity.Rank()
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+}
+
+// CountAtLeast returns the number of findings at least as serious as severity
+func (r *AnalysisReport) CountAtLeast(severity Severity) int {
+	count := 0
+	for _, finding := range r.Findings {
+		if finding.Severity.Rank() >= severity.Rank() {
+			count++
+		}
+	}
+	return count
+}
+
+// JSON renders the report as indented JSON
+func (r *AnalysisReport) JSON() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal analysis: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// Markdown renders the report as Markdown
+func (r *AnalysisReport) Markdown() string {
+	var out strings.Builder
+	out.WriteString("# Code analysis\n\n")
+	if r.Summary != "" {
+		out.WriteString(strings.TrimSpace(r.Summary) + "\n\n")
+	}
+
+	if len(r.Scores) > 0 {
+		out.WriteString("## Scores\n\n| Category | Score |\n|---|---|\n")
+		for _, name := range r.scoreNames() {
+			out.WriteString(fmt.Sprintf("| %s | %d |\n", name, r.Scores[name]))
+		}
+		out.WriteString("\n")
+	}
+
+	out.WriteString(fmt.Sprintf("## Findings (%d)\n\n", len(r.Findings)))
+	if len(r.Findings) == 0 {
+		out.WriteString("No findings.\n")
+	}
+	for _, finding := range r.Findings {
+		out.WriteString(fmt.Sprintf("### [%s] %s (%s)\n\n", strings.ToUpper(string(finding.Severity)), finding.Title, finding.Category))
+		if location := finding.location(); location != "" {
+			out.WriteString(fmt.Sprintf("`%s`\n\n", location))
+		}
+		if finding.Description != "" {
+			out.WriteString(strings.TrimSpace(finding.Description) + "\n\n")
+		}
+	}
+
+	return out.String()
+}
+
+// scoreNames returns the names of the scores, overall first
+func (r *AnalysisReport) scoreNames() []string {
+	var names []string
+	for name := range r.Scores {
+		if name != OverallScore {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := r.Scores[OverallScore]; ok {
+		names = append([]string{OverallScore}, names...)
+	}
+	return names
+}
+
+// location returns the file and line of the finding as file:line
+func (f Finding) location() string {
+	if f.File == "" {
+		return ""
+	}
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return f.File
+}
+
+// SARIF types, see https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+
+	sarifResult struct {
+		RuleID     string            `json:"ruleId"`
+		Level      string            `json:"level"`
+		Message    sarifMessage      `json:"message"`
+		Locations  []sarifLocation   `json:"locations,omitempty"`
+		Properties map[string]string `json:"properties"`
+	}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+
+	sarifRegion struct {
+		StartLine int `json:"startLine"`
+	}
+)
+
+// sarifLevel maps a severity to a SARIF result level
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case SeverityCritical, SeverityHigh:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// SARIF renders the report as a SARIF 2.1.0 log for code scanning tools. Every category
+// is a rule, and findings are results of the rule of their category.
+func (r *AnalysisReport) SARIF() (string, error) {
+	driver := sarifDriver{
+		Name:           "cc",
+		InformationURI: "https://github.com/fr0g-66723067/cc",
+	}
+	for _, category := range Categories {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:               string(category),
+			ShortDescription: sarifMessage{Text: fmt.Sprintf("%s issue found by AI code analysis", category)},
+		})
+	}
+
+	results := []sarifResult{}
+	for _, finding := range r.Findings {
+		message := finding.Title
+		if finding.Description != "" {
+			message += ": " + finding.Description
+		}
+
+		result := sarifResult{
+			RuleID:     string(finding.Category),
+			Level:      sarifLevel(finding.Severity),
+			Message:    sarifMessage{Text: message},
+			Properties: map[string]string{"severity": string(finding.Severity)},
+		}
+		if finding.File != "" {
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: finding.File},
+			}}
+			if finding.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: finding.Line}
+			}
+			result.Locations = []sarifLocation{location}
+		}
+		results = append(results, result)
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal SARIF: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// AnalysisMetadataKey is the branch metadata key analysis reports are stored under
+const AnalysisMetadataKey = "analysis"
+
+// StoreInMetadata stores the report as JSON in branch metadata
+func (r *AnalysisReport) StoreInMetadata(metadata map[string]string) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal analysis: %w", err)
+	}
+	metadata[AnalysisMetadataKey] = string(data)
+	return nil
+}
+
+// AnalysisFromMetadata returns the report stored in branch metadata, or nil if there is none
+func AnalysisFromMetadata(metadata map[string]string) (*AnalysisReport, error) {
+	data, ok := metadata[AnalysisMetadataKey]
+	if !ok || data == "" {
+		return nil, nil
+	}
+
+	var report AnalysisReport
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, fmt.Errorf("failed to parse stored analysis: %w", err)
+	}
+	return &report, nil
+}
+
+// Formats an analysis report can be rendered in
+const (
+	AnalysisFormatJSON     = "json"
+	AnalysisFormatMarkdown = "markdown"
+	AnalysisFormatSARIF    = "sarif"
+)
+
+// ParseAnalysisFormat returns the analysis format with the given name
+func ParseAnalysisFormat(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case AnalysisFormatJSON:
+		return AnalysisFormatJSON, nil
+	case AnalysisFormatMarkdown, "md":
+		return AnalysisFormatMarkdown, nil
+	case AnalysisFormatSARIF:
+		return AnalysisFormatSARIF, nil
+	default:
+		return "", fmt.Errorf("unknown analysis format: %s (valid formats: json, markdown, sarif)", name)
+	}
+}
+
+// Render renders the report in the given format
+func (r *AnalysisReport) Render(format string) (string, error) {
+	format, err := ParseAnalysisFormat(format)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case AnalysisFormatJSON:
+		return r.JSON()
+	case AnalysisFormatSARIF:
+		return r.SARIF()
+	default:
+		return r.Markdown(), nil
+	}
+}
@@ -0,0 +1,137 @@
+package ai_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleReport returns a report with one finding of each kind of location
+func sampleReport() *ai.AnalysisReport {
+	return &ai.AnalysisReport{
+		Summary: "A small REST API",
+		Scores:  map[string]int{ai.OverallScore: 70, "security": 50},
+		Findings: []ai.Finding{
+			{Severity: ai.SeverityCritical, Category: ai.CategorySecurity, File: "src/auth.js", Line: 7, Title: "Hardcoded secret", Description: "Move it to the environment."},
+			{Severity: ai.SeverityLow, Category: ai.CategoryMaintainability, Title: "No README"},
+		},
+	}
+}
+
+// TestParseAnalysisReport tests parsing and normalizing analysis output
+func TestParseAnalysisReport(t *testing.T) {
+	output := "Sure!\n```json\n" + `{
+		"summary": "Flask app",
+		"scores": {"security": 80, "performance": 150, "tests": -5},
+		"findings": [
+			{"severity": "low", "category": "style", "file": "app.py", "line": 3, "title": "Long function"},
+			{"severity": "Critical", "category": "Security", "file": "app.py", "line": 10, "title": "Debug mode enabled"},
+			{"severity": "urgent", "category": "tests", "title": "No tests"}
+		]
+	}` + "\n```\n"
+
+	report, err := ai.ParseAnalysisReport(output)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Flask app", report.Summary)
+	assert.Equal(t, 100, report.Scores["performance"])
+	assert.Equal(t, 0, report.Scores["tests"])
+	assert.Equal(t, 60, report.Scores[ai.OverallScore])
+
+	require.Len(t, report.Findings, 3)
+	assert.Equal(t, ai.SeverityCritical, report.Findings[0].Severity)
+	assert.Equal(t, ai.CategorySecurity, report.Findings[0].Category)
+	assert.Equal(t, ai.SeverityLow, report.Findings[1].Severity)
+	assert.Equal(t, ai.CategoryMaintainability, report.Findings[1].Category)
+	assert.Equal(t, ai.SeverityInfo, report.Findings[2].Severity)
+
+	assert.Equal(t, 2, report.CountAtLeast(ai.SeverityLow))
+	assert.Equal(t, 1, report.CountAtLeast(ai.SeverityHigh))
+
+	_, err = ai.ParseAnalysisReport("The code looks fine.")
+	assert.Error(t, err)
+}
+
+// TestAnalysisMetadata tests storing reports in branch metadata
+func TestAnalysisMetadata(t *testing.T) {
+	report, err := ai.AnalysisFromMetadata(map[string]string{"framework": "react"})
+	require.NoError(t, err)
+	assert.Nil(t, report)
+
+	metadata := map[string]string{"framework": "react"}
+	require.NoError(t, sampleReport().StoreInMetadata(metadata))
+	assert.Equal(t, "react", metadata["framework"])
+
+	report, err = ai.AnalysisFromMetadata(metadata)
+	require.NoError(t, err)
+	assert.Equal(t, sampleReport(), report)
+}
+
+// TestRenderAnalysis tests rendering reports as JSON, Markdown and SARIF
+func TestRenderAnalysis(t *testing.T) {
+	report := sampleReport()
+
+	output, err := report.Render("json")
+	require.NoError(t, err)
+	var decoded ai.AnalysisReport
+	require.NoError(t, json.Unmarshal([]byte(output), &decoded))
+	assert.Equal(t, report, &decoded)
+
+	output, err = report.Render("md")
+	require.NoError(t, err)
+	assert.Contains(t, output, "A small REST API")
+	assert.Contains(t, output, "| overall | 70 |")
+	assert.Contains(t, output, "### [CRITICAL] Hardcoded secret (security)")
+	assert.Contains(t, output, "`src/auth.js:7`")
+	assert.True(t, strings.Index(output, "overall") < strings.Index(output, "| security"))
+
+	output, err = report.Render("sarif")
+	require.NoError(t, err)
+	var sarif struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(output), &sarif))
+	assert.Equal(t, "2.1.0", sarif.Version)
+	require.Len(t, sarif.Runs, 1)
+	assert.Len(t, sarif.Runs[0].Tool.Driver.Rules, len(ai.Categories))
+
+	results := sarif.Runs[0].Results
+	require.Len(t, results, 2)
+	assert.Equal(t, "security", results[0].RuleID)
+	assert.Equal(t, "error", results[0].Level)
+	require.Len(t, results[0].Locations, 1)
+	assert.Equal(t, "src/auth.js", results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 7, results[0].Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Equal(t, "note", results[1].Level)
+	assert.Empty(t, results[1].Locations)
+
+	_, err = report.Render("xml")
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@ package claude
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,7 +11,9 @@ import (
 	"time"
 
 	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/ai/prompt"
 	"github.com/fr0g-66723067/cc/internal/container"
+	"github.com/fr0g-66723067/cc/internal/profile"
 )
 
 // Provider implements the AI provider interface for Claude
@@ -18,25 +21,50 @@ type Provider struct {
 	containerProvider container.Provider
 	containerID       string
 	config            map[string]string
-	frameworks        []string
+	profiles          []profile.Profile
+
+	// Egress policy of the running container
+	egress container.EgressPolicy
+
+	// Local directory the code generated in pooled containers is copied to
+	workspaceDir string
 }
 
+// apiDomain is the domain of the Claude API, which the Claude CLI needs to reach
+const apiDomain = "api.anthropic.com"
+
+// containerWorkspace is the directory of the container Claude works in
+const containerWorkspace = "/workspace"
+
+// readyFile is the file of pooled containers recording the egress policy they were set up
+// with, so containers leased again skip the setup
+const readyFile = "/home/node/.cc-ready"
+
 // NewProvider creates a new Claude provider
 func NewProvider(config map[string]string) (*Provider, error) {
-	// Set default frameworks if not specified
-	frameworks := []string{
-		"react", "vue", "svelte", "angular",
-		"nextjs", "nuxt", "express", "fastify",
-		"django", "flask", "spring", "rails",
+	if config == nil {
+		config = make(map[string]string)
 	}
 
+	// Use all framework profiles if no frameworks are specified
+	frameworks := profile.Names()
 	if customFrameworks, ok := config["frameworks"]; ok && customFrameworks != "" {
 		frameworks = strings.Split(customFrameworks, ",")
 	}
 
+	// Frameworks without a profile get an empty one
+	profiles := make([]profile.Profile, 0, len(frameworks))
+	for _, name := range frameworks {
+		framework, ok := profile.Get(name)
+		if !ok {
+			framework = profile.Profile{Name: name}
+		}
+		profiles = append(profiles, framework)
+	}
+
 	return &Provider{
-		config:     config,
-		frameworks: frameworks,
+		config:   config,
+		profiles: profiles,
 	}, nil
 }
 
@@ -53,47 +81,128 @@ func (p *Provider) Initialize(ctx context.Context, config map[string]string) err
 		containerType = "docker"
 	}
 
+	containerConfig := p.containerConfig()
+
+	// Create the container provider unless one was already set
+	if p.containerProvider == nil {
+		var err error
+		p.containerProvider, err = container.Create(containerType, containerConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create container provider: %w", err)
+		}
+	}
+
+	// Initialize container provider
+	if err := p.containerProvider.Initialize(ctx, containerConfig); err != nil {
+		return fmt.Errorf("failed to initialize container provider: %w", err)
+	}
+
+	return nil
+}
+
+// containerConfig returns the config of the container provider, the config keys prefixed
+// with container_
+func (p *Provider) containerConfig() map[string]string {
 	containerConfig := make(map[string]string)
 	for k, v := range p.config {
 		if strings.HasPrefix(k, "container_") {
 			containerConfig[strings.TrimPrefix(k, "container_")] = v
 		}
 	}
+	return containerConfig
+}
 
-	var err error
-	p.containerProvider, err = container.Create(containerType, containerConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create container provider: %w", err)
+// pooled returns whether containers are leased from a pool, to be reused by later commands.
+// Pooled containers do not mount the workspace directory, code is copied in and out instead.
+func (p *Provider) pooled() bool {
+	return p.config["container_provider"] == "pool"
+}
+
+// userMounted returns whether the workspace of the container is the directory of
+// ai.WorkspaceDirKey mounted, which holds the user's code, like a worktree or the main
+// checkout. Otherwise it is a scratch directory: the provider's temporary directory mounted,
+// or a directory of a pooled container.
+func (p *Provider) userMounted() bool {
+	return !p.pooled() && p.config[ai.WorkspaceDirKey] != ""
+}
+
+// mountedAt returns whether localPath is the directory mounted as the workspace of the
+// container, whose files need no copying in or out
+func (p *Provider) mountedAt(localPath string) bool {
+	absLocalPath, err := filepath.Abs(localPath)
+	return err == nil && !p.pooled() && absLocalPath == p.workspaceDir
+}
+
+// cleanWorkspace empties a scratch workspace of the container, hidden files included. A
+// mounted directory of the user is never cleaned.
+func (p *Provider) cleanWorkspace(ctx context.Context) error {
+	if p.userMounted() {
+		return nil
 	}
+	cleanCmd := []string{"find", containerWorkspace, "-mindepth", "1", "-delete"}
+	_, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, cleanCmd)
+	return err
+}
 
-	// Initialize container provider
-	if err := p.containerProvider.Initialize(ctx, containerConfig); err != nil {
-		return fmt.Errorf("failed to initialize container provider: %w", err)
+// copyToWorkspace copies the files of localPath into the workspace of the container, unless
+// localPath is mounted as the workspace. Other directories are never copied into a mounted
+// directory of the user, where they would mix with the user's code.
+func (p *Provider) copyToWorkspace(ctx context.Context, localPath string) error {
+	if p.mountedAt(localPath) {
+		return nil
+	}
+	if p.userMounted() {
+		return fmt.Errorf("%s is not the mounted workspace directory %s", localPath, p.workspaceDir)
+	}
+	return p.containerProvider.CopyFilesToContainer(ctx, p.containerID, localPath+string(filepath.Separator)+".", containerWorkspace)
+}
+
+// copyFromWorkspace copies the files of the workspace of the container into localPath, unless
+// localPath is mounted as the workspace
+func (p *Provider) copyFromWorkspace(ctx context.Context, localPath string) error {
+	if p.mountedAt(localPath) {
+		return nil
 	}
+	return p.containerProvider.CopyFilesFromContainer(ctx, p.containerID, containerWorkspace+"/.", localPath)
+}
 
+// copyGenerated copies the code generated in a pooled container to the workspace directory,
+// which containers that are not pooled mount
+func (p *Provider) copyGenerated(ctx context.Context) error {
+	if !p.pooled() {
+		return nil
+	}
+	if err := os.MkdirAll(p.workspaceDir, 0755); err != nil {
+		return fmt.Errorf("failed to create workspace directory: %w", err)
+	}
+	if err := p.copyFromWorkspace(ctx, p.workspaceDir); err != nil {
+		return fmt.Errorf("failed to copy generated files from container: %w", err)
+	}
 	return nil
 }
 
 // GenerateProject generates a project structure based on description
 func (p *Provider) GenerateProject(ctx context.Context, description string) (string, error) {
 	// Make sure we have a container
-	if err := p.ensureContainer(ctx); err != nil {
+	if err := p.ensureContainer(ctx, ""); err != nil {
 		return "", err
 	}
 
 	// Create prompt for Claude
-	prompt := fmt.Sprintf(
-		"Create a project structure for: %s\n\n"+
-			"Please create the necessary directory structure, configuration files, and basic scaffolding "+
-			"for a new project based on this description. Focus on setting up a solid foundation "+
-			"that can be used for multiple implementation approaches.",
-		description,
-	)
+	prompt, err := p.renderPrompt(prompt.Project, prompt.Data{Description: description})
+	if err != nil {
+		return "", err
+	}
+
+	// Scratch workspaces may hold the code of an earlier command
+	if err := p.cleanWorkspace(ctx); err != nil {
+		return "", fmt.Errorf("failed to clean workspace directory: %w", err)
+	}
 
 	// Create a workspace directory in the container
-	workspacePath := "/workspace"
+	workspacePath := containerWorkspace
 	createDirCmd := []string{"mkdir", "-p", workspacePath}
-	_, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, createDirCmd)
+	_, err = p.containerProvider.ExecuteCommand(ctx, p.containerID, createDirCmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to create workspace directory: %w", err)
 	}
@@ -102,45 +211,29 @@ func (p *Provider) GenerateProject(ctx context.Context, description string) (str
 	// The Claude Code CLI syntax is typically:
 	// claude code generate [--output DIR] "prompt"
 	cmd := []string{"claude", "code", "generate", "--output", workspacePath, prompt}
-	output, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, cmd)
+	output, err := p.runClaude(ctx, cmd, workspacePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute command: %w", err)
+		return "", err
 	}
-
-	// Log the generation output
-	fmt.Printf("Claude generation complete. Output summary:\n%s\n",
-		truncateString(output, 500))
-
-	return output, nil
+	return output, p.copyGenerated(ctx)
 }
 
 // GenerateImplementation generates code with a specific framework
 func (p *Provider) GenerateImplementation(ctx context.Context, description string, framework string) (string, error) {
-	// Make sure we have a container
-	if err := p.ensureContainer(ctx); err != nil {
+	// Make sure we have a container, with the generation image of the framework's profile
+	if err := p.ensureContainer(ctx, framework); err != nil {
 		return "", err
 	}
 
 	// Create prompt for Claude
-	prompt := fmt.Sprintf(
-		"Create a %s implementation for: %s\n\n"+
-			"Please implement a complete, working application using the %s framework based on this description. "+
-			"Include all necessary files, configuration, and code to make the application fully functional. "+
-			"Focus on best practices, performance, and maintainability.\n\n"+
-			"Make sure to adhere to the following guidelines:\n"+
-			"1. Use modern %s patterns and libraries\n"+
-			"2. Include proper error handling\n"+
-			"3. Add comments explaining key logic\n"+
-			"4. Include necessary dependencies and configuration\n"+
-			"5. Implement a modular, maintainable architecture",
-		framework, description, framework, framework,
-	)
+	prompt, err := p.renderPrompt(prompt.Implementation, prompt.Data{Description: description, Framework: framework})
+	if err != nil {
+		return "", err
+	}
 
 	// Create a clean workspace directory in the container
-	workspacePath := "/workspace"
-	cleanCmd := []string{"rm", "-rf", workspacePath + "/*"}
-	_, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, cleanCmd)
-	if err != nil {
+	workspacePath := containerWorkspace
+	if err := p.cleanWorkspace(ctx); err != nil {
 		return "", fmt.Errorf("failed to clean workspace directory: %w", err)
 	}
 
@@ -170,39 +263,61 @@ func (p *Provider) GenerateImplementation(ctx context.Context, description strin
 	// Execute command in container with proper Claude Code CLI arguments
 	fmt.Printf("Generating %s implementation for: %s\n", framework, description)
 	cmd := []string{"claude", "code", "generate", "--output", workspacePath, prompt}
-	output, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, cmd)
+	output, err := p.runClaude(ctx, cmd, workspacePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute command: %w", err)
+		return "", err
 	}
-
-	// Log the generation output
-	fmt.Printf("Claude generation complete. Output summary:\n%s\n",
-		truncateString(output, 500))
-
-	return output, nil
+	return output, p.copyGenerated(ctx)
 }
 
 // AddFeature adds a feature to existing code
 func (p *Provider) AddFeature(ctx context.Context, codeDir string, description string) (string, error) {
+	containerPath := "/workspace"
+
+	// Create prompt for Claude
+	prompt, err := p.renderPrompt(prompt.Feature, prompt.Data{Description: description, Dir: containerPath})
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Asking Claude to add feature: %s\n", description)
+	return p.modifyCode(ctx, codeDir, prompt)
+}
+
+// ResolveConflicts resolves merge conflicts in codeDir, writing the resolved files in place
+func (p *Provider) ResolveConflicts(ctx context.Context, codeDir string, request ai.ConflictRequest) (string, error) {
+	containerPath := "/workspace"
+
+	// Create prompt for Claude, describing both sides of every conflicted file
+	prompt, err := p.renderPrompt(prompt.Resolve, prompt.Data{Dir: containerPath, Conflicts: request})
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Asking Claude to resolve conflicts in %d file(s)\n", len(request.Files))
+	return p.modifyCode(ctx, codeDir, prompt)
+}
+
+// modifyCode copies codeDir into the container, lets Claude modify it as described
+// by prompt and copies the modified files back
+func (p *Provider) modifyCode(ctx context.Context, codeDir string, prompt string) (string, error) {
 	// Make sure we have a container
-	if err := p.ensureContainer(ctx); err != nil {
+	if err := p.ensureContainer(ctx, p.config[ai.FrameworkKey]); err != nil {
 		return "", err
 	}
 
 	// Create relative path for code directory
-	containerPath := "/workspace"
+	containerPath := containerWorkspace
 	localPath := codeDir
 
 	// Clean workspace directory in container
-	cleanCmd := []string{"rm", "-rf", containerPath + "/*"}
-	_, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, cleanCmd)
-	if err != nil {
+	if err := p.cleanWorkspace(ctx); err != nil {
 		return "", fmt.Errorf("failed to clean workspace directory: %w", err)
 	}
 
 	// Create workspace directory
 	createDirCmd := []string{"mkdir", "-p", containerPath}
-	_, err = p.containerProvider.ExecuteCommand(ctx, p.containerID, createDirCmd)
+	_, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, createDirCmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to create workspace directory: %w", err)
 	}
@@ -216,14 +331,14 @@ func (p *Provider) AddFeature(ctx context.Context, codeDir string, description s
 	}
 
 	// Make sure the target directory exists and has correct permissions
-	mkdirCmd := []string{"mkdir", "-p", containerPath, "&&", "chmod", "755", containerPath}
+	mkdirCmd := []string{"sh", "-c", fmt.Sprintf("mkdir -p %s && chmod 755 %s", containerPath, containerPath)}
 	_, err = p.containerProvider.ExecuteCommand(ctx, p.containerID, mkdirCmd)
 	if err != nil {
 		fmt.Printf("Warning: Failed to prepare container directory: %v\n", err)
 	}
 
 	// Now copy files
-	if err := p.containerProvider.CopyFilesToContainer(ctx, p.containerID, absLocalPath, containerPath); err != nil {
+	if err := p.copyToWorkspace(ctx, absLocalPath); err != nil {
 		return "", fmt.Errorf("failed to copy files to container: %w", err)
 	}
 
@@ -235,40 +350,23 @@ func (p *Provider) AddFeature(ctx context.Context, codeDir string, description s
 	}
 	fmt.Printf("Files in container workspace:\n%s\n", lsOutput)
 
-	// Create prompt for Claude
-	prompt := fmt.Sprintf(
-		"Add a new feature to the existing code: %s\n\n"+
-			"Please analyze the existing codebase in %s and add a new feature that: %s\n\n"+
-			"Make all necessary changes to implement this feature completely while maintaining the following:\n"+
-			"1. Keep the existing code style and architecture\n"+
-			"2. Follow the same patterns as existing code\n"+
-			"3. Add appropriate error handling\n"+
-			"4. Include unit tests for new functionality\n"+
-			"5. Update documentation as needed\n"+
-			"6. Ensure the feature is fully integrated with the existing functionality\n\n"+
-			"Describe your changes in detail and explain your implementation choices.",
-		description, containerPath, description,
-	)
-
 	// Execute command in container
-	fmt.Printf("Asking Claude to add feature: %s\n", description)
 	cmd := []string{"claude", "code", "modify", "--dir", containerPath, prompt}
-	output, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, cmd)
+	output, err := p.runClaude(ctx, cmd, containerPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute command: %w", err)
+		return "", err
 	}
 
-	// Log the changes that Claude made
-	fmt.Printf("Claude made the following changes:\n%s\n",
-		truncateString(output, 500))
-
 	// Copy files back from container
 	fmt.Printf("Copying modified files back from container to %s...\n", localPath)
-	// Make sure all files in the container have the right permissions
-	chmodCmd := []string{"find", containerPath, "-type", "f", "-exec", "chmod", "644", "{}", ";"}
-	_, err = p.containerProvider.ExecuteCommand(ctx, p.containerID, chmodCmd)
-	if err != nil {
-		fmt.Printf("Warning: Failed to set file permissions in container: %v\n", err)
+	// Make sure all files in the container have the right permissions, leaving the mounted
+	// files of the user as they are
+	if !p.mountedAt(localPath) {
+		chmodCmd := []string{"find", containerPath, "-type", "f", "-exec", "chmod", "644", "{}", ";"}
+		_, err = p.containerProvider.ExecuteCommand(ctx, p.containerID, chmodCmd)
+		if err != nil {
+			fmt.Printf("Warning: Failed to set file permissions in container: %v\n", err)
+		}
 	}
 
 	// Create target directory if it doesn't exist
@@ -282,7 +380,7 @@ func (p *Provider) AddFeature(ctx context.Context, codeDir string, description s
 	}
 
 	// Copy files back
-	if err := p.containerProvider.CopyFilesFromContainer(ctx, p.containerID, containerPath, absLocalPath); err != nil {
+	if err := p.copyFromWorkspace(ctx, absLocalPath); err != nil {
 		return "", fmt.Errorf("failed to copy files from container: %w", err)
 	}
 
@@ -302,72 +400,153 @@ func (p *Provider) AddFeature(ctx context.Context, codeDir string, description s
 	return output, nil
 }
 
-// AnalyzeCode analyzes existing code and provides feedback
-func (p *Provider) AnalyzeCode(ctx context.Context, codeDir string) (string, error) {
+// AnalyzeCode analyzes existing code and reports findings and scores. When Claude does not
+// answer with the requested JSON, the whole answer is used as the summary.
+func (p *Provider) AnalyzeCode(ctx context.Context, codeDir string) (*ai.AnalysisReport, error) {
 	// Make sure we have a container
-	if err := p.ensureContainer(ctx); err != nil {
-		return "", err
+	if err := p.ensureContainer(ctx, p.config[ai.FrameworkKey]); err != nil {
+		return nil, err
 	}
 
 	// Create relative path for code directory
-	containerPath := "/workspace"
+	containerPath := containerWorkspace
 	localPath := codeDir
 
 	// Clean workspace directory in container
-	cleanCmd := []string{"rm", "-rf", containerPath + "/*"}
-	_, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, cleanCmd)
-	if err != nil {
-		return "", fmt.Errorf("failed to clean workspace directory: %w", err)
+	if err := p.cleanWorkspace(ctx); err != nil {
+		return nil, fmt.Errorf("failed to clean workspace directory: %w", err)
 	}
 
 	// Create workspace directory
 	createDirCmd := []string{"mkdir", "-p", containerPath}
-	_, err = p.containerProvider.ExecuteCommand(ctx, p.containerID, createDirCmd)
+	_, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, createDirCmd)
 	if err != nil {
-		return "", fmt.Errorf("failed to create workspace directory: %w", err)
+		return nil, fmt.Errorf("failed to create workspace directory: %w", err)
 	}
 
 	// Copy files to container
 	fmt.Printf("Copying project files from %s to container for analysis...\n", localPath)
-	if err := p.containerProvider.CopyFilesToContainer(ctx, p.containerID, localPath, containerPath); err != nil {
-		return "", fmt.Errorf("failed to copy files to container: %w", err)
+	if err := p.copyToWorkspace(ctx, localPath); err != nil {
+		return nil, fmt.Errorf("failed to copy files to container: %w", err)
 	}
 
 	// Verify files were copied correctly
-	lsCmd := []string{"find", containerPath, "-type", "f", "-not", "-path", "*/\\.*", "|", "sort"}
+	lsCmd := []string{"sh", "-c", fmt.Sprintf("find %s -type f -not -path '*/.*' | sort", containerPath)}
 	lsOutput, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, lsCmd)
 	if err != nil {
-		return "", fmt.Errorf("failed to list files in container: %w", err)
+		return nil, fmt.Errorf("failed to list files in container: %w", err)
 	}
 	fmt.Printf("Files in container for analysis:\n%s\n",
 		truncateString(lsOutput, 200))
 
 	// Create prompt for Claude
-	prompt := "Analyze the codebase in /workspace\n\n" +
-		"Please provide a comprehensive analysis of the codebase including:\n" +
-		"1. Overall architecture and design patterns used\n" +
-		"2. Code quality assessment with specific examples\n" +
-		"3. Potential issues or improvements, prioritized by impact\n" +
-		"4. Security concerns and recommendations\n" +
-		"5. Performance considerations and possible optimizations\n" +
-		"6. Maintainability assessment and technical debt\n" +
-		"7. Documentation quality and completeness\n" +
-		"8. Test coverage and quality assessment\n\n" +
-		"Format your analysis as a structured report with clear sections and bullet points."
+	prompt, err := p.renderPrompt(prompt.Analyze, prompt.Data{Dir: containerPath})
+	if err != nil {
+		return nil, err
+	}
 
 	// Execute command in container
 	fmt.Printf("Starting Claude code analysis...\n")
 	cmd := []string{"claude", "code", "analyze", "--dir", containerPath, prompt}
-	output, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, cmd)
+	output, err := p.runClaude(ctx, cmd, containerPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute command: %w", err)
+		return nil, err
 	}
 
 	fmt.Printf("Code analysis complete. Generated %d characters of analysis.\n", len(output))
 
+	report, err := ai.ParseAnalysisReport(output)
+	if err != nil {
+		fmt.Printf("Warning: Failed to parse structured analysis, keeping it as summary: %v\n", err)
+		return &ai.AnalysisReport{Summary: strings.TrimSpace(output), Scores: map[string]int{}}, nil
+	}
+
+	return report, nil
+}
+
+// renderPrompt renders the prompt template name with data, using the overrides configured
+func (p *Provider) renderPrompt(name string, data prompt.Data) (string, error) {
+	return prompt.FromConfig(p.config).Render(name, data)
+}
+
+// runClaude runs a Claude Code CLI command in the container, reporting its output and the
+// files it touches under codePath as progress while it runs, and returns the whole output.
+// The usage of the command is reported as well, with token counts and cost when the CLI
+// prints its JSON result (claude_output_format set to json or stream-json).
+func (p *Provider) runClaude(ctx context.Context, cmd []string, codePath string) (string, error) {
+	if format := p.config["claude_output_format"]; format != "" && len(cmd) > 3 {
+		cmd = append(append(append([]string{}, cmd[:3]...), "--output-format", format), cmd[3:]...)
+	}
+
+	progress := ai.NewProgressWriter(ctx, codePath)
+	err := p.containerProvider.ExecuteCommandStream(ctx, p.containerID, cmd, progress)
+	progress.Flush()
+
+	// Tokens are spent even when the command fails. The CLI reports them in its result once it
+	// exits, so a budget can only stop commands running alongside this one, never this one.
+	output := progress.Output()
+	usage := ai.Usage{Duration: progress.Elapsed()}
+	if result, ok := parseResult(output); ok {
+		usage.InputTokens = result.Usage.InputTokens + result.Usage.CacheCreationInputTokens + result.Usage.CacheReadInputTokens
+		usage.OutputTokens = result.Usage.OutputTokens
+		usage.CostUSD = result.TotalCostUSD
+		if usage.CostUSD == 0 {
+			usage.CostUSD = result.CostUSD
+		}
+		output = result.Result
+	}
+	ai.ReportUsage(ctx, usage)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to execute command: %w", err)
+	}
+
+	fmt.Printf("Claude finished in %s, touching %d files",
+		usage.Duration.Round(time.Second), len(progress.Files()))
+	if usage.Tokens() > 0 {
+		fmt.Printf(" (%d tokens, $%.4f)", usage.Tokens(), usage.CostUSD)
+	}
+	fmt.Println()
+
 	return output, nil
 }
 
+// claudeResult is the result the Claude Code CLI prints last in its JSON output formats
+type claudeResult struct {
+	Type         string  `json:"type"`
+	Result       string  `json:"result"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	CostUSD      float64 `json:"cost_usd"`
+	Usage        struct {
+		InputTokens              int `json:"input_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// parseResult finds the result in the output of the Claude Code CLI, either the whole
+// output (json format) or its last result line (stream-json format)
+func parseResult(output string) (*claudeResult, bool) {
+	var result claudeResult
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &result); err == nil && result.Type == "result" {
+		return &result, true
+	}
+
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		result = claudeResult{}
+		if err := json.Unmarshal([]byte(line), &result); err == nil && result.Type == "result" {
+			return &result, true
+		}
+	}
+	return nil, false
+}
+
 // Name returns the provider's name
 func (p *Provider) Name() string {
 	return "claude"
@@ -375,7 +554,23 @@ func (p *Provider) Name() string {
 
 // SupportedFrameworks returns the frameworks this provider can work with
 func (p *Provider) SupportedFrameworks() []string {
-	return p.frameworks
+	frameworks := make([]string, len(p.profiles))
+	for i, framework := range p.profiles {
+		frameworks[i] = framework.Name
+	}
+	return frameworks
+}
+
+// profile returns the profile of a framework supported by the provider, or the registered
+// profile of another framework
+func (p *Provider) profile(name string) profile.Profile {
+	for _, framework := range p.profiles {
+		if strings.EqualFold(framework.Name, name) {
+			return framework
+		}
+	}
+	framework, _ := profile.Get(name)
+	return framework
 }
 
 // Cleanup performs necessary cleanup operations
@@ -397,14 +592,17 @@ func (p *Provider) Cleanup(ctx context.Context) error {
 	return nil
 }
 
-// ensureContainer ensures a container is running with proper authentication
-func (p *Provider) ensureContainer(ctx context.Context) error {
+// ensureContainer ensures a container is running with proper authentication. The container
+// runs the generation image of the profile of framework if it has one, never its toolchain
+// image, which lacks the Claude Code CLI.
+func (p *Provider) ensureContainer(ctx context.Context, framework string) error {
 	if p.containerID != "" {
 		// Check if container is still running
 		pingCmd := []string{"echo", "ping"}
 		_, err := p.containerProvider.ExecuteCommand(ctx, p.containerID, pingCmd)
 		if err == nil {
 			// Container is still running
+			ai.ReportEgress(ctx, p.egress.String())
 			return nil
 		}
 		fmt.Printf("Container with ID %s is no longer available, recreating...\n", p.containerID)
@@ -413,7 +611,12 @@ func (p *Provider) ensureContainer(ctx context.Context) error {
 	}
 
 	// Get container image
-	image := p.config["claude_image"]
+	image := p.profile(framework).GenerationImage
+	if image != "" {
+		fmt.Printf("Using the %s generation image: %s\n", framework, image)
+	} else {
+		image = p.config["claude_image"]
+	}
 	if image == "" {
 		// Check if image is set in environment
 		envImage := os.Getenv("CLAUDE_CODE_IMAGE")
@@ -474,13 +677,22 @@ func (p *Provider) ensureContainer(ctx context.Context) error {
 	}
 	fmt.Printf("Created workspace directory: %s\n", tmpDir)
 
+	// Mount the configured workspace directory if there is one, otherwise the temporary one
+	workspaceDir := tmpDir
+	if dir := p.config[ai.WorkspaceDirKey]; dir != "" {
+		workspaceDir = dir
+	}
+
 	// Set up volume mounts - ensure absolute paths
 	volumeMounts := make(map[string]string)
-	absoluteTmpDir, err := filepath.Abs(tmpDir)
+	absoluteWorkspaceDir, err := filepath.Abs(workspaceDir)
 	if err != nil {
 		return fmt.Errorf("failed to get absolute path for workspace directory: %w", err)
 	}
-	volumeMounts[absoluteTmpDir] = "/workspace"
+	p.workspaceDir = absoluteWorkspaceDir
+	if !p.pooled() {
+		volumeMounts[absoluteWorkspaceDir] = containerWorkspace
+	}
 
 	// Set up environment variables
 	env := make(map[string]string)
@@ -523,9 +735,38 @@ func (p *Provider) ensureContainer(ctx context.Context) error {
 	env["CLAUDE_CLI_LOG_LEVEL"] = "info" // Set logging level
 	env["HOME"] = "/home/node"           // Ensure HOME is set correctly for Claude CLI
 
+	// Limit and harden the container as the container config says
+	options, err := container.ParseRunOptions(p.containerConfig())
+	if err != nil {
+		return fmt.Errorf("invalid container options: %w", err)
+	}
+
+	// Restrict the network access of the container, always letting the Claude CLI reach the API
+	egress, err := container.ParseEgressPolicy(p.containerConfig())
+	if err != nil {
+		return fmt.Errorf("invalid egress policy: %w", err)
+	}
+	egress = egress.With(apiDomain)
+	if egress.Mode == container.EgressNone {
+		fmt.Println("Warning: The egress policy is none, so the Claude CLI cannot reach the Claude API")
+	}
+	if err := egress.Apply(&options); err != nil {
+		return fmt.Errorf("invalid egress policy: %w", err)
+	}
+	if p.pooled() && options.ReadOnly {
+		return fmt.Errorf("read_only containers cannot be pooled, they need the workspace mounted to write code")
+	}
+
+	// Label the container with its project and egress policy, so pools lease it again only
+	// for the same project and policy
+	options.Labels = map[string]string{container.EgressLabel: egress.String()}
+	if name := p.config[ai.ProjectNameKey]; name != "" {
+		options.Labels[container.ProjectLabel] = name
+	}
+
 	// Run container
 	fmt.Printf("Starting Claude container with image: %s\n", image)
-	containerID, err := p.containerProvider.RunContainer(ctx, image, volumeMounts, env)
+	containerID, err := p.containerProvider.RunContainer(ctx, image, volumeMounts, env, options)
 	if err != nil {
 		return fmt.Errorf("failed to run container: %w", err)
 	}
@@ -533,6 +774,29 @@ func (p *Provider) ensureContainer(ctx context.Context) error {
 	p.containerID = containerID
 	fmt.Printf("Claude container started with ID: %s\n", containerID)
 
+	// Pooled containers leased again are set up already
+	if p.pooled() {
+		ready, err := p.containerProvider.ExecuteCommand(ctx, containerID, []string{"cat", readyFile})
+		if err == nil && strings.TrimSpace(ready) == egress.String() {
+			fmt.Println("Reusing the warm container, which is set up already")
+			p.egress = egress
+			ai.ReportEgress(ctx, egress.String())
+			return nil
+		}
+	}
+
+	// Set up the firewall before anything runs, and never use a container it failed in
+	if egress.Mode == container.EgressAllowlist {
+		fmt.Printf("Restricting network access to %s\n", strings.Join(egress.Allow, ", "))
+	}
+	if err := egress.Enforce(ctx, p.containerProvider, containerID); err != nil {
+		if cleanupErr := p.Cleanup(context.Background()); cleanupErr != nil {
+			fmt.Printf("Warning: Failed to remove container: %v\n", cleanupErr)
+		}
+		return err
+	}
+	p.egress = egress
+
 	// Create a temporary .env file
 	envFile := filepath.Join(tmpDir, ".env")
 	envContent := fmt.Sprintf("CLAUDE_API_KEY=%s\n", apiKey)
@@ -614,6 +878,16 @@ func (p *Provider) ensureContainer(ctx context.Context) error {
 	}
 
 	fmt.Printf("Claude CLI is operational: %s\n", strings.TrimSpace(testOutput))
+
+	// Let the commands leasing the container from the pool later skip the setup
+	if p.pooled() {
+		readyCmd := []string{"sh", "-c", `printf '%s\n' "$1" > ` + readyFile, "sh", egress.String()}
+		if _, err := p.containerProvider.ExecuteCommand(ctx, containerID, readyCmd); err != nil {
+			fmt.Printf("Warning: Failed to mark the container as set up: %v\n", err)
+		}
+	}
+
+	ai.ReportEgress(ctx, egress.String())
 	return nil
 }
 
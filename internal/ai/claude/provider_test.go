@@ -2,11 +2,19 @@ package claude_test
 
 import (
 	"context"
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fr0g-66723067/cc/internal/ai"
 	"github.com/fr0g-66723067/cc/internal/ai/claude"
+	"github.com/fr0g-66723067/cc/internal/budget"
+	"github.com/fr0g-66723067/cc/internal/container"
 	"github.com/fr0g-66723067/cc/internal/container/mocks"
+	"github.com/fr0g-66723067/cc/internal/profile"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -49,10 +57,17 @@ func setupMockContainerProvider(t *testing.T) *mocks.MockProvider {
 
 	// Set up mock methods
 	mockProvider.On("Initialize", mock.Anything, mock.Anything).Return(nil)
-	mockProvider.On("RunContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("test-container-id", nil)
+	mockProvider.On("RunContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("test-container-id", nil)
 	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", []string{"echo", "ping"}).Return("ping", nil)
 	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", []string{"claude", "code", "--version"}).Return("Claude Code CLI v1.0.0", nil)
 	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", mock.Anything).Return("Command executed successfully", nil)
+	mockProvider.On("ExecuteCommandStream", mock.Anything, "test-container-id", mock.MatchedBy(func(cmd []string) bool {
+		return len(cmd) > 2 && cmd[0] == "claude" && cmd[2] == "analyze"
+	}), mock.Anything).Return(sampleAnalysis, nil)
+	mockProvider.On("ExecuteCommandStream", mock.Anything, "test-container-id", mock.MatchedBy(func(cmd []string) bool {
+		return len(cmd) > 2 && cmd[0] == "claude" && cmd[2] == "generate"
+	}), mock.Anything).Return(sampleGeneration, nil)
+	mockProvider.On("ExecuteCommandStream", mock.Anything, "test-container-id", mock.Anything, mock.Anything).Return("Command executed successfully", nil)
 	mockProvider.On("CopyFilesToContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockProvider.On("CopyFilesFromContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	mockProvider.On("StopContainer", mock.Anything, mock.Anything).Return(nil)
@@ -116,16 +131,40 @@ func TestGenerateProjectWithMock(t *testing.T) {
 	err = provider.Initialize(ctx, nil)
 	require.NoError(t, err)
 
-	// Test generate project
+	// Test generate project, collecting the progress
+	var progress []ai.Progress
+	progressCtx := ai.WithProgress(ctx, func(p ai.Progress) {
+		progress = append(progress, p)
+	})
 	description := "Create a test project with React"
-	output, err := provider.GenerateProject(ctx, description)
+	output, err := provider.GenerateProject(progressCtx, description)
 	require.NoError(t, err)
-	assert.NotEmpty(t, output)
+	assert.Equal(t, sampleGeneration, output)
+
+	// Every line and every touched file is reported once
+	var lines, files []string
+	for _, p := range progress {
+		if p.File != "" {
+			files = append(files, p.File)
+		} else {
+			lines = append(lines, p.Line)
+		}
+	}
+	assert.Equal(t, strings.Split(sampleGeneration, "\n"), lines)
+	assert.Equal(t, []string{"package.json", "src/App.jsx"}, files)
 
 	// Verify mock was called with expected commands
 	mockProvider.AssertCalled(t, "ExecuteCommand", mock.Anything, "test-container-id", mock.Anything)
+	mockProvider.AssertCalled(t, "ExecuteCommandStream", mock.Anything, "test-container-id", mock.Anything, mock.Anything)
 }
 
+// sampleGeneration is the output of the mock Claude CLI to generation requests
+const sampleGeneration = "Planning the project structure\n" +
+	"Write(/workspace/package.json)\n" +
+	"Write(src/App.jsx)\n" +
+	"Updated /workspace/src/App.jsx with the main component\n" +
+	"Done"
+
 // TestAddFeatureWithMock tests adding a feature with a mock container provider
 func TestAddFeatureWithMock(t *testing.T) {
 	// Skip the test if we don't want to run integration tests
@@ -172,10 +211,117 @@ func TestAddFeatureWithMock(t *testing.T) {
 
 	// Verify mocks were called with expected commands
 	mockProvider.AssertCalled(t, "CopyFilesToContainer", mock.Anything, "test-container-id", mock.Anything, mock.Anything)
-	mockProvider.AssertCalled(t, "ExecuteCommand", mock.Anything, "test-container-id", mock.Anything)
+	mockProvider.AssertCalled(t, "ExecuteCommandStream", mock.Anything, "test-container-id", mock.Anything, mock.Anything)
 	mockProvider.AssertCalled(t, "CopyFilesFromContainer", mock.Anything, "test-container-id", mock.Anything, mock.Anything)
 }
 
+// TestResolveConflictsWithMock tests resolving conflicts with a mock container provider
+func TestResolveConflictsWithMock(t *testing.T) {
+	// Skip the test if we don't want to run integration tests
+	if os.Getenv("SKIP_INTEGRATION_TESTS") == "true" {
+		t.Skip("Skipping integration test")
+	}
+
+	// Create mock container provider
+	mockProvider := setupMockContainerProvider(t)
+
+	// Create Claude provider with test config
+	config := map[string]string{
+		"container_provider": "mock",
+		"claude_api_key":     "test-api-key",
+	}
+	provider, err := claude.NewProvider(config)
+	require.NoError(t, err)
+	require.NotNil(t, provider)
+
+	// Set mock container provider
+	err = provider.SetContainerProviderForTest(mockProvider)
+	require.NoError(t, err)
+
+	// Initialize provider
+	ctx := context.Background()
+	err = provider.Initialize(ctx, nil)
+	require.NoError(t, err)
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "claude-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	// Test resolve conflicts
+	request := ai.ConflictRequest{
+		OursDescription:   "React todo app",
+		TheirsDescription: "Add a dark mode toggle",
+		Files: []ai.ConflictFile{
+			{Path: "src/App.js", Base: "base version", Ours: "our version", Theirs: "their version"},
+		},
+	}
+	output, err := provider.ResolveConflicts(ctx, tempDir, request)
+	require.NoError(t, err)
+	assert.NotEmpty(t, output)
+
+	// Verify the prompt contains both sides of the conflict
+	mockProvider.AssertCalled(t, "ExecuteCommandStream", mock.Anything, "test-container-id", mock.MatchedBy(func(cmd []string) bool {
+		if len(cmd) < 2 || cmd[0] != "claude" {
+			return false
+		}
+		prompt := cmd[len(cmd)-1]
+		return strings.Contains(prompt, "src/App.js") &&
+			strings.Contains(prompt, "our version") &&
+			strings.Contains(prompt, "their version") &&
+			strings.Contains(prompt, "Add a dark mode toggle")
+	}), mock.Anything)
+	mockProvider.AssertCalled(t, "CopyFilesFromContainer", mock.Anything, "test-container-id", mock.Anything, mock.Anything)
+}
+
+// sampleAnalysis is the answer of the mock Claude CLI to analysis requests
+const sampleAnalysis = "Here is the analysis:\n```json\n" + `{
+  "summary": "Express API with a single router",
+  "scores": {"security": 40, "tests": 60},
+  "findings": [
+    {"severity": "medium", "category": "testing", "file": "test/app.test.js", "title": "Missing error case tests"},
+    {"severity": "HIGH", "category": "security", "file": "src/db.js", "line": 12, "title": "SQL injection"}
+  ]
+}` + "\n```"
+
+// TestAnalyzeCodeWithMock tests analyzing code with a mock container provider
+func TestAnalyzeCodeWithMock(t *testing.T) {
+	// Skip the test if we don't want to run integration tests
+	if os.Getenv("SKIP_INTEGRATION_TESTS") == "true" {
+		t.Skip("Skipping integration test")
+	}
+
+	// Create mock container provider
+	mockProvider := setupMockContainerProvider(t)
+
+	// Create Claude provider with test config
+	config := map[string]string{
+		"container_provider": "mock",
+		"claude_api_key":     "test-api-key",
+	}
+	provider, err := claude.NewProvider(config)
+	require.NoError(t, err)
+	require.NoError(t, provider.SetContainerProviderForTest(mockProvider))
+
+	ctx := context.Background()
+	require.NoError(t, provider.Initialize(ctx, nil))
+
+	tempDir, err := os.MkdirTemp("", "claude-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	// Test analyze code
+	report, err := provider.AnalyzeCode(ctx, tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, "Express API with a single router", report.Summary)
+	assert.Equal(t, 50, report.Scores[ai.OverallScore])
+	require.Len(t, report.Findings, 2)
+	assert.Equal(t, ai.SeverityHigh, report.Findings[0].Severity)
+	assert.Equal(t, ai.CategorySecurity, report.Findings[0].Category)
+	assert.Equal(t, 12, report.Findings[0].Line)
+	assert.Equal(t, ai.CategoryTests, report.Findings[1].Category)
+}
+
 // TestCleanupWithMock tests cleanup operations with a mock container provider
 func TestCleanupWithMock(t *testing.T) {
 	// Create mock container provider
@@ -211,3 +357,307 @@ func TestCleanupWithMock(t *testing.T) {
 	mockProvider.AssertCalled(t, "StopContainer", mock.Anything, "test-container-id")
 	mockProvider.AssertCalled(t, "RemoveContainer", mock.Anything, "test-container-id")
 }
+
+// sampleStreamResult is the output of the mock Claude CLI in stream-json format
+const sampleStreamResult = `{"type":"system","subtype":"init"}
+{"type":"assistant","message":{"content":[{"type":"text","text":"Adding dark mode"}]}}
+{"type":"result","subtype":"success","result":"Added a dark mode toggle","total_cost_usd":0.125,"usage":{"input_tokens":1000,"cache_read_input_tokens":500,"output_tokens":250}}`
+
+// TestUsageFromJSONOutput tests reporting the tokens and cost of the Claude CLI's JSON result
+func TestUsageFromJSONOutput(t *testing.T) {
+	mockProvider := new(mocks.MockProvider)
+	mockProvider.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	mockProvider.On("RunContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("test-container-id", nil)
+	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", mock.Anything).Return("ok", nil)
+	mockProvider.On("ExecuteCommandStream", mock.Anything, "test-container-id", mock.MatchedBy(func(cmd []string) bool {
+		return len(cmd) > 4 && cmd[2] == "modify" && cmd[3] == "--output-format" && cmd[4] == "stream-json"
+	}), mock.Anything).Return(sampleStreamResult, nil)
+	mockProvider.On("CopyFilesToContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockProvider.On("CopyFilesFromContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	provider, err := claude.NewProvider(map[string]string{
+		"claude_api_key":       "test-api-key",
+		"claude_output_format": "stream-json",
+	})
+	require.NoError(t, err)
+	require.NoError(t, provider.SetContainerProviderForTest(mockProvider))
+
+	ctx, tracker := ai.TrackUsage(context.Background())
+	require.NoError(t, provider.Initialize(ctx, nil))
+
+	output, err := provider.AddFeature(ctx, t.TempDir(), "Add dark mode")
+	require.NoError(t, err)
+	assert.Equal(t, "Added a dark mode toggle", output)
+
+	usage := tracker.Usage()
+	assert.Equal(t, 1500, usage.InputTokens)
+	assert.Equal(t, 250, usage.OutputTokens)
+	assert.Equal(t, 0.125, usage.CostUSD)
+	assert.Greater(t, usage.Duration, time.Duration(0))
+}
+
+// TestBudgetCancelsRunningCommand tests that a running command is canceled once another call
+// reaches a budget cap. Usage is only reported when a command exits, so a command never
+// stops itself: a single call can overrun a cap by its own usage.
+func TestBudgetCancelsRunningCommand(t *testing.T) {
+	ledger := budget.NewLedger(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	ctx, cancel := budget.NewGuard(ledger, budget.Limits{ProjectCostUSD: 1}, "todo").Context(context.Background())
+	defer cancel()
+
+	started := make(chan struct{}, 1)
+	mockProvider := new(mocks.MockProvider)
+	mockProvider.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	mockProvider.On("RunContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("test-container-id", nil)
+	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", mock.Anything).Return("ok", nil)
+	mockProvider.On("ExecuteCommandStream", mock.Anything, "test-container-id", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		// Run until canceled, like the container providers do
+		started <- struct{}{}
+		<-args.Get(0).(context.Context).Done()
+	}).Return("", context.Canceled).Once()
+	mockProvider.On("StopContainer", mock.Anything, "test-container-id").Return(nil)
+	mockProvider.On("RemoveContainer", mock.Anything, "test-container-id").Return(nil)
+
+	provider, err := claude.NewProvider(map[string]string{"claude_api_key": "test-api-key"})
+	require.NoError(t, err)
+	require.NoError(t, provider.SetContainerProviderForTest(mockProvider))
+	require.NoError(t, provider.Initialize(ctx, nil))
+
+	// Another call, like one generating a parallel implementation, finishes over the cap
+	go func() {
+		<-started
+		ai.ReportUsage(ctx, ai.Usage{CostUSD: 1.5})
+	}()
+
+	_, err = provider.GenerateProject(ctx, "A todo app")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, budget.Exceeded(ctx), budget.ErrExceeded)
+	mockProvider.AssertNumberOfCalls(t, "ExecuteCommandStream", 1)
+}
+
+// TestFrameworkProfile tests generating code in the generation image and with the
+// instructions of a framework profile, never in its toolchain image
+func TestFrameworkProfile(t *testing.T) {
+	profile.Register(profile.Profile{
+		Name:            "phoenix",
+		Instructions:    "Use LiveView for interactive pages.",
+		Image:           "elixir:1.16",
+		GenerationImage: "claude-elixir:latest",
+		EntryFiles:      []string{"mix.exs"},
+	})
+
+	mockProvider := new(mocks.MockProvider)
+	mockProvider.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	mockProvider.On("RunContainer", mock.Anything, "claude-elixir:latest", mock.Anything, mock.Anything, mock.Anything).Return("test-container-id", nil)
+	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", mock.Anything).Return("ok", nil)
+	mockProvider.On("ExecuteCommandStream", mock.Anything, "test-container-id", mock.MatchedBy(func(cmd []string) bool {
+		prompt := cmd[len(cmd)-1]
+		return strings.Contains(prompt, "Create a phoenix implementation") &&
+			strings.Contains(prompt, "6. Include these files: mix.exs") &&
+			strings.Contains(prompt, "phoenix instructions:\nUse LiveView for interactive pages.")
+	}), mock.Anything).Return(sampleGeneration, nil)
+
+	provider, err := claude.NewProvider(map[string]string{"claude_api_key": "test-api-key"})
+	require.NoError(t, err)
+	assert.Contains(t, provider.SupportedFrameworks(), "phoenix")
+	require.NoError(t, provider.SetContainerProviderForTest(mockProvider))
+	require.NoError(t, provider.Initialize(context.Background(), nil))
+
+	_, err = provider.GenerateImplementation(context.Background(), "A chat app", "phoenix")
+	require.NoError(t, err)
+	mockProvider.AssertExpectations(t)
+}
+
+// TestEgressPolicy tests restricting the network access of the container to an allowlist
+// and reporting the policy
+func TestEgressPolicy(t *testing.T) {
+	firewallCmd := mock.MatchedBy(func(command []string) bool {
+		return len(command) == 6 && command[3] == container.FirewallScript &&
+			command[4] == "registry.npmjs.org" && command[5] == "api.anthropic.com"
+	})
+	config := map[string]string{
+		"claude_api_key":         "test-api-key",
+		"claude_image":           "claude-code:latest",
+		"container_egress":       "allowlist",
+		"container_egress_allow": "registry.npmjs.org",
+		"container_cap_drop":     "MKNOD",
+	}
+
+	// The firewall is set up in a container allowed to administer its network, and the
+	// Claude API is always allowed
+	mockProvider := new(mocks.MockProvider)
+	mockProvider.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	mockProvider.On("RunContainer", mock.Anything, "claude-code:latest", mock.Anything, mock.Anything, container.RunOptions{
+		CapDrop: []string{"MKNOD"},
+		CapAdd:  []string{"NET_ADMIN", "NET_RAW"},
+		Labels:  map[string]string{container.EgressLabel: "allowlist: registry.npmjs.org, api.anthropic.com"},
+	}).Return("test-container-id", nil)
+	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", firewallCmd).Return("Firewall configuration complete", nil).Once()
+	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", mock.Anything).Return("ok", nil)
+	mockProvider.On("ExecuteCommandStream", mock.Anything, "test-container-id", mock.Anything, mock.Anything).Return(sampleGeneration, nil)
+
+	provider, err := claude.NewProvider(config)
+	require.NoError(t, err)
+	require.NoError(t, provider.SetContainerProviderForTest(mockProvider))
+	require.NoError(t, provider.Initialize(context.Background(), nil))
+
+	var egress []string
+	ctx := ai.WithEgress(context.Background(), func(policy string) { egress = append(egress, policy) })
+	_, err = provider.GenerateProject(ctx, "A todo app")
+	require.NoError(t, err)
+	_, err = provider.GenerateProject(ctx, "A todo app")
+	require.NoError(t, err)
+	mockProvider.AssertExpectations(t)
+
+	// Calls reusing the container report its policy as well
+	assert.Equal(t, []string{"allowlist: registry.npmjs.org, api.anthropic.com", "allowlist: registry.npmjs.org, api.anthropic.com"}, egress)
+
+	// Containers whose firewall fails are removed instead of used
+	failing := new(mocks.MockProvider)
+	failing.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	failing.On("RunContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("test-container-id", nil)
+	failing.On("ExecuteCommand", mock.Anything, "test-container-id", firewallCmd).Return("ipset: command not found", errors.New("exit status 127"))
+	failing.On("StopContainer", mock.Anything, "test-container-id").Return(nil)
+	failing.On("RemoveContainer", mock.Anything, "test-container-id").Return(nil)
+
+	provider, err = claude.NewProvider(config)
+	require.NoError(t, err)
+	require.NoError(t, provider.SetContainerProviderForTest(failing))
+	require.NoError(t, provider.Initialize(context.Background(), nil))
+
+	_, err = provider.GenerateProject(context.Background(), "A todo app")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to apply the egress allowlist")
+	assert.Contains(t, err.Error(), "ipset: command not found")
+	failing.AssertExpectations(t)
+	failing.AssertNotCalled(t, "ExecuteCommandStream", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// Allowlists that cannot be enforced and unknown modes are refused before starting a container
+	for key, value := range map[string]string{"container_cap_drop": "ALL", "container_egress": "partial"} {
+		config[key] = value
+		provider, err = claude.NewProvider(config)
+		require.NoError(t, err)
+		unused := new(mocks.MockProvider)
+		unused.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+		require.NoError(t, provider.SetContainerProviderForTest(unused))
+		require.NoError(t, provider.Initialize(context.Background(), nil))
+		_, err = provider.GenerateProject(context.Background(), "A todo app")
+		assert.ErrorContains(t, err, "invalid egress policy", key)
+		unused.AssertNotCalled(t, "RunContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
+// TestPooledContainer tests working in containers leased from a pool: the workspace is copied
+// rather than mounted, and containers set up by earlier commands are not set up again
+func TestPooledContainer(t *testing.T) {
+	workspace := t.TempDir()
+	config := map[string]string{
+		"claude_api_key":     "test-api-key",
+		"claude_image":       "claude-code:latest",
+		"container_provider": "pool",
+		ai.WorkspaceDirKey:   workspace,
+		ai.ProjectNameKey:    "todo",
+	}
+	readyCmd := []string{"cat", "/home/node/.cc-ready"}
+	versionCmd := []string{"claude", "code", "--version"}
+
+	// A fresh container is labelled with its project and set up
+	mockProvider := new(mocks.MockProvider)
+	mockProvider.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	mockProvider.On("RunContainer", mock.Anything, "claude-code:latest", map[string]string{}, mock.Anything, container.RunOptions{
+		Labels: map[string]string{container.EgressLabel: "full", container.ProjectLabel: "todo"},
+	}).Return("test-container-id", nil)
+	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", readyCmd).Return("", errors.New("exit status 1")).Once()
+	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", versionCmd).Return("1.0.0", nil).Once()
+	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", []string{"find", "/workspace", "-mindepth", "1", "-delete"}).Return("", nil).Once()
+	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", mock.Anything).Return("ok", nil)
+	mockProvider.On("ExecuteCommandStream", mock.Anything, "test-container-id", mock.Anything, mock.Anything).Return(sampleGeneration, nil)
+	mockProvider.On("CopyFilesFromContainer", mock.Anything, "test-container-id", "/workspace/.", workspace).Return(nil).Once()
+
+	provider, err := claude.NewProvider(config)
+	require.NoError(t, err)
+	require.NoError(t, provider.SetContainerProviderForTest(mockProvider))
+	require.NoError(t, provider.Initialize(context.Background(), nil))
+	_, err = provider.GenerateImplementation(context.Background(), "A todo app", "react")
+	require.NoError(t, err)
+	mockProvider.AssertExpectations(t)
+	mockProvider.AssertCalled(t, "ExecuteCommand", mock.Anything, "test-container-id", []string{"sh", "-c", `printf '%s\n' "$1" > /home/node/.cc-ready`, "sh", "full"})
+
+	// A container leased again skips the setup
+	leased := new(mocks.MockProvider)
+	leased.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	leased.On("RunContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("test-container-id", nil)
+	leased.On("ExecuteCommand", mock.Anything, "test-container-id", readyCmd).Return("full\n", nil)
+	leased.On("ExecuteCommand", mock.Anything, "test-container-id", mock.Anything).Return("ok", nil)
+	leased.On("ExecuteCommandStream", mock.Anything, "test-container-id", mock.Anything, mock.Anything).Return(sampleGeneration, nil)
+	leased.On("CopyFilesToContainer", mock.Anything, "test-container-id", workspace+"/.", "/workspace").Return(nil)
+	leased.On("CopyFilesFromContainer", mock.Anything, "test-container-id", "/workspace/.", workspace).Return(nil)
+
+	provider, err = claude.NewProvider(config)
+	require.NoError(t, err)
+	require.NoError(t, provider.SetContainerProviderForTest(leased))
+	require.NoError(t, provider.Initialize(context.Background(), nil))
+	_, err = provider.AddFeature(context.Background(), workspace, "Add due dates")
+	require.NoError(t, err)
+	leased.AssertExpectations(t)
+	leased.AssertNotCalled(t, "ExecuteCommand", mock.Anything, "test-container-id", versionCmd)
+
+	// Pooled containers have no mount to write code to when read-only
+	config["container_read_only"] = "true"
+	provider, err = claude.NewProvider(config)
+	require.NoError(t, err)
+	unused := new(mocks.MockProvider)
+	unused.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	require.NoError(t, provider.SetContainerProviderForTest(unused))
+	require.NoError(t, provider.Initialize(context.Background(), nil))
+	_, err = provider.GenerateProject(context.Background(), "A todo app")
+	assert.ErrorContains(t, err, "read_only containers cannot be pooled")
+}
+
+// TestMountedWorkspace tests working on the mounted workspace directory, whose files are
+// neither cleaned nor copied, so commands on a worktree keep its code
+func TestMountedWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, ".git"), []byte("gitdir: ../.git/worktrees/todo\n"), 0644))
+	config := map[string]string{
+		"claude_api_key":   "test-api-key",
+		"claude_image":     "claude-code:latest",
+		ai.WorkspaceDirKey: workspace,
+	}
+
+	// Removing files in the container removes them from the mounted directory
+	deletes := func(command []string) bool {
+		joined := strings.Join(command, " ")
+		return strings.Contains(joined, "rm ") || strings.Contains(joined, "-delete")
+	}
+	mockProvider := new(mocks.MockProvider)
+	mockProvider.On("Initialize", mock.Anything, mock.Anything).Return(nil)
+	mockProvider.On("RunContainer", mock.Anything, "claude-code:latest", map[string]string{workspace: "/workspace"}, mock.Anything, mock.Anything).Return("test-container-id", nil)
+	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", mock.MatchedBy(deletes)).Run(func(args mock.Arguments) {
+		entries, _ := os.ReadDir(workspace)
+		for _, entry := range entries {
+			os.RemoveAll(filepath.Join(workspace, entry.Name()))
+		}
+	}).Return("", nil)
+	mockProvider.On("ExecuteCommand", mock.Anything, "test-container-id", mock.Anything).Return("ok", nil)
+	mockProvider.On("ExecuteCommandStream", mock.Anything, "test-container-id", mock.Anything, mock.Anything).Return(sampleGeneration, nil)
+
+	provider, err := claude.NewProvider(config)
+	require.NoError(t, err)
+	require.NoError(t, provider.SetContainerProviderForTest(mockProvider))
+	require.NoError(t, provider.Initialize(context.Background(), nil))
+
+	_, err = provider.AddFeature(context.Background(), workspace, "Add due dates")
+	require.NoError(t, err)
+	_, err = provider.AnalyzeCode(context.Background(), workspace)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(workspace, "main.go"))
+	assert.FileExists(t, filepath.Join(workspace, ".git"))
+	mockProvider.AssertNotCalled(t, "CopyFilesToContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockProvider.AssertNotCalled(t, "CopyFilesFromContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// Other directories are not copied into the mounted directory
+	_, err = provider.AddFeature(context.Background(), t.TempDir(), "Add due dates")
+	assert.ErrorContains(t, err, "is not the mounted workspace directory")
+	assert.FileExists(t, filepath.Join(workspace, "main.go"))
+}
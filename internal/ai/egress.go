@@ -0,0 +1,24 @@
+package ai
+
+import "context"
+
+// EgressFunc receives the egress policy of the container an AI call runs in, like
+// "allowlist: api.anthropic.com". Calls running in parallel call it concurrently.
+type EgressFunc func(policy string)
+
+// egressKey is the context key of the egress function
+type egressKey struct{}
+
+// WithEgress returns a context making providers report the egress policy of the containers
+// calls run in to fn
+func WithEgress(ctx context.Context, fn EgressFunc) context.Context {
+	return context.WithValue(ctx, egressKey{}, fn)
+}
+
+// ReportEgress reports the egress policy of a call's container to the egress function of
+// ctx, if any. Providers not running calls in containers report nothing.
+func ReportEgress(ctx context.Context, policy string) {
+	if fn, ok := ctx.Value(egressKey{}).(EgressFunc); ok && fn != nil {
+		fn(policy)
+	}
+}
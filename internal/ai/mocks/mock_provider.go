@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 
+	"github.com/fr0g-66723067/cc/internal/ai"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -35,9 +36,16 @@ func (m *MockProvider) AddFeature(ctx context.Context, codeDir string, descripti
 	return args.String(0), args.Error(1)
 }
 
-// AnalyzeCode analyzes existing code and provides feedback
-func (m *MockProvider) AnalyzeCode(ctx context.Context, codeDir string) (string, error) {
+// AnalyzeCode analyzes existing code and reports findings and scores
+func (m *MockProvider) AnalyzeCode(ctx context.Context, codeDir string) (*ai.AnalysisReport, error) {
 	args := m.Called(ctx, codeDir)
+	report, _ := args.Get(0).(*ai.AnalysisReport)
+	return report, args.Error(1)
+}
+
+// ResolveConflicts resolves merge conflicts in codeDir, writing the resolved files in place
+func (m *MockProvider) ResolveConflicts(ctx context.Context, codeDir string, request ai.ConflictRequest) (string, error) {
+	args := m.Called(ctx, codeDir, request)
 	return args.String(0), args.Error(1)
 }
 
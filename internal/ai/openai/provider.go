@@ -0,0 +1,541 @@
+// Package openai implements an AI provider for OpenAI-compatible chat completion APIs,
+// such as vLLM, the llama.cpp server and LM Studio. Code never leaves the machines
+// serving the configured endpoint.
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/ai/prompt"
+	"github.com/fr0g-66723067/cc/internal/profile"
+	"github.com/fr0g-66723067/cc/internal/source"
+)
+
+const (
+	// defaultBaseURL is the address of a llama.cpp server running locally
+	defaultBaseURL = "http://localhost:8080/v1"
+
+	// defaultTimeout is how long a chat completion may take, local models can be slow
+	defaultTimeout = 10 * time.Minute
+
+	// defaultMaxContextBytes is how much existing code is sent along with a request
+	defaultMaxContextBytes = 256 * 1024
+
+	// maxFileBytes is the size of the largest file sent along with a request
+	maxFileBytes = 100 * 1024
+)
+
+// editInstructions tells the model how to answer requests that change code
+const editInstructions = `You are an expert software engineer. Answer with a single JSON object and nothing else, in this format:
+{
+  "summary": "description of the changes and the choices made",
+  "files": [
+    {"path": "path of a file to create or change, relative to the project root", "content": "complete new content of the file"},
+    {"path": "path of a file to remove", "delete": true}
+  ]
+}
+Always give the complete content of every file you create or change, never only the changed lines.`
+
+// Provider implements the AI provider interface for OpenAI-compatible HTTP APIs
+type Provider struct {
+	config     map[string]string
+	frameworks []string
+	client     *http.Client
+	baseURL    string
+}
+
+// NewProvider creates a new OpenAI-compatible provider
+func NewProvider(config map[string]string) (*Provider, error) {
+	if config == nil {
+		config = make(map[string]string)
+	}
+
+	// Use all framework profiles if no frameworks are specified
+	frameworks := profile.Names()
+
+	if customFrameworks, ok := config["frameworks"]; ok && customFrameworks != "" {
+		frameworks = strings.Split(customFrameworks, ",")
+	}
+
+	return &Provider{
+		config:     config,
+		frameworks: frameworks,
+		client:     &http.Client{Timeout: defaultTimeout},
+		baseURL:    defaultBaseURL,
+	}, nil
+}
+
+// Initialize sets up the provider. The endpoint is configured with openai_base_url,
+// openai_model, openai_api_key (or the OPENAI_API_KEY environment variable) and
+// openai_timeout in seconds.
+func (p *Provider) Initialize(ctx context.Context, config map[string]string) error {
+	// Merge configs
+	for k, v := range config {
+		p.config[k] = v
+	}
+
+	if baseURL := p.config["openai_base_url"]; baseURL != "" {
+		parsed, err := url.Parse(baseURL)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("invalid openai_base_url: %s", baseURL)
+		}
+		p.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+
+	if timeout := p.config["openai_timeout"]; timeout != "" {
+		seconds, err := strconv.Atoi(timeout)
+		if err != nil || seconds < 0 {
+			return fmt.Errorf("invalid openai_timeout: %s", timeout)
+		}
+		p.client.Timeout = time.Duration(seconds) * time.Second
+	}
+
+	return nil
+}
+
+// GenerateProject generates a project structure based on description
+func (p *Provider) GenerateProject(ctx context.Context, description string) (string, error) {
+	dir, err := p.workspaceDir()
+	if err != nil {
+		return "", err
+	}
+
+	prompt, err := p.renderPrompt(prompt.Project, prompt.Data{Description: description})
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Generating project structure for: %s\n", description)
+	return p.editCode(ctx, dir, prompt)
+}
+
+// GenerateImplementation generates code with a specific framework
+func (p *Provider) GenerateImplementation(ctx context.Context, description string, framework string) (string, error) {
+	dir, err := p.workspaceDir()
+	if err != nil {
+		return "", err
+	}
+
+	prompt, err := p.renderPrompt(prompt.Implementation, prompt.Data{Description: description, Framework: framework})
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Generating %s implementation for: %s\n", framework, description)
+	return p.editCode(ctx, dir, prompt)
+}
+
+// AddFeature adds a feature to existing code
+func (p *Provider) AddFeature(ctx context.Context, codeDir string, description string) (string, error) {
+	files, err := readFiles(ctx, codeDir, p.maxContextBytes())
+	if err != nil {
+		return "", err
+	}
+
+	prompt, err := p.renderPrompt(prompt.Feature, prompt.Data{Description: description, Files: files})
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Adding feature: %s\n", description)
+	return p.editCode(ctx, codeDir, prompt)
+}
+
+// ResolveConflicts resolves merge conflicts in codeDir, writing the resolved files in place
+func (p *Provider) ResolveConflicts(ctx context.Context, codeDir string, request ai.ConflictRequest) (string, error) {
+	// The prompt describes both sides of every conflicted file
+	prompt, err := p.renderPrompt(prompt.Resolve, prompt.Data{Conflicts: request})
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Resolving conflicts in %d file(s)\n", len(request.Files))
+	return p.editCode(ctx, codeDir, prompt)
+}
+
+// AnalyzeCode analyzes existing code and reports findings and scores. When the model does
+// not answer with the requested JSON, the whole answer is used as the summary.
+func (p *Provider) AnalyzeCode(ctx context.Context, codeDir string) (*ai.AnalysisReport, error) {
+	files, err := readFiles(ctx, codeDir, p.maxContextBytes())
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, err := p.renderPrompt(prompt.Analyze, prompt.Data{Files: files})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Analyzing code in %s...\n", codeDir)
+	output, err := p.chat(ctx, "You are an expert software engineer reviewing code.", prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := ai.ParseAnalysisReport(output)
+	if err != nil {
+		fmt.Printf("Warning: Failed to parse structured analysis, keeping it as summary: %v\n", err)
+		return &ai.AnalysisReport{Summary: strings.TrimSpace(output), Scores: map[string]int{}}, nil
+	}
+
+	return report, nil
+}
+
+// Name returns the provider's name
+func (p *Provider) Name() string {
+	return "openai"
+}
+
+// SupportedFrameworks returns the frameworks this provider can work with
+func (p *Provider) SupportedFrameworks() []string {
+	return p.frameworks
+}
+
+// Cleanup performs necessary cleanup operations
+func (p *Provider) Cleanup(ctx context.Context) error {
+	p.client.CloseIdleConnections()
+	return nil
+}
+
+// workspaceDir returns the directory generated code is written to, creating a temporary
+// directory if none is configured
+func (p *Provider) workspaceDir() (string, error) {
+	if dir := p.config[ai.WorkspaceDirKey]; dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create workspace directory: %w", err)
+		}
+		return dir, nil
+	}
+
+	dir, err := os.MkdirTemp("", "cc-openai-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create workspace directory: %w", err)
+	}
+	p.config[ai.WorkspaceDirKey] = dir
+	fmt.Printf("Created workspace directory: %s\n", dir)
+	return dir, nil
+}
+
+// maxContextBytes returns how much existing code may be sent along with a request
+func (p *Provider) maxContextBytes() int {
+	if value, err := strconv.Atoi(p.config["openai_max_context_bytes"]); err == nil && value > 0 {
+		return value
+	}
+	return defaultMaxContextBytes
+}
+
+// renderPrompt renders the prompt template name with data, using the overrides configured
+func (p *Provider) renderPrompt(name string, data prompt.Data) (string, error) {
+	return prompt.FromConfig(p.config).Render(name, data)
+}
+
+// editCode asks the model for the file edits described by prompt and applies them to dir
+func (p *Provider) editCode(ctx context.Context, dir string, prompt string) (string, error) {
+	start := time.Now()
+	output, err := p.chat(ctx, editInstructions, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	edits, err := parseEdits(output)
+	if err != nil {
+		return "", err
+	}
+
+	changed, err := applyEdits(dir, edits.Files)
+	if err != nil {
+		return "", err
+	}
+	for _, edit := range edits.Files {
+		ai.ReportProgress(ctx, ai.Progress{File: edit.Path, Elapsed: time.Since(start)})
+	}
+	fmt.Printf("Applied %d file change(s) to %s\n", len(changed), dir)
+
+	var out strings.Builder
+	out.WriteString(strings.TrimSpace(edits.Summary))
+	out.WriteString("\n\nChanged files:\n")
+	for _, change := range changed {
+		out.WriteString(" - " + change + "\n")
+	}
+	return out.String(), nil
+}
+
+// chatMessage is a message of a chat completion request or response
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest is the body of a chat completion request
+type chatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []chatMessage     `json:"messages"`
+	Temperature    *float64          `json:"temperature,omitempty"`
+	MaxTokens      int               `json:"max_tokens,omitempty"`
+	ResponseFormat map[string]string `json:"response_format,omitempty"`
+}
+
+// chatResponse is the body of a chat completion response
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// chat sends a chat completion request and returns the content of the answer
+func (p *Provider) chat(ctx context.Context, system, prompt string) (string, error) {
+	request := chatRequest{
+		Model: p.config["openai_model"],
+		Messages: []chatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: prompt},
+		},
+	}
+	if value, err := strconv.ParseFloat(p.config["openai_temperature"], 64); err == nil {
+		request.Temperature = &value
+	}
+	if value, err := strconv.Atoi(p.config["openai_max_tokens"]); err == nil && value > 0 {
+		request.MaxTokens = value
+	}
+	if p.config["openai_json_mode"] == "true" {
+		request.ResponseFormat = map[string]string{"type": "json_object"}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal chat request: %w", err)
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create chat request: %w", err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	apiKey := p.config["openai_api_key"]
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	if apiKey != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	start := time.Now()
+	httpResponse, err := p.client.Do(httpRequest)
+	if err != nil {
+		return "", fmt.Errorf("failed to send chat request: %w", err)
+	}
+	defer httpResponse.Body.Close()
+
+	data, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read chat response: %w", err)
+	}
+
+	var response chatResponse
+	parseErr := json.Unmarshal(data, &response)
+	ai.ReportUsage(ctx, p.usage(&response, time.Since(start)))
+
+	if httpResponse.StatusCode != http.StatusOK {
+		message := strings.TrimSpace(string(data))
+		if parseErr == nil && response.Error != nil {
+			message = response.Error.Message
+		}
+		return "", fmt.Errorf("chat request failed with status %d: %s", httpResponse.StatusCode, truncateString(message, 500))
+	}
+	if parseErr != nil {
+		return "", fmt.Errorf("failed to parse chat response: %w", parseErr)
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("chat response has no choices")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
+
+// fileEdit is a change of a file requested by the model
+type fileEdit struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Delete  bool   `json:"delete"`
+}
+
+// editResponse is the answer of the model to requests that change code
+type editResponse struct {
+	Summary string     `json:"summary"`
+	Files   []fileEdit `json:"files"`
+}
+
+// parseEdits parses the file edits in the answer of the model, which may be surrounded
+// by other text or a Markdown code block
+func parseEdits(output string) (*editResponse, error) {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no file edits found in answer: %s", truncateString(output, 200))
+	}
+
+	var edits editResponse
+	if err := json.Unmarshal([]byte(output[start:end+1]), &edits); err != nil {
+		return nil, fmt.Errorf("failed to parse file edits: %w", err)
+	}
+	return &edits, nil
+}
+
+// usage returns the usage of a chat request from the token counts of the response and the
+// prices configured with openai_input_price and openai_output_price, in US dollars per
+// million tokens
+func (p *Provider) usage(response *chatResponse, duration time.Duration) ai.Usage {
+	usage := ai.Usage{Duration: duration}
+	if response.Usage == nil {
+		return usage
+	}
+
+	usage.InputTokens = response.Usage.PromptTokens
+	usage.OutputTokens = response.Usage.CompletionTokens
+	inputPrice, _ := strconv.ParseFloat(p.config["openai_input_price"], 64)
+	outputPrice, _ := strconv.ParseFloat(p.config["openai_output_price"], 64)
+	usage.CostUSD = (float64(usage.InputTokens)*inputPrice + float64(usage.OutputTokens)*outputPrice) / 1e6
+	return usage
+}
+
+// applyEdits writes or removes the edited files in dir and returns a description of each
+// change. All paths are checked before any file is changed, so invalid answers leave dir untouched.
+func applyEdits(dir string, edits []fileEdit) ([]string, error) {
+	paths := make([]string, len(edits))
+	for i, edit := range edits {
+		path, err := editPath(dir, edit.Path)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = path
+	}
+
+	var changed []string
+	for i, edit := range edits {
+		if edit.Delete {
+			if err := os.Remove(paths[i]); err != nil && !os.IsNotExist(err) {
+				return changed, fmt.Errorf("failed to remove %s: %w", edit.Path, err)
+			}
+			changed = append(changed, edit.Path+" (deleted)")
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(paths[i]), 0755); err != nil {
+			return changed, fmt.Errorf("failed to create directory for %s: %w", edit.Path, err)
+		}
+		// Edits replace symlinks rather than being written through them
+		if info, err := os.Lstat(paths[i]); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			if err := os.Remove(paths[i]); err != nil {
+				return changed, fmt.Errorf("failed to replace symlink %s: %w", edit.Path, err)
+			}
+		}
+		if err := os.WriteFile(paths[i], []byte(edit.Content), 0644); err != nil {
+			return changed, fmt.Errorf("failed to write %s: %w", edit.Path, err)
+		}
+		changed = append(changed, edit.Path)
+	}
+
+	return changed, nil
+}
+
+// editPath returns the path of an edited file in dir, refusing paths outside of dir,
+// below symlinks, which may point outside of dir, and in the repository metadata
+func editPath(dir, name string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(name))
+	if name == "" || filepath.IsAbs(rel) || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to edit file outside of the project: %q", name)
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	if parts[0] == ".git" {
+		return "", fmt.Errorf("refusing to edit repository metadata: %q", name)
+	}
+
+	parent := dir
+	for _, part := range parts[:len(parts)-1] {
+		parent = filepath.Join(parent, part)
+		info, err := os.Lstat(parent)
+		if err != nil {
+			// Missing directories are created as directories
+			break
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("refusing to edit file below a symlink: %q", name)
+		}
+	}
+	return filepath.Join(dir, rel), nil
+}
+
+// readFiles returns the text files in dir, each preceded by its path, up to maxBytes
+func readFiles(ctx context.Context, dir string, maxBytes int) (string, error) {
+	var out strings.Builder
+	omitted := 0
+
+	err := source.Walk(ctx, dir, func(path, rel string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.Size() > maxFileBytes || out.Len()+int(info.Size()) > maxBytes {
+			omitted++
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.IndexByte(data, 0) >= 0 {
+			// Binary file
+			return nil
+		}
+
+		fmt.Fprintf(&out, "=== %s ===\n%s\n", filepath.ToSlash(rel), data)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read files: %w", err)
+	}
+
+	if out.Len() == 0 {
+		out.WriteString("(no files)\n")
+	}
+	if omitted > 0 {
+		fmt.Fprintf(&out, "(%d large files omitted)\n", omitted)
+	}
+	return out.String(), nil
+}
+
+// truncateString truncates a string to a maximum length and adds "..." if truncated
+func truncateString(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
+
+// Register registers this provider factory
+func init() {
+	ai.Register("openai", func(config map[string]string) (ai.Provider, error) {
+		return NewProvider(config)
+	})
+}
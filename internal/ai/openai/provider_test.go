@@ -0,0 +1,253 @@
+package openai_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/ai/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubServer is a chat completions endpoint answering every request with the same content
+type stubServer struct {
+	*httptest.Server
+
+	// Answer content of the model
+	answer string
+
+	// Requests received, decoded
+	requests []map[string]interface{}
+
+	// Authorization headers received
+	auth []string
+}
+
+// newStubServer starts a stub chat completions endpoint
+func newStubServer(t *testing.T, answer string) *stubServer {
+	stub := &stubServer{answer: answer}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+
+		var request map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		stub.requests = append(stub.requests, request)
+		stub.auth = append(stub.auth, r.Header.Get("Authorization"))
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": stub.answer}},
+			},
+			"usage": map[string]int{"prompt_tokens": 3000, "completion_tokens": 1000},
+		})
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+// prompt returns the user message of the i-th request
+func (s *stubServer) prompt(i int) string {
+	messages := s.requests[i]["messages"].([]interface{})
+	return messages[len(messages)-1].(map[string]interface{})["content"].(string)
+}
+
+// newProvider creates an initialized provider talking to the stub server
+func newProvider(t *testing.T, stub *stubServer, config map[string]string) *openai.Provider {
+	provider, err := openai.NewProvider(map[string]string{
+		"openai_base_url": stub.URL + "/v1/",
+		"openai_model":    "qwen2.5-coder",
+	})
+	require.NoError(t, err)
+	require.NoError(t, provider.Initialize(context.Background(), config))
+	return provider
+}
+
+// TestProviderRegistered tests that the provider is registered as "openai"
+func TestProviderRegistered(t *testing.T) {
+	provider, err := ai.Create("openai", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", provider.Name())
+	assert.Contains(t, provider.SupportedFrameworks(), "react")
+
+	assert.Error(t, provider.Initialize(context.Background(), map[string]string{"openai_base_url": "localhost"}))
+}
+
+// TestGenerateImplementation tests writing the files of the answer to the workspace
+func TestGenerateImplementation(t *testing.T) {
+	stub := newStubServer(t, "Here you go:\n```json\n"+`{
+		"summary": "React counter app",
+		"files": [
+			{"path": "package.json", "content": "{\"name\": \"counter\"}"},
+			{"path": "src/App.jsx", "content": "export default function App() {}\n"}
+		]
+	}`+"\n```")
+
+	workspace := t.TempDir()
+	provider := newProvider(t, stub, map[string]string{
+		ai.WorkspaceDirKey:    workspace,
+		"openai_api_key":      "secret",
+		"openai_temperature":  "0.2",
+		"openai_json_mode":    "true",
+		"openai_input_price":  "2.5",
+		"openai_output_price": "10",
+	})
+
+	ctx, tracker := ai.TrackUsage(context.Background())
+	output, err := provider.GenerateImplementation(ctx, "A counter", "react")
+	require.NoError(t, err)
+	assert.Contains(t, output, "React counter app")
+	assert.Contains(t, output, "src/App.jsx")
+
+	data, err := os.ReadFile(filepath.Join(workspace, "src", "App.jsx"))
+	require.NoError(t, err)
+	assert.Equal(t, "export default function App() {}\n", string(data))
+	assert.FileExists(t, filepath.Join(workspace, "package.json"))
+
+	require.Len(t, stub.requests, 1)
+	assert.Equal(t, "qwen2.5-coder", stub.requests[0]["model"])
+	assert.Equal(t, 0.2, stub.requests[0]["temperature"])
+	assert.Equal(t, map[string]interface{}{"type": "json_object"}, stub.requests[0]["response_format"])
+	assert.Equal(t, "Bearer secret", stub.auth[0])
+	assert.Contains(t, stub.prompt(0), "Create a react implementation for: A counter")
+
+	// Token counts are priced per million tokens
+	usage := tracker.Usage()
+	assert.Equal(t, 3000, usage.InputTokens)
+	assert.Equal(t, 1000, usage.OutputTokens)
+	assert.InDelta(t, 0.0175, usage.CostUSD, 1e-9)
+}
+
+// TestAddFeature tests sending the existing code and applying changes and deletions
+func TestAddFeature(t *testing.T) {
+	stub := newStubServer(t, `{
+		"summary": "Added dark mode",
+		"files": [
+			{"path": "src/theme.js", "content": "export const dark = true;\n"},
+			{"path": "old.js", "delete": true}
+		]
+	}`)
+
+	codeDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(codeDir, "index.js"), []byte("console.log('hello');\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(codeDir, "old.js"), []byte("legacy\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(codeDir, "node_modules", "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(codeDir, "node_modules", "lib", "index.js"), []byte("dependency\n"), 0644))
+
+	provider := newProvider(t, stub, nil)
+	output, err := provider.AddFeature(context.Background(), codeDir, "Add dark mode")
+	require.NoError(t, err)
+	assert.Contains(t, output, "old.js (deleted)")
+
+	assert.FileExists(t, filepath.Join(codeDir, "src", "theme.js"))
+	assert.NoFileExists(t, filepath.Join(codeDir, "old.js"))
+
+	prompt := stub.prompt(0)
+	assert.Contains(t, prompt, "=== index.js ===\nconsole.log('hello');")
+	assert.NotContains(t, prompt, "dependency")
+	assert.Empty(t, stub.auth[0])
+}
+
+// TestRejectsEditsOutsideProject tests that answers editing files outside of the project change nothing
+func TestRejectsEditsOutsideProject(t *testing.T) {
+	for _, path := range []string{"../escape.txt", "/etc/passwd", ".git/config"} {
+		stub := newStubServer(t, `{"summary": "", "files": [
+			{"path": "ok.txt", "content": "ok"},
+			{"path": "`+path+`", "content": "bad"}
+		]}`)
+
+		codeDir := t.TempDir()
+		provider := newProvider(t, stub, nil)
+		_, err := provider.AddFeature(context.Background(), codeDir, "Escape")
+		assert.Error(t, err, path)
+		assert.NoFileExists(t, filepath.Join(codeDir, "ok.txt"), path)
+	}
+}
+
+// TestRejectsEditsThroughSymlinks tests that symlinks in the project never let edits write outside of it
+func TestRejectsEditsThroughSymlinks(t *testing.T) {
+	outside := t.TempDir()
+	codeDir := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(codeDir, "linked")))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "target.txt"), []byte("outside"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "target.txt"), filepath.Join(codeDir, "file.txt")))
+
+	stub := newStubServer(t, `{"summary": "", "files": [{"path": "linked/escape.txt", "content": "bad"}]}`)
+	provider := newProvider(t, stub, nil)
+	_, err := provider.AddFeature(context.Background(), codeDir, "Escape")
+	assert.ErrorContains(t, err, "below a symlink")
+	assert.NoFileExists(t, filepath.Join(outside, "escape.txt"))
+
+	// A symlinked file is replaced by the edited file
+	stub = newStubServer(t, `{"summary": "", "files": [{"path": "file.txt", "content": "edited"}]}`)
+	provider = newProvider(t, stub, nil)
+	_, err = provider.AddFeature(context.Background(), codeDir, "Edit")
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(outside, "target.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "outside", string(data))
+	data, err = os.ReadFile(filepath.Join(codeDir, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "edited", string(data))
+}
+
+// TestAnalyzeCode tests parsing the analysis of the model
+func TestAnalyzeCode(t *testing.T) {
+	stub := newStubServer(t, `{
+		"summary": "Small script",
+		"scores": {"security": 90, "tests": 10},
+		"findings": [{"severity": "medium", "category": "tests", "title": "No tests"}]
+	}`)
+
+	codeDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(codeDir, "main.py"), []byte("print('hi')\n"), 0644))
+
+	provider := newProvider(t, stub, nil)
+	report, err := provider.AnalyzeCode(context.Background(), codeDir)
+	require.NoError(t, err)
+	assert.Equal(t, "Small script", report.Summary)
+	assert.Equal(t, 50, report.Scores[ai.OverallScore])
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, ai.CategoryTests, report.Findings[0].Category)
+	assert.Contains(t, stub.prompt(0), "=== main.py ===")
+
+	// Free-form answers become the summary
+	stub.answer = "Looks good to me."
+	report, err = provider.AnalyzeCode(context.Background(), codeDir)
+	require.NoError(t, err)
+	assert.Equal(t, "Looks good to me.", report.Summary)
+	assert.Empty(t, report.Findings)
+}
+
+// TestChatErrors tests reporting errors of the endpoint
+func TestChatErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"message": "model not found"}}`))
+	}))
+	defer server.Close()
+
+	provider, err := openai.NewProvider(map[string]string{"openai_base_url": server.URL})
+	require.NoError(t, err)
+	require.NoError(t, provider.Initialize(context.Background(), map[string]string{ai.WorkspaceDirKey: t.TempDir()}))
+
+	_, err = provider.GenerateProject(context.Background(), "Anything")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status 400: model not found")
+
+	// Answers without file edits are errors
+	stub := newStubServer(t, "I cannot do that.")
+	_, err = newProvider(t, stub, map[string]string{ai.WorkspaceDirKey: t.TempDir()}).GenerateProject(context.Background(), "Anything")
+	assert.Error(t, err)
+}
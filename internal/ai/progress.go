@@ -0,0 +1,169 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Progress is an update on a running AI operation
+type Progress struct {
+	// Line of output of the operation, empty when the update reports a file
+	Line string
+
+	// File touched by the operation for the first time, relative to the code directory
+	File string
+
+	// Elapsed time since the operation started
+	Elapsed time.Duration
+}
+
+// ProgressFunc receives progress updates. Operations running in parallel call it concurrently.
+type ProgressFunc func(Progress)
+
+// progressKey is the context key of the progress function
+type progressKey struct{}
+
+// WithProgress returns a context making providers report the progress of operations to fn
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress reports progress to the progress function of ctx, if any
+func ReportProgress(ctx context.Context, progress Progress) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		fn(progress)
+	}
+}
+
+// toolFilePattern matches files named in the tool calls logged by coding agents, like Write(src/App.jsx)
+var toolFilePattern = regexp.MustCompile(`\b(?:Write|Edit|MultiEdit|Create|Update)\(([^()\s]+)\)`)
+
+// ProgressWriter collects the output of an operation and reports it line by line as progress,
+// along with the files under the code directory the lines mention
+type ProgressWriter struct {
+	ctx   context.Context
+	root  string
+	start time.Time
+
+	mu      sync.Mutex
+	output  bytes.Buffer
+	partial []byte
+	files   map[string]bool
+}
+
+// NewProgressWriter creates a writer reporting progress to ctx. Paths under root, the code
+// directory as seen by the operation, are reported as touched files.
+func NewProgressWriter(ctx context.Context, root string) *ProgressWriter {
+	return &ProgressWriter{
+		ctx:   ctx,
+		root:  strings.TrimSuffix(root, "/") + "/",
+		start: time.Now(),
+		files: make(map[string]bool),
+	}
+}
+
+// Write collects p and reports the lines it completes
+func (w *ProgressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.output.Write(p)
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.report(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush reports the last line if it was not terminated
+func (w *ProgressWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.report(string(w.partial))
+		w.partial = nil
+	}
+}
+
+// report reports a line and the files it touches for the first time
+func (w *ProgressWriter) report(line string) {
+	line = strings.TrimRight(line, "\r")
+	elapsed := time.Since(w.start)
+	if strings.TrimSpace(line) != "" {
+		ReportProgress(w.ctx, Progress{Line: line, Elapsed: elapsed})
+	}
+
+	for _, file := range w.filesIn(line) {
+		if w.files[file] {
+			continue
+		}
+		w.files[file] = true
+		ReportProgress(w.ctx, Progress{File: file, Elapsed: elapsed})
+	}
+}
+
+// filesIn returns the files under the root a line mentions, relative to the root
+func (w *ProgressWriter) filesIn(line string) []string {
+	var files []string
+	add := func(file string) {
+		file = strings.TrimLeft(file, "`'\"([{")
+		file = strings.TrimRight(file, "`'\")]},.:;")
+		if rel, ok := strings.CutPrefix(file, w.root); ok {
+			file = rel
+		} else if path.IsAbs(file) {
+			return
+		}
+		file = path.Clean(file)
+		if file == "." || strings.HasPrefix(file, "../") || !strings.Contains(path.Base(file), ".") {
+			return
+		}
+		files = append(files, file)
+	}
+
+	for _, match := range toolFilePattern.FindAllStringSubmatch(line, -1) {
+		add(match[1])
+	}
+	for _, field := range strings.Fields(line) {
+		if i := strings.Index(field, w.root); i >= 0 {
+			add(field[i:])
+		}
+	}
+	return files
+}
+
+// Output returns everything written so far
+func (w *ProgressWriter) Output() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.output.String()
+}
+
+// Files returns the files touched so far, sorted
+func (w *ProgressWriter) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	files := make([]string, 0, len(w.files))
+	for file := range w.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Elapsed returns the time since the writer was created
+func (w *ProgressWriter) Elapsed() time.Duration {
+	return time.Since(w.start)
+}
@@ -0,0 +1,43 @@
+package ai_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestProgressWriter tests reporting output line by line along with the files it touches
+func TestProgressWriter(t *testing.T) {
+	var lines, files []string
+	ctx := ai.WithProgress(context.Background(), func(p ai.Progress) {
+		if p.File != "" {
+			files = append(files, p.File)
+			return
+		}
+		lines = append(lines, p.Line)
+	})
+
+	w := ai.NewProgressWriter(ctx, "/workspace")
+
+	// Lines are reported once complete, however the output is split
+	w.Write([]byte("Reading the proj"))
+	assert.Empty(t, lines)
+	w.Write([]byte("ect\r\n\nWrite(./src/App.jsx)\nEdit(\"/workspace/src/App.jsx\")\n"))
+	w.Write([]byte("Created /workspace/package.json, /workspace/src and /etc/hosts\nDone"))
+	assert.Equal(t, []string{"Reading the project", "Write(./src/App.jsx)", "Edit(\"/workspace/src/App.jsx\")", "Created /workspace/package.json, /workspace/src and /etc/hosts"}, lines)
+
+	w.Flush()
+	assert.Equal(t, "Done", lines[len(lines)-1])
+
+	// Files are reported the first time they are touched, relative to the root
+	assert.Equal(t, []string{"src/App.jsx", "package.json"}, files)
+	assert.Equal(t, []string{"package.json", "src/App.jsx"}, w.Files())
+	assert.Contains(t, w.Output(), "Reading the project\r\n\nWrite")
+
+	// Without a progress function nothing is reported
+	quiet := ai.NewProgressWriter(context.Background(), "/workspace")
+	quiet.Write([]byte("Write(main.go)\n"))
+	assert.Equal(t, []string{"main.go"}, quiet.Files())
+}
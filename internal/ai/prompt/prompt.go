@@ -0,0 +1,216 @@
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/profile"
+)
+
+// Names of the prompt templates
+const (
+	// Project is the prompt generating a project structure
+	Project = "project"
+
+	// Implementation is the prompt generating an implementation with a framework
+	Implementation = "implementation"
+
+	// Feature is the prompt adding a feature to existing code
+	Feature = "feature"
+
+	// Analyze is the prompt analyzing existing code
+	Analyze = "analyze"
+
+	// Resolve is the prompt resolving merge conflicts
+	Resolve = "resolve"
+)
+
+// Configuration keys of AI providers read by FromConfig
+const (
+	// DirKey is the directory with the user's prompt overrides (~/.cc/prompts by default)
+	DirKey = "prompt_dir"
+
+	// ProjectDirKey is the directory with the project's prompt overrides, taking precedence
+	// over the user's
+	ProjectDirKey = "prompt_project_dir"
+
+	// ProjectKey is the description of the project
+	ProjectKey = "prompt_project"
+
+	// FeaturesKey is the descriptions of the features the code already has, one per line
+	FeaturesKey = "prompt_features"
+)
+
+// conventionsFile is the file in the override directories with the coding conventions
+const conventionsFile = "conventions.md"
+
+//go:embed templates/*.tmpl
+var builtin embed.FS
+
+// Data is the variables available to prompt templates
+type Data struct {
+	// What to build: the project description, or the feature description when adding a feature
+	Description string
+
+	// Description of the project
+	Project string
+
+	// Framework of the implementation
+	Framework string
+
+	// Descriptions of the features the code already has
+	Features []string
+
+	// Instructions of the framework profile
+	Instructions string
+
+	// Files the framework profile expects every implementation to have
+	EntryFiles []string
+
+	// Coding conventions to follow, from the conventions.md files of the override directories
+	Conventions string
+
+	// Directory holding the code as seen by the AI, empty when the files are sent along
+	Dir string
+
+	// Content of the files of the project, for providers sending them along with the prompt
+	Files string
+
+	// JSON format of analysis answers
+	AnalysisFormat string
+
+	// Merge conflicts to resolve
+	Conflicts ai.ConflictRequest
+}
+
+// Templates renders prompts from the built-in templates, overridden by the files with the
+// same name in the override directories
+type Templates struct {
+	// Directories with overrides, later ones taking precedence
+	dirs []string
+
+	// Variables set by the provider configuration
+	project   string
+	framework string
+	features  []string
+}
+
+// New creates templates overridden by the .tmpl files in dirs, later ones taking precedence.
+// Directories that do not exist are ignored.
+func New(dirs ...string) *Templates {
+	return &Templates{dirs: dirs}
+}
+
+// FromConfig creates the templates of an AI provider from its configuration, overridden by
+// the user's and then the project's prompt directories
+func FromConfig(config map[string]string) *Templates {
+	userDir := config[DirKey]
+	if userDir == "" {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			userDir = filepath.Join(homeDir, ".cc", "prompts")
+		}
+	}
+
+	t := New(userDir, config[ProjectDirKey])
+	t.project = config[ProjectKey]
+	t.framework = config[ai.FrameworkKey]
+	if features := strings.TrimSpace(config[FeaturesKey]); features != "" {
+		t.features = strings.Split(features, "\n")
+	}
+	return t
+}
+
+// Render renders the prompt template name with data. The project description, framework,
+// features, coding conventions and the framework profile's instructions and entry files are
+// filled in when data does not set them.
+func (t *Templates) Render(name string, data Data) (string, error) {
+	tmpl, err := t.parse()
+	if err != nil {
+		return "", err
+	}
+
+	if data.Project == "" {
+		data.Project = t.project
+	}
+	if data.Framework == "" {
+		data.Framework = t.framework
+	}
+	if data.Features == nil {
+		data.Features = t.features
+	}
+	if framework, ok := profile.Get(data.Framework); ok {
+		if data.Instructions == "" {
+			data.Instructions = framework.Instructions
+		}
+		if data.EntryFiles == nil {
+			data.EntryFiles = framework.EntryFiles
+		}
+	}
+	if data.Conventions == "" {
+		if data.Conventions, err = t.conventions(); err != nil {
+			return "", err
+		}
+	}
+	if data.AnalysisFormat == "" {
+		data.AnalysisFormat = ai.AnalysisPrompt
+	}
+
+	var prompt bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&prompt, name+".tmpl", data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt: %w", name, err)
+	}
+	return strings.TrimSpace(prompt.String()), nil
+}
+
+// parse parses the built-in templates and then the overrides, which replace the templates
+// with the same file name
+func (t *Templates) parse() (*template.Template, error) {
+	tmpl, err := template.New("").Funcs(template.FuncMap{"join": strings.Join}).ParseFS(builtin, "templates/*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse built-in prompts: %w", err)
+	}
+
+	for _, dir := range t.dirs {
+		if dir == "" {
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list prompts in %s: %w", dir, err)
+		}
+		if len(files) == 0 {
+			continue
+		}
+		if tmpl, err = tmpl.ParseFiles(files...); err != nil {
+			return nil, fmt.Errorf("failed to parse prompts in %s: %w", dir, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// conventions returns the coding conventions of the override directories, joined in order
+func (t *Templates) conventions() (string, error) {
+	var conventions []string
+	for _, dir := range t.dirs {
+		if dir == "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, conventionsFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read coding conventions: %w", err)
+		}
+		if text := strings.TrimSpace(string(data)); text != "" {
+			conventions = append(conventions, text)
+		}
+	}
+	return strings.Join(conventions, "\n\n"), nil
+}
@@ -0,0 +1,98 @@
+package prompt_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/ai/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBuiltinTemplates tests rendering every built-in prompt
+func TestBuiltinTemplates(t *testing.T) {
+	templates := prompt.New()
+
+	implementation, err := templates.Render(prompt.Implementation, prompt.Data{Description: "A counter", Framework: "react"})
+	require.NoError(t, err)
+	assert.Contains(t, implementation, "Create a react implementation for: A counter")
+	assert.Contains(t, implementation, "1. Use modern react patterns and libraries")
+	assert.NotContains(t, implementation, "coding conventions")
+
+	feature, err := templates.Render(prompt.Feature, prompt.Data{
+		Description: "Add dark mode",
+		Project:     "A todo app",
+		Features:    []string{"Add user accounts", "Add avatars"},
+		Files:       "=== index.js ===",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, feature, "The project is: A todo app")
+	assert.Contains(t, feature, "already has these features:\n- Add user accounts\n- Add avatars\n")
+	assert.Contains(t, feature, "The existing files of the project are:\n\n=== index.js ===")
+	assert.NotContains(t, feature, "Analyze the existing codebase in")
+
+	analyze, err := templates.Render(prompt.Analyze, prompt.Data{Dir: "/workspace"})
+	require.NoError(t, err)
+	assert.Contains(t, analyze, "Analyze the codebase in /workspace")
+	assert.Contains(t, analyze, ai.AnalysisPrompt)
+
+	resolve, err := templates.Render(prompt.Resolve, prompt.Data{Conflicts: ai.ConflictRequest{
+		OursDescription:   "Add accounts",
+		TheirsDescription: "Add dark mode",
+		Files:             []ai.ConflictFile{{Path: "src/App.js", Base: "base", Ours: "ours", Theirs: "theirs"}},
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, resolve, "File: src/App.js\n--- common ancestor ---\nbase\n--- ours ---\nours\n--- theirs ---\ntheirs\n\nGive the resolved content")
+
+	project, err := templates.Render(prompt.Project, prompt.Data{Description: "A todo app"})
+	require.NoError(t, err)
+	assert.Contains(t, project, "Create a project structure for: A todo app")
+
+	_, err = templates.Render("unknown", prompt.Data{})
+	assert.Error(t, err)
+}
+
+// TestTemplateOverrides tests overriding templates and conventions per user and per project
+func TestTemplateOverrides(t *testing.T) {
+	userDir := t.TempDir()
+	projectDir := filepath.Join(t.TempDir(), ".cc", "prompts")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+
+	write := func(dir, name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write(userDir, "implementation.tmpl", "Build {{.Description}} with {{.Framework}}{{template \"conventions.tmpl\" .}}")
+	write(userDir, "feature.tmpl", "user feature")
+	write(userDir, "conventions.md", "Use tabs.\n")
+	write(projectDir, "feature.tmpl", "{{.Description}} on top of {{range .Features}}[{{.}}]{{end}} for {{.Project}}")
+	write(projectDir, "conventions.md", "Name files in kebab-case.")
+
+	templates := prompt.FromConfig(map[string]string{
+		prompt.DirKey:        userDir,
+		prompt.ProjectDirKey: projectDir,
+		prompt.ProjectKey:    "A todo app",
+		prompt.FeaturesKey:   "Add user accounts\nAdd avatars\n",
+	})
+
+	// User templates replace the built-in ones, conventions of both directories are included
+	implementation, err := templates.Render(prompt.Implementation, prompt.Data{Description: "A counter", Framework: "vue"})
+	require.NoError(t, err)
+	assert.Equal(t, "Build A counter with vue\n\nFollow these coding conventions:\nUse tabs.\n\nName files in kebab-case.", implementation)
+
+	// Project templates replace the user's, variables come from the configuration
+	feature, err := templates.Render(prompt.Feature, prompt.Data{Description: "Add dark mode"})
+	require.NoError(t, err)
+	assert.Equal(t, "Add dark mode on top of [Add user accounts][Add avatars] for A todo app", feature)
+
+	// Templates that are not overridden stay built in
+	analyze, err := templates.Render(prompt.Analyze, prompt.Data{})
+	require.NoError(t, err)
+	assert.Contains(t, analyze, "Follow these coding conventions:\nUse tabs.")
+
+	// Broken overrides are reported
+	write(projectDir, "analyze.tmpl", "{{.Missing")
+	_, err = templates.Render(prompt.Analyze, prompt.Data{})
+	assert.ErrorContains(t, err, "failed to parse prompts in "+projectDir)
+}
@@ -5,6 +5,19 @@ import (
 	"fmt"
 )
 
+// WorkspaceDirKey is the configuration key for the local directory that
+// generated code is written to. Providers fall back to a temporary directory
+// when it is not set.
+const WorkspaceDirKey = "workspace_dir"
+
+// FrameworkKey is the configuration key for the framework of the code a provider works on,
+// selecting the framework profile used when the operation does not name the framework
+const FrameworkKey = "framework"
+
+// ProjectNameKey is the configuration key for the name of the project a provider works on,
+// which providers running containers label them with so they can be reused for the project
+const ProjectNameKey = "project_name"
+
 // Provider defines the interface for AI code generation services
 type Provider interface {
 	// Initialize sets up the AI provider with necessary configuration
@@ -19,8 +32,11 @@ type Provider interface {
 	// AddFeature adds a feature to existing code
 	AddFeature(ctx context.Context, codeDir string, description string) (string, error)
 
-	// AnalyzeCode analyzes existing code and provides feedback
-	AnalyzeCode(ctx context.Context, codeDir string) (string, error)
+	// AnalyzeCode analyzes existing code and reports findings and scores
+	AnalyzeCode(ctx context.Context, codeDir string) (*AnalysisReport, error)
+
+	// ResolveConflicts resolves merge conflicts in codeDir, writing the resolved files in place
+	ResolveConflicts(ctx context.Context, codeDir string, request ConflictRequest) (string, error)
 
 	// Name returns the provider's name
 	Name() string
@@ -32,6 +48,33 @@ type Provider interface {
 	Cleanup(ctx context.Context) error
 }
 
+// ConflictRequest describes merge conflicts to be resolved
+type ConflictRequest struct {
+	// Description of the changes on the branch being merged into
+	OursDescription string
+
+	// Description of the changes on the branch being merged
+	TheirsDescription string
+
+	// Files with conflicts
+	Files []ConflictFile
+}
+
+// ConflictFile holds the versions of a file with conflicts
+type ConflictFile struct {
+	// Path of the file relative to the code directory
+	Path string
+
+	// Content of the common ancestor (empty if the file was added on both branches)
+	Base string
+
+	// Content on the branch being merged into
+	Ours string
+
+	// Content on the branch being merged
+	Theirs string
+}
+
 // Factory creates a provider based on name
 type Factory func(config map[string]string) (Provider, error)
 
@@ -51,8 +51,12 @@ func (p *mockProvider) AddFeature(ctx context.Context, codeDir string, descripti
 	return "added feature", nil
 }
 
-func (p *mockProvider) AnalyzeCode(ctx context.Context, codeDir string) (string, error) {
-	return "code analysis", nil
+func (p *mockProvider) AnalyzeCode(ctx context.Context, codeDir string) (*ai.AnalysisReport, error) {
+	return &ai.AnalysisReport{Summary: "code analysis"}, nil
+}
+
+func (p *mockProvider) ResolveConflicts(ctx context.Context, codeDir string, request ai.ConflictRequest) (string, error) {
+	return "resolved conflicts", nil
 }
 
 func (p *mockProvider) Name() string {
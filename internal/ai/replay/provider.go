@@ -0,0 +1,561 @@
+// Package replay implements an AI provider that records the calls of another provider,
+// with the files each call changed, to a cassette directory and replays them later
+// without containers or network access. Cassettes make tests and demos deterministic.
+package replay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+)
+
+// Modes of the provider
+const (
+	// ModeRecord passes calls to the wrapped provider and records them
+	ModeRecord = "record"
+
+	// ModeReplay answers calls from the cassette
+	ModeReplay = "replay"
+)
+
+// Configuration keys of the provider
+const (
+	// ModeKey selects the mode (record or replay, replay by default)
+	ModeKey = "replay_mode"
+
+	// CassetteKey is the cassette directory
+	CassetteKey = "replay_cassette"
+
+	// ProviderKey is the name of the provider to record (claude by default)
+	ProviderKey = "replay_provider"
+)
+
+// Names of the recorded methods
+const (
+	methodGenerateProject        = "GenerateProject"
+	methodGenerateImplementation = "GenerateImplementation"
+	methodAddFeature             = "AddFeature"
+	methodAnalyzeCode            = "AnalyzeCode"
+	methodResolveConflicts       = "ResolveConflicts"
+)
+
+const (
+	// cassetteFile describes the recorded provider
+	cassetteFile = "cassette.json"
+
+	// interactionFile describes a recorded call, in a directory per call
+	interactionFile = "interaction.json"
+
+	// filesDir holds the files a call created or changed, in the directory of the call
+	filesDir = "files"
+)
+
+// Cassette describes the provider calls were recorded from
+type Cassette struct {
+	// Name of the recorded provider
+	Provider string `json:"provider"`
+
+	// Frameworks supported by the recorded provider
+	Frameworks []string `json:"frameworks"`
+}
+
+// Interaction is a recorded call
+type Interaction struct {
+	// Method called
+	Method string `json:"method"`
+
+	// Inputs of the call, except directories, which differ between runs
+	Inputs map[string]string `json:"inputs"`
+
+	// Output of the call
+	Output string `json:"output,omitempty"`
+
+	// Report returned by AnalyzeCode
+	Analysis *ai.AnalysisReport `json:"analysis,omitempty"`
+
+	// Error returned by the call
+	Error string `json:"error,omitempty"`
+
+	// Usage reported by the call, reported again when it is replayed
+	Usage *ai.Usage `json:"usage,omitempty"`
+
+	// Files created or changed by the call, relative to the code directory
+	Changed []string `json:"changed,omitempty"`
+
+	// Files removed by the call, relative to the code directory
+	Deleted []string `json:"deleted,omitempty"`
+
+	// Directory of the interaction in the cassette
+	dir string
+}
+
+// key identifies calls with the same inputs
+func (i *Interaction) key() string {
+	inputs, _ := json.Marshal(i.Inputs)
+	return i.Method + string(inputs)
+}
+
+// Provider records or replays AI provider calls
+type Provider struct {
+	mode     string
+	dir      string
+	config   map[string]string
+	inner    ai.Provider
+	cassette Cassette
+
+	// Recorded interactions by key and how many of them were replayed
+	mu           sync.Mutex
+	interactions map[string][]*Interaction
+	replayed     map[string]int
+}
+
+// NewRecorder creates a provider recording the calls of inner to the cassette in dir
+func NewRecorder(inner ai.Provider, dir string) *Provider {
+	return &Provider{
+		mode:   ModeRecord,
+		dir:    dir,
+		config: make(map[string]string),
+		inner:  inner,
+	}
+}
+
+// NewPlayer creates a provider replaying the cassette in dir
+func NewPlayer(dir string) *Provider {
+	return &Provider{
+		mode:   ModeReplay,
+		dir:    dir,
+		config: make(map[string]string),
+	}
+}
+
+// NewProvider creates a recording or replaying provider from configuration. In record
+// mode the wrapped provider is created with the same configuration.
+func NewProvider(config map[string]string) (*Provider, error) {
+	dir := config[CassetteKey]
+	if dir == "" {
+		return nil, fmt.Errorf("no cassette directory configured (%s)", CassetteKey)
+	}
+
+	var p *Provider
+	switch mode := config[ModeKey]; mode {
+	case ModeReplay, "":
+		p = NewPlayer(dir)
+	case ModeRecord:
+		name := config[ProviderKey]
+		if name == "" {
+			name = "claude"
+		}
+		if name == "replay" {
+			return nil, fmt.Errorf("cannot record the replay provider")
+		}
+
+		inner, err := ai.Create(name, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create recorded provider: %w", err)
+		}
+		p = NewRecorder(inner, dir)
+	default:
+		return nil, fmt.Errorf("unknown replay mode: %s (valid modes: %s, %s)", mode, ModeRecord, ModeReplay)
+	}
+
+	for k, v := range config {
+		p.config[k] = v
+	}
+	return p, nil
+}
+
+// Initialize sets up the provider. In record mode the wrapped provider is initialized and
+// described in the cassette, in replay mode the cassette is loaded.
+func (p *Provider) Initialize(ctx context.Context, config map[string]string) error {
+	// Merge configs
+	for k, v := range config {
+		p.config[k] = v
+	}
+
+	if p.mode == ModeRecord {
+		if err := p.inner.Initialize(ctx, config); err != nil {
+			return err
+		}
+
+		p.cassette = Cassette{Provider: p.inner.Name(), Frameworks: p.inner.SupportedFrameworks()}
+		data, err := json.MarshalIndent(p.cassette, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal cassette: %w", err)
+		}
+		if err := os.MkdirAll(p.dir, 0755); err != nil {
+			return fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(p.dir, cassetteFile), append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write cassette: %w", err)
+		}
+		return nil
+	}
+
+	return p.load()
+}
+
+// load reads the cassette and its interactions
+func (p *Provider) load() error {
+	data, err := os.ReadFile(filepath.Join(p.dir, cassetteFile))
+	if err != nil {
+		return fmt.Errorf("failed to read cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &p.cassette); err != nil {
+		return fmt.Errorf("failed to parse cassette: %w", err)
+	}
+
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cassette directory: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.interactions = make(map[string][]*Interaction)
+	p.replayed = make(map[string]int)
+
+	// Directory names start with a sequence number, so calls with the same inputs replay in order
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(p.dir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, interactionFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read interaction %s: %w", entry.Name(), err)
+		}
+
+		interaction := &Interaction{dir: dir}
+		if err := json.Unmarshal(data, interaction); err != nil {
+			return fmt.Errorf("failed to parse interaction %s: %w", entry.Name(), err)
+		}
+		key := interaction.key()
+		p.interactions[key] = append(p.interactions[key], interaction)
+	}
+
+	return nil
+}
+
+// GenerateProject generates a project structure based on description
+func (p *Provider) GenerateProject(ctx context.Context, description string) (string, error) {
+	inputs := map[string]string{"description": description}
+	interaction, err := p.call(ctx, methodGenerateProject, inputs, p.config[ai.WorkspaceDirKey], func(ctx context.Context) (string, *ai.AnalysisReport, error) {
+		output, err := p.inner.GenerateProject(ctx, description)
+		return output, nil, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return interaction.Output, interaction.err()
+}
+
+// GenerateImplementation generates code with a specific framework
+func (p *Provider) GenerateImplementation(ctx context.Context, description string, framework string) (string, error) {
+	inputs := map[string]string{"description": description, "framework": framework}
+	interaction, err := p.call(ctx, methodGenerateImplementation, inputs, p.config[ai.WorkspaceDirKey], func(ctx context.Context) (string, *ai.AnalysisReport, error) {
+		output, err := p.inner.GenerateImplementation(ctx, description, framework)
+		return output, nil, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return interaction.Output, interaction.err()
+}
+
+// AddFeature adds a feature to existing code
+func (p *Provider) AddFeature(ctx context.Context, codeDir string, description string) (string, error) {
+	inputs := map[string]string{"description": description}
+	interaction, err := p.call(ctx, methodAddFeature, inputs, codeDir, func(ctx context.Context) (string, *ai.AnalysisReport, error) {
+		output, err := p.inner.AddFeature(ctx, codeDir, description)
+		return output, nil, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return interaction.Output, interaction.err()
+}
+
+// AnalyzeCode analyzes existing code and reports findings and scores
+func (p *Provider) AnalyzeCode(ctx context.Context, codeDir string) (*ai.AnalysisReport, error) {
+	interaction, err := p.call(ctx, methodAnalyzeCode, map[string]string{}, codeDir, func(ctx context.Context) (string, *ai.AnalysisReport, error) {
+		report, err := p.inner.AnalyzeCode(ctx, codeDir)
+		return "", report, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := interaction.err(); err != nil {
+		return nil, err
+	}
+	return interaction.Analysis, nil
+}
+
+// ResolveConflicts resolves merge conflicts in codeDir, writing the resolved files in place
+func (p *Provider) ResolveConflicts(ctx context.Context, codeDir string, request ai.ConflictRequest) (string, error) {
+	files, err := json.Marshal(request.Files)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal conflicts: %w", err)
+	}
+	inputs := map[string]string{
+		"ours":   request.OursDescription,
+		"theirs": request.TheirsDescription,
+		"files":  string(files),
+	}
+	interaction, err := p.call(ctx, methodResolveConflicts, inputs, codeDir, func(ctx context.Context) (string, *ai.AnalysisReport, error) {
+		output, err := p.inner.ResolveConflicts(ctx, codeDir, request)
+		return output, nil, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return interaction.Output, interaction.err()
+}
+
+// Name returns the provider's name
+func (p *Provider) Name() string {
+	return "replay"
+}
+
+// SupportedFrameworks returns the frameworks of the recorded provider
+func (p *Provider) SupportedFrameworks() []string {
+	if p.mode == ModeRecord {
+		return p.inner.SupportedFrameworks()
+	}
+	return p.cassette.Frameworks
+}
+
+// Cleanup performs necessary cleanup operations
+func (p *Provider) Cleanup(ctx context.Context) error {
+	if p.mode == ModeRecord {
+		return p.inner.Cleanup(ctx)
+	}
+	return nil
+}
+
+// err returns the error recorded for the interaction
+func (i *Interaction) err() error {
+	if i.Error == "" {
+		return nil
+	}
+	return fmt.Errorf("%s", i.Error)
+}
+
+// call records the call made by run along with the changes it makes to codeDir, or replays
+// the recorded call with the same method and inputs
+func (p *Provider) call(ctx context.Context, method string, inputs map[string]string, codeDir string, run func(ctx context.Context) (string, *ai.AnalysisReport, error)) (*Interaction, error) {
+	if p.mode == ModeRecord {
+		return p.record(ctx, method, inputs, codeDir, run)
+	}
+
+	interaction, err := p.replay(method, inputs, codeDir)
+	if err == nil && interaction.Usage != nil {
+		ai.ReportUsage(ctx, *interaction.Usage)
+	}
+	return interaction, err
+}
+
+// record runs a call and writes it to the cassette
+func (p *Provider) record(ctx context.Context, method string, inputs map[string]string, codeDir string, run func(ctx context.Context) (string, *ai.AnalysisReport, error)) (*Interaction, error) {
+	var before map[string]string
+	if codeDir != "" {
+		var err error
+		if before, err = snapshot(codeDir); err != nil {
+			return nil, err
+		}
+	}
+
+	interaction := &Interaction{Method: method, Inputs: inputs}
+	runCtx, tracker := ai.TrackUsage(ctx)
+	output, report, runErr := run(runCtx)
+	interaction.Output = output
+	interaction.Analysis = report
+	if usage := tracker.Usage(); !usage.IsZero() {
+		interaction.Usage = &usage
+	}
+	if runErr != nil {
+		interaction.Error = runErr.Error()
+	}
+
+	dir, err := p.claimDir(method)
+	if err != nil {
+		return nil, err
+	}
+	interaction.dir = dir
+
+	if codeDir != "" {
+		after, err := snapshot(codeDir)
+		if err != nil {
+			return nil, err
+		}
+		for path, hash := range after {
+			if before[path] != hash {
+				interaction.Changed = append(interaction.Changed, path)
+				rel := filepath.FromSlash(path)
+				if err := copyFile(filepath.Join(codeDir, rel), filepath.Join(dir, filesDir, rel)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for path := range before {
+			if _, ok := after[path]; !ok {
+				interaction.Deleted = append(interaction.Deleted, path)
+			}
+		}
+		sort.Strings(interaction.Changed)
+		sort.Strings(interaction.Deleted)
+	}
+
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal interaction: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, interactionFile), append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write interaction: %w", err)
+	}
+
+	return interaction, nil
+}
+
+// claimDir creates the directory of the next interaction in the cassette. Directories are
+// created atomically, so recorders running in parallel never share one.
+func (p *Provider) claimDir(method string) (string, error) {
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cassette directory: %w", err)
+	}
+
+	for seq := len(entries); ; seq++ {
+		dir := filepath.Join(p.dir, fmt.Sprintf("%04d-%s", seq, method))
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			return dir, nil
+		}
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("failed to create interaction directory: %w", err)
+		}
+	}
+}
+
+// replay applies the changes of the next recorded call with the same method and inputs to
+// codeDir (a temporary directory if empty). Once all such calls were replayed, the last
+// one is replayed again.
+func (p *Provider) replay(method string, inputs map[string]string, codeDir string) (*Interaction, error) {
+	key := (&Interaction{Method: method, Inputs: inputs}).key()
+
+	p.mu.Lock()
+	recorded := p.interactions[key]
+	if len(recorded) == 0 {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("no recorded %s call with inputs %v in cassette %s", method, inputs, p.dir)
+	}
+	index := p.replayed[key]
+	if index >= len(recorded) {
+		index = len(recorded) - 1
+	}
+	p.replayed[key]++
+	interaction := recorded[index]
+	p.mu.Unlock()
+
+	if len(interaction.Changed) == 0 && len(interaction.Deleted) == 0 {
+		return interaction, nil
+	}
+
+	if codeDir == "" {
+		dir, err := os.MkdirTemp("", "cc-replay-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workspace directory: %w", err)
+		}
+		p.config[ai.WorkspaceDirKey] = dir
+		codeDir = dir
+	}
+
+	paths := append(append([]string{}, interaction.Changed...), interaction.Deleted...)
+	for _, path := range paths {
+		if !filepath.IsLocal(filepath.FromSlash(path)) {
+			return nil, fmt.Errorf("refusing to replay change outside of the code directory: %q", path)
+		}
+	}
+	for _, path := range interaction.Changed {
+		rel := filepath.FromSlash(path)
+		if err := copyFile(filepath.Join(interaction.dir, filesDir, rel), filepath.Join(codeDir, rel)); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range interaction.Deleted {
+		if err := os.Remove(filepath.Join(codeDir, filepath.FromSlash(path))); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	return interaction, nil
+}
+
+// snapshot returns the hashes of the files in dir by relative path, ignoring repository metadata
+func snapshot(dir string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)] = fmt.Sprintf("%x", sha256.Sum256(data))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read files in %s: %w", dir, err)
+	}
+	return hashes, nil
+}
+
+// copyFile copies the file src to dst, creating the directories of dst
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", dst, err)
+	}
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return nil
+}
+
+// Register registers this provider factory
+func init() {
+	ai.Register("replay", func(config map[string]string) (ai.Provider, error) {
+		return NewProvider(config)
+	})
+}
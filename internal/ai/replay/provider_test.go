@@ -0,0 +1,178 @@
+package replay_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/ai/replay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedProvider writes fixed files, standing in for a real provider
+type scriptedProvider struct {
+	workspace string
+	calls     int
+}
+
+func (p *scriptedProvider) Initialize(ctx context.Context, config map[string]string) error {
+	p.workspace = config[ai.WorkspaceDirKey]
+	return nil
+}
+
+func (p *scriptedProvider) GenerateProject(ctx context.Context, description string) (string, error) {
+	return "", fmt.Errorf("quota exceeded")
+}
+
+func (p *scriptedProvider) GenerateImplementation(ctx context.Context, description string, framework string) (string, error) {
+	p.calls++
+	files := map[string]string{
+		"package.json": `{"name": "todo"}`,
+		"src/App.jsx":  fmt.Sprintf("// %s %s, call %d\n", framework, description, p.calls),
+	}
+	for name, content := range files {
+		path := filepath.Join(p.workspace, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return "", err
+		}
+	}
+	return "generated " + framework, nil
+}
+
+func (p *scriptedProvider) AddFeature(ctx context.Context, codeDir string, description string) (string, error) {
+	if err := os.WriteFile(filepath.Join(codeDir, "src", "App.jsx"), []byte("// with "+description+"\n"), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Remove(filepath.Join(codeDir, "package.json")); err != nil {
+		return "", err
+	}
+	return "added " + description, nil
+}
+
+func (p *scriptedProvider) AnalyzeCode(ctx context.Context, codeDir string) (*ai.AnalysisReport, error) {
+	ai.ReportUsage(ctx, ai.Usage{InputTokens: 100, OutputTokens: 20, CostUSD: 0.01})
+	return &ai.AnalysisReport{Summary: "fine", Scores: map[string]int{ai.OverallScore: 75}}, nil
+}
+
+func (p *scriptedProvider) ResolveConflicts(ctx context.Context, codeDir string, request ai.ConflictRequest) (string, error) {
+	return "resolved", nil
+}
+
+func (p *scriptedProvider) Name() string                      { return "scripted" }
+func (p *scriptedProvider) SupportedFrameworks() []string     { return []string{"react"} }
+func (p *scriptedProvider) Cleanup(ctx context.Context) error { return nil }
+
+// readTree returns the content of the files in dir by relative path
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	require.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	}))
+	return files
+}
+
+// TestRecordAndReplay tests that replaying a cassette reproduces the recorded file trees
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	cassette := t.TempDir()
+
+	// Record
+	recordDir := t.TempDir()
+	recorder := replay.NewRecorder(&scriptedProvider{}, cassette)
+	require.NoError(t, recorder.Initialize(ctx, map[string]string{ai.WorkspaceDirKey: recordDir}))
+
+	output, err := recorder.GenerateImplementation(ctx, "todo app", "react")
+	require.NoError(t, err)
+	assert.Equal(t, "generated react", output)
+	generated := readTree(t, recordDir)
+
+	_, err = recorder.GenerateImplementation(ctx, "todo app", "react")
+	require.NoError(t, err)
+	regenerated := readTree(t, recordDir)
+	assert.NotEqual(t, generated, regenerated)
+
+	_, err = recorder.AddFeature(ctx, recordDir, "dark mode")
+	require.NoError(t, err)
+	featured := readTree(t, recordDir)
+
+	usageCtx, tracker := ai.TrackUsage(ctx)
+	report, err := recorder.AnalyzeCode(usageCtx, recordDir)
+	require.NoError(t, err)
+	assert.Equal(t, "fine", report.Summary)
+	assert.Equal(t, 120, tracker.Usage().Tokens())
+
+	_, err = recorder.GenerateProject(ctx, "todo app")
+	assert.EqualError(t, err, "quota exceeded")
+	require.NoError(t, recorder.Cleanup(ctx))
+
+	// Replay through the registry, into a different directory
+	replayDir := t.TempDir()
+	provider, err := ai.Create("replay", map[string]string{replay.CassetteKey: cassette})
+	require.NoError(t, err)
+	require.NoError(t, provider.Initialize(ctx, map[string]string{ai.WorkspaceDirKey: replayDir}))
+	assert.Equal(t, []string{"react"}, provider.SupportedFrameworks())
+
+	output, err = provider.GenerateImplementation(ctx, "todo app", "react")
+	require.NoError(t, err)
+	assert.Equal(t, "generated react", output)
+	assert.Equal(t, generated, readTree(t, replayDir))
+
+	// Calls with the same inputs replay in recorded order
+	_, err = provider.GenerateImplementation(ctx, "todo app", "react")
+	require.NoError(t, err)
+	assert.Equal(t, regenerated, readTree(t, replayDir))
+
+	output, err = provider.AddFeature(ctx, replayDir, "dark mode")
+	require.NoError(t, err)
+	assert.Equal(t, "added dark mode", output)
+	assert.Equal(t, featured, readTree(t, replayDir))
+	assert.NoFileExists(t, filepath.Join(replayDir, "package.json"))
+
+	// Recorded usage is reported again
+	usageCtx, tracker = ai.TrackUsage(ctx)
+	report, err = provider.AnalyzeCode(usageCtx, replayDir)
+	require.NoError(t, err)
+	assert.Equal(t, 75, report.Scores[ai.OverallScore])
+	assert.Equal(t, ai.Usage{InputTokens: 100, OutputTokens: 20, CostUSD: 0.01}, tracker.Usage())
+
+	_, err = provider.GenerateProject(ctx, "todo app")
+	assert.EqualError(t, err, "quota exceeded")
+
+	// Calls that were never recorded fail
+	_, err = provider.GenerateImplementation(ctx, "todo app", "vue")
+	assert.Error(t, err)
+}
+
+// TestNewProviderConfig tests configuration errors
+func TestNewProviderConfig(t *testing.T) {
+	_, err := replay.NewProvider(map[string]string{})
+	assert.Error(t, err)
+
+	_, err = replay.NewProvider(map[string]string{replay.CassetteKey: t.TempDir(), replay.ModeKey: "rewind"})
+	assert.Error(t, err)
+
+	_, err = replay.NewProvider(map[string]string{replay.CassetteKey: t.TempDir(), replay.ModeKey: replay.ModeRecord, replay.ProviderKey: "replay"})
+	assert.Error(t, err)
+
+	// Replaying a missing cassette fails on initialization
+	provider, err := replay.NewProvider(map[string]string{replay.CassetteKey: filepath.Join(t.TempDir(), "missing")})
+	require.NoError(t, err)
+	assert.Error(t, provider.Initialize(context.Background(), nil))
+}
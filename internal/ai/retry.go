@@ -0,0 +1,56 @@
+package ai
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy decides how often failed AI calls are attempted and how long to wait in between
+type RetryPolicy struct {
+	// Attempts in total, including the first (1 or less for no retries)
+	Attempts int
+
+	// Wait before the first retry, doubled before every further retry
+	Backoff time.Duration
+
+	// Longest wait between attempts (0 for no maximum)
+	MaxBackoff time.Duration
+}
+
+// Delay returns how long to wait before the given retry, 1 being the first retry
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// Retry calls fn until it succeeds or the attempts of the policy are used up, waiting between
+// attempts as the policy says. Attempts are numbered from 1. Once ctx is done no further
+// attempts are made. It returns the error of the last attempt.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(attempt int) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(attempt); err == nil {
+			return nil
+		}
+		if attempt >= policy.Attempts || ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(policy.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
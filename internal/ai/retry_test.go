@@ -0,0 +1,71 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRetryPolicyDelay tests the exponential backoff between attempts
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := ai.RetryPolicy{Attempts: 5, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, policy.Delay(1))
+	assert.Equal(t, 2*time.Second, policy.Delay(2))
+	assert.Equal(t, 4*time.Second, policy.Delay(3))
+	assert.Equal(t, 5*time.Second, policy.Delay(4))
+	assert.Equal(t, 5*time.Second, policy.Delay(60))
+
+	unlimited := ai.RetryPolicy{Backoff: time.Second}
+	assert.Equal(t, 8*time.Second, unlimited.Delay(4))
+}
+
+// TestRetry tests retrying failed calls until they succeed or the attempts are used up
+func TestRetry(t *testing.T) {
+	policy := ai.RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+
+	// Calls are retried until they succeed
+	var attempts []int
+	err := ai.Retry(context.Background(), policy, func(attempt int) error {
+		attempts = append(attempts, attempt)
+		if attempt < 2 {
+			return errors.New("overloaded")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, attempts)
+
+	// The error of the last attempt is returned
+	attempts = nil
+	err = ai.Retry(context.Background(), policy, func(attempt int) error {
+		attempts = append(attempts, attempt)
+		return fmt.Errorf("attempt %d failed", attempt)
+	})
+	assert.EqualError(t, err, "attempt 3 failed")
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+
+	// Without retries there is a single attempt
+	attempts = nil
+	err = ai.Retry(context.Background(), ai.RetryPolicy{}, func(attempt int) error {
+		attempts = append(attempts, attempt)
+		return errors.New("failed")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []int{1}, attempts)
+
+	// Canceled work is not retried, even while waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts = nil
+	err = ai.Retry(ctx, ai.RetryPolicy{Attempts: 3, Backoff: time.Hour}, func(attempt int) error {
+		attempts = append(attempts, attempt)
+		go cancel()
+		return errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, []int{1}, attempts)
+}
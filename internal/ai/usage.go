@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Usage is the resources used by AI calls
+type Usage struct {
+	// Tokens sent to the model, including cached prompt tokens
+	InputTokens int `json:"inputTokens"`
+
+	// Tokens generated by the model
+	OutputTokens int `json:"outputTokens"`
+
+	// Cost in US dollars, zero when the provider does not report it
+	CostUSD float64 `json:"costUSD"`
+
+	// Wall-clock time of the calls
+	Duration time.Duration `json:"duration"`
+}
+
+// Tokens returns the number of input and output tokens
+func (u Usage) Tokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// Add adds other to the usage
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CostUSD += other.CostUSD
+	u.Duration += other.Duration
+}
+
+// IsZero returns whether no usage was recorded
+func (u Usage) IsZero() bool {
+	return u == Usage{}
+}
+
+// Metric name suffixes of usage figures
+const (
+	inputTokensMetric  = "_input_tokens"
+	outputTokensMetric = "_output_tokens"
+	costMetric         = "_cost_usd"
+	secondsMetric      = "_seconds"
+)
+
+// Metric name prefixes of usage figures
+const (
+	// UsageMetricPrefix prefixes the usage of generating an implementation or feature
+	UsageMetricPrefix = "ai"
+
+	// AnalysisUsageMetricPrefix prefixes the usage of analyzing an implementation or feature
+	AnalysisUsageMetricPrefix = "analysis"
+)
+
+// AddToMetrics adds the usage to the metrics named with prefix, like ai_cost_usd
+func (u Usage) AddToMetrics(metrics map[string]float64, prefix string) {
+	metrics[prefix+inputTokensMetric] += float64(u.InputTokens)
+	metrics[prefix+outputTokensMetric] += float64(u.OutputTokens)
+	metrics[prefix+costMetric] += u.CostUSD
+	metrics[prefix+secondsMetric] += u.Duration.Seconds()
+}
+
+// UsageFromMetrics returns the usage stored in the metrics named with prefix
+func UsageFromMetrics(metrics map[string]float64, prefix string) Usage {
+	return Usage{
+		InputTokens:  int(metrics[prefix+inputTokensMetric]),
+		OutputTokens: int(metrics[prefix+outputTokensMetric]),
+		CostUSD:      metrics[prefix+costMetric],
+		Duration:     time.Duration(metrics[prefix+secondsMetric] * float64(time.Second)),
+	}
+}
+
+// UsageFunc receives the usage of AI calls. Calls running in parallel call it concurrently.
+type UsageFunc func(Usage)
+
+// usageKey is the context key of the usage function
+type usageKey struct{}
+
+// WithUsage returns a context making providers report the usage of calls to fn, in addition
+// to the usage functions ctx already has
+func WithUsage(ctx context.Context, fn UsageFunc) context.Context {
+	if parent, ok := ctx.Value(usageKey{}).(UsageFunc); ok && parent != nil {
+		inner := fn
+		fn = func(usage Usage) {
+			parent(usage)
+			inner(usage)
+		}
+	}
+	return context.WithValue(ctx, usageKey{}, fn)
+}
+
+// ReportUsage reports the usage of a call to the usage functions of ctx, if any
+func ReportUsage(ctx context.Context, usage Usage) {
+	if fn, ok := ctx.Value(usageKey{}).(UsageFunc); ok && fn != nil {
+		fn(usage)
+	}
+}
+
+// UsageTracker adds up the usage reported by AI calls
+type UsageTracker struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+// TrackUsage returns a context whose AI calls add their usage to the returned tracker
+func TrackUsage(ctx context.Context) (context.Context, *UsageTracker) {
+	tracker := &UsageTracker{}
+	return WithUsage(ctx, tracker.add), tracker
+}
+
+// add adds the usage of a call
+func (t *UsageTracker) add(usage Usage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage.Add(usage)
+}
+
+// Usage returns the usage reported so far
+func (t *UsageTracker) Usage() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage
+}
@@ -0,0 +1,48 @@
+package ai_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUsageTracking tests adding up reported usage and storing it as metrics
+func TestUsageTracking(t *testing.T) {
+	outer, total := ai.TrackUsage(context.Background())
+	inner, call := ai.TrackUsage(outer)
+
+	// Without a usage function reporting is a no-op
+	ai.ReportUsage(context.Background(), ai.Usage{InputTokens: 1})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ai.ReportUsage(inner, ai.Usage{InputTokens: 100, OutputTokens: 25, CostUSD: 0.01, Duration: time.Second})
+		}()
+	}
+	wg.Wait()
+	ai.ReportUsage(outer, ai.Usage{InputTokens: 10})
+
+	expected := ai.Usage{InputTokens: 400, OutputTokens: 100, CostUSD: 0.04, Duration: 4 * time.Second}
+	assert.Equal(t, expected.Tokens(), call.Usage().Tokens())
+	assert.InDelta(t, expected.CostUSD, call.Usage().CostUSD, 1e-9)
+	assert.Equal(t, 410, total.Usage().InputTokens)
+
+	// Usage round-trips through metrics, adding to what is stored
+	metrics := map[string]float64{}
+	expected.AddToMetrics(metrics, ai.UsageMetricPrefix)
+	expected.AddToMetrics(metrics, ai.UsageMetricPrefix)
+	assert.Equal(t, 800.0, metrics["ai_input_tokens"])
+	assert.Equal(t, 8.0, metrics["ai_seconds"])
+
+	stored := ai.UsageFromMetrics(metrics, ai.UsageMetricPrefix)
+	assert.Equal(t, 1000, stored.Tokens())
+	assert.Equal(t, 8*time.Second, stored.Duration)
+	assert.True(t, ai.UsageFromMetrics(metrics, ai.AnalysisUsageMetricPrefix).IsZero())
+}
@@ -0,0 +1,154 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+)
+
+// ErrExceeded is the error, and the cause of canceled contexts, when a budget cap is reached
+var ErrExceeded = errors.New("AI budget exceeded")
+
+// Limits caps the usage of AI calls. Zero values are unlimited.
+type Limits struct {
+	// Spend in US dollars per project
+	ProjectCostUSD float64
+
+	// Input and output tokens per project
+	ProjectTokens int
+
+	// Spend in US dollars per day, over all projects
+	DailyCostUSD float64
+
+	// Input and output tokens per day, over all projects
+	DailyTokens int
+}
+
+// IsZero returns whether no cap is set
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// check returns an error wrapping ErrExceeded if the usage of the project or of the day
+// reached a cap
+func (l Limits) check(project, daily ai.Usage) error {
+	switch {
+	case l.ProjectCostUSD > 0 && project.CostUSD >= l.ProjectCostUSD:
+		return fmt.Errorf("%w: $%.2f of the $%.2f per project spent", ErrExceeded, project.CostUSD, l.ProjectCostUSD)
+	case l.ProjectTokens > 0 && project.Tokens() >= l.ProjectTokens:
+		return fmt.Errorf("%w: %d of the %d tokens per project used", ErrExceeded, project.Tokens(), l.ProjectTokens)
+	case l.DailyCostUSD > 0 && daily.CostUSD >= l.DailyCostUSD:
+		return fmt.Errorf("%w: $%.2f of the $%.2f per day spent", ErrExceeded, daily.CostUSD, l.DailyCostUSD)
+	case l.DailyTokens > 0 && daily.Tokens() >= l.DailyTokens:
+		return fmt.Errorf("%w: %d of the %d tokens per day used", ErrExceeded, daily.Tokens(), l.DailyTokens)
+	}
+	return nil
+}
+
+// Guard enforces the limits of a project, recording the usage of its AI calls in a ledger
+type Guard struct {
+	ledger  *Ledger
+	limits  Limits
+	project string
+
+	mu      sync.Mutex
+	day     time.Time
+	used    ai.Usage
+	usedDay ai.Usage
+}
+
+// NewGuard creates a guard for the AI calls of project
+func NewGuard(ledger *Ledger, limits Limits, project string) *Guard {
+	return &Guard{
+		ledger:  ledger,
+		limits:  limits,
+		project: project,
+	}
+}
+
+// startOfDay returns midnight of the day of t, in its location
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Check loads the usage recorded in the ledger and returns an error wrapping ErrExceeded if
+// a cap is already reached, so that no work is started
+func (g *Guard) Check() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.load(); err != nil {
+		return err
+	}
+	return g.limits.check(g.used, g.usedDay)
+}
+
+// load totals the usage of the project and of the current day from the ledger
+func (g *Guard) load() error {
+	entries, err := g.ledger.Entries()
+	if err != nil {
+		return err
+	}
+
+	g.day = startOfDay(time.Now())
+	g.used, g.usedDay = ai.Usage{}, ai.Usage{}
+	for _, entry := range entries {
+		if entry.Project == g.project {
+			g.used.Add(entry.Usage)
+		}
+		if !entry.Time.Before(g.day) {
+			g.usedDay.Add(entry.Usage)
+		}
+	}
+	return nil
+}
+
+// Context returns a context recording the usage AI calls report in the ledger. Once a cap
+// is reached the context is canceled with a cause wrapping ErrExceeded, which stops the
+// container commands running in it. Calls report their usage when they finish, so the call
+// reaching a cap may overrun it by its own usage; the calls still running are canceled.
+// Without limits ledger errors are ignored, otherwise they cancel the context as the budget
+// can no longer be enforced.
+func (g *Guard) Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	ctx = ai.WithUsage(ctx, func(usage ai.Usage) {
+		if err := g.record(usage); err != nil {
+			cancel(err)
+		}
+	})
+	return ctx, func() { cancel(nil) }
+}
+
+// record records the usage of a call and returns an error if a cap is reached
+func (g *Guard) record(usage ai.Usage) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	err := g.ledger.Record(Entry{Time: now, Project: g.project, Usage: usage})
+	if err != nil && !g.limits.IsZero() {
+		return err
+	}
+
+	// Usage of the previous day no longer counts against the daily caps
+	if day := startOfDay(now); day.After(g.day) {
+		g.day = day
+		g.usedDay = ai.Usage{}
+	}
+	g.used.Add(usage)
+	g.usedDay.Add(usage)
+	return g.limits.check(g.used, g.usedDay)
+}
+
+// Exceeded returns the error wrapping ErrExceeded ctx was canceled with, if any
+func Exceeded(ctx context.Context) error {
+	if err := context.Cause(ctx); errors.Is(err, ErrExceeded) {
+		return err
+	}
+	return nil
+}
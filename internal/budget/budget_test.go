@@ -0,0 +1,86 @@
+package budget_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+	"github.com/fr0g-66723067/cc/internal/budget"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGuard tests refusing and canceling AI work once a cap is reached
+func TestGuard(t *testing.T) {
+	ledger := budget.NewLedger(filepath.Join(t.TempDir(), "usage", "ledger.jsonl"))
+
+	// Usage of yesterday only counts against the project caps
+	require.NoError(t, ledger.Record(budget.Entry{
+		Time:    time.Now().AddDate(0, 0, -1),
+		Project: "todo",
+		Usage:   ai.Usage{InputTokens: 8000, CostUSD: 4},
+	}))
+	require.NoError(t, ledger.Record(budget.Entry{
+		Time:    time.Now(),
+		Project: "other",
+		Usage:   ai.Usage{OutputTokens: 500, CostUSD: 0.5},
+	}))
+
+	guard := budget.NewGuard(ledger, budget.Limits{ProjectCostUSD: 5, DailyTokens: 1000}, "todo")
+	require.NoError(t, guard.Check())
+
+	ctx, cancel := guard.Context(context.Background())
+	defer cancel()
+
+	ai.ReportUsage(ctx, ai.Usage{InputTokens: 300, CostUSD: 0.25})
+	assert.NoError(t, ctx.Err())
+	assert.NoError(t, budget.Exceeded(ctx))
+
+	// 1100 tokens were used today
+	ai.ReportUsage(ctx, ai.Usage{InputTokens: 300, CostUSD: 0.25})
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	err := budget.Exceeded(ctx)
+	assert.ErrorIs(t, err, budget.ErrExceeded)
+	assert.EqualError(t, err, "AI budget exceeded: 1100 of the 1000 tokens per day used")
+
+	// Usage is recorded and refuses new work
+	entries, err := ledger.Entries()
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+	assert.Equal(t, 300, entries[3].InputTokens)
+	assert.ErrorIs(t, guard.Check(), budget.ErrExceeded)
+
+	// Projects have caps of their own
+	other := budget.NewGuard(ledger, budget.Limits{ProjectCostUSD: 5}, "other")
+	assert.NoError(t, other.Check())
+	project := budget.NewGuard(ledger, budget.Limits{ProjectCostUSD: 4.5}, "todo")
+	assert.EqualError(t, project.Check(), "AI budget exceeded: $4.50 of the $4.50 per project spent")
+
+	// Canceling the context is not exceeding the budget
+	ctx, cancel = budget.NewGuard(ledger, budget.Limits{}, "todo").Context(context.Background())
+	cancel()
+	assert.Error(t, ctx.Err())
+	assert.NoError(t, budget.Exceeded(ctx))
+}
+
+// TestLedgerErrors tests that ledger errors only stop work when there is a budget to enforce
+func TestLedgerErrors(t *testing.T) {
+	// The ledger directory is a file
+	dir := filepath.Join(t.TempDir(), "ledger.jsonl")
+	ledger := budget.NewLedger(filepath.Join(dir, "ledger.jsonl"))
+	require.NoError(t, budget.NewLedger(dir).Record(budget.Entry{Project: "todo"}))
+
+	ctx, cancel := budget.NewGuard(ledger, budget.Limits{}, "todo").Context(context.Background())
+	defer cancel()
+	ai.ReportUsage(ctx, ai.Usage{CostUSD: 1})
+	assert.NoError(t, ctx.Err())
+
+	ctx, cancel = budget.NewGuard(ledger, budget.Limits{DailyCostUSD: 10}, "todo").Context(context.Background())
+	defer cancel()
+	ai.ReportUsage(ctx, ai.Usage{CostUSD: 1})
+	assert.Error(t, context.Cause(ctx))
+	assert.False(t, errors.Is(context.Cause(ctx), budget.ErrExceeded))
+}
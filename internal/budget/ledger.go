@@ -0,0 +1,95 @@
+package budget
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/ai"
+)
+
+// Entry is the usage of one AI call recorded in the ledger
+type Entry struct {
+	// Time the call finished
+	Time time.Time `json:"time"`
+
+	// Name of the project the call worked on
+	Project string `json:"project"`
+
+	// Usage of the call
+	ai.Usage
+}
+
+// Ledger is a local file recording the usage of AI calls, one JSON entry per line
+type Ledger struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLedger creates a ledger stored at path, which is created on the first record
+func NewLedger(path string) *Ledger {
+	return &Ledger{path: path}
+}
+
+// Record appends an entry to the ledger
+func (l *Ledger) Record(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode ledger entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create ledger directory: %w", err)
+	}
+
+	// Appends of a single line are atomic, so several commands can share the ledger
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open ledger: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write ledger: %w", err)
+	}
+	return nil
+}
+
+// Entries returns the entries of the ledger, in the order they were recorded
+func (l *Ledger) Entries() ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse ledger line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+	return entries, nil
+}
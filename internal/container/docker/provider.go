@@ -2,8 +2,10 @@
 package docker
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/user"
@@ -73,7 +75,7 @@ func (p *Provider) Initialize(ctx context.Context, config map[string]string) err
 }
 
 // RunContainer starts a container with the given image and returns its ID
-func (p *Provider) RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string) (string, error) {
+func (p *Provider) RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string, options cccontainer.RunOptions) (string, error) {
 	// Build docker run command
 	args := []string{"run", "-d"}
 	
@@ -87,8 +89,12 @@ func (p *Provider) RunContainer(ctx context.Context, image string, volumeMounts
 		args = append(args, "-v", fmt.Sprintf("%s:%s", host, container))
 	}
 	
+	// Add resource limits and hardening
+	args = append(args, options.Args()...)
+	
 	// Add the image and command to keep container running
-	args = append(args, image, "tail", "-f", "/dev/null")
+	args = append(args, image)
+	args = append(args, options.KeepAliveCommand()...)
 	
 	// Check if sudo should be used
 	useSudo := p.config["use_sudo"] == "true"
@@ -145,6 +151,28 @@ func (p *Provider) RunContainer(ctx context.Context, image string, volumeMounts
 
 // ExecuteCommand executes a command in the container
 func (p *Provider) ExecuteCommand(ctx context.Context, containerID string, command []string) (string, error) {
+	var output bytes.Buffer
+	if err := p.exec(ctx, containerID, command, &output); err != nil {
+		return "", fmt.Errorf("failed to execute command in container: %w\n%s", err, output.String())
+	}
+	
+	return output.String(), nil
+}
+
+// ExecuteCommandStream executes a command in the container, writing its combined
+// output to output while the command runs
+func (p *Provider) ExecuteCommandStream(ctx context.Context, containerID string, command []string, output io.Writer) error {
+	// Keep the end of the output for the error message
+	tail := &cccontainer.TailBuffer{Max: cccontainer.MaxErrorOutput}
+	if err := p.exec(ctx, containerID, command, io.MultiWriter(output, tail)); err != nil {
+		return fmt.Errorf("failed to execute command in container: %w\n%s", err, tail.String())
+	}
+	
+	return nil
+}
+
+// exec runs docker exec, writing stdout and stderr to output
+func (p *Provider) exec(ctx context.Context, containerID string, command []string, output io.Writer) error {
 	// Build docker exec command
 	args := []string{"exec", containerID}
 	args = append(args, command...)
@@ -161,12 +189,10 @@ func (p *Provider) ExecuteCommand(ctx context.Context, containerID string, comma
 		cmd = exec.CommandContext(ctx, "docker", args...)
 	}
 	
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("failed to execute command in container: %w\n%s", err, output)
-	}
-	
-	return string(output), nil
+	// The same writer for both streams makes exec serialize the writes
+	cmd.Stdout = output
+	cmd.Stderr = output
+	return cmd.Run()
 }
 
 // CopyFilesToContainer copies files from local to container
@@ -2,9 +2,13 @@ package docker_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/fr0g-66723067/cc/internal/container"
 	"github.com/fr0g-66723067/cc/internal/container/docker"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -72,7 +76,7 @@ func TestContainerLifecycle(t *testing.T) {
 	t.Skip("This test would run actual Docker containers - skipping by default")
 	
 	// Run a container
-	containerID, err := provider.RunContainer(ctx, "alpine:latest", nil, nil)
+	containerID, err := provider.RunContainer(ctx, "alpine:latest", nil, nil, container.RunOptions{})
 	require.NoError(t, err)
 	assert.NotEmpty(t, containerID)
 	
@@ -81,6 +85,13 @@ func TestContainerLifecycle(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, strings.TrimSpace(output), "hello")
 	
+	// Stream the output of a command, both stdout and stderr
+	var streamed strings.Builder
+	err = provider.ExecuteCommandStream(ctx, containerID, []string{"sh", "-c", "echo one; echo two >&2"}, &streamed)
+	require.NoError(t, err)
+	assert.Contains(t, streamed.String(), "one")
+	assert.Contains(t, streamed.String(), "two")
+	
 	// Stop container
 	err = provider.StopContainer(ctx, containerID)
 	require.NoError(t, err)
@@ -88,4 +99,33 @@ func TestContainerLifecycle(t *testing.T) {
 	// Remove container
 	err = provider.RemoveContainer(ctx, containerID)
 	require.NoError(t, err)
-}
\ No newline at end of file
+}
+// TestRunContainerOptions tests passing limits and hardening to docker run, with a fake
+// docker on the PATH logging its arguments
+func TestRunContainerOptions(t *testing.T) {
+	dir := t.TempDir()
+	fakeDocker := "#!/bin/sh\necho \"$@\" >> \"$(dirname \"$0\")/calls.log\"\necho c0ffee\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker"), []byte(fakeDocker), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	provider, err := docker.NewProvider(nil)
+	require.NoError(t, err)
+
+	options := container.RunOptions{
+		CPUs:      2,
+		Memory:    1 << 30,
+		PidsLimit: 256,
+		ReadOnly:  true,
+		CapDrop:   []string{"ALL"},
+		User:      "1000:1000",
+		Network:   "none",
+		Timeout:   90 * time.Second,
+	}
+	containerID, err := provider.RunContainer(context.Background(), "node:20", nil, nil, options)
+	require.NoError(t, err)
+	assert.Equal(t, "c0ffee", containerID)
+
+	calls, err := os.ReadFile(filepath.Join(dir, "calls.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "run -d --cpus 2 --memory 1073741824 --pids-limit 256 --read-only --cap-drop ALL --user 1000:1000 --network none node:20 sleep 90\n", string(calls))
+}
@@ -0,0 +1,154 @@
+package dockerapi
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// writeArchive writes src, a file or a directory, to w as a tar stream whose root entry is
+// named name
+func writeArchive(w io.Writer, src, name string) error {
+	tw := tar.NewWriter(w)
+
+	err := filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", file, err)
+		}
+		header.Name = path.Join(name, filepath.ToSlash(rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// extractArchive extracts the tar stream r into dir. If rename is set, the root entry of the
+// archive, root, is extracted as rename instead. Entries leaving dir are refused, as are
+// entries below a symlink, which may point out of dir.
+func extractArchive(r io.Reader, dir, root, rename string) error {
+	dir = filepath.Clean(dir)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := path.Clean(header.Name)
+		if rename != "" {
+			if name == root {
+				name = rename
+			} else if strings.HasPrefix(name, root+"/") {
+				name = rename + strings.TrimPrefix(name, root)
+			}
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if target != dir && !strings.HasPrefix(target, dir+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+		if throughSymlink(dir, target) {
+			return fmt.Errorf("invalid path in archive: %s is below a symlink", header.Name)
+		}
+		// Entries replace symlinks rather than being written through them
+		if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 && target != dir {
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+
+		mode := os.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", header.Name, err)
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// throughSymlink returns whether a directory between dir and target is a symlink
+func throughSymlink(dir, target string) bool {
+	rel, err := filepath.Rel(dir, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return false
+	}
+
+	parent := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		parent = filepath.Join(parent, part)
+		info, err := os.Lstat(parent)
+		if err != nil {
+			// Missing directories are created as directories
+			return false
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
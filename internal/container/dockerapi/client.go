@@ -0,0 +1,152 @@
+package dockerapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// defaultHost is the socket of a local Docker Engine
+const defaultHost = "unix:///var/run/docker.sock"
+
+// client sends requests to the Docker Engine API
+type client struct {
+	http *http.Client
+
+	// URL requests are relative to, including the API version
+	base string
+
+	// Whether the Engine runs on another host
+	remote bool
+}
+
+// newClient creates a client for the Engine listening on host (a unix:// socket or a
+// tcp:// or http:// address, $DOCKER_HOST or the local socket if empty) using the given
+// API version (the Engine's own version if empty)
+func newClient(host, version string) (*client, error) {
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = defaultHost
+	}
+
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Docker host %s: %w", host, err)
+	}
+
+	c := &client{}
+	transport := &http.Transport{}
+	switch hostURL.Scheme {
+	case "unix":
+		socket := hostURL.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		c.base = "http://docker"
+	case "tcp", "http":
+		c.base = "http://" + hostURL.Host
+		c.remote = true
+	default:
+		return nil, fmt.Errorf("unsupported Docker host %s (use unix://, tcp:// or http://)", host)
+	}
+	c.http = &http.Client{Transport: transport}
+
+	if version != "" {
+		c.base += "/" + strings.TrimPrefix(version, "/")
+	}
+	return c, nil
+}
+
+// apiError is an error response of the Engine API
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error returns the message of the Engine
+func (e *apiError) Error() string {
+	return fmt.Sprintf("Docker API error (%d): %s", e.StatusCode, e.Message)
+}
+
+// isNotFound returns whether err is an Engine API error saying something does not exist
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// do sends a request and returns the response, or an apiError if the Engine refused it.
+// Callers close the body of the response.
+func (c *client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, header http.Header) (*http.Response, error) {
+	target := c.base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+		// Errors are JSON objects with a message, but proxies may answer otherwise
+		var message struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &message) != nil || message.Message == "" {
+			message.Message = strings.TrimSpace(string(data))
+		}
+		return nil, &apiError{StatusCode: resp.StatusCode, Message: message.Message}
+	}
+	return resp, nil
+}
+
+// doJSON sends in as JSON (no body if nil) and decodes the response into out (ignored if nil)
+func (c *client) doJSON(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	header := http.Header{}
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+		header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.do(ctx, method, path, query, body, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,442 @@
+// Docker Engine API provider implementation
+package dockerapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cccontainer "github.com/fr0g-66723067/cc/internal/container"
+)
+
+// Config keys of the Docker Engine API provider
+const (
+	// HostKey is the address of the Engine: a unix:// socket or a tcp:// or http:// address
+	// ($DOCKER_HOST or unix:///var/run/docker.sock by default)
+	HostKey = "host"
+
+	// APIVersionKey is the Engine API version requests use (v1.41 by default, empty for the
+	// version of the Engine)
+	APIVersionKey = "api_version"
+)
+
+// defaultAPIVersion is the API version of Docker 20.10, also understood by Podman
+const defaultAPIVersion = "v1.41"
+
+// Provider implements the container provider interface with the Docker Engine HTTP API,
+// so commands are passed as argument lists and files as tar streams, without the docker CLI
+type Provider struct {
+	config map[string]string
+	client *client
+}
+
+// NewProvider creates a new Docker Engine API provider
+func NewProvider(config map[string]string) (*Provider, error) {
+	if config == nil {
+		config = make(map[string]string)
+	}
+
+	p := &Provider{
+		config: config,
+	}
+	if err := p.connect(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// connect creates the client for the configured Engine
+func (p *Provider) connect() error {
+	version, ok := p.config[APIVersionKey]
+	if !ok {
+		version = defaultAPIVersion
+	}
+
+	client, err := newClient(p.config[HostKey], version)
+	if err != nil {
+		return err
+	}
+	p.client = client
+	return nil
+}
+
+// Initialize sets up the provider and checks that the Engine answers
+func (p *Provider) Initialize(ctx context.Context, config map[string]string) error {
+	// Merge configs
+	for k, v := range config {
+		p.config[k] = v
+	}
+	if err := p.connect(); err != nil {
+		return err
+	}
+
+	if err := p.client.doJSON(ctx, http.MethodGet, "/_ping", nil, nil, nil); err != nil {
+		return fmt.Errorf("Docker Engine is not reachable: %w", err)
+	}
+	return nil
+}
+
+// containerConfig is the configuration of a container to create
+type containerConfig struct {
+	Image      string            `json:"Image"`
+	Cmd        []string          `json:"Cmd"`
+	Env        []string          `json:"Env,omitempty"`
+	User       string            `json:"User,omitempty"`
+	Labels     map[string]string `json:"Labels,omitempty"`
+	HostConfig hostConfig        `json:"HostConfig"`
+}
+
+// hostConfig is the host specific configuration of a container to create
+type hostConfig struct {
+	Binds          []string `json:"Binds,omitempty"`
+	NanoCPUs       int64    `json:"NanoCpus,omitempty"`
+	Memory         int64    `json:"Memory,omitempty"`
+	PidsLimit      int64    `json:"PidsLimit,omitempty"`
+	ReadonlyRootfs bool     `json:"ReadonlyRootfs,omitempty"`
+	CapDrop        []string `json:"CapDrop,omitempty"`
+	CapAdd         []string `json:"CapAdd,omitempty"`
+	NetworkMode    string   `json:"NetworkMode,omitempty"`
+}
+
+// RunContainer starts a container with the given image and returns its ID
+func (p *Provider) RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string, options cccontainer.RunOptions) (string, error) {
+	// Keep the container running until it is stopped, limited and hardened as the options say
+	config := containerConfig{
+		Image:  image,
+		Cmd:    options.KeepAliveCommand(),
+		User:   options.User,
+		Labels: options.Labels,
+		HostConfig: hostConfig{
+			NanoCPUs:       int64(options.CPUs * 1e9),
+			Memory:         options.Memory,
+			PidsLimit:      options.PidsLimit,
+			ReadonlyRootfs: options.ReadOnly,
+			CapDrop:        options.CapDrop,
+			CapAdd:         options.CapAdd,
+			NetworkMode:    options.Network,
+		},
+	}
+	for k, v := range env {
+		config.Env = append(config.Env, fmt.Sprintf("%s=%s", k, v))
+	}
+	for host, container := range volumeMounts {
+		config.HostConfig.Binds = append(config.HostConfig.Binds, fmt.Sprintf("%s:%s", host, container))
+	}
+	sort.Strings(config.Env)
+	sort.Strings(config.HostConfig.Binds)
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	err := p.client.doJSON(ctx, http.MethodPost, "/containers/create", nil, config, &created)
+	if isNotFound(err) {
+		// If the image doesn't exist, try to pull it
+		if err := p.pullImage(ctx, image); err != nil {
+			return "", err
+		}
+
+		// Try to create the container again
+		err = p.client.doJSON(ctx, http.MethodPost, "/containers/create", nil, config, &created)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %w", err)
+	}
+
+	if err := p.client.doJSON(ctx, http.MethodPost, "/containers/"+created.ID+"/start", nil, nil, nil); err != nil {
+		return "", fmt.Errorf("failed to start container: %w", err)
+	}
+
+	return created.ID, nil
+}
+
+// pullImage pulls an image, waiting for the pull to finish
+func (p *Provider) pullImage(ctx context.Context, image string) error {
+	name, tag := splitImage(image)
+	query := url.Values{"fromImage": {name}}
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+
+	resp, err := p.client.do(ctx, http.MethodPost, "/images/create", query, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", image, err)
+	}
+	defer resp.Body.Close()
+
+	// The pull reports its progress as a stream of JSON messages, failures included
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to pull image %s: %w", image, err)
+		}
+		if message.Error != "" {
+			return fmt.Errorf("failed to pull image %s: %s", image, message.Error)
+		}
+	}
+}
+
+// splitImage splits an image reference into the name and tag the Engine pulls. References
+// by digest are pulled as they are.
+func splitImage(image string) (string, string) {
+	if strings.Contains(image, "@") {
+		return image, ""
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
+
+// ExecuteCommand executes a command in the container
+func (p *Provider) ExecuteCommand(ctx context.Context, containerID string, command []string) (string, error) {
+	var output bytes.Buffer
+	if err := p.exec(ctx, containerID, command, &output); err != nil {
+		return "", fmt.Errorf("failed to execute command in container: %w\n%s", err, output.String())
+	}
+
+	return output.String(), nil
+}
+
+// ExecuteCommandStream executes a command in the container, writing its combined
+// output to output while the command runs
+func (p *Provider) ExecuteCommandStream(ctx context.Context, containerID string, command []string, output io.Writer) error {
+	// Keep the end of the output for the error message
+	tail := &cccontainer.TailBuffer{Max: cccontainer.MaxErrorOutput}
+	if err := p.exec(ctx, containerID, command, io.MultiWriter(output, tail)); err != nil {
+		return fmt.Errorf("failed to execute command in container: %w\n%s", err, tail.String())
+	}
+
+	return nil
+}
+
+// exec runs a command in the container, attaching to it to write stdout and stderr to
+// output, and fails if the command exits with another code than 0
+func (p *Provider) exec(ctx context.Context, containerID string, command []string, output io.Writer) error {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	config := map[string]interface{}{
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Cmd":          command,
+	}
+	if err := p.client.doJSON(ctx, http.MethodPost, "/containers/"+containerID+"/exec", nil, config, &created); err != nil {
+		return fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	// Starting the exec attached upgrades the connection to a stream of the command's output
+	start, err := json.Marshal(map[string]bool{"Detach": false, "Tty": false})
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Connection", "Upgrade")
+	header.Set("Upgrade", "tcp")
+	resp, err := p.client.do(ctx, http.MethodPost, "/exec/"+created.ID+"/start", nil, bytes.NewReader(start), header)
+	if err != nil {
+		return fmt.Errorf("failed to start exec: %w", err)
+	}
+
+	// Upgraded connections outlive the request, so close them when ctx is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			resp.Body.Close()
+		case <-done:
+		}
+	}()
+
+	err = copyStream(output, resp.Body)
+	resp.Body.Close()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	var inspect struct {
+		ExitCode int `json:"ExitCode"`
+	}
+	if err := p.client.doJSON(ctx, http.MethodGet, "/exec/"+created.ID+"/json", nil, nil, &inspect); err != nil {
+		return fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf("command exited with code %d", inspect.ExitCode)
+	}
+	return nil
+}
+
+// copyStream copies the output of a command from the multiplexed stream of the Engine to
+// output, stdout and stderr alike. Every frame starts with a header of 8 bytes: the stream,
+// 3 zero bytes and the size of the frame as a big endian uint32.
+func copyStream(output io.Writer, stream io.Reader) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(stream, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read output: %w", err)
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(output, stream, size); err != nil {
+			return fmt.Errorf("failed to read output: %w", err)
+		}
+	}
+}
+
+// pathStat is the stat of a path in a container
+type pathStat struct {
+	Name string      `json:"name"`
+	Mode os.FileMode `json:"mode"`
+}
+
+// statPath returns the stat of a path in the container
+func (p *Provider) statPath(ctx context.Context, containerID, containerPath string) (*pathStat, error) {
+	resp, err := p.client.do(ctx, http.MethodHead, "/containers/"+containerID+"/archive", url.Values{"path": {containerPath}}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	data, err := base64.StdEncoding.DecodeString(resp.Header.Get("X-Docker-Container-Path-Stat"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode stat of %s: %w", containerPath, err)
+	}
+	var stat pathStat
+	if err := json.Unmarshal(data, &stat); err != nil {
+		return nil, fmt.Errorf("failed to decode stat of %s: %w", containerPath, err)
+	}
+	return &stat, nil
+}
+
+// CopyFilesToContainer copies files from local to container. Like docker cp, a directory
+// that exists at containerPath receives a copy of localPath, otherwise localPath is copied
+// to containerPath.
+func (p *Provider) CopyFilesToContainer(ctx context.Context, containerID string, localPath string, containerPath string) error {
+	// Verify local path exists
+	if _, err := os.Stat(localPath); os.IsNotExist(err) {
+		return fmt.Errorf("local path does not exist: %s", localPath)
+	}
+
+	dir, name := containerPath, filepath.Base(localPath)
+	stat, err := p.statPath(ctx, containerID, containerPath)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to copy files to container: %w", err)
+	}
+	if stat == nil || !stat.Mode.IsDir() {
+		dir, name = path.Dir(containerPath), path.Base(containerPath)
+	} else if name == "." {
+		// Like docker cp, a directory ending in /. has its contents copied
+		name = ""
+	}
+
+	// Stream the archive while it is written
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeArchive(writer, localPath, name))
+	}()
+	defer reader.Close()
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-tar")
+	resp, err := p.client.do(ctx, http.MethodPut, "/containers/"+containerID+"/archive", url.Values{"path": {dir}}, reader, header)
+	if err != nil {
+		return fmt.Errorf("failed to copy files to container: %w", err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// CopyFilesFromContainer copies files from container to local. Like docker cp, a directory
+// that exists at localPath receives a copy of containerPath, otherwise containerPath is
+// copied to localPath, and a containerPath ending in /. has its contents copied.
+func (p *Provider) CopyFilesFromContainer(ctx context.Context, containerID string, containerPath string, localPath string) error {
+	// Create destination directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	// Like docker cp, a directory ending in /. has its contents copied into localPath
+	contents := path.Base(containerPath) == "."
+	containerPath = path.Clean(containerPath)
+
+	resp, err := p.client.do(ctx, http.MethodGet, "/containers/"+containerID+"/archive", url.Values{"path": {containerPath}}, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to copy files from container: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The archive holds containerPath under its base name
+	dir, rename := localPath, ""
+	if contents {
+		if err := os.MkdirAll(localPath, 0755); err != nil {
+			return fmt.Errorf("failed to create destination directory: %w", err)
+		}
+		rename = "."
+	} else if info, err := os.Stat(localPath); err != nil || !info.IsDir() {
+		dir, rename = filepath.Dir(localPath), filepath.Base(localPath)
+	}
+	if err := extractArchive(resp.Body, dir, path.Base(containerPath), rename); err != nil {
+		return fmt.Errorf("failed to copy files from container: %w", err)
+	}
+
+	return nil
+}
+
+// StopContainer stops a running container
+func (p *Provider) StopContainer(ctx context.Context, containerID string) error {
+	if err := p.client.doJSON(ctx, http.MethodPost, "/containers/"+containerID+"/stop", nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveContainer removes a container
+func (p *Provider) RemoveContainer(ctx context.Context, containerID string) error {
+	// Remove the container with force
+	if err := p.client.doJSON(ctx, http.MethodDelete, "/containers/"+containerID, url.Values{"force": {"true"}}, nil, nil); err != nil {
+		return fmt.Errorf("failed to remove container: %w", err)
+	}
+
+	return nil
+}
+
+// Name returns the provider's name
+func (p *Provider) Name() string {
+	return "docker-api"
+}
+
+// IsRemote returns whether the provider is running containers remotely
+func (p *Provider) IsRemote() bool {
+	return p.client.remote
+}
+
+// Register registers this provider factory
+func init() {
+	cccontainer.Register("docker-api", func(config map[string]string) (cccontainer.Provider, error) {
+		return NewProvider(config)
+	})
+}
@@ -0,0 +1,440 @@
+package dockerapi_test
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/container"
+	"github.com/fr0g-66723067/cc/internal/container/dockerapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEngine is a Docker Engine API stand-in with a single container, c0ffee. Its files live
+// in memory, exec runs the command on the host and only pulled images can be run.
+type fakeEngine struct {
+	mu      sync.Mutex
+	calls   []string
+	images  map[string]bool
+	created map[string]interface{}
+	files   map[string][]byte
+	links   map[string]string
+	dirs    map[string]bool
+	execs   map[string][]string
+	exits   map[string]int
+}
+
+// newFakeEngine serves a fake Engine on a unix socket and returns it with the socket's host
+func newFakeEngine(t *testing.T) (*fakeEngine, string) {
+	engine := &fakeEngine{
+		images: map[string]bool{"node:20": true},
+		files:  map[string][]byte{},
+		links:  map[string]string{},
+		dirs:   map[string]bool{"/": true, "/workspace": true},
+		execs:  map[string][]string{},
+		exits:  map[string]int{},
+	}
+
+	// Socket paths are limited in length, so avoid the long test directory
+	dir, err := os.MkdirTemp("", "dockerapi")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(engine)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return engine, "unix://" + socket
+}
+
+// log returns the requests the engine received
+func (e *fakeEngine) log() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string{}, e.calls...)
+}
+
+// ServeHTTP answers the Engine API requests
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := strings.TrimPrefix(r.URL.Path, "/v1.41")
+	e.mu.Lock()
+	e.calls = append(e.calls, strings.TrimSpace(r.Method+" "+route+" "+r.URL.RawQuery))
+	e.mu.Unlock()
+
+	switch {
+	case route == "/_ping":
+		fmt.Fprint(w, "OK")
+	case route == "/containers/create":
+		var config map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&config)
+		image := config["Image"].(string)
+		if !strings.Contains(image, ":") {
+			image += ":latest"
+		}
+		if !e.images[image] {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"message": "No such image: %s"}`, config["Image"])
+			return
+		}
+		e.created = config
+		fmt.Fprint(w, `{"Id": "c0ffee"}`)
+	case route == "/images/create":
+		name := r.URL.Query().Get("fromImage")
+		if name == "private" {
+			fmt.Fprint(w, "{\"status\": \"Pulling\"}\n{\"error\": \"pull access denied\"}\n")
+			return
+		}
+		e.images[name+":"+r.URL.Query().Get("tag")] = true
+		fmt.Fprint(w, "{\"status\": \"Pulling\"}\n{\"status\": \"Done\"}\n")
+	case route == "/containers/c0ffee/start" || route == "/containers/c0ffee/stop" || route == "/containers/c0ffee":
+		w.WriteHeader(http.StatusNoContent)
+	case route == "/containers/c0ffee/exec":
+		var config struct{ Cmd []string }
+		json.NewDecoder(r.Body).Decode(&config)
+		e.mu.Lock()
+		id := fmt.Sprintf("exec%d", len(e.execs))
+		e.execs[id] = config.Cmd
+		e.mu.Unlock()
+		fmt.Fprintf(w, `{"Id": "%s"}`, id)
+	case strings.HasPrefix(route, "/exec/") && strings.HasSuffix(route, "/start"):
+		e.startExec(w, r, strings.TrimSuffix(strings.TrimPrefix(route, "/exec/"), "/start"))
+	case strings.HasPrefix(route, "/exec/") && strings.HasSuffix(route, "/json"):
+		e.mu.Lock()
+		code := e.exits[strings.TrimSuffix(strings.TrimPrefix(route, "/exec/"), "/json")]
+		e.mu.Unlock()
+		fmt.Fprintf(w, `{"ExitCode": %d}`, code)
+	case route == "/containers/c0ffee/archive":
+		e.archive(w, r, r.URL.Query().Get("path"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"message": "page not found: %s"}`, route)
+	}
+}
+
+// frameWriter writes output to an attached exec stream as frames of its stream
+type frameWriter struct {
+	mu     *sync.Mutex
+	conn   io.Writer
+	stream byte
+}
+
+// Write writes p as a frame
+func (f *frameWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	header := make([]byte, 8)
+	header[0] = f.stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(p)))
+	if _, err := f.conn.Write(append(header, p...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// startExec upgrades the connection and runs the command of the exec on the host
+func (e *fakeEngine) startExec(w http.ResponseWriter, r *http.Request, id string) {
+	io.Copy(io.Discard, r.Body)
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprint(buf, "HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	buf.Flush()
+
+	e.mu.Lock()
+	command := e.execs[id]
+	e.mu.Unlock()
+
+	var mu sync.Mutex
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = &frameWriter{mu: &mu, conn: conn, stream: 1}
+	cmd.Stderr = &frameWriter{mu: &mu, conn: conn, stream: 2}
+	cmd.Run()
+
+	e.mu.Lock()
+	e.exits[id] = cmd.ProcessState.ExitCode()
+	e.mu.Unlock()
+}
+
+// archive stats, extracts or archives the files of the container at containerPath
+func (e *fakeEngine) archive(w http.ResponseWriter, r *http.Request, containerPath string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, isFile := e.files[containerPath]
+	if r.Method != http.MethodPut && !isFile && !e.dirs[containerPath] {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"message": "Could not find the file %s in container c0ffee"}`, containerPath)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead:
+		mode := os.FileMode(0644)
+		if !isFile {
+			mode = os.ModeDir | 0755
+		}
+		stat, _ := json.Marshal(map[string]interface{}{"name": path.Base(containerPath), "mode": mode})
+		w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(stat))
+	case http.MethodPut:
+		if !e.dirs[containerPath] {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "destination directory does not exist"}`)
+			return
+		}
+		tr := tar.NewReader(r.Body)
+		for {
+			header, err := tr.Next()
+			if err != nil {
+				break
+			}
+			target := path.Join(containerPath, header.Name)
+			if header.Typeflag == tar.TypeDir {
+				e.dirs[target] = true
+				continue
+			}
+			e.files[target], _ = io.ReadAll(tr)
+		}
+	case http.MethodGet:
+		tw := tar.NewWriter(w)
+		var names []string
+		for name := range e.files {
+			if name == containerPath || strings.HasPrefix(name, containerPath+"/") {
+				names = append(names, name)
+			}
+		}
+		for name := range e.links {
+			if strings.HasPrefix(name, containerPath+"/") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			entry := path.Base(containerPath) + strings.TrimPrefix(name, containerPath)
+			if link, ok := e.links[name]; ok {
+				tw.WriteHeader(&tar.Header{Name: entry, Mode: 0777, Linkname: link, Typeflag: tar.TypeSymlink})
+				continue
+			}
+			tw.WriteHeader(&tar.Header{Name: entry, Mode: 0644, Size: int64(len(e.files[name])), Typeflag: tar.TypeReg})
+			tw.Write(e.files[name])
+		}
+		tw.Close()
+	}
+}
+
+// TestNewProvider verifies the provider is registered as docker-api and knows where the
+// Engine runs
+func TestNewProvider(t *testing.T) {
+	provider, err := container.Create("docker-api", map[string]string{dockerapi.HostKey: "unix:///run/docker.sock"})
+	require.NoError(t, err)
+	assert.Equal(t, "docker-api", provider.Name())
+	assert.False(t, provider.IsRemote())
+
+	remote, err := dockerapi.NewProvider(map[string]string{dockerapi.HostKey: "tcp://docker.example.com:2375"})
+	require.NoError(t, err)
+	assert.True(t, remote.IsRemote())
+
+	_, err = dockerapi.NewProvider(map[string]string{dockerapi.HostKey: "ssh://docker.example.com"})
+	assert.ErrorContains(t, err, "unsupported Docker host")
+}
+
+// TestInitialize tests checking that the Engine answers
+func TestInitialize(t *testing.T) {
+	engine, host := newFakeEngine(t)
+	provider, err := dockerapi.NewProvider(nil)
+	require.NoError(t, err)
+
+	require.NoError(t, provider.Initialize(context.Background(), map[string]string{dockerapi.HostKey: host}))
+	assert.Equal(t, []string{"GET /_ping"}, engine.log())
+
+	// A socket nobody listens on is reported
+	missing, err := dockerapi.NewProvider(map[string]string{dockerapi.HostKey: "unix://" + filepath.Join(t.TempDir(), "missing.sock")})
+	require.NoError(t, err)
+	err = missing.Initialize(context.Background(), nil)
+	assert.ErrorContains(t, err, "Docker Engine is not reachable")
+}
+
+// TestContainerLifecycle tests running, using and removing a container
+func TestContainerLifecycle(t *testing.T) {
+	engine, host := newFakeEngine(t)
+	provider, err := dockerapi.NewProvider(map[string]string{dockerapi.HostKey: host})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Missing images are pulled before creating the container again
+	containerID, err := provider.RunContainer(ctx, "alpine", map[string]string{"/src": "/workspace"}, map[string]string{"KEY": "value"}, container.RunOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "c0ffee", containerID)
+	assert.Equal(t, []string{
+		"POST /containers/create",
+		"POST /images/create fromImage=alpine&tag=latest",
+		"POST /containers/create",
+		"POST /containers/c0ffee/start",
+	}, engine.log())
+	assert.Equal(t, []interface{}{"tail", "-f", "/dev/null"}, engine.created["Cmd"])
+	assert.Equal(t, []interface{}{"KEY=value"}, engine.created["Env"])
+	assert.Equal(t, map[string]interface{}{"Binds": []interface{}{"/src:/workspace"}}, engine.created["HostConfig"])
+
+	// Execute command
+	output, err := provider.ExecuteCommand(ctx, containerID, []string{"echo", "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", output)
+
+	_, err = provider.ExecuteCommand(ctx, containerID, []string{"sh", "-c", "echo broken; exit 3"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "command exited with code 3")
+	assert.Contains(t, err.Error(), "broken")
+
+	// Stream the output of a command, both stdout and stderr
+	var streamed strings.Builder
+	err = provider.ExecuteCommandStream(ctx, containerID, []string{"sh", "-c", "echo one; echo two >&2"}, &streamed)
+	require.NoError(t, err)
+	assert.Len(t, streamed.String(), len("one\ntwo\n"))
+	assert.Contains(t, streamed.String(), "one\n")
+	assert.Contains(t, streamed.String(), "two\n")
+
+	// Files copied to an existing directory go inside it, other paths are the copy itself
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "app.txt"), []byte("app"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "project", "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "project", "src", "main.go"), []byte("package main"), 0644))
+
+	require.NoError(t, provider.CopyFilesToContainer(ctx, containerID, filepath.Join(src, "app.txt"), "/workspace"))
+	require.NoError(t, provider.CopyFilesToContainer(ctx, containerID, filepath.Join(src, "project"), "/workspace/output"))
+	assert.Equal(t, []byte("app"), engine.files["/workspace/app.txt"])
+	assert.Equal(t, []byte("package main"), engine.files["/workspace/output/src/main.go"])
+
+	// Directories ending in /. have their contents copied
+	require.NoError(t, provider.CopyFilesToContainer(ctx, containerID, filepath.Join(src, "project")+"/.", "/workspace"))
+	assert.Equal(t, []byte("package main"), engine.files["/workspace/src/main.go"])
+
+	err = provider.CopyFilesToContainer(ctx, containerID, filepath.Join(src, "missing"), "/workspace")
+	assert.ErrorContains(t, err, "local path does not exist")
+
+	// Files copied from the container work the same way
+	out := filepath.Join(t.TempDir(), "nested", "out.txt")
+	require.NoError(t, provider.CopyFilesFromContainer(ctx, containerID, "/workspace/app.txt", out))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "app", string(data))
+
+	dest := t.TempDir()
+	require.NoError(t, provider.CopyFilesFromContainer(ctx, containerID, "/workspace/output", dest))
+	data, err = os.ReadFile(filepath.Join(dest, "output", "src", "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main", string(data))
+
+	contents := filepath.Join(t.TempDir(), "contents")
+	require.NoError(t, provider.CopyFilesFromContainer(ctx, containerID, "/workspace/output/.", contents))
+	data, err = os.ReadFile(filepath.Join(contents, "src", "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main", string(data))
+
+	err = provider.CopyFilesFromContainer(ctx, containerID, "/workspace/missing", dest)
+	assert.ErrorContains(t, err, "Could not find the file")
+
+	require.NoError(t, provider.StopContainer(ctx, containerID))
+	require.NoError(t, provider.RemoveContainer(ctx, containerID))
+	log := engine.log()
+	assert.Equal(t, []string{"POST /containers/c0ffee/stop", "DELETE /containers/c0ffee force=true"}, log[len(log)-2:])
+}
+
+// TestCopySymlinks tests copying symlinks from the container, which files are never written
+// through
+func TestCopySymlinks(t *testing.T) {
+	ctx := context.Background()
+	engine, host := newFakeEngine(t)
+	provider, err := dockerapi.NewProvider(map[string]string{dockerapi.HostKey: host})
+	require.NoError(t, err)
+	require.NoError(t, provider.Initialize(ctx, nil))
+
+	// Symlinks within the copy are kept
+	engine.dirs["/workspace/app"] = true
+	engine.files["/workspace/app/main.js"] = []byte("main")
+	engine.links["/workspace/app/index.js"] = "main.js"
+	dest := t.TempDir()
+	require.NoError(t, provider.CopyFilesFromContainer(ctx, "c0ffee", "/workspace/app", dest))
+	link, err := os.Readlink(filepath.Join(dest, "app", "index.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "main.js", link)
+
+	// A file below a symlink pointing out of the copy is refused
+	outside := t.TempDir()
+	engine.dirs["/workspace/evil"] = true
+	engine.links["/workspace/evil/etc"] = outside
+	engine.files["/workspace/evil/etc/passwd"] = []byte("root::0:0::/root:/bin/sh")
+	err = provider.CopyFilesFromContainer(ctx, "c0ffee", "/workspace/evil", t.TempDir())
+	assert.ErrorContains(t, err, "evil/etc/passwd is below a symlink")
+	assert.NoFileExists(t, filepath.Join(outside, "passwd"))
+}
+
+// TestPullFailure tests reporting the errors of pulls
+func TestPullFailure(t *testing.T) {
+	_, host := newFakeEngine(t)
+	provider, err := dockerapi.NewProvider(map[string]string{dockerapi.HostKey: host})
+	require.NoError(t, err)
+
+	_, err = provider.RunContainer(context.Background(), "private", nil, nil, container.RunOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to pull image private")
+	assert.Contains(t, err.Error(), "pull access denied")
+
+	// Images that are present are created without pulling
+	containerID, err := provider.RunContainer(context.Background(), "node:20", nil, nil, container.RunOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "c0ffee", containerID)
+}
+
+// TestRunContainerOptions tests creating containers with limits and hardening
+func TestRunContainerOptions(t *testing.T) {
+	engine, host := newFakeEngine(t)
+	provider, err := dockerapi.NewProvider(map[string]string{dockerapi.HostKey: host})
+	require.NoError(t, err)
+
+	options := container.RunOptions{
+		CPUs:      1.5,
+		Memory:    512 << 20,
+		PidsLimit: 128,
+		ReadOnly:  true,
+		CapDrop:   []string{"ALL"},
+		User:      "1000:1000",
+		Network:   "none",
+		Timeout:   10 * time.Minute,
+		Labels:    map[string]string{container.ProjectLabel: "todo"},
+	}
+	_, err = provider.RunContainer(context.Background(), "node:20", nil, nil, options)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"sleep", "600"}, engine.created["Cmd"])
+	assert.Equal(t, "1000:1000", engine.created["User"])
+	assert.Equal(t, map[string]interface{}{"cc.project": "todo"}, engine.created["Labels"])
+	assert.Equal(t, map[string]interface{}{
+		"NanoCpus":       1.5e9,
+		"Memory":         float64(512 << 20),
+		"PidsLimit":      128.0,
+		"ReadonlyRootfs": true,
+		"CapDrop":        []interface{}{"ALL"},
+		"NetworkMode":    "none",
+	}, engine.created["HostConfig"])
+}
@@ -0,0 +1,172 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Config keys of the egress policy of containers
+const (
+	// EgressKey is the egress mode of containers: none, allowlist or full (the default)
+	EgressKey = "egress"
+
+	// EgressAllowKey is a comma separated list of the domains and CIDRs containers may reach
+	// with the allowlist mode (DefaultEgressAllowlist if empty)
+	EgressAllowKey = "egress_allow"
+)
+
+// EgressLabel is the label recording the egress policy a container was set up with
+const EgressLabel = "cc.egress"
+
+// EgressMode is the network access containers have
+type EgressMode string
+
+// Egress modes
+const (
+	// EgressNone gives containers no network access
+	EgressNone EgressMode = "none"
+
+	// EgressAllowlist lets containers reach the domains and CIDRs of an allowlist only
+	EgressAllowlist EgressMode = "allowlist"
+
+	// EgressFull gives containers unrestricted network access
+	EgressFull EgressMode = "full"
+)
+
+// DefaultEgressAllowlist is what containers may reach with the allowlist mode when the config
+// does not list anything: GitHub, the npm registry and the Claude API
+var DefaultEgressAllowlist = []string{
+	"github.com",
+	"registry.npmjs.org",
+	"api.anthropic.com",
+	"sentry.io",
+	"statsig.anthropic.com",
+	"statsig.com",
+}
+
+// FirewallScript is the script of the Claude Code image restricting the network access of a
+// container to the domains and CIDRs it is given
+const FirewallScript = "/usr/local/bin/init-firewall.sh"
+
+// firewallCapabilities are the capabilities setting up the firewall needs: sudo switches users
+// with SETUID and SETGID, and the firewall rules need NET_ADMIN and NET_RAW
+var firewallCapabilities = []string{"SETUID", "SETGID", "NET_ADMIN", "NET_RAW"}
+
+// domainPattern matches domain names
+var domainPattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}$`)
+
+// EgressPolicy is the network access of a container
+type EgressPolicy struct {
+	// Mode of access
+	Mode EgressMode
+
+	// Domains and CIDRs the container may reach with the allowlist mode
+	Allow []string
+}
+
+// ParseEgressPolicy reads the egress policy from provider config, ignoring other keys
+func ParseEgressPolicy(config map[string]string) (EgressPolicy, error) {
+	policy := EgressPolicy{Mode: EgressMode(strings.ToLower(strings.TrimSpace(config[EgressKey])))}
+	switch policy.Mode {
+	case "":
+		policy.Mode = EgressFull
+	case EgressNone, EgressFull:
+	case EgressAllowlist:
+		for _, entry := range strings.Split(config[EgressAllowKey], ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				policy.Allow = append(policy.Allow, entry)
+			}
+		}
+		if len(policy.Allow) == 0 {
+			policy.Allow = append(policy.Allow, DefaultEgressAllowlist...)
+		}
+	default:
+		return policy, fmt.Errorf("invalid %s: %s (use none, allowlist or full)", EgressKey, config[EgressKey])
+	}
+
+	for _, entry := range policy.Allow {
+		if !validEgressEntry(entry) {
+			return policy, fmt.Errorf("invalid %s entry: %s (use domains or CIDRs)", EgressAllowKey, entry)
+		}
+	}
+	return policy, nil
+}
+
+// validEgressEntry returns whether entry is a domain, an IPv4 address or an IPv4 CIDR
+func validEgressEntry(entry string) bool {
+	if ip, _, err := net.ParseCIDR(entry); err == nil {
+		return ip.To4() != nil
+	}
+	if ip := net.ParseIP(entry); ip != nil {
+		return ip.To4() != nil
+	}
+	return domainPattern.MatchString(entry)
+}
+
+// With returns the policy allowing the given entries as well, if it is an allowlist
+func (p EgressPolicy) With(entries ...string) EgressPolicy {
+	if p.Mode != EgressAllowlist {
+		return p
+	}
+
+	allow := append([]string{}, p.Allow...)
+	for _, entry := range entries {
+		found := false
+		for _, allowed := range allow {
+			found = found || allowed == entry
+		}
+		if !found {
+			allow = append(allow, entry)
+		}
+	}
+	p.Allow = allow
+	return p
+}
+
+// Apply sets the run options the policy needs: no network for none, and the capabilities to
+// set up the firewall of allowlists. Allowlists cannot be enforced in containers dropping the
+// capabilities sudo and the firewall need, so those options are refused rather than leaving
+// the network open.
+func (p EgressPolicy) Apply(options *RunOptions) error {
+	switch p.Mode {
+	case EgressNone:
+		options.Network = "none"
+	case EgressAllowlist:
+		for _, capability := range options.CapDrop {
+			name := strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+			for _, needed := range append([]string{"ALL"}, firewallCapabilities...) {
+				if name == needed {
+					return fmt.Errorf("the egress allowlist cannot be enforced with %s %s: its firewall is set up through sudo with the %s capabilities, drop other capabilities by name instead",
+						CapDropKey, capability, strings.Join(firewallCapabilities, ", "))
+				}
+			}
+		}
+		options.CapAdd = append(options.CapAdd, "NET_ADMIN", "NET_RAW")
+	}
+	return nil
+}
+
+// Enforce sets up the firewall of allowlists in a running container with FirewallScript, through
+// sudo unless the container runs as root. Containers whose firewall fails must not be used.
+func (p EgressPolicy) Enforce(ctx context.Context, provider Provider, containerID string) error {
+	if p.Mode != EgressAllowlist {
+		return nil
+	}
+
+	command := append([]string{"sh", "-c", `if [ "$(id -u)" != 0 ]; then exec sudo "$0" "$@"; fi; exec "$0" "$@"`, FirewallScript}, p.Allow...)
+	if output, err := provider.ExecuteCommand(ctx, containerID, command); err != nil {
+		return fmt.Errorf("failed to apply the egress allowlist: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// String returns the policy as recorded on implementations, like allowlist: github.com
+func (p EgressPolicy) String() string {
+	if p.Mode == EgressAllowlist {
+		return fmt.Sprintf("%s: %s", p.Mode, strings.Join(p.Allow, ", "))
+	}
+	return string(p.Mode)
+}
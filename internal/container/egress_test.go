@@ -0,0 +1,97 @@
+package container_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fr0g-66723067/cc/internal/container"
+	"github.com/fr0g-66723067/cc/internal/container/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseEgressPolicy tests reading the egress policy from provider config
+func TestParseEgressPolicy(t *testing.T) {
+	// Containers have full access by default
+	policy, err := container.ParseEgressPolicy(nil)
+	require.NoError(t, err)
+	assert.Equal(t, container.EgressFull, policy.Mode)
+	assert.Equal(t, "full", policy.String())
+
+	policy, err = container.ParseEgressPolicy(map[string]string{
+		container.EgressKey:      "allowlist",
+		container.EgressAllowKey: "registry.npmjs.org, 140.82.112.0/20,10.0.0.1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"registry.npmjs.org", "140.82.112.0/20", "10.0.0.1"}, policy.Allow)
+	assert.Equal(t, "allowlist: registry.npmjs.org, 140.82.112.0/20, 10.0.0.1", policy.String())
+
+	// Allowlists without entries allow the defaults
+	policy, err = container.ParseEgressPolicy(map[string]string{container.EgressKey: "allowlist"})
+	require.NoError(t, err)
+	assert.Equal(t, container.DefaultEgressAllowlist, policy.Allow)
+
+	_, err = container.ParseEgressPolicy(map[string]string{container.EgressKey: "some"})
+	assert.ErrorContains(t, err, "invalid egress: some")
+	for _, entry := range []string{"https://example.com", "localhost", "2001:db8::/32", "example.com; rm -rf /"} {
+		_, err = container.ParseEgressPolicy(map[string]string{container.EgressKey: "allowlist", container.EgressAllowKey: entry})
+		assert.ErrorContains(t, err, "invalid egress_allow entry", entry)
+	}
+}
+
+// TestEgressPolicyApply tests the run options of egress policies
+func TestEgressPolicyApply(t *testing.T) {
+	options := container.RunOptions{Network: "bridge"}
+	container.EgressPolicy{Mode: container.EgressNone}.Apply(&options)
+	assert.Equal(t, "none", options.Network)
+
+	options = container.RunOptions{CapDrop: []string{"MKNOD", "SYS_CHROOT"}}
+	policy := container.EgressPolicy{Mode: container.EgressAllowlist, Allow: []string{"github.com"}}.With("api.anthropic.com", "github.com")
+	require.NoError(t, policy.Apply(&options))
+	assert.Equal(t, []string{"github.com", "api.anthropic.com"}, policy.Allow)
+	assert.Equal(t, []string{"NET_ADMIN", "NET_RAW"}, options.CapAdd)
+	assert.Contains(t, options.Args(), "--cap-add")
+
+	// Dropping the capabilities of the firewall would leave the network open
+	for _, capability := range []string{"ALL", "cap_setuid", "NET_ADMIN"} {
+		options = container.RunOptions{CapDrop: []string{"MKNOD", capability}}
+		err := policy.Apply(&options)
+		assert.ErrorContains(t, err, "the egress allowlist cannot be enforced with cap_drop "+capability, capability)
+		assert.Empty(t, options.CapAdd, capability)
+	}
+
+	// Full access changes nothing, and allows nothing more
+	options = container.RunOptions{CapDrop: []string{"ALL"}}
+	policy = container.EgressPolicy{Mode: container.EgressFull}.With("api.anthropic.com")
+	require.NoError(t, policy.Apply(&options))
+	assert.Empty(t, policy.Allow)
+	assert.Equal(t, container.RunOptions{CapDrop: []string{"ALL"}}, options)
+}
+
+// TestEgressPolicyEnforce tests setting up the firewall of allowlists in containers
+func TestEgressPolicyEnforce(t *testing.T) {
+	policy := container.EgressPolicy{Mode: container.EgressAllowlist, Allow: []string{"github.com", "10.0.0.0/8"}}
+	firewallCmd := mock.MatchedBy(func(command []string) bool {
+		return len(command) == 6 && command[0] == "sh" && strings.Contains(command[2], "sudo") &&
+			command[3] == container.FirewallScript && command[4] == "github.com" && command[5] == "10.0.0.0/8"
+	})
+
+	mockProvider := new(mocks.MockProvider)
+	mockProvider.On("ExecuteCommand", mock.Anything, "container-id", firewallCmd).Return("Firewall configuration complete", nil).Once()
+	require.NoError(t, policy.Enforce(context.Background(), mockProvider, "container-id"))
+	mockProvider.AssertExpectations(t)
+
+	mockProvider = new(mocks.MockProvider)
+	mockProvider.On("ExecuteCommand", mock.Anything, "container-id", firewallCmd).Return("ipset: command not found", errors.New("exit status 127"))
+	err := policy.Enforce(context.Background(), mockProvider, "container-id")
+	assert.ErrorContains(t, err, "failed to apply the egress allowlist")
+	assert.ErrorContains(t, err, "ipset: command not found")
+
+	// Other policies need no firewall
+	unused := new(mocks.MockProvider)
+	require.NoError(t, container.EgressPolicy{Mode: container.EgressNone}.Enforce(context.Background(), unused, "container-id"))
+	unused.AssertNotCalled(t, "ExecuteCommand", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -2,7 +2,9 @@ package mocks
 
 import (
 	"context"
+	"io"
 
+	"github.com/fr0g-66723067/cc/internal/container"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -18,8 +20,8 @@ func (m *MockProvider) Initialize(ctx context.Context, config map[string]string)
 }
 
 // RunContainer starts a container with the given image and returns its ID
-func (m *MockProvider) RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string) (string, error) {
-	args := m.Called(ctx, image, volumeMounts, env)
+func (m *MockProvider) RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string, options container.RunOptions) (string, error) {
+	args := m.Called(ctx, image, volumeMounts, env, options)
 	return args.String(0), args.Error(1)
 }
 
@@ -29,6 +31,14 @@ func (m *MockProvider) ExecuteCommand(ctx context.Context, containerID string, c
 	return args.String(0), args.Error(1)
 }
 
+// ExecuteCommandStream executes a command in the container, writing the output returned
+// by the expectation to output
+func (m *MockProvider) ExecuteCommandStream(ctx context.Context, containerID string, command []string, output io.Writer) error {
+	args := m.Called(ctx, containerID, command, output)
+	io.WriteString(output, args.String(0))
+	return args.Error(1)
+}
+
 // CopyFilesToContainer copies files from local to container
 func (m *MockProvider) CopyFilesToContainer(ctx context.Context, containerID string, localPath string, containerPath string) error {
 	args := m.Called(ctx, containerID, localPath, containerPath)
@@ -0,0 +1,191 @@
+package container
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config keys of the options of containers, shared by all providers
+const (
+	// CPUsKey is the number of CPUs containers may use, like 1.5
+	CPUsKey = "cpus"
+
+	// MemoryKey is the memory limit of containers in bytes, or with a unit like 512m or 2g
+	MemoryKey = "memory"
+
+	// PidsLimitKey is the maximum number of processes in containers
+	PidsLimitKey = "pids_limit"
+
+	// ReadOnlyKey makes the root filesystem of containers read-only when "true"
+	ReadOnlyKey = "read_only"
+
+	// CapDropKey is a comma separated list of capabilities to drop, like ALL
+	CapDropKey = "cap_drop"
+
+	// UserKey is the user containers run as, a name or uid[:gid]
+	UserKey = "user"
+
+	// NetworkKey is the network mode of containers, like none or bridge
+	NetworkKey = "network"
+
+	// TimeoutKey is the time containers may run for, like 30m (a number is seconds)
+	TimeoutKey = "timeout"
+)
+
+// ProjectLabel is the label naming the project a container works on
+const ProjectLabel = "cc.project"
+
+// RunOptions are the resource limits and hardening of a container. Zero values leave the
+// provider's defaults.
+type RunOptions struct {
+	// CPUs the container may use
+	CPUs float64
+
+	// Memory limit in bytes
+	Memory int64
+
+	// Maximum number of processes
+	PidsLimit int64
+
+	// Whether the root filesystem is read-only, so only mounted paths are writable
+	ReadOnly bool
+
+	// Capabilities to drop, like ALL
+	CapDrop []string
+
+	// Capabilities to add, like NET_ADMIN, even if all are dropped
+	CapAdd []string
+
+	// User to run as, a name or uid[:gid]
+	User string
+
+	// Network mode, like none
+	Network string
+
+	// Wall-clock time after which the container stops, killing the commands it runs
+	Timeout time.Duration
+
+	// Labels of the container, like ProjectLabel
+	Labels map[string]string
+}
+
+// ParseRunOptions reads run options from provider config, ignoring other keys
+func ParseRunOptions(config map[string]string) (RunOptions, error) {
+	var options RunOptions
+	var err error
+
+	if value := config[CPUsKey]; value != "" {
+		if options.CPUs, err = strconv.ParseFloat(value, 64); err != nil || options.CPUs < 0 {
+			return options, fmt.Errorf("invalid %s: %s", CPUsKey, value)
+		}
+	}
+	if value := config[MemoryKey]; value != "" {
+		if options.Memory, err = ParseBytes(value); err != nil {
+			return options, fmt.Errorf("invalid %s: %w", MemoryKey, err)
+		}
+	}
+	if value := config[PidsLimitKey]; value != "" {
+		if options.PidsLimit, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return options, fmt.Errorf("invalid %s: %s", PidsLimitKey, value)
+		}
+	}
+	if value := config[ReadOnlyKey]; value != "" {
+		if options.ReadOnly, err = strconv.ParseBool(value); err != nil {
+			return options, fmt.Errorf("invalid %s: %s", ReadOnlyKey, value)
+		}
+	}
+	for _, capability := range strings.Split(config[CapDropKey], ",") {
+		if capability = strings.TrimSpace(capability); capability != "" {
+			options.CapDrop = append(options.CapDrop, capability)
+		}
+	}
+	options.User = config[UserKey]
+	options.Network = config[NetworkKey]
+	if value := config[TimeoutKey]; value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			options.Timeout = time.Duration(seconds) * time.Second
+		} else if options.Timeout, err = time.ParseDuration(value); err != nil {
+			return options, fmt.Errorf("invalid %s: %s", TimeoutKey, value)
+		}
+	}
+
+	return options, nil
+}
+
+// ParseBytes parses a size in bytes, optionally with a unit of k, m or g (powers of 1024)
+// and a trailing b, like 512m or 2gb
+func ParseBytes(value string) (int64, error) {
+	number := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), "b")
+	multiplier := int64(1)
+	if number != "" {
+		switch number[len(number)-1] {
+		case 'k':
+			multiplier = 1 << 10
+		case 'm':
+			multiplier = 1 << 20
+		case 'g':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			number = number[:len(number)-1]
+		}
+	}
+
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size: %s", value)
+	}
+	return size * multiplier, nil
+}
+
+// KeepAliveCommand returns the command keeping containers running until they are stopped,
+// or until the timeout of the options
+func (o RunOptions) KeepAliveCommand() []string {
+	if o.Timeout > 0 {
+		// Round up so short timeouts still start the container
+		seconds := int64((o.Timeout + time.Second - 1) / time.Second)
+		return []string{"sleep", strconv.FormatInt(seconds, 10)}
+	}
+	return []string{"tail", "-f", "/dev/null"}
+}
+
+// Args returns the options as flags of docker run, which Podman shares
+func (o RunOptions) Args() []string {
+	var args []string
+	if o.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(o.CPUs, 'f', -1, 64))
+	}
+	if o.Memory > 0 {
+		args = append(args, "--memory", strconv.FormatInt(o.Memory, 10))
+	}
+	if o.PidsLimit > 0 {
+		args = append(args, "--pids-limit", strconv.FormatInt(o.PidsLimit, 10))
+	}
+	if o.ReadOnly {
+		args = append(args, "--read-only")
+	}
+	for _, capability := range o.CapDrop {
+		args = append(args, "--cap-drop", capability)
+	}
+	for _, capability := range o.CapAdd {
+		args = append(args, "--cap-add", capability)
+	}
+	if o.User != "" {
+		args = append(args, "--user", o.User)
+	}
+	if o.Network != "" {
+		args = append(args, "--network", o.Network)
+	}
+	keys := make([]string, 0, len(o.Labels))
+	for key := range o.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--label", key+"="+o.Labels[key])
+	}
+	return args
+}
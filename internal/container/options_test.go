@@ -0,0 +1,89 @@
+package container_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseRunOptions tests reading run options from provider config
+func TestParseRunOptions(t *testing.T) {
+	options, err := container.ParseRunOptions(map[string]string{
+		container.CPUsKey:      "1.5",
+		container.MemoryKey:    "2g",
+		container.PidsLimitKey: "256",
+		container.ReadOnlyKey:  "true",
+		container.CapDropKey:   "ALL, NET_RAW",
+		container.UserKey:      "1000:1000",
+		container.NetworkKey:   "none",
+		container.TimeoutKey:   "30m",
+		"use_sudo":             "true",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, container.RunOptions{
+		CPUs:      1.5,
+		Memory:    2 << 30,
+		PidsLimit: 256,
+		ReadOnly:  true,
+		CapDrop:   []string{"ALL", "NET_RAW"},
+		User:      "1000:1000",
+		Network:   "none",
+		Timeout:   30 * time.Minute,
+	}, options)
+
+	assert.Equal(t, []string{
+		"--cpus", "1.5", "--memory", "2147483648", "--pids-limit", "256", "--read-only",
+		"--cap-drop", "ALL", "--cap-drop", "NET_RAW", "--user", "1000:1000", "--network", "none",
+	}, options.Args())
+	assert.Equal(t, []string{"sleep", "1800"}, options.KeepAliveCommand())
+
+	// Labels are set by callers rather than config, in a stable order
+	options.Labels = map[string]string{container.ProjectLabel: "todo", container.EgressLabel: "full"}
+	assert.Equal(t, []string{"--label", "cc.egress=full", "--label", "cc.project=todo"}, options.Args()[15:])
+
+	// Without options containers are not limited and run until they are stopped
+	options, err = container.ParseRunOptions(nil)
+	require.NoError(t, err)
+	assert.Empty(t, options.Args())
+	assert.Equal(t, []string{"tail", "-f", "/dev/null"}, options.KeepAliveCommand())
+
+	// Timeouts in plain numbers are seconds
+	options, err = container.ParseRunOptions(map[string]string{container.TimeoutKey: "90"})
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, options.Timeout)
+
+	for key, value := range map[string]string{
+		container.CPUsKey:      "many",
+		container.MemoryKey:    "2x",
+		container.PidsLimitKey: "lots",
+		container.ReadOnlyKey:  "maybe",
+		container.TimeoutKey:   "soon",
+	} {
+		_, err := container.ParseRunOptions(map[string]string{key: value})
+		assert.ErrorContains(t, err, "invalid "+key, key)
+	}
+}
+
+// TestParseBytes tests parsing sizes with units
+func TestParseBytes(t *testing.T) {
+	for value, expected := range map[string]int64{
+		"1024":  1024,
+		"512b":  512,
+		"64k":   64 << 10,
+		"512m":  512 << 20,
+		"512MB": 512 << 20,
+		"2g":    2 << 30,
+	} {
+		size, err := container.ParseBytes(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, size, value)
+	}
+
+	for _, value := range []string{"", "g", "-1m", "1.5g"} {
+		_, err := container.ParseBytes(value)
+		assert.Error(t, err, value)
+	}
+}
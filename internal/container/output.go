@@ -0,0 +1,27 @@
+package container
+
+// MaxErrorOutput is the amount of output of failed streamed commands included in errors
+const MaxErrorOutput = 4096
+
+// TailBuffer keeps the last Max bytes written to it, like the end of the output of a streamed
+// command for its error message
+type TailBuffer struct {
+	// Max is the number of bytes kept
+	Max int
+
+	data []byte
+}
+
+// Write appends p, dropping the oldest bytes beyond Max
+func (b *TailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if len(b.data) > b.Max {
+		b.data = b.data[len(b.data)-b.Max:]
+	}
+	return len(p), nil
+}
+
+// String returns the kept output
+func (b *TailBuffer) String() string {
+	return string(b.data)
+}
@@ -0,0 +1,21 @@
+package container_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fr0g-66723067/cc/internal/container"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTailBuffer tests keeping the end of the output written
+func TestTailBuffer(t *testing.T) {
+	tail := &container.TailBuffer{Max: 8}
+	n, err := fmt.Fprint(tail, "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "hello", tail.String())
+
+	fmt.Fprint(tail, " world")
+	assert.Equal(t, "lo world", tail.String())
+}
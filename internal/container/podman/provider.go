@@ -0,0 +1,265 @@
+// Podman provider implementation
+package podman
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	cccontainer "github.com/fr0g-66723067/cc/internal/container"
+)
+
+// Config keys of the Podman provider
+const (
+	// PathKey is the Podman binary to run ("podman" from the PATH by default)
+	PathKey = "podman_path"
+
+	// UsernsKey is the user namespace mode of containers ("keep-id" by default, which maps
+	// the current user into containers so files written to mounts stay owned by them; empty
+	// for the Podman default)
+	UsernsKey = "userns"
+
+	// RegistryKey is the registry images without one are pulled from ("docker.io" by default)
+	RegistryKey = "registry"
+)
+
+// Provider implements the container provider interface for Podman. Podman runs rootless
+// without a daemon, so unlike the Docker provider it never needs sudo.
+type Provider struct {
+	config map[string]string
+}
+
+// NewProvider creates a new Podman provider
+func NewProvider(config map[string]string) (*Provider, error) {
+	if config == nil {
+		config = make(map[string]string)
+	}
+
+	return &Provider{
+		config: config,
+	}, nil
+}
+
+// Initialize sets up the Podman provider
+func (p *Provider) Initialize(ctx context.Context, config map[string]string) error {
+	// Merge configs
+	for k, v := range config {
+		p.config[k] = v
+	}
+
+	// Check if Podman is installed and usable by the current user
+	output, err := p.command(ctx, "info").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Podman is not installed or not usable: %w\n%s", err, output)
+	}
+
+	return nil
+}
+
+// RunContainer starts a container with the given image and returns its ID
+func (p *Provider) RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string, options cccontainer.RunOptions) (string, error) {
+	// Build podman run command
+	args := []string{"run", "-d"}
+
+	// Map the current user into the container
+	userns, ok := p.config[UsernsKey]
+	if !ok {
+		userns = "keep-id"
+	}
+	if userns != "" {
+		args = append(args, "--userns="+userns)
+	}
+
+	// Add environment variables
+	for k, v := range env {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	// Add volume mounts
+	for host, container := range volumeMounts {
+		args = append(args, "-v", fmt.Sprintf("%s:%s", host, container))
+	}
+
+	// Add resource limits and hardening, which Podman takes like Docker
+	args = append(args, options.Args()...)
+
+	// Add the image and command to keep container running
+	run := func(image string) ([]byte, error) {
+		runArgs := append(append([]string{}, args...), image)
+		runArgs = append(runArgs, options.KeepAliveCommand()...)
+		return p.command(ctx, runArgs...).CombinedOutput()
+	}
+
+	output, err := run(image)
+	if err != nil {
+		// If the image doesn't exist, try to pull it
+		if !imageMissing(string(output)) {
+			return "", fmt.Errorf("failed to run container: %w\n%s", err, output)
+		}
+
+		// Short names are ambiguous to Podman, so pull from the configured registry
+		image = p.qualify(image)
+		pullOutput, pullErr := p.command(ctx, "pull", image).CombinedOutput()
+		if pullErr != nil {
+			return "", fmt.Errorf("failed to pull image %s: %w\n%s", image, pullErr, pullOutput)
+		}
+
+		// Try to run the container again
+		output, err = run(image)
+		if err != nil {
+			return "", fmt.Errorf("failed to run container after pulling image: %w\n%s", err, output)
+		}
+	}
+
+	// Get container ID from the last line of output, after any pull progress
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	containerID := strings.TrimSpace(lines[len(lines)-1])
+
+	return containerID, nil
+}
+
+// imageMissing returns whether the output of podman run says the image does not exist locally.
+// Other errors mentioning something not found, like a missing executable, are not about
+// the image.
+func imageMissing(output string) bool {
+	return strings.Contains(output, "No such image") ||
+		strings.Contains(output, "image not known") ||
+		strings.Contains(output, "manifest unknown")
+}
+
+// qualify prefixes images without a registry with the configured registry, leaving
+// fully qualified and local images as they are
+func (p *Provider) qualify(image string) string {
+	first, _, found := strings.Cut(image, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return image
+	}
+
+	registry := p.config[RegistryKey]
+	if registry == "" {
+		registry = "docker.io"
+	}
+	if !found && registry == "docker.io" {
+		// Official images live in the library namespace of Docker Hub
+		return registry + "/library/" + image
+	}
+	return registry + "/" + image
+}
+
+// ExecuteCommand executes a command in the container
+func (p *Provider) ExecuteCommand(ctx context.Context, containerID string, command []string) (string, error) {
+	var output bytes.Buffer
+	if err := p.exec(ctx, containerID, command, &output); err != nil {
+		return "", fmt.Errorf("failed to execute command in container: %w\n%s", err, output.String())
+	}
+
+	return output.String(), nil
+}
+
+// ExecuteCommandStream executes a command in the container, writing its combined
+// output to output while the command runs
+func (p *Provider) ExecuteCommandStream(ctx context.Context, containerID string, command []string, output io.Writer) error {
+	// Keep the end of the output for the error message
+	tail := &cccontainer.TailBuffer{Max: cccontainer.MaxErrorOutput}
+	if err := p.exec(ctx, containerID, command, io.MultiWriter(output, tail)); err != nil {
+		return fmt.Errorf("failed to execute command in container: %w\n%s", err, tail.String())
+	}
+
+	return nil
+}
+
+// exec runs podman exec, writing stdout and stderr to output
+func (p *Provider) exec(ctx context.Context, containerID string, command []string, output io.Writer) error {
+	args := append([]string{"exec", containerID}, command...)
+	cmd := p.command(ctx, args...)
+
+	// The same writer for both streams makes exec serialize the writes
+	cmd.Stdout = output
+	cmd.Stderr = output
+	return cmd.Run()
+}
+
+// CopyFilesToContainer copies files from local to container
+func (p *Provider) CopyFilesToContainer(ctx context.Context, containerID string, localPath string, containerPath string) error {
+	// Verify local path exists
+	if _, err := os.Stat(localPath); os.IsNotExist(err) {
+		return fmt.Errorf("local path does not exist: %s", localPath)
+	}
+
+	// Use podman cp to copy files
+	output, err := p.command(ctx, "cp", localPath, fmt.Sprintf("%s:%s", containerID, containerPath)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to copy files to container: %w\n%s", err, output)
+	}
+
+	return nil
+}
+
+// CopyFilesFromContainer copies files from container to local. Rootless Podman copies
+// as the current user, so the files need no change of ownership.
+func (p *Provider) CopyFilesFromContainer(ctx context.Context, containerID string, containerPath string, localPath string) error {
+	// Create destination directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	// Use podman cp to copy files
+	output, err := p.command(ctx, "cp", fmt.Sprintf("%s:%s", containerID, containerPath), localPath).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to copy files from container: %w\n%s", err, output)
+	}
+
+	return nil
+}
+
+// StopContainer stops a running container
+func (p *Provider) StopContainer(ctx context.Context, containerID string) error {
+	output, err := p.command(ctx, "stop", containerID).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to stop container: %w\n%s", err, output)
+	}
+
+	return nil
+}
+
+// RemoveContainer removes a container
+func (p *Provider) RemoveContainer(ctx context.Context, containerID string) error {
+	// Remove the container with force
+	output, err := p.command(ctx, "rm", "-f", containerID).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to remove container: %w\n%s", err, output)
+	}
+
+	return nil
+}
+
+// command returns a podman command with the given arguments
+func (p *Provider) command(ctx context.Context, args ...string) *exec.Cmd {
+	binary := p.config[PathKey]
+	if binary == "" {
+		binary = "podman"
+	}
+	return exec.CommandContext(ctx, binary, args...)
+}
+
+// Name returns the provider's name
+func (p *Provider) Name() string {
+	return "podman"
+}
+
+// IsRemote returns whether the provider is running containers remotely
+func (p *Provider) IsRemote() bool {
+	return false
+}
+
+// Register registers this provider factory
+func init() {
+	cccontainer.Register("podman", func(config map[string]string) (cccontainer.Provider, error) {
+		return NewProvider(config)
+	})
+}
@@ -0,0 +1,213 @@
+package podman_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/container"
+	"github.com/fr0g-66723067/cc/internal/container/podman"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePodman is a podman stand-in logging its arguments. Running an image pulled from
+// docker.io/library only works after pulling it, run fails with the error in run-error until
+// an image is pulled, exec runs the command on the host and
+// cp copies on the host, dropping the container ID.
+const fakePodman = `#!/bin/sh
+dir=$(dirname "$0")
+echo "$@" >> "$dir/calls.log"
+case "$1" in
+info|stop|rm)
+	;;
+pull)
+	touch "$dir/pulled"
+	echo "Trying to pull $2..."
+	;;
+run)
+	if [ -f "$dir/run-error" ] && [ ! -f "$dir/pulled" ]; then
+		cat "$dir/run-error" >&2
+		exit 125
+	fi
+	for arg; do image=$arg; [ "$arg" = "tail" ] && break; prev=$image; done
+	if [ "$prev" = "alpine" ] && [ ! -f "$dir/pulled" ]; then
+		echo "Error: alpine: image not known" >&2
+		exit 125
+	fi
+	echo "Resolving image..."
+	echo "c0ffee"
+	;;
+exec)
+	shift 2
+	exec "$@"
+	;;
+cp)
+	src=${2#c0ffee:}
+	dst=${3#c0ffee:}
+	cp -r "$src" "$dst"
+	;;
+*)
+	echo "unknown command $1" >&2
+	exit 1
+	;;
+esac
+`
+
+// setupFakePodman writes the fake podman to a temporary directory and returns it
+func setupFakePodman(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "podman"), []byte(fakePodman), 0755))
+	return dir
+}
+
+// calls returns the commands the fake podman in dir was run with
+func calls(t *testing.T, dir string) []string {
+	data, err := os.ReadFile(filepath.Join(dir, "calls.log"))
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+// TestNewProvider verifies the provider is registered as podman
+func TestNewProvider(t *testing.T) {
+	provider, err := container.Create("podman", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "podman", provider.Name())
+	assert.False(t, provider.IsRemote())
+}
+
+// TestInitialize tests checking that Podman is usable, without sudo
+func TestInitialize(t *testing.T) {
+	dir := setupFakePodman(t)
+	provider, err := podman.NewProvider(nil)
+	require.NoError(t, err)
+
+	require.NoError(t, provider.Initialize(context.Background(), map[string]string{podman.PathKey: filepath.Join(dir, "podman")}))
+	assert.Equal(t, []string{"info"}, calls(t, dir))
+
+	// A missing binary is reported
+	missing, err := podman.NewProvider(map[string]string{podman.PathKey: filepath.Join(dir, "missing")})
+	require.NoError(t, err)
+	err = missing.Initialize(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Podman is not installed or not usable")
+}
+
+// TestContainerLifecycle tests running, using and removing a container
+func TestContainerLifecycle(t *testing.T) {
+	dir := setupFakePodman(t)
+	provider, err := podman.NewProvider(map[string]string{podman.PathKey: filepath.Join(dir, "podman")})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Missing images are pulled from the registry by their fully qualified name
+	containerID, err := provider.RunContainer(ctx, "alpine", map[string]string{"/src": "/workspace"}, map[string]string{"KEY": "value"}, container.RunOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "c0ffee", containerID)
+	assert.Equal(t, []string{
+		"run -d --userns=keep-id -e KEY=value -v /src:/workspace alpine tail -f /dev/null",
+		"pull docker.io/library/alpine",
+		"run -d --userns=keep-id -e KEY=value -v /src:/workspace docker.io/library/alpine tail -f /dev/null",
+	}, calls(t, dir))
+
+	// Execute command
+	output, err := provider.ExecuteCommand(ctx, containerID, []string{"echo", "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", output)
+
+	_, err = provider.ExecuteCommand(ctx, containerID, []string{"sh", "-c", "echo broken; exit 3"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken")
+
+	// Stream the output of a command, both stdout and stderr
+	var streamed strings.Builder
+	err = provider.ExecuteCommandStream(ctx, containerID, []string{"sh", "-c", "echo one; echo two >&2"}, &streamed)
+	require.NoError(t, err)
+	assert.Equal(t, "one\ntwo\n", streamed.String())
+
+	// Copy files in and out
+	src := filepath.Join(t.TempDir(), "app.txt")
+	require.NoError(t, os.WriteFile(src, []byte("app"), 0644))
+	inside := filepath.Join(t.TempDir(), "inside.txt")
+	require.NoError(t, provider.CopyFilesToContainer(ctx, containerID, src, inside))
+
+	out := filepath.Join(t.TempDir(), "nested", "out.txt")
+	require.NoError(t, provider.CopyFilesFromContainer(ctx, containerID, inside, out))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "app", string(data))
+
+	err = provider.CopyFilesToContainer(ctx, containerID, filepath.Join(dir, "missing"), "/workspace")
+	assert.ErrorContains(t, err, "local path does not exist")
+
+	require.NoError(t, provider.StopContainer(ctx, containerID))
+	require.NoError(t, provider.RemoveContainer(ctx, containerID))
+	log := calls(t, dir)
+	assert.Equal(t, []string{"stop c0ffee", "rm -f c0ffee"}, log[len(log)-2:])
+
+	// No command ever runs through sudo
+	for _, call := range log {
+		assert.NotContains(t, call, "sudo")
+	}
+}
+
+// TestRunContainerPull tests pulling images only when podman run fails on a missing image
+func TestRunContainerPull(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		pulled bool
+	}{
+		{"image not known", "Error: node: image not known", true},
+		{"no such image", "Error: No such image: node", true},
+		{"manifest unknown", "Error: initializing source docker://node:99: reading manifest 99 in docker.io/library/node: manifest unknown", true},
+		{"executable not found", `Error: crun: executable file not found in $PATH: No such file or directory: OCI runtime attempted to invoke a command that was not found`, false},
+		{"volume not found", "Error: no volume with name \"cache\" found: no such volume", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setupFakePodman(t)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "run-error"), []byte(tt.output+"\n"), 0644))
+			provider, err := podman.NewProvider(map[string]string{podman.PathKey: filepath.Join(dir, "podman")})
+			require.NoError(t, err)
+
+			containerID, err := provider.RunContainer(context.Background(), "node", nil, nil, container.RunOptions{})
+			if tt.pulled {
+				require.NoError(t, err)
+				assert.Equal(t, "c0ffee", containerID)
+				assert.Contains(t, calls(t, dir), "pull docker.io/library/node")
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "failed to run container")
+			assert.Contains(t, err.Error(), tt.output)
+			assert.Len(t, calls(t, dir), 1)
+		})
+	}
+}
+
+// TestRunContainerOptions tests the user namespace and registry settings
+func TestRunContainerOptions(t *testing.T) {
+	dir := setupFakePodman(t)
+	provider, err := podman.NewProvider(map[string]string{
+		podman.PathKey:     filepath.Join(dir, "podman"),
+		podman.UsernsKey:   "",
+		podman.RegistryKey: "registry.example.com",
+	})
+	require.NoError(t, err)
+
+	// Images that are present are run as they are, without a user namespace
+	_, err = provider.RunContainer(context.Background(), "node:20", nil, nil, container.RunOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"run -d node:20 tail -f /dev/null"}, calls(t, dir))
+
+	// Limits and hardening are passed like to Docker, and timeouts end the container
+	options := container.RunOptions{Memory: 512 << 20, PidsLimit: 128, CapDrop: []string{"ALL"}, Network: "none", Timeout: 10 * time.Minute}
+	_, err = provider.RunContainer(context.Background(), "node:20", nil, nil, options)
+	require.NoError(t, err)
+	assert.Equal(t, "run -d --memory 536870912 --pids-limit 128 --cap-drop ALL --network none node:20 sleep 600", calls(t, dir)[1])
+}
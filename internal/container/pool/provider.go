@@ -0,0 +1,463 @@
+// Package pool keeps containers running between commands, so commands lease a warm
+// container instead of paying for starting one
+package pool
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/container"
+)
+
+// Config keys of the pool. The other keys configure the provider running the containers.
+const (
+	// ProviderKey is the container provider running the pooled containers ("docker" by default)
+	ProviderKey = "pool_provider"
+
+	// MinKey is the number of idle containers Maintain keeps warm (0 by default)
+	MinKey = "pool_min"
+
+	// MaxKey is the number of containers the pool keeps at most (4 by default). Leases
+	// beyond it get containers that are removed when they are released.
+	MaxKey = "pool_max"
+
+	// IdleTTLKey is how long idle containers are kept, like 30m (the default)
+	IdleTTLKey = "pool_idle_ttl"
+
+	// StateKey is the file the pool is kept in, shared by all processes using the pool
+	// (~/.cc/pool.json by default)
+	StateKey = "pool_state"
+)
+
+// Pool defaults
+const (
+	defaultMax     = 4
+	defaultIdleTTL = 30 * time.Minute
+)
+
+// healthCommand is the command pooled containers must run to be leased
+var healthCommand = []string{"echo", "ok"}
+
+// Provider implements the container provider interface with a pool of containers run by
+// another provider. RunContainer leases an idle container running with the same image,
+// mounts, environment and options if there is one, preferring containers of the same
+// project and never using those of other projects (the ProjectLabel). RemoveContainer
+// releases leased containers to the pool and StopContainer leaves them running.
+type Provider struct {
+	backend   container.Provider
+	config    map[string]string
+	min       int
+	max       int
+	idleTTL   time.Duration
+	statePath string
+}
+
+// NewProvider creates a pool of containers run by the provider of the ProviderKey
+func NewProvider(config map[string]string) (*Provider, error) {
+	name := config[ProviderKey]
+	if name == "" {
+		name = "docker"
+	}
+	if name == "pool" {
+		return nil, fmt.Errorf("invalid %s: pool", ProviderKey)
+	}
+
+	backend, err := container.Create(name, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pooled container provider: %w", err)
+	}
+	return New(backend, config)
+}
+
+// New creates a pool of containers run by backend
+func New(backend container.Provider, config map[string]string) (*Provider, error) {
+	if config == nil {
+		config = make(map[string]string)
+	}
+
+	p := &Provider{
+		backend: backend,
+		config:  config,
+		max:     defaultMax,
+		idleTTL: defaultIdleTTL,
+	}
+
+	var err error
+	if value := config[MinKey]; value != "" {
+		if p.min, err = strconv.Atoi(value); err != nil || p.min < 0 {
+			return nil, fmt.Errorf("invalid %s: %s", MinKey, value)
+		}
+	}
+	if value := config[MaxKey]; value != "" {
+		if p.max, err = strconv.Atoi(value); err != nil || p.max < 0 {
+			return nil, fmt.Errorf("invalid %s: %s", MaxKey, value)
+		}
+	}
+	if p.min > p.max {
+		return nil, fmt.Errorf("invalid %s: %d is more than %s %d", MinKey, p.min, MaxKey, p.max)
+	}
+	if value := config[IdleTTLKey]; value != "" {
+		if p.idleTTL, err = time.ParseDuration(value); err != nil || p.idleTTL <= 0 {
+			return nil, fmt.Errorf("invalid %s: %s", IdleTTLKey, value)
+		}
+	}
+
+	p.statePath = config[StateKey]
+	if p.statePath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = os.TempDir()
+		}
+		p.statePath = filepath.Join(home, ".cc", "pool.json")
+	}
+
+	return p, nil
+}
+
+// Initialize sets up the provider running the containers
+func (p *Provider) Initialize(ctx context.Context, config map[string]string) error {
+	// Merge configs
+	for k, v := range config {
+		p.config[k] = v
+	}
+
+	return p.backend.Initialize(ctx, p.config)
+}
+
+// RunContainer leases a healthy idle container of the pool running with the same image,
+// mounts, environment and options, and starts one if there is none
+func (p *Provider) RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string, options container.RunOptions) (string, error) {
+	spec := newSpec(image, volumeMounts, env, options)
+	key := spec.Key()
+	project := options.Labels[container.ProjectLabel]
+
+	for {
+		var leased *Entry
+		err := p.update(ctx, func(s *state) error {
+			s.Specs[key] = spec
+			s.Latest = key
+			leased = lease(s, key, project)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		if leased == nil {
+			break
+		}
+
+		if _, err := p.backend.ExecuteCommand(ctx, leased.ID, healthCommand); err == nil {
+			return leased.ID, nil
+		}
+		fmt.Printf("Pooled container %s is unhealthy, removing it\n", leased.ID)
+		if err := p.discard(ctx, leased.ID); err != nil {
+			return "", err
+		}
+	}
+
+	containerID, err := p.backend.RunContainer(ctx, image, volumeMounts, env, options)
+	if err != nil {
+		return "", err
+	}
+
+	// Pool the container unless the pool is full, in which case it is removed when released
+	now := time.Now()
+	err = p.update(ctx, func(s *state) error {
+		if len(s.Containers) < p.max {
+			s.Containers = append(s.Containers, &Entry{
+				ID:       containerID,
+				Spec:     key,
+				Image:    image,
+				Project:  project,
+				InUse:    true,
+				Owner:    os.Getpid(),
+				Created:  now,
+				LastUsed: now,
+				Uses:     1,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to pool container %s: %v\n", containerID, err)
+	}
+	return containerID, nil
+}
+
+// lease marks the idle container of the spec to use for project as leased and returns it,
+// or nil if there is none. Containers project used before are preferred over spares.
+func lease(s *state, key string, project string) *Entry {
+	var leased *Entry
+	for _, entry := range s.Containers {
+		if entry.InUse || entry.Spec != key || (entry.Project != "" && entry.Project != project) {
+			continue
+		}
+		if leased == nil || (entry.Project != "" && leased.Project == "") ||
+			(entry.Project == leased.Project && entry.LastUsed.After(leased.LastUsed)) {
+			leased = entry
+		}
+	}
+	if leased == nil {
+		return nil
+	}
+
+	leased.InUse = true
+	leased.Owner = os.Getpid()
+	leased.Project = project
+	leased.LastUsed = time.Now()
+	leased.Uses++
+	copied := *leased
+	return &copied
+}
+
+// ExecuteCommand executes a command in the container
+func (p *Provider) ExecuteCommand(ctx context.Context, containerID string, command []string) (string, error) {
+	return p.backend.ExecuteCommand(ctx, containerID, command)
+}
+
+// ExecuteCommandStream executes a command in the container, writing its combined output to
+// output while the command runs
+func (p *Provider) ExecuteCommandStream(ctx context.Context, containerID string, command []string, output io.Writer) error {
+	return p.backend.ExecuteCommandStream(ctx, containerID, command, output)
+}
+
+// CopyFilesToContainer copies files from local to container
+func (p *Provider) CopyFilesToContainer(ctx context.Context, containerID string, localPath string, containerPath string) error {
+	return p.backend.CopyFilesToContainer(ctx, containerID, localPath, containerPath)
+}
+
+// CopyFilesFromContainer copies files from container to local
+func (p *Provider) CopyFilesFromContainer(ctx context.Context, containerID string, containerPath string, localPath string) error {
+	return p.backend.CopyFilesFromContainer(ctx, containerID, containerPath, localPath)
+}
+
+// StopContainer stops a container unless it is pooled, keeping pooled containers warm
+func (p *Provider) StopContainer(ctx context.Context, containerID string) error {
+	pooled := false
+	err := p.update(ctx, func(s *state) error {
+		pooled = s.find(containerID) != nil
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if pooled {
+		return nil
+	}
+	return p.backend.StopContainer(ctx, containerID)
+}
+
+// RemoveContainer releases a pooled container to the pool, and removes other containers.
+// Releasing removes the containers idle for longer than the idle TTL.
+func (p *Provider) RemoveContainer(ctx context.Context, containerID string) error {
+	pooled := false
+	var expired []string
+	err := p.update(ctx, func(s *state) error {
+		if entry := s.find(containerID); entry != nil {
+			pooled = true
+			entry.InUse = false
+			entry.Owner = 0
+			entry.LastUsed = time.Now()
+		}
+		expired = p.expire(s)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !pooled {
+		expired = append(expired, containerID)
+	}
+	return p.removeAll(ctx, expired)
+}
+
+// expire removes the idle containers past the idle TTL and the containers leased by
+// processes that died from the state, and returns their IDs
+func (p *Provider) expire(s *state) []string {
+	var expired []string
+	for _, entry := range append([]*Entry{}, s.Containers...) {
+		idle := !entry.InUse && time.Since(entry.LastUsed) > p.idleTTL
+		leaked := entry.InUse && !alive(entry.Owner)
+		if idle || leaked {
+			s.remove(entry.ID)
+			expired = append(expired, entry.ID)
+		}
+	}
+	return expired
+}
+
+// discard removes a container from the pool and removes it
+func (p *Provider) discard(ctx context.Context, containerID string) error {
+	err := p.update(ctx, func(s *state) error {
+		s.remove(containerID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return p.removeAll(ctx, []string{containerID})
+}
+
+// removeAll stops and removes containers, which may be gone already
+func (p *Provider) removeAll(ctx context.Context, containerIDs []string) error {
+	var failed error
+	for _, containerID := range containerIDs {
+		if err := p.backend.StopContainer(ctx, containerID); err != nil {
+			fmt.Printf("Warning: Failed to stop container %s: %v\n", containerID, err)
+		}
+		if err := p.backend.RemoveContainer(ctx, containerID); err != nil && failed == nil {
+			failed = fmt.Errorf("failed to remove container %s: %w", containerID, err)
+		}
+	}
+	return failed
+}
+
+// Maintain health checks the idle containers of the pool, removes those that are unhealthy,
+// idle for longer than the idle TTL or leased by processes that died, and starts spares
+// until the pool has its minimum of idle containers. Spares run with the spec last leased,
+// and are leased by the first project needing them.
+func (p *Provider) Maintain(ctx context.Context) error {
+	var expired []string
+	var idle []string
+	err := p.update(ctx, func(s *state) error {
+		expired = p.expire(s)
+		for _, entry := range s.Containers {
+			if !entry.InUse {
+				idle = append(idle, entry.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := p.removeAll(ctx, expired); err != nil {
+		return err
+	}
+
+	for _, containerID := range idle {
+		if _, err := p.backend.ExecuteCommand(ctx, containerID, healthCommand); err != nil {
+			fmt.Printf("Pooled container %s is unhealthy, removing it\n", containerID)
+			if err := p.discard(ctx, containerID); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Start the missing spares, as many as the pool has room for
+	var spec Spec
+	missing := 0
+	err = p.update(ctx, func(s *state) error {
+		idle := 0
+		for _, entry := range s.Containers {
+			if !entry.InUse {
+				idle++
+			}
+		}
+		missing = p.min - idle
+		if room := p.max - len(s.Containers); missing > room {
+			missing = room
+		}
+		var ok bool
+		if spec, ok = s.Specs[s.Latest]; !ok {
+			missing = 0
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < missing; i++ {
+		containerID, err := p.backend.RunContainer(ctx, spec.Image, spec.VolumeMounts, spec.Env, spec.Options)
+		if err != nil {
+			return fmt.Errorf("failed to start spare container: %w", err)
+		}
+		now := time.Now()
+		err = p.update(ctx, func(s *state) error {
+			s.Containers = append(s.Containers, &Entry{
+				ID:       containerID,
+				Spec:     spec.Key(),
+				Image:    spec.Image,
+				Created:  now,
+				LastUsed: now,
+			})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Started spare container %s\n", containerID)
+	}
+
+	return nil
+}
+
+// Containers returns the containers of the pool, leased ones first and then by last use
+func (p *Provider) Containers(ctx context.Context) ([]Entry, error) {
+	var entries []Entry
+	err := p.update(ctx, func(s *state) error {
+		for _, entry := range s.Containers {
+			entries = append(entries, *entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].InUse != entries[j].InUse {
+			return entries[i].InUse
+		}
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// Drain removes the idle containers of the pool, and the leased ones as well with all
+func (p *Provider) Drain(ctx context.Context, all bool) (int, error) {
+	var drained []string
+	err := p.update(ctx, func(s *state) error {
+		for _, entry := range append([]*Entry{}, s.Containers...) {
+			if all || !entry.InUse {
+				s.remove(entry.ID)
+				drained = append(drained, entry.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(drained), p.removeAll(ctx, drained)
+}
+
+// StatePath returns the file the pool is kept in
+func (p *Provider) StatePath() string {
+	return p.statePath
+}
+
+// Name returns the provider's name
+func (p *Provider) Name() string {
+	return "pool"
+}
+
+// IsRemote returns whether the pooled containers run remotely
+func (p *Provider) IsRemote() bool {
+	return p.backend.IsRemote()
+}
+
+// Register registers this provider factory
+func init() {
+	container.Register("pool", func(config map[string]string) (container.Provider, error) {
+		return NewProvider(config)
+	})
+}
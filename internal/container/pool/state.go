@@ -0,0 +1,214 @@
+package pool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/container"
+)
+
+// Timing of the lock of the state file
+const (
+	// lockRetry is how often a held lock is tried again
+	lockRetry = 50 * time.Millisecond
+
+	// lockStale is how old a lock is when its holder is assumed to have died. The state is
+	// only locked while it is read and written, never while containers start.
+	lockStale = 30 * time.Second
+)
+
+// Entry is a pooled container
+type Entry struct {
+	// ID of the container
+	ID string `json:"id"`
+
+	// Spec is the key of the image, mounts, environment and options the container runs with
+	Spec string `json:"spec"`
+
+	// Image the container runs
+	Image string `json:"image"`
+
+	// Project the container works on, empty for spares no project used yet
+	Project string `json:"project,omitempty"`
+
+	// Whether the container is leased
+	InUse bool `json:"in_use"`
+
+	// Owner is the process ID of the process leasing the container
+	Owner int `json:"owner,omitempty"`
+
+	// When the container was started
+	Created time.Time `json:"created"`
+
+	// When the container was last leased or released
+	LastUsed time.Time `json:"last_used"`
+
+	// Uses is how many times the container was leased
+	Uses int `json:"uses"`
+}
+
+// Spec is what a container runs with, which leased containers match exactly except for
+// the project label
+type Spec struct {
+	Image        string               `json:"image"`
+	VolumeMounts map[string]string    `json:"volume_mounts,omitempty"`
+	Env          map[string]string    `json:"env,omitempty"`
+	Options      container.RunOptions `json:"options"`
+}
+
+// newSpec returns the spec of containers run with the given arguments, without the project label
+func newSpec(image string, volumeMounts, env map[string]string, options container.RunOptions) Spec {
+	labels := make(map[string]string)
+	for k, v := range options.Labels {
+		if k != container.ProjectLabel {
+			labels[k] = v
+		}
+	}
+	options.Labels = labels
+	if len(labels) == 0 {
+		options.Labels = nil
+	}
+	return Spec{Image: image, VolumeMounts: volumeMounts, Env: env, Options: options}
+}
+
+// Key returns the key identifying the spec
+func (s Spec) Key() string {
+	// Maps are encoded with sorted keys, so equal specs have equal keys
+	data, _ := json.Marshal(s)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:6])
+}
+
+// state is the pool shared by all processes using the same state file
+type state struct {
+	// Containers of the pool
+	Containers []*Entry `json:"containers"`
+
+	// Specs by key, so spares can be started for them
+	Specs map[string]Spec `json:"specs,omitempty"`
+
+	// Latest is the key of the spec last leased, which spares run with
+	Latest string `json:"latest,omitempty"`
+}
+
+// find returns the container with the given ID, or nil
+func (s *state) find(id string) *Entry {
+	for _, entry := range s.Containers {
+		if entry.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// remove removes the container with the given ID
+func (s *state) remove(id string) {
+	for i, entry := range s.Containers {
+		if entry.ID == id {
+			s.Containers = append(s.Containers[:i], s.Containers[i+1:]...)
+			return
+		}
+	}
+}
+
+// update locks the state file, reads the state, lets fn change it and writes it back
+func (p *Provider) update(ctx context.Context, fn func(s *state) error) error {
+	unlock, err := p.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s := &state{}
+	data, err := os.ReadFile(p.statePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read pool state: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, s); err != nil {
+			return fmt.Errorf("failed to parse pool state %s: %w", p.statePath, err)
+		}
+	}
+	if s.Specs == nil {
+		s.Specs = make(map[string]Spec)
+	}
+
+	if err := fn(s); err != nil {
+		return err
+	}
+
+	// Forget the specs no container runs with anymore, unless spares run with them
+	for key := range s.Specs {
+		used := key == s.Latest
+		for _, entry := range s.Containers {
+			used = used || entry.Spec == key
+		}
+		if !used {
+			delete(s.Specs, key)
+		}
+	}
+
+	data, err = json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pool state: %w", err)
+	}
+	// The specs hold the environment of containers
+	tmp := p.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write pool state: %w", err)
+	}
+	if err := os.Rename(tmp, p.statePath); err != nil {
+		return fmt.Errorf("failed to write pool state: %w", err)
+	}
+	return nil
+}
+
+// lock takes the lock of the state file, shared by the processes using the pool, and returns
+// the function releasing it
+func (p *Provider) lock(ctx context.Context) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(p.statePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create pool state directory: %w", err)
+	}
+
+	lockPath := p.statePath + ".lock"
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock pool state: %w", err)
+		}
+
+		// Break the lock of a process that died holding it
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(lockPath)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to lock pool state: %w", ctx.Err())
+		case <-time.After(lockRetry):
+		}
+	}
+}
+
+// alive returns whether the process with the given ID is running
+func alive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,281 @@
+package pool_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/container"
+	"github.com/fr0g-66723067/cc/internal/container/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBackend is a container provider keeping track of the containers it runs
+type fakeBackend struct {
+	mu        sync.Mutex
+	started   []string
+	running   map[string]bool
+	unhealthy map[string]bool
+	removed   []string
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{running: make(map[string]bool), unhealthy: make(map[string]bool)}
+}
+
+func (f *fakeBackend) Initialize(ctx context.Context, config map[string]string) error {
+	return nil
+}
+
+func (f *fakeBackend) RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string, options container.RunOptions) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := fmt.Sprintf("c%d", len(f.started)+1)
+	f.started = append(f.started, id)
+	f.running[id] = true
+	return id, nil
+}
+
+func (f *fakeBackend) ExecuteCommand(ctx context.Context, containerID string, command []string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.running[containerID] || f.unhealthy[containerID] {
+		return "", fmt.Errorf("container %s is not running", containerID)
+	}
+	return "ok", nil
+}
+
+func (f *fakeBackend) ExecuteCommandStream(ctx context.Context, containerID string, command []string, output io.Writer) error {
+	_, err := f.ExecuteCommand(ctx, containerID, command)
+	return err
+}
+
+func (f *fakeBackend) CopyFilesToContainer(ctx context.Context, containerID string, localPath string, containerPath string) error {
+	return nil
+}
+
+func (f *fakeBackend) CopyFilesFromContainer(ctx context.Context, containerID string, containerPath string, localPath string) error {
+	return nil
+}
+
+func (f *fakeBackend) StopContainer(ctx context.Context, containerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running[containerID] = false
+	return nil
+}
+
+func (f *fakeBackend) RemoveContainer(ctx context.Context, containerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.running, containerID)
+	f.removed = append(f.removed, containerID)
+	return nil
+}
+
+func (f *fakeBackend) Name() string {
+	return "fake"
+}
+
+func (f *fakeBackend) IsRemote() bool {
+	return false
+}
+
+// newPool returns a pool of containers run by backend, with its state in a temporary directory
+func newPool(t *testing.T, backend *fakeBackend, config map[string]string) *pool.Provider {
+	if config == nil {
+		config = make(map[string]string)
+	}
+	if config[pool.StateKey] == "" {
+		config[pool.StateKey] = filepath.Join(t.TempDir(), "pool.json")
+	}
+	provider, err := pool.New(backend, config)
+	require.NoError(t, err)
+	require.NoError(t, provider.Initialize(context.Background(), nil))
+	return provider
+}
+
+// projectOptions returns run options labelling containers with project
+func projectOptions(project string) container.RunOptions {
+	return container.RunOptions{CapDrop: []string{"ALL"}, Labels: map[string]string{container.ProjectLabel: project}}
+}
+
+// release stops and removes a container as the AI providers do when they clean up
+func release(t *testing.T, provider container.Provider, containerID string) {
+	require.NoError(t, provider.StopContainer(context.Background(), containerID))
+	require.NoError(t, provider.RemoveContainer(context.Background(), containerID))
+}
+
+// TestNewProvider tests creating pools
+func TestNewProvider(t *testing.T) {
+	provider := newPool(t, newFakeBackend(), nil)
+	assert.Equal(t, "pool", provider.Name())
+	assert.False(t, provider.IsRemote())
+
+	_, err := pool.NewProvider(map[string]string{pool.ProviderKey: "pool"})
+	assert.ErrorContains(t, err, "invalid pool_provider")
+	_, err = pool.NewProvider(map[string]string{pool.ProviderKey: "unknown"})
+	assert.ErrorContains(t, err, "unknown container provider: unknown")
+
+	for key, value := range map[string]string{
+		pool.MinKey:     "-1",
+		pool.MaxKey:     "many",
+		pool.IdleTTLKey: "forever",
+	} {
+		_, err := pool.New(newFakeBackend(), map[string]string{key: value})
+		assert.ErrorContains(t, err, "invalid "+key, key)
+	}
+	_, err = pool.New(newFakeBackend(), map[string]string{pool.MinKey: "3", pool.MaxKey: "2"})
+	assert.ErrorContains(t, err, "invalid pool_min: 3 is more than pool_max 2")
+}
+
+// TestLease tests reusing released containers of the same spec and project
+func TestLease(t *testing.T) {
+	ctx := context.Background()
+	backend := newFakeBackend()
+	provider := newPool(t, backend, nil)
+	mounts := map[string]string{}
+	env := map[string]string{"HOME": "/home/node"}
+
+	id, err := provider.RunContainer(ctx, "claude-code:latest", mounts, env, projectOptions("todo"))
+	require.NoError(t, err)
+	assert.Equal(t, "c1", id)
+	release(t, provider, id)
+
+	// Released containers keep running
+	assert.True(t, backend.running["c1"])
+	assert.Empty(t, backend.removed)
+
+	// Other pools sharing the state lease them
+	other := newPool(t, backend, map[string]string{pool.StateKey: provider.StatePath()})
+	id, err = other.RunContainer(ctx, "claude-code:latest", mounts, env, projectOptions("todo"))
+	require.NoError(t, err)
+	assert.Equal(t, "c1", id)
+
+	// Leased containers, containers of other projects and other specs are not leased
+	id, err = provider.RunContainer(ctx, "claude-code:latest", mounts, env, projectOptions("todo"))
+	require.NoError(t, err)
+	assert.Equal(t, "c2", id)
+	release(t, provider, "c1")
+	id, err = provider.RunContainer(ctx, "claude-code:latest", mounts, env, projectOptions("blog"))
+	require.NoError(t, err)
+	assert.Equal(t, "c3", id)
+	id, err = provider.RunContainer(ctx, "claude-elixir:latest", mounts, env, projectOptions("todo"))
+	require.NoError(t, err)
+	assert.Equal(t, "c4", id)
+
+	entries, err := provider.Containers(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.False(t, entries[3].InUse)
+	assert.Equal(t, "c1", entries[3].ID)
+	assert.Equal(t, "todo", entries[3].Project)
+	assert.Equal(t, 2, entries[3].Uses)
+}
+
+// TestPoolMax tests removing the containers started beyond the size of the pool
+func TestPoolMax(t *testing.T) {
+	ctx := context.Background()
+	backend := newFakeBackend()
+	provider := newPool(t, backend, map[string]string{pool.MaxKey: "1"})
+
+	first, err := provider.RunContainer(ctx, "claude-code:latest", nil, nil, projectOptions("todo"))
+	require.NoError(t, err)
+	second, err := provider.RunContainer(ctx, "claude-code:latest", nil, nil, projectOptions("todo"))
+	require.NoError(t, err)
+
+	release(t, provider, second)
+	release(t, provider, first)
+	assert.Equal(t, []string{second}, backend.removed)
+	assert.True(t, backend.running[first])
+}
+
+// TestUnhealthy tests replacing containers failing their health check
+func TestUnhealthy(t *testing.T) {
+	ctx := context.Background()
+	backend := newFakeBackend()
+	provider := newPool(t, backend, nil)
+
+	id, err := provider.RunContainer(ctx, "claude-code:latest", nil, nil, projectOptions("todo"))
+	require.NoError(t, err)
+	release(t, provider, id)
+	backend.unhealthy[id] = true
+
+	replacement, err := provider.RunContainer(ctx, "claude-code:latest", nil, nil, projectOptions("todo"))
+	require.NoError(t, err)
+	assert.NotEqual(t, id, replacement)
+	assert.Equal(t, []string{id}, backend.removed)
+
+	entries, err := provider.Containers(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, replacement, entries[0].ID)
+}
+
+// TestMaintain tests keeping spares warm, health checks and removing idle containers
+func TestMaintain(t *testing.T) {
+	ctx := context.Background()
+	backend := newFakeBackend()
+	provider := newPool(t, backend, map[string]string{pool.MinKey: "2", pool.MaxKey: "3", pool.IdleTTLKey: "200ms"})
+
+	// Without a spec leased yet there is nothing to warm
+	require.NoError(t, provider.Maintain(ctx))
+	assert.Empty(t, backend.started)
+
+	id, err := provider.RunContainer(ctx, "claude-code:latest", nil, nil, projectOptions("todo"))
+	require.NoError(t, err)
+	require.NoError(t, provider.Maintain(ctx))
+	assert.Equal(t, []string{"c1", "c2", "c3"}, backend.started)
+
+	// Spares are leased by any project, the container of a project only by that project
+	release(t, provider, id)
+	backend.unhealthy["c2"] = true
+	require.NoError(t, provider.Maintain(ctx))
+	assert.Equal(t, []string{"c2"}, backend.removed)
+	assert.Len(t, backend.started, 3)
+	id, err = provider.RunContainer(ctx, "claude-code:latest", nil, nil, projectOptions("blog"))
+	require.NoError(t, err)
+	assert.Equal(t, "c3", id)
+
+	// Idle containers past the idle TTL are removed, and replaced by spares
+	time.Sleep(300 * time.Millisecond)
+	release(t, provider, id)
+	require.NoError(t, provider.Maintain(ctx))
+	assert.Equal(t, []string{"c2", "c1"}, backend.removed)
+
+	entries, err := provider.Containers(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "c4", entries[0].ID)
+	assert.Empty(t, entries[0].Project)
+	assert.Equal(t, "c3", entries[1].ID)
+	assert.Equal(t, "blog", entries[1].Project)
+}
+
+// TestDrain tests removing the containers of the pool
+func TestDrain(t *testing.T) {
+	ctx := context.Background()
+	backend := newFakeBackend()
+	provider := newPool(t, backend, nil)
+
+	idle, err := provider.RunContainer(ctx, "claude-code:latest", nil, nil, projectOptions("todo"))
+	require.NoError(t, err)
+	release(t, provider, idle)
+	leased, err := provider.RunContainer(ctx, "claude-code:latest", nil, nil, projectOptions("blog"))
+	require.NoError(t, err)
+
+	drained, err := provider.Drain(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, drained)
+	assert.Equal(t, []string{idle}, backend.removed)
+
+	drained, err = provider.Drain(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, drained)
+	assert.Equal(t, []string{idle, leased}, backend.removed)
+}
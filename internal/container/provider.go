@@ -3,6 +3,7 @@ package container
 import (
 	"context"
 	"fmt"
+	"io"
 )
 
 // Provider defines the interface for container systems
@@ -10,12 +11,17 @@ type Provider interface {
 	// Initialize sets up the container environment
 	Initialize(ctx context.Context, config map[string]string) error
 
-	// RunContainer starts a container with the given image and returns its ID
-	RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string) (string, error)
+	// RunContainer starts a container with the given image and returns its ID. The
+	// container is limited and hardened as the options say.
+	RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string, options RunOptions) (string, error)
 
 	// ExecuteCommand executes a command in the container
 	ExecuteCommand(ctx context.Context, containerID string, command []string) (string, error)
 
+	// ExecuteCommandStream executes a command in the container, writing its combined
+	// output to output while the command runs
+	ExecuteCommandStream(ctx context.Context, containerID string, command []string, output io.Writer) error
+
 	// CopyFilesToContainer copies files from local to container
 	CopyFilesToContainer(ctx context.Context, containerID string, localPath string, containerPath string) error
 
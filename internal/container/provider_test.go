@@ -3,6 +3,7 @@ package container_test
 import (
 	"context"
 	"fmt"
+	"io"
 	"testing"
 
 	"github.com/fr0g-66723067/cc/internal/container"
@@ -58,7 +59,7 @@ func (p *mockProvider) Initialize(ctx context.Context, config map[string]string)
 	return nil
 }
 
-func (p *mockProvider) RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string) (string, error) {
+func (p *mockProvider) RunContainer(ctx context.Context, image string, volumeMounts map[string]string, env map[string]string, options container.RunOptions) (string, error) {
 	return "container-id", nil
 }
 
@@ -66,6 +67,11 @@ func (p *mockProvider) ExecuteCommand(ctx context.Context, containerID string, c
 	return "command output", nil
 }
 
+func (p *mockProvider) ExecuteCommandStream(ctx context.Context, containerID string, command []string, output io.Writer) error {
+	_, err := io.WriteString(output, "command output")
+	return err
+}
+
 func (p *mockProvider) CopyFilesToContainer(ctx context.Context, containerID string, localPath string, containerPath string) error {
 	return nil
 }
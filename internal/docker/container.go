@@ -64,8 +64,14 @@ func (m *Manager) RunClaudeContainer(ctx context.Context, projectPath string) (s
 		}
 	}
 
+	// Limit and harden the container as the container config says
+	options, err := container.ParseRunOptions(m.config.Container.Config)
+	if err != nil {
+		return "", fmt.Errorf("invalid container options: %w", err)
+	}
+
 	// Run the container
-	containerID, err := m.provider.RunContainer(ctx, image, volumeMounts, env)
+	containerID, err := m.provider.RunContainer(ctx, image, volumeMounts, env, options)
 	if err != nil {
 		return "", fmt.Errorf("failed to run Claude Code container: %w", err)
 	}
@@ -32,6 +32,9 @@ type Job struct {
 	CreatedAt   time.Time
 	StartedAt   *time.Time
 	CompletedAt *time.Time
+
+	// done is closed once the job has finished
+	done chan struct{}
 }
 
 // Handler is a function that processes a job
@@ -41,6 +44,8 @@ type Handler func(ctx context.Context, payload map[string]interface{}) (interfac
 type Queue struct {
 	jobs     map[string]*Job
 	handlers map[string]Handler
+	slots    chan struct{}
+	nextID   int
 	mutex    sync.RWMutex
 }
 
@@ -60,6 +65,20 @@ func (q *Queue) RegisterHandler(jobType string, handler Handler) {
 	q.handlers[jobType] = handler
 }
 
+// SetMaxConcurrent limits the number of jobs that run at the same time.
+// A value of zero or less removes the limit. It only affects jobs submitted
+// after the call.
+func (q *Queue) SetMaxConcurrent(n int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if n <= 0 {
+		q.slots = nil
+		return
+	}
+	q.slots = make(chan struct{}, n)
+}
+
 // Submit adds a new job to the queue
 func (q *Queue) Submit(jobType string, payload map[string]interface{}) (string, error) {
 	q.mutex.Lock()
@@ -72,20 +91,22 @@ func (q *Queue) Submit(jobType string, payload map[string]interface{}) (string,
 	}
 
 	// Create a new job
-	jobID := fmt.Sprintf("%d", time.Now().UnixNano())
+	q.nextID++
+	jobID := fmt.Sprintf("%d-%d", time.Now().UnixNano(), q.nextID)
 	job := &Job{
 		ID:        jobID,
 		Type:      jobType,
 		Payload:   payload,
 		Status:    StatusPending,
 		CreatedAt: time.Now(),
+		done:      make(chan struct{}),
 	}
 
 	// Add it to the map
 	q.jobs[jobID] = job
 
 	// Start processing the job in the background
-	go q.processJob(job)
+	go q.processJob(job, q.slots)
 
 	return jobID, nil
 }
@@ -103,8 +124,27 @@ func (q *Queue) GetJob(jobID string) (*Job, error) {
 	return job, nil
 }
 
+// Wait blocks until the job has finished and returns it
+func (q *Queue) Wait(jobID string) (*Job, error) {
+	job, err := q.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	<-job.done
+	return job, nil
+}
+
 // processJob processes a job in the background
-func (q *Queue) processJob(job *Job) {
+func (q *Queue) processJob(job *Job, slots chan struct{}) {
+	defer close(job.done)
+
+	// Wait for a free slot if concurrency is limited
+	if slots != nil {
+		slots <- struct{}{}
+		defer func() { <-slots }()
+	}
+
 	// Get the handler
 	q.mutex.RLock()
 	handler, exists := q.handlers[job.Type]
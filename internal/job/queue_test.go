@@ -3,6 +3,7 @@ package job_test
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -129,4 +130,68 @@ func TestGetNonexistentJob(t *testing.T) {
 	// Get a non-existent job
 	_, err := queue.GetJob("nonexistent")
 	assert.Error(t, err)
-}
\ No newline at end of file
+}
+func TestWaitJob(t *testing.T) {
+	queue := job.NewQueue()
+	
+	// Register a slow handler
+	queue.RegisterHandler("slow", func(ctx context.Context, payload map[string]interface{}) (interface{}, error) {
+		time.Sleep(50 * time.Millisecond)
+		return "done", nil
+	})
+	
+	// Submit a job and wait for it
+	jobID, err := queue.Submit("slow", nil)
+	assert.NoError(t, err)
+	
+	j, err := queue.Wait(jobID)
+	assert.NoError(t, err)
+	assert.Equal(t, job.StatusCompleted, j.Status)
+	assert.Equal(t, "done", j.Result)
+	
+	// Waiting on a non-existent job fails
+	_, err = queue.Wait("nonexistent")
+	assert.Error(t, err)
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	queue := job.NewQueue()
+	queue.SetMaxConcurrent(2)
+	
+	// Track how many handlers run at the same time
+	var mutex sync.Mutex
+	running := 0
+	maxRunning := 0
+	queue.RegisterHandler("test", func(ctx context.Context, payload map[string]interface{}) (interface{}, error) {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		
+		time.Sleep(50 * time.Millisecond)
+		
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		return nil, nil
+	})
+	
+	// Submit more jobs than the limit
+	var jobIDs []string
+	for i := 0; i < 5; i++ {
+		jobID, err := queue.Submit("test", nil)
+		assert.NoError(t, err)
+		jobIDs = append(jobIDs, jobID)
+	}
+	
+	// Wait for all jobs
+	for _, jobID := range jobIDs {
+		j, err := queue.Wait(jobID)
+		assert.NoError(t, err)
+		assert.Equal(t, job.StatusCompleted, j.Status)
+	}
+	
+	assert.Equal(t, 2, maxRunning)
+}
@@ -0,0 +1,205 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fr0g-66723067/cc/internal/verify"
+)
+
+// Profile describes how to generate and check code written with a framework
+type Profile struct {
+	// Name of the framework
+	Name string `json:"name"`
+
+	// Instructions added to the prompts generating or changing code with the framework
+	Instructions string `json:"instructions,omitempty"`
+
+	// Toolchain image of the framework, like node:20, which verification recipes without an
+	// image run in. AI providers never generate code in it.
+	Image string `json:"image,omitempty"`
+
+	// Base container image code is generated in. It must provide the AI provider's tools,
+	// like the Claude Code CLI, and may add the framework's toolchain. Empty for the AI
+	// provider's default image.
+	GenerationImage string `json:"generationImage,omitempty"`
+
+	// Recipe verifying the code, using the toolchain image if it sets none. Without steps the
+	// recipe registered for the framework is kept.
+	Verify verify.Recipe `json:"verify"`
+
+	// Files every implementation is expected to have, relative to the code (may contain wildcards)
+	EntryFiles []string `json:"entryFiles,omitempty"`
+}
+
+// MissingEntryFiles returns the entry files of the profile that dir does not have
+func (p Profile) MissingEntryFiles(dir string) []string {
+	var missing []string
+	for _, pattern := range p.EntryFiles {
+		matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+		if err != nil || len(matches) == 0 {
+			missing = append(missing, pattern)
+		}
+	}
+	return missing
+}
+
+// merge returns the profile with the fields set in other replacing its own
+func (p Profile) merge(other Profile) Profile {
+	if other.Instructions != "" {
+		p.Instructions = other.Instructions
+	}
+	if other.Image != "" {
+		p.Image = other.Image
+	}
+	if other.GenerationImage != "" {
+		p.GenerationImage = other.GenerationImage
+	}
+	if len(other.Verify.Steps) > 0 {
+		p.Verify = other.Verify
+	}
+	if other.EntryFiles != nil {
+		p.EntryFiles = other.EntryFiles
+	}
+	return p
+}
+
+// nodeEntryFiles are the entry files of JavaScript frameworks
+var nodeEntryFiles = []string{"package.json"}
+
+// builtinInstructions are the instructions of the built-in profiles
+var builtinInstructions = map[string]string{
+	"react": "Use function components with hooks, Vite as the build tool and React Testing Library " +
+		"with Vitest for tests. Keep state local and lift it only when components share it.",
+	"vue": "Use Vue 3 single-file components with the Composition API and <script setup>, Vite as " +
+		"the build tool, Pinia for shared state and Vitest with Vue Test Utils for tests.",
+	"svelte": "Use SvelteKit with TypeScript, file-based routes under src/routes, stores for shared " +
+		"state and Vitest for tests.",
+	"angular": "Use standalone components, the Angular CLI project layout, services with dependency " +
+		"injection for data access, reactive forms and Karma or Jest specs next to each component.",
+	"nextjs": "Use the App Router under app/, server components by default and client components only " +
+		"where interactivity needs them, route handlers for APIs and TypeScript throughout.",
+	"nuxt": "Use Nuxt 3 with auto-imported components and composables, pages/ for routes, server/api " +
+		"for API endpoints and useFetch for data loading.",
+	"express": "Structure the app as routers per resource with middleware for validation and error " +
+		"handling, keep the server start separate from the app for testing, and test with Jest and supertest.",
+	"fastify": "Split the app into plugins per resource, declare JSON schemas for request and response " +
+		"validation, and test routes with fastify.inject and the built-in node:test runner.",
+	"django": "Use a project with one app per domain area, class-based views, the ORM with migrations, " +
+		"Django templates or the Django REST framework for APIs, and TestCase tests per app.",
+	"flask": "Use the application factory pattern with blueprints, Flask-SQLAlchemy for persistence, " +
+		"pin dependencies in requirements.txt and test with pytest and the Flask test client.",
+	"spring": "Use Spring Boot 3 with Maven and Java 17, a controller, service and repository layer, " +
+		"Spring Data JPA with H2 for persistence and JUnit 5 tests with MockMvc.",
+	"rails": "Use Rails conventions: RESTful resources, ActiveRecord models with validations and " +
+		"migrations, strong parameters in controllers, SQLite, and Minitest tests.",
+}
+
+// builtin returns the built-in profile of a framework, whose toolchain image is the image of
+// its built-in recipe
+func builtin(name string, entryFiles []string) Profile {
+	recipe, _ := verify.GetRecipe(name)
+	return Profile{Name: name, Instructions: builtinInstructions[name], Image: recipe.Image, EntryFiles: entryFiles}
+}
+
+var (
+	profilesMutex sync.RWMutex
+
+	// names holds the names of the profiles in the order they were registered
+	names []string
+
+	profiles = make(map[string]Profile)
+)
+
+func init() {
+	for _, name := range []string{"react", "vue", "svelte", "angular", "nextjs", "nuxt", "express", "fastify"} {
+		Register(builtin(name, nodeEntryFiles))
+	}
+	Register(builtin("django", []string{"manage.py", "requirements.txt"}))
+	Register(builtin("flask", []string{"requirements.txt"}))
+	Register(builtin("spring", []string{"pom.xml"}))
+	Register(builtin("rails", []string{"Gemfile", "config/routes.rb"}))
+}
+
+// Register registers a profile. The fields it sets replace those of the profile already
+// registered for the framework, and its verification recipe is registered with verify.
+func Register(profile Profile) {
+	profilesMutex.Lock()
+	defer profilesMutex.Unlock()
+
+	name := strings.ToLower(profile.Name)
+	existing, exists := profiles[name]
+	if !exists {
+		names = append(names, name)
+		existing = Profile{Name: name}
+	}
+	profiles[name] = existing.merge(profile)
+
+	if len(profile.Verify.Steps) > 0 {
+		recipe := profile.Verify
+		recipe.Framework = name
+		if recipe.Image == "" {
+			recipe.Image = profiles[name].Image
+		}
+		verify.RegisterRecipe(recipe)
+	}
+}
+
+// Get returns the profile of a framework
+func Get(name string) (Profile, bool) {
+	profilesMutex.RLock()
+	defer profilesMutex.RUnlock()
+	profile, exists := profiles[strings.ToLower(name)]
+	return profile, exists
+}
+
+// Names returns the names of the frameworks with a profile, built-in frameworks first
+func Names() []string {
+	profilesMutex.RLock()
+	defer profilesMutex.RUnlock()
+	return append([]string(nil), names...)
+}
+
+// Load registers the profiles in the JSON files of dir, in file name order, and then the
+// given profiles, usually from the configuration. A missing dir is ignored.
+func Load(dir string, configured []Profile) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list framework profiles: %w", err)
+	}
+	sort.Strings(files)
+
+	var loaded []Profile
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read framework profile: %w", err)
+		}
+
+		var profile Profile
+		if err := json.Unmarshal(data, &profile); err != nil {
+			return fmt.Errorf("failed to parse framework profile %s: %w", file, err)
+		}
+		if profile.Name == "" {
+			profile.Name = strings.TrimSuffix(filepath.Base(file), ".json")
+		}
+		loaded = append(loaded, profile)
+	}
+
+	for _, profile := range configured {
+		if profile.Name == "" {
+			return fmt.Errorf("framework profile without a name")
+		}
+		loaded = append(loaded, profile)
+	}
+
+	for _, profile := range loaded {
+		Register(profile)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package profile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fr0g-66723067/cc/internal/profile"
+	"github.com/fr0g-66723067/cc/internal/verify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBuiltinProfiles tests the profiles of the built-in frameworks
+func TestBuiltinProfiles(t *testing.T) {
+	names := profile.Names()
+	require.GreaterOrEqual(t, len(names), 12)
+	assert.Equal(t, []string{"react", "vue", "svelte", "angular"}, names[:4])
+
+	django, ok := profile.Get("Django")
+	require.True(t, ok)
+	assert.Equal(t, []string{"manage.py", "requirements.txt"}, django.EntryFiles)
+	assert.Equal(t, "python:3.12", django.Image)
+	assert.Empty(t, django.GenerationImage)
+
+	// Every built-in framework has its own instructions and a toolchain image, which differ
+	// between languages
+	instructions := make(map[string]string)
+	for _, name := range names[:12] {
+		builtin, _ := profile.Get(name)
+		assert.NotEmpty(t, builtin.Instructions, name)
+		assert.NotEmpty(t, builtin.Image, name)
+		assert.NotContains(t, instructions, builtin.Instructions, name)
+		instructions[builtin.Instructions] = name
+	}
+	images := make(map[string]bool)
+	for _, name := range []string{"react", "django", "spring", "rails"} {
+		builtin, _ := profile.Get(name)
+		images[builtin.Image] = true
+	}
+	assert.Len(t, images, 4)
+
+	// Built-in profiles keep the built-in recipes
+	recipe, ok := verify.GetRecipe("django")
+	require.True(t, ok)
+	assert.Equal(t, "python:3.12", recipe.Image)
+
+	_, ok = profile.Get("cobol")
+	assert.False(t, ok)
+}
+
+// TestLoadProfiles tests loading profiles from plugin files and the configuration
+func TestLoadProfiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "laravel.json"), []byte(`{
+		"instructions": "Use Eloquent models.",
+		"image": "php:8.3",
+		"generationImage": "claude-php:latest",
+		"verify": {
+			"steps": [
+				{"name": "install", "command": "composer install"},
+				{"name": "test", "command": "php artisan test"}
+			]
+		},
+		"entryFiles": ["artisan", "composer.json"]
+	}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a profile"), 0644))
+
+	err := profile.Load(dir, []profile.Profile{
+		{Name: "flask", Instructions: "Use blueprints."},
+		{Name: "laravel", EntryFiles: []string{"artisan"}},
+	})
+	require.NoError(t, err)
+
+	// The file name names the profile, the configuration replaces the fields it sets
+	laravel, ok := profile.Get("laravel")
+	require.True(t, ok)
+	assert.Equal(t, "Use Eloquent models.", laravel.Instructions)
+	assert.Equal(t, []string{"artisan"}, laravel.EntryFiles)
+	assert.Equal(t, "claude-php:latest", laravel.GenerationImage)
+	assert.Contains(t, profile.Names(), "laravel")
+
+	// Recipes without an image use the toolchain image, not the generation image
+	recipe, ok := verify.GetRecipe("laravel")
+	require.True(t, ok)
+	assert.Equal(t, "php:8.3", recipe.Image)
+	assert.Len(t, recipe.Steps, 2)
+
+	// Built-in profiles keep the fields the configuration does not set
+	flask, _ := profile.Get("flask")
+	assert.Equal(t, "Use blueprints.", flask.Instructions)
+	assert.Equal(t, []string{"requirements.txt"}, flask.EntryFiles)
+
+	// A missing directory is not an error, broken files and nameless profiles are
+	assert.NoError(t, profile.Load(filepath.Join(dir, "missing"), nil))
+	assert.Error(t, profile.Load(dir, []profile.Profile{{Instructions: "nameless"}}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644))
+	assert.ErrorContains(t, profile.Load(dir, nil), "broken.json")
+}
+
+// TestMissingEntryFiles tests checking the entry files of a profile
+func TestMissingEntryFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "config"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Gemfile"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config", "routes.rb"), nil, 0644))
+
+	rails := profile.Profile{Name: "rails", EntryFiles: []string{"Gemfile", "config/*.rb", "bin/rails"}}
+	assert.Equal(t, []string{"bin/rails"}, rails.MissingEntryFiles(dir))
+}
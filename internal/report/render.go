@@ -0,0 +1,283 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formats a comparison can be rendered in
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// maxTreeFiles is the number of files shown in the file tree of an implementation
+const maxTreeFiles = 300
+
+// ParseFormat returns the report format with the given name
+func ParseFormat(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case FormatMarkdown, "md":
+		return FormatMarkdown, nil
+	case FormatHTML:
+		return FormatHTML, nil
+	default:
+		return "", fmt.Errorf("unknown report format: %s (valid formats: %s, %s)", name, FormatMarkdown, FormatHTML)
+	}
+}
+
+// Render renders the comparison in the given format
+func Render(c *Comparison, format string) (string, error) {
+	format, err := ParseFormat(format)
+	if err != nil {
+		return "", err
+	}
+	if format == FormatHTML {
+		return HTML(c)
+	}
+	return Markdown(c), nil
+}
+
+// row is a row of the overview table
+type row struct {
+	Label  string
+	Values []string
+}
+
+// overview returns the rows of the table comparing the implementations side by side
+func (c *Comparison) overview() []row {
+	rows := []row{
+		{Label: "Framework"},
+		{Label: "Score"},
+		{Label: "Files"},
+		{Label: "Lines of code"},
+		{Label: "Dependencies"},
+		{Label: "Main language"},
+	}
+	for _, impl := range c.Implementations {
+		mainLanguage := "-"
+		if len(impl.Languages) > 0 {
+			mainLanguage = impl.Languages[0].Language
+		}
+		values := []string{
+			impl.Framework,
+			strconv.Itoa(impl.Score),
+			strconv.Itoa(len(impl.Files)),
+			strconv.Itoa(impl.Lines()),
+			strconv.Itoa(impl.Dependencies()),
+			mainLanguage,
+		}
+		for i := range values {
+			rows[i].Values = append(rows[i].Values, values[i])
+		}
+	}
+
+	// One row per metric recorded for any implementation
+	names := make(map[string]bool)
+	for _, impl := range c.Implementations {
+		for name := range impl.Metrics {
+			names[name] = true
+		}
+	}
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		metricRow := row{Label: name}
+		for _, impl := range c.Implementations {
+			value, ok := impl.Metrics[name]
+			if !ok {
+				metricRow.Values = append(metricRow.Values, "-")
+				continue
+			}
+			metricRow.Values = append(metricRow.Values, formatMetric(value))
+		}
+		rows = append(rows, metricRow)
+	}
+
+	return rows
+}
+
+// formatMetric formats a metric value, showing at most two decimals
+func formatMetric(value float64) string {
+	if value == math.Trunc(value) {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// FileTree renders file paths as an indented tree, showing at most maxFiles files
+func FileTree(files []string, maxFiles int) string {
+	var out strings.Builder
+	var previous []string
+
+	for i, path := range files {
+		if i == maxFiles {
+			out.WriteString(fmt.Sprintf("... and %d more files\n", len(files)-maxFiles))
+			break
+		}
+
+		parts := strings.Split(path, "/")
+		dirs := parts[:len(parts)-1]
+
+		// Print the directories that differ from the previous file's
+		common := 0
+		for common < len(dirs) && common < len(previous) && dirs[common] == previous[common] {
+			common++
+		}
+		for depth := common; depth < len(dirs); depth++ {
+			out.WriteString(strings.Repeat("  ", depth) + dirs[depth] + "/\n")
+		}
+		out.WriteString(strings.Repeat("  ", len(dirs)) + parts[len(parts)-1] + "\n")
+		previous = dirs
+	}
+
+	return out.String()
+}
+
+// Markdown renders the comparison as Markdown
+func Markdown(c *Comparison) string {
+	var out strings.Builder
+
+	out.WriteString(fmt.Sprintf("# Implementation comparison: %s\n\n", c.Project))
+	out.WriteString(fmt.Sprintf("Generated %s\n\n", c.CreatedAt.Format("2006-01-02 15:04:05")))
+
+	// Overview table with one column per implementation
+	out.WriteString("## Overview\n\n| |")
+	for _, impl := range c.Implementations {
+		out.WriteString(" " + markdownCell(impl.Branch) + " |")
+	}
+	out.WriteString("\n|---|")
+	for range c.Implementations {
+		out.WriteString("---|")
+	}
+	out.WriteString("\n")
+	for _, r := range c.overview() {
+		out.WriteString("| " + markdownCell(r.Label) + " |")
+		for _, value := range r.Values {
+			out.WriteString(" " + markdownCell(value) + " |")
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("\n")
+
+	if c.Summary != "" {
+		out.WriteString("## Architectural differences\n\n")
+		out.WriteString(strings.TrimSpace(c.Summary) + "\n\n")
+	}
+
+	for _, impl := range c.Implementations {
+		out.WriteString(fmt.Sprintf("## %s (%s)\n\n", impl.Branch, impl.Framework))
+		if impl.Description != "" {
+			out.WriteString(impl.Description + "\n\n")
+		}
+
+		out.WriteString("### Languages\n\n")
+		if len(impl.Languages) == 0 {
+			out.WriteString("No source files found.\n\n")
+		} else {
+			out.WriteString("| Language | Files | Lines |\n|---|---|---|\n")
+			for _, stats := range impl.Languages {
+				out.WriteString(fmt.Sprintf("| %s | %d | %d |\n", markdownCell(stats.Language), stats.Files, stats.Lines))
+			}
+			out.WriteString("\n")
+		}
+
+		out.WriteString("### Dependencies\n\n")
+		if len(impl.Manifests) == 0 {
+			out.WriteString("No dependency manifests found.\n\n")
+		} else {
+			for _, manifest := range impl.Manifests {
+				out.WriteString(fmt.Sprintf("- `%s` (%d): %s\n", manifest.Path, len(manifest.Dependencies), strings.Join(manifest.Dependencies, ", ")))
+			}
+			out.WriteString("\n")
+		}
+
+		out.WriteString("### Files\n\n```\n")
+		out.WriteString(FileTree(impl.Files, maxTreeFiles))
+		out.WriteString("```\n\n")
+	}
+
+	return out.String()
+}
+
+// markdownCell escapes a value for use in a Markdown table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// htmlTemplate renders the comparison with the implementations in columns side by side
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"fileTree": func(files []string) string { return FileTree(files, maxTreeFiles) },
+	"join":     strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Implementation comparison: {{.Comparison.Project}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+.columns { display: grid; grid-template-columns: repeat({{len .Comparison.Implementations}}, minmax(0, 1fr)); gap: 1.5em; }
+.column { border: 1px solid #ddd; border-radius: 4px; padding: 0 1em; overflow-x: auto; }
+pre { background: #f7f7f7; padding: 0.8em; overflow-x: auto; }
+.summary { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Implementation comparison: {{.Comparison.Project}}</h1>
+<p>Generated {{.Comparison.CreatedAt.Format "2006-01-02 15:04:05"}}</p>
+
+<h2>Overview</h2>
+<table>
+<tr><th></th>{{range .Comparison.Implementations}}<th>{{.Branch}}</th>{{end}}</tr>
+{{range .Overview}}<tr><th>{{.Label}}</th>{{range .Values}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{if .Comparison.Summary}}
+<h2>Architectural differences</h2>
+<div class="summary">{{.Comparison.Summary}}</div>
+{{end}}
+<div class="columns">
+{{range .Comparison.Implementations}}<div class="column">
+<h2>{{.Branch}} ({{.Framework}})</h2>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+<h3>Languages</h3>
+{{if .Languages}}<table>
+<tr><th>Language</th><th>Files</th><th>Lines</th></tr>
+{{range .Languages}}<tr><td>{{.Language}}</td><td>{{.Files}}</td><td>{{.Lines}}</td></tr>
+{{end}}</table>{{else}}<p>No source files found.</p>{{end}}
+<h3>Dependencies</h3>
+{{if .Manifests}}<ul>
+{{range .Manifests}}<li><code>{{.Path}}</code> ({{len .Dependencies}}): {{join .Dependencies ", "}}</li>
+{{end}}</ul>{{else}}<p>No dependency manifests found.</p>{{end}}
+<h3>Files</h3>
+<pre>{{fileTree .Files}}</pre>
+</div>
+{{end}}</div>
+</body>
+</html>
+`))
+
+// HTML renders the comparison as a standalone HTML page
+func HTML(c *Comparison) (string, error) {
+	var out strings.Builder
+	data := struct {
+		Comparison *Comparison
+		Overview   []row
+	}{c, c.overview()}
+
+	if err := htmlTemplate.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return out.String(), nil
+}
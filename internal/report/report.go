@@ -0,0 +1,132 @@
+// Package report builds side-by-side comparison reports of implementations
+package report
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/source"
+)
+
+// LanguageStats is the amount of code written in a language
+type LanguageStats struct {
+	// Name of the language
+	Language string
+
+	// Number of files in the language
+	Files int
+
+	// Number of non-blank lines in the language
+	Lines int
+}
+
+// Implementation describes one branch in a comparison
+type Implementation struct {
+	// Branch the code was checked out from
+	Branch string
+
+	// Framework used for the implementation
+	Framework string
+
+	// Description of the implementation or feature
+	Description string
+
+	// Evaluation score (0-100)
+	Score int
+
+	// Metrics recorded for the implementation
+	Metrics map[string]float64
+
+	// Paths of the source files, relative to the code directory
+	Files []string
+
+	// Languages sorted by number of lines, largest first
+	Languages []LanguageStats
+
+	// Dependency manifests at the root of the code
+	Manifests []source.Manifest
+}
+
+// Comparison is a side-by-side comparison of implementations
+type Comparison struct {
+	// Name of the project
+	Project string
+
+	// When the comparison was made
+	CreatedAt time.Time
+
+	// Implementations being compared
+	Implementations []Implementation
+
+	// AI-written summary of the architectural differences (may be empty)
+	Summary string
+}
+
+// Inspect reads the file tree, language breakdown and dependency manifests of the code
+// in codeDir into impl
+func Inspect(ctx context.Context, codeDir string, impl *Implementation) error {
+	languages := make(map[string]*LanguageStats)
+	impl.Files = nil
+
+	err := source.Walk(ctx, codeDir, func(path, rel string) error {
+		impl.Files = append(impl.Files, rel)
+
+		name, kind := source.Language(path)
+		if kind == source.KindOther {
+			return nil
+		}
+
+		lines, err := source.CountLines(path)
+		if err != nil {
+			return err
+		}
+		if languages[name] == nil {
+			languages[name] = &LanguageStats{Language: name}
+		}
+		languages[name].Files++
+		languages[name].Lines += lines
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read files: %w", err)
+	}
+	sort.Strings(impl.Files)
+
+	impl.Languages = nil
+	for _, stats := range languages {
+		impl.Languages = append(impl.Languages, *stats)
+	}
+	sort.Slice(impl.Languages, func(i, j int) bool {
+		if impl.Languages[i].Lines != impl.Languages[j].Lines {
+			return impl.Languages[i].Lines > impl.Languages[j].Lines
+		}
+		return impl.Languages[i].Language < impl.Languages[j].Language
+	})
+
+	impl.Manifests, err = source.ReadManifests(codeDir)
+	if err != nil {
+		return fmt.Errorf("failed to read dependency manifests: %w", err)
+	}
+
+	return nil
+}
+
+// Lines returns the total number of lines of code and markup
+func (i *Implementation) Lines() int {
+	total := 0
+	for _, stats := range i.Languages {
+		total += stats.Lines
+	}
+	return total
+}
+
+// Dependencies returns the total number of declared dependencies
+func (i *Implementation) Dependencies() int {
+	total := 0
+	for _, manifest := range i.Manifests {
+		total += len(manifest.Dependencies)
+	}
+	return total
+}
@@ -0,0 +1,124 @@
+package report_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/report"
+	"github.com/fr0g-66723067/cc/internal/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"package.json":              `{"dependencies": {"react": "^18"}}`,
+		"src/App.js":                "import React from 'react'\n\nexport default App\n",
+		"src/components/Todo.js":    "export default Todo\n",
+		"src/App.css":               ".app {}\n",
+		"node_modules/react/one.js": "skipped\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	impl := report.Implementation{Branch: "impl-react"}
+	require.NoError(t, report.Inspect(context.Background(), dir, &impl))
+
+	assert.Equal(t, []string{"package.json", "src/App.css", "src/App.js", "src/components/Todo.js"}, impl.Files)
+	assert.Equal(t, []report.LanguageStats{
+		{Language: "JavaScript", Files: 2, Lines: 3},
+		{Language: "CSS", Files: 1, Lines: 1},
+	}, impl.Languages)
+	assert.Equal(t, []source.Manifest{{Path: "package.json", Dependencies: []string{"react"}}}, impl.Manifests)
+	assert.Equal(t, 4, impl.Lines())
+	assert.Equal(t, 1, impl.Dependencies())
+}
+
+func TestFileTree(t *testing.T) {
+	files := []string{"package.json", "src/App.js", "src/components/Todo.js", "src/index.js", "tests/App.test.js"}
+
+	expected := "package.json\n" +
+		"src/\n" +
+		"  App.js\n" +
+		"  components/\n" +
+		"    Todo.js\n" +
+		"  index.js\n" +
+		"tests/\n" +
+		"  App.test.js\n"
+	assert.Equal(t, expected, report.FileTree(files, 10))
+
+	// Long trees are cut off
+	assert.Equal(t, "package.json\nsrc/\n  App.js\n... and 3 more files\n", report.FileTree(files, 2))
+}
+
+// testComparison returns a comparison of two implementations
+func testComparison() *report.Comparison {
+	return &report.Comparison{
+		Project:   "todo-app",
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Summary:   "React uses hooks, Vue uses the <script setup> syntax.",
+		Implementations: []report.Implementation{
+			{
+				Branch:    "impl-react",
+				Framework: "react",
+				Score:     86,
+				Metrics:   map[string]float64{"build_seconds": 12.3456, "tests_passed": 4},
+				Files:     []string{"package.json", "src/App.js"},
+				Languages: []report.LanguageStats{{Language: "JavaScript", Files: 1, Lines: 20}},
+				Manifests: []source.Manifest{{Path: "package.json", Dependencies: []string{"react", "react-dom"}}},
+			},
+			{
+				Branch:    "impl-vue",
+				Framework: "vue",
+				Score:     70,
+				Metrics:   map[string]float64{"build_seconds": 8},
+				Files:     []string{"package.json", "src/App.vue"},
+				Languages: []report.LanguageStats{{Language: "Vue", Files: 1, Lines: 30}},
+				Manifests: []source.Manifest{{Path: "package.json", Dependencies: []string{"vue"}}},
+			},
+		},
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	output, err := report.Render(testComparison(), "md")
+	require.NoError(t, err)
+
+	assert.Contains(t, output, "# Implementation comparison: todo-app\n")
+	assert.Contains(t, output, "| | impl-react | impl-vue |\n|---|---|---|\n")
+	assert.Contains(t, output, "| Score | 86 | 70 |\n")
+	assert.Contains(t, output, "| Lines of code | 20 | 30 |\n")
+	assert.Contains(t, output, "| build_seconds | 12.35 | 8 |\n")
+	assert.Contains(t, output, "| tests_passed | 4 | - |\n")
+	assert.Contains(t, output, "## Architectural differences\n\nReact uses hooks")
+	assert.Contains(t, output, "## impl-vue (vue)\n")
+	assert.Contains(t, output, "| Vue | 1 | 30 |\n")
+	assert.Contains(t, output, "- `package.json` (2): react, react-dom\n")
+	assert.Contains(t, output, "```\npackage.json\nsrc/\n  App.vue\n```\n")
+}
+
+func TestHTML(t *testing.T) {
+	output, err := report.Render(testComparison(), report.FormatHTML)
+	require.NoError(t, err)
+
+	assert.Contains(t, output, "<title>Implementation comparison: todo-app</title>")
+	assert.Contains(t, output, "grid-template-columns: repeat(2, minmax(0, 1fr))")
+	assert.Contains(t, output, "<tr><th>Score</th><td>86</td><td>70</td></tr>")
+	assert.Contains(t, output, "<h2>impl-react (react)</h2>")
+	assert.Contains(t, output, "<code>package.json</code> (1): vue")
+
+	// AI output is escaped
+	assert.Contains(t, output, "Vue uses the &lt;script setup&gt; syntax.")
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := report.Render(testComparison(), "pdf")
+	assert.Error(t, err)
+}
@@ -0,0 +1,186 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fr0g-66723067/cc/pkg/models"
+)
+
+// Status is the state of the generation of a framework
+type Status string
+
+const (
+	// StatusPending indicates the framework has not been generated yet
+	StatusPending Status = "pending"
+	// StatusRunning indicates the framework is being generated
+	StatusRunning Status = "running"
+	// StatusCompleted indicates the implementation of the framework was generated and committed
+	StatusCompleted Status = "completed"
+	// StatusFailed indicates the generation of the framework failed
+	StatusFailed Status = "failed"
+)
+
+// Framework is the state of one framework of a generation run
+type Framework struct {
+	// Name of the framework
+	Name string `json:"name"`
+
+	// Branch the implementation is generated on, once created
+	Branch string `json:"branch,omitempty"`
+
+	// Worktree the implementation is generated in, once created
+	Worktree string `json:"worktree,omitempty"`
+
+	// Status of the generation
+	Status Status `json:"status"`
+
+	// Error of the last failed attempt
+	Error string `json:"error,omitempty"`
+
+	// Generated implementation, once completed
+	Implementation *models.Implementation `json:"implementation,omitempty"`
+}
+
+// Record is the persisted state of a run generating implementations with several frameworks
+type Record struct {
+	// Identifier of the run
+	ID string `json:"id"`
+
+	// Name of the project the implementations belong to
+	Project string `json:"project"`
+
+	// Description the implementations are generated from
+	Description string `json:"description"`
+
+	// Branch the implementation branches are created from
+	BaseBranch string `json:"baseBranch"`
+
+	// Whether frameworks are generated in parallel
+	Parallel bool `json:"parallel"`
+
+	// Frameworks in the order they were requested
+	Frameworks []*Framework `json:"frameworks"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Completed returns whether every framework of the run was generated
+func (r *Record) Completed() bool {
+	for _, framework := range r.Frameworks {
+		if framework.Status != StatusCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// Store persists run records as JSON files in a directory, one per run
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore creates a store keeping its records in dir, which is created on the first save
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Create creates and saves the record of a new run generating frameworks, all pending
+func (s *Store) Create(project, description, baseBranch string, frameworks []string, parallel bool) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	record := &Record{
+		ID:          now.Format("20060102-150405"),
+		Project:     project,
+		Description: description,
+		BaseBranch:  baseBranch,
+		Parallel:    parallel,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	for _, name := range frameworks {
+		record.Frameworks = append(record.Frameworks, &Framework{Name: name, Status: StatusPending})
+	}
+
+	// Runs started within the same second get a suffix
+	for i := 2; ; i++ {
+		if _, err := os.Stat(s.path(record.ID)); os.IsNotExist(err) {
+			break
+		}
+		record.ID = fmt.Sprintf("%s-%d", now.Format("20060102-150405"), i)
+	}
+
+	if err := s.save(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Load loads the record of a run
+func (s *Store) Load(id string) (*Record, error) {
+	if id == "" || filepath.Base(id) != id {
+		return nil, fmt.Errorf("invalid run id: %s", id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("run %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run %s: %w", id, err)
+	}
+
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse run %s: %w", id, err)
+	}
+	return &record, nil
+}
+
+// Update changes a record with fn and saves it. Records shared by goroutines must only be
+// changed through Update.
+func (s *Store) Update(record *Record, fn func(record *Record)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(record)
+	record.UpdatedAt = time.Now()
+	return s.save(record)
+}
+
+// path returns the file of the record of a run
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// save writes a record, replacing the previous version atomically
+func (s *Store) save(record *Record) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create runs directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run %s: %w", record.ID, err)
+	}
+
+	tmp := s.path(record.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write run %s: %w", record.ID, err)
+	}
+	if err := os.Rename(tmp, s.path(record.ID)); err != nil {
+		return fmt.Errorf("failed to write run %s: %w", record.ID, err)
+	}
+	return nil
+}
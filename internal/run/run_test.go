@@ -0,0 +1,67 @@
+package run_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fr0g-66723067/cc/internal/run"
+	"github.com/fr0g-66723067/cc/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStore tests creating, updating and loading run records
+func TestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "runs")
+	store := run.NewStore(dir)
+
+	record, err := store.Create("todo", "A todo app", "main", []string{"react", "vue"}, true)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, record.ID+".json"))
+	require.Len(t, record.Frameworks, 2)
+	assert.Equal(t, run.StatusPending, record.Frameworks[0].Status)
+	assert.False(t, record.Completed())
+
+	// Runs started within the same second get distinct ids
+	other, err := store.Create("todo", "A todo app", "main", []string{"react"}, false)
+	require.NoError(t, err)
+	assert.NotEqual(t, record.ID, other.ID)
+
+	require.NoError(t, store.Update(record, func(r *run.Record) {
+		for _, framework := range r.Frameworks {
+			framework.Status = run.StatusCompleted
+			framework.Branch = "impl-" + framework.Name
+			framework.Implementation = &models.Implementation{Framework: framework.Name, BranchName: framework.Branch}
+		}
+	}))
+
+	loaded, err := store.Load(record.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "todo", loaded.Project)
+	assert.Equal(t, "main", loaded.BaseBranch)
+	assert.True(t, loaded.Parallel)
+	assert.True(t, loaded.Completed())
+	assert.Equal(t, "impl-vue", loaded.Frameworks[1].Implementation.BranchName)
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+// TestStoreLoadErrors tests loading missing, invalid and corrupt records
+func TestStoreLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	store := run.NewStore(dir)
+
+	_, err := store.Load("missing")
+	assert.ErrorContains(t, err, "run missing not found")
+
+	_, err = store.Load("../config")
+	assert.ErrorContains(t, err, "invalid run id")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "corrupt.json"), []byte("{"), 0644))
+	_, err = store.Load("corrupt")
+	assert.ErrorContains(t, err, "failed to parse run corrupt")
+}
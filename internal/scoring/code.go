@@ -0,0 +1,175 @@
+package scoring
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fr0g-66723067/cc/internal/source"
+)
+
+// Scorers based on reading the code
+
+// linesOfCodeScorer rates the size of the code base, preferring smaller code bases
+type linesOfCodeScorer struct {
+	thresholds thresholds
+}
+
+// Name returns the scorer's name
+func (s *linesOfCodeScorer) Name() string {
+	return "lines_of_code"
+}
+
+// Score counts the non-blank lines of code and markup
+func (s *linesOfCodeScorer) Score(ctx context.Context, codeDir string, metrics map[string]float64) (float64, error) {
+	lines := 0
+	err := source.Walk(ctx, codeDir, func(path, rel string) error {
+		if _, kind := source.Language(path); kind != source.KindCode && kind != source.KindMarkup {
+			return nil
+		}
+		count, err := source.CountLines(path)
+		lines += count
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count lines of code: %w", err)
+	}
+	if lines == 0 {
+		return 0, ErrNotApplicable
+	}
+
+	metrics["lines_of_code"] = float64(lines)
+	return s.thresholds.scaleDown(float64(lines)), nil
+}
+
+// dependenciesScorer rates the number of declared dependencies, preferring fewer
+type dependenciesScorer struct {
+	thresholds thresholds
+}
+
+// Name returns the scorer's name
+func (s *dependenciesScorer) Name() string {
+	return "dependencies"
+}
+
+// Score counts the dependencies declared in the manifests at the root of codeDir
+func (s *dependenciesScorer) Score(ctx context.Context, codeDir string, metrics map[string]float64) (float64, error) {
+	manifests, err := source.ReadManifests(codeDir)
+	if err != nil {
+		return 0, err
+	}
+	if len(manifests) == 0 {
+		return 0, ErrNotApplicable
+	}
+
+	count := 0
+	for _, manifest := range manifests {
+		count += len(manifest.Dependencies)
+	}
+
+	metrics["dependency_count"] = float64(count)
+	return s.thresholds.scaleDown(float64(count)), nil
+}
+
+// complexityScorer rates the average cyclomatic complexity of functions
+type complexityScorer struct {
+	thresholds thresholds
+}
+
+// Name returns the scorer's name
+func (s *complexityScorer) Name() string {
+	return "complexity"
+}
+
+var (
+	// Keywords and operators adding a path through a function
+	decisionPattern = regexp.MustCompile(`\b(?:if|for|while|case|catch|elif|except|unless|until|when)\b|&&|\|\|`)
+
+	// Function declarations per language family
+	jsFunctionPattern     = regexp.MustCompile(`\bfunction\b|=>`)
+	defFunctionPattern    = regexp.MustCompile(`\bdef\b`)
+	goFunctionPattern     = regexp.MustCompile(`\bfunc\b`)
+	methodFunctionPattern = regexp.MustCompile(`(?m)^\s*(?:(?:public|private|protected|internal|static|final|override|abstract|async)\s+)+[\w<>\[\],.? ]*\(|\bfun\b|\bfunction\b`)
+)
+
+// functionPattern returns the pattern matching function declarations in files with the extension
+func functionPattern(ext string) *regexp.Regexp {
+	switch ext {
+	case ".py", ".rb":
+		return defFunctionPattern
+	case ".go":
+		return goFunctionPattern
+	case ".java", ".kt", ".php", ".cs":
+		return methodFunctionPattern
+	default:
+		return jsFunctionPattern
+	}
+}
+
+// Score approximates the average cyclomatic complexity of functions by counting
+// decision points and function declarations. Comments and strings are not excluded,
+// which is accurate enough to compare implementations with each other.
+func (s *complexityScorer) Score(ctx context.Context, codeDir string, metrics map[string]float64) (float64, error) {
+	decisions, functions := 0, 0
+	err := source.Walk(ctx, codeDir, func(path, rel string) error {
+		if _, kind := source.Language(path); kind != source.KindCode {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		code := string(data)
+		decisions += len(decisionPattern.FindAllString(code, -1))
+
+		// Code outside of functions counts as one function per file
+		fileFunctions := len(functionPattern(strings.ToLower(filepath.Ext(path))).FindAllString(code, -1))
+		if fileFunctions == 0 {
+			fileFunctions = 1
+		}
+		functions += fileFunctions
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure complexity: %w", err)
+	}
+	if functions == 0 {
+		return 0, ErrNotApplicable
+	}
+
+	complexity := 1 + float64(decisions)/float64(functions)
+	metrics["cyclomatic_complexity"] = complexity
+	return s.thresholds.scaleDown(complexity), nil
+}
+
+func init() {
+	// Up to 1000 lines score 100, 50000 lines or more score 0
+	Register("lines_of_code", func(config map[string]string) (Scorer, error) {
+		t, err := parseThresholds(config, thresholds{good: 1000, bad: 50000})
+		if err != nil {
+			return nil, err
+		}
+		return &linesOfCodeScorer{thresholds: t}, nil
+	})
+
+	// Up to 10 dependencies score 100, 200 or more score 0
+	Register("dependencies", func(config map[string]string) (Scorer, error) {
+		t, err := parseThresholds(config, thresholds{good: 10, bad: 200})
+		if err != nil {
+			return nil, err
+		}
+		return &dependenciesScorer{thresholds: t}, nil
+	})
+
+	// An average complexity up to 3 scores 100, 20 or more scores 0
+	Register("complexity", func(config map[string]string) (Scorer, error) {
+		t, err := parseThresholds(config, thresholds{good: 3, bad: 20})
+		if err != nil {
+			return nil, err
+		}
+		return &complexityScorer{thresholds: t}, nil
+	})
+}
@@ -0,0 +1,113 @@
+package scoring
+
+import (
+	"context"
+)
+
+// Scorers based on the metrics recorded by verifying the implementation
+
+// verificationScorer uses the score of the build and test verification
+type verificationScorer struct{}
+
+// Name returns the scorer's name
+func (s *verificationScorer) Name() string {
+	return "verification"
+}
+
+// Score returns the verification score
+func (s *verificationScorer) Score(ctx context.Context, codeDir string, metrics map[string]float64) (float64, error) {
+	score, ok := metrics["verify_score"]
+	if !ok {
+		return 0, ErrNotApplicable
+	}
+	return score, nil
+}
+
+// testPassRateScorer rates the share of passing tests
+type testPassRateScorer struct{}
+
+// Name returns the scorer's name
+func (s *testPassRateScorer) Name() string {
+	return "test_pass_rate"
+}
+
+// Score returns the percentage of passing tests
+func (s *testPassRateScorer) Score(ctx context.Context, codeDir string, metrics map[string]float64) (float64, error) {
+	passed, failed := metrics["tests_passed"], metrics["tests_failed"]
+	if passed+failed == 0 {
+		return 0, ErrNotApplicable
+	}
+
+	rate := passed / (passed + failed)
+	metrics["test_pass_rate"] = rate
+	return rate * 100, nil
+}
+
+// buildTimeScorer rates how long the build takes
+type buildTimeScorer struct {
+	thresholds thresholds
+}
+
+// Name returns the scorer's name
+func (s *buildTimeScorer) Name() string {
+	return "build_time"
+}
+
+// Score rates the build duration, scoring failed builds 0
+func (s *buildTimeScorer) Score(ctx context.Context, codeDir string, metrics map[string]float64) (float64, error) {
+	seconds, ok := metrics["build_seconds"]
+	if !ok {
+		return 0, ErrNotApplicable
+	}
+	if metrics["build_passed"] == 0 {
+		return 0, nil
+	}
+	return s.thresholds.scaleDown(seconds), nil
+}
+
+// bundleSizeScorer rates the size of the build output
+type bundleSizeScorer struct {
+	thresholds thresholds
+}
+
+// Name returns the scorer's name
+func (s *bundleSizeScorer) Name() string {
+	return "bundle_size"
+}
+
+// Score rates the bundle size
+func (s *bundleSizeScorer) Score(ctx context.Context, codeDir string, metrics map[string]float64) (float64, error) {
+	bytes, ok := metrics["bundle_bytes"]
+	if !ok {
+		return 0, ErrNotApplicable
+	}
+	return s.thresholds.scaleDown(bytes), nil
+}
+
+func init() {
+	Register("verification", func(config map[string]string) (Scorer, error) {
+		return &verificationScorer{}, nil
+	})
+
+	Register("test_pass_rate", func(config map[string]string) (Scorer, error) {
+		return &testPassRateScorer{}, nil
+	})
+
+	// Builds taking up to 30 seconds score 100, builds taking 15 minutes or more score 0
+	Register("build_time", func(config map[string]string) (Scorer, error) {
+		t, err := parseThresholds(config, thresholds{good: 30, bad: 900})
+		if err != nil {
+			return nil, err
+		}
+		return &buildTimeScorer{thresholds: t}, nil
+	})
+
+	// Bundles up to 250 KB score 100, bundles of 20 MB or more score 0
+	Register("bundle_size", func(config map[string]string) (Scorer, error) {
+		t, err := parseThresholds(config, thresholds{good: 250 << 10, bad: 20 << 20})
+		if err != nil {
+			return nil, err
+		}
+		return &bundleSizeScorer{thresholds: t}, nil
+	})
+}
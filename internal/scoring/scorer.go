@@ -0,0 +1,178 @@
+// Package scoring rates implementations by combining weighted scorers
+package scoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNotApplicable is returned by scorers that cannot measure an implementation,
+// for example the test pass rate of an implementation without tests
+var ErrNotApplicable = errors.New("scorer not applicable")
+
+// Scorer rates one aspect of an implementation
+type Scorer interface {
+	// Name returns the scorer's name
+	Name() string
+
+	// Score rates the code in codeDir from 0 (worst) to 100 (best). Scorers may use the
+	// metrics recorded so far and add the raw values they measure to them.
+	Score(ctx context.Context, codeDir string, metrics map[string]float64) (float64, error)
+}
+
+// Factory creates a scorer based on configuration
+type Factory func(config map[string]string) (Scorer, error)
+
+var scorers = make(map[string]Factory)
+
+// Register registers a scorer factory
+func Register(name string, factory Factory) {
+	scorers[name] = factory
+}
+
+// Create creates a scorer with the given name
+func Create(name string, config map[string]string) (Scorer, error) {
+	factory, exists := scorers[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown scorer: %s", name)
+	}
+	return factory(config)
+}
+
+// DefaultWeights returns the weights of the built-in scorers. Whether the code builds
+// and passes its tests matters most.
+func DefaultWeights() map[string]float64 {
+	return map[string]float64{
+		"verification":   4,
+		"test_pass_rate": 3,
+		"complexity":     2,
+		"lines_of_code":  1,
+		"dependencies":   1,
+		"build_time":     1,
+		"bundle_size":    1,
+	}
+}
+
+// Engine combines scorers into a single score
+type Engine struct {
+	scorers []Scorer
+	weights map[string]float64
+}
+
+// NewEngine creates an engine using the given weights on top of the default weights.
+// A weight of 0 disables a scorer. Scorer configuration is read from config keys
+// prefixed with the scorer's name and an underscore (e.g. "lines_of_code_good").
+func NewEngine(weights map[string]float64, config map[string]string) (*Engine, error) {
+	combined := DefaultWeights()
+	for name, weight := range weights {
+		if weight < 0 {
+			return nil, fmt.Errorf("weight of scorer %s must not be negative", name)
+		}
+		combined[name] = weight
+	}
+
+	// Create the scorers in a stable order
+	var names []string
+	for name, weight := range combined {
+		if weight > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	engine := &Engine{weights: combined}
+	for _, name := range names {
+		scorer, err := Create(name, scorerConfig(name, config))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create scorer: %w", err)
+		}
+		engine.scorers = append(engine.scorers, scorer)
+	}
+
+	return engine, nil
+}
+
+// Score runs all scorers on the code in codeDir and returns their weighted average,
+// rounded to an integer. Scorers that are not applicable are left out. The score of
+// each scorer is added to metrics as "score_<name>". It returns false if no scorer
+// was applicable.
+func (e *Engine) Score(ctx context.Context, codeDir string, metrics map[string]float64) (int, bool, error) {
+	var total, weights float64
+	for _, scorer := range e.scorers {
+		score, err := scorer.Score(ctx, codeDir, metrics)
+		if errors.Is(err, ErrNotApplicable) {
+			continue
+		}
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to run scorer %s: %w", scorer.Name(), err)
+		}
+
+		score = math.Max(0, math.Min(100, score))
+		metrics["score_"+scorer.Name()] = score
+
+		weight := e.weights[scorer.Name()]
+		total += weight * score
+		weights += weight
+	}
+
+	if weights == 0 {
+		return 0, false, nil
+	}
+	return int(math.Round(total / weights)), true, nil
+}
+
+// scorerConfig returns the config keys for a scorer with its name prefix removed
+func scorerConfig(name string, config map[string]string) map[string]string {
+	result := make(map[string]string)
+	prefix := name + "_"
+	for key, value := range config {
+		if strings.HasPrefix(key, prefix) {
+			result[strings.TrimPrefix(key, prefix)] = value
+		}
+	}
+	return result
+}
+
+// thresholds are the values of a metric that score 100 (good) and 0 (bad)
+type thresholds struct {
+	good float64
+	bad  float64
+}
+
+// parseThresholds reads the "good" and "bad" config keys, falling back to defaults
+func parseThresholds(config map[string]string, defaults thresholds) (thresholds, error) {
+	result := defaults
+	for key, target := range map[string]*float64{"good": &result.good, "bad": &result.bad} {
+		value, ok := config[key]
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return thresholds{}, fmt.Errorf("invalid %s threshold %q: %w", key, value, err)
+		}
+		*target = parsed
+	}
+
+	if result.good <= 0 || result.bad <= result.good {
+		return thresholds{}, fmt.Errorf("thresholds must satisfy 0 < good < bad (good %g, bad %g)", result.good, result.bad)
+	}
+	return result, nil
+}
+
+// scaleDown scores a value where lower is better: 100 at or below the good threshold,
+// 0 at or above the bad threshold, interpolated on a logarithmic scale in between
+func (t thresholds) scaleDown(value float64) float64 {
+	switch {
+	case value <= t.good:
+		return 100
+	case value >= t.bad:
+		return 0
+	}
+	return 100 * (math.Log(t.bad) - math.Log(value)) / (math.Log(t.bad) - math.Log(t.good))
+}
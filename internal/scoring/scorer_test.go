@@ -0,0 +1,172 @@
+package scoring_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fr0g-66723067/cc/internal/scoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates files in a temporary directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+// score runs a single built-in scorer
+func score(t *testing.T, name string, config map[string]string, codeDir string, metrics map[string]float64) (float64, error) {
+	scorer, err := scoring.Create(name, config)
+	require.NoError(t, err)
+	assert.Equal(t, name, scorer.Name())
+	return scorer.Score(context.Background(), codeDir, metrics)
+}
+
+func TestLinesOfCodeScorer(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"src/App.js":                "import React from 'react'\n\nexport default App\n",
+		"src/App.css":               ".app {}\n",
+		"README.md":                 "not code\n",
+		"node_modules/react/one.js": "skipped\n",
+	})
+
+	metrics := make(map[string]float64)
+	result, err := score(t, "lines_of_code", map[string]string{"good": "2", "bad": "20"}, dir, metrics)
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, metrics["lines_of_code"])
+	assert.InDelta(t, 82.4, result, 0.1)
+
+	// Directories without code cannot be scored
+	_, err = score(t, "lines_of_code", nil, t.TempDir(), metrics)
+	assert.ErrorIs(t, err, scoring.ErrNotApplicable)
+}
+
+func TestDependenciesScorer(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"package.json":     `{"dependencies": {"react": "^18", "react-dom": "^18"}, "devDependencies": {"vite": "^5"}}`,
+		"requirements.txt": "# comment\nflask==3.0\n-r base.txt\npytest\n",
+		"go.mod":           "module example\n\nrequire github.com/a/b v1.0.0\n\nrequire (\n\tgithub.com/c/d v1.2.0\n\tgithub.com/e/f v0.1.0 // indirect\n)\n",
+	})
+
+	metrics := make(map[string]float64)
+	result, err := score(t, "dependencies", nil, dir, metrics)
+	require.NoError(t, err)
+	assert.Equal(t, 8.0, metrics["dependency_count"])
+	assert.Equal(t, 100.0, result)
+
+	_, err = score(t, "dependencies", nil, t.TempDir(), metrics)
+	assert.ErrorIs(t, err, scoring.ErrNotApplicable)
+}
+
+func TestComplexityScorer(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app.py": "def simple():\n    return 1\n\ndef branchy(x):\n    if x and x > 1:\n        return 1\n    elif x:\n        return 2\n    for i in range(3):\n        pass\n",
+		"app.js": "function check(a, b) {\n  if (a && b) { return 1 }\n  return a || b\n}\nconst f = () => 1\n",
+	})
+
+	// 6 decision points in 4 functions
+	metrics := make(map[string]float64)
+	result, err := score(t, "complexity", nil, dir, metrics)
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, metrics["cyclomatic_complexity"])
+	assert.Equal(t, 100.0, result)
+}
+
+func TestMetricScorers(t *testing.T) {
+	metrics := map[string]float64{
+		"verify_score":  70,
+		"tests_passed":  9,
+		"tests_failed":  1,
+		"build_passed":  1,
+		"build_seconds": 10,
+		"bundle_bytes":  40 << 20,
+	}
+
+	result, err := score(t, "verification", nil, "", metrics)
+	require.NoError(t, err)
+	assert.Equal(t, 70.0, result)
+
+	result, err = score(t, "test_pass_rate", nil, "", metrics)
+	require.NoError(t, err)
+	assert.InDelta(t, 90.0, result, 0.001)
+	assert.InDelta(t, 0.9, metrics["test_pass_rate"], 0.001)
+
+	result, err = score(t, "build_time", nil, "", metrics)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, result)
+
+	result, err = score(t, "bundle_size", nil, "", metrics)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, result)
+
+	// Failed builds score 0 regardless of how long they took
+	metrics["build_passed"] = 0
+	result, err = score(t, "build_time", nil, "", metrics)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, result)
+
+	// Scorers without their metrics are not applicable
+	for _, name := range []string{"verification", "test_pass_rate", "build_time", "bundle_size"} {
+		_, err := score(t, name, nil, "", map[string]float64{})
+		assert.ErrorIs(t, err, scoring.ErrNotApplicable, name)
+	}
+}
+
+func TestInvalidThresholds(t *testing.T) {
+	_, err := scoring.Create("lines_of_code", map[string]string{"good": "many"})
+	assert.Error(t, err)
+
+	_, err = scoring.Create("build_time", map[string]string{"good": "60", "bad": "10"})
+	assert.Error(t, err)
+
+	_, err = scoring.Create("unknown", nil)
+	assert.Error(t, err)
+}
+
+func TestEngine(t *testing.T) {
+	metrics := map[string]float64{
+		"verify_score": 100,
+		"tests_passed": 1,
+		"tests_failed": 1,
+	}
+
+	// Only verification (weight 4) and the test pass rate (weight 1) apply
+	engine, err := scoring.NewEngine(map[string]float64{"test_pass_rate": 1, "complexity": 0}, nil)
+	require.NoError(t, err)
+
+	score, ok, err := engine.Score(context.Background(), t.TempDir(), metrics)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 90, score)
+	assert.Equal(t, 100.0, metrics["score_verification"])
+	assert.Equal(t, 50.0, metrics["score_test_pass_rate"])
+
+	// Scorer configuration is passed on without the scorer name prefix
+	dir := writeFiles(t, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	engine, err = scoring.NewEngine(nil, map[string]string{"lines_of_code_good": "1", "lines_of_code_bad": "4"})
+	require.NoError(t, err)
+	metrics = make(map[string]float64)
+	_, ok, err = engine.Score(context.Background(), dir, metrics)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.InDelta(t, 50.0, metrics["score_lines_of_code"], 0.01)
+
+	// Nothing applies to an empty directory
+	_, ok, err = engine.Score(context.Background(), t.TempDir(), make(map[string]float64))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Weights must not be negative and scorers must exist
+	_, err = scoring.NewEngine(map[string]float64{"complexity": -1}, nil)
+	assert.Error(t, err)
+	_, err = scoring.NewEngine(map[string]float64{"unknown": 1}, nil)
+	assert.Error(t, err)
+}
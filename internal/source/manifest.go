@@ -0,0 +1,133 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Manifest is a file declaring the dependencies of a project
+type Manifest struct {
+	// Path of the manifest relative to the code directory
+	Path string
+
+	// Names of the declared dependencies
+	Dependencies []string
+}
+
+var (
+	gemPattern           = regexp.MustCompile(`(?m)^\s*gem\s+['"]([^'"]+)['"]`)
+	mavenPattern         = regexp.MustCompile(`(?s)<dependency>.*?<groupId>\s*([^<\s]+)\s*</groupId>.*?<artifactId>\s*([^<\s]+)\s*</artifactId>.*?</dependency>`)
+	goRequirePattern     = regexp.MustCompile(`(?m)^require[ \t]+([^\s(]+)[ \t]+\S+`)
+	goRequireBlock       = regexp.MustCompile(`(?s)require\s*\((.*?)\)`)
+	goRequireBlockDep    = regexp.MustCompile(`(?m)^\s*([^\s/)][^\s)]*)\s+v\S+`)
+	requirementSeparator = regexp.MustCompile(`[\s<>=!~;\[@]`)
+)
+
+// manifestParsers parse the manifests found at the root of a code directory
+var manifestParsers = map[string]func(data string) ([]string, error){
+	"package.json":     parsePackageJSON,
+	"requirements.txt": parseRequirements,
+	"pom.xml":          parsePOM,
+	"Gemfile":          parseGemfile,
+	"go.mod":           parseGoMod,
+}
+
+// ReadManifests reads the dependency manifests (package.json, requirements.txt, pom.xml,
+// Gemfile and go.mod) at the root of codeDir, sorted by path
+func ReadManifests(codeDir string) ([]Manifest, error) {
+	var names []string
+	for name := range manifestParsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var manifests []Manifest
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(codeDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		dependencies, err := manifestParsers[name](string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		manifests = append(manifests, Manifest{Path: name, Dependencies: dependencies})
+	}
+
+	return manifests, nil
+}
+
+// parsePackageJSON returns the dependencies and development dependencies of a package.json
+func parsePackageJSON(data string) ([]string, error) {
+	var manifest struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal([]byte(data), &manifest); err != nil {
+		return nil, err
+	}
+
+	var dependencies []string
+	for name := range manifest.Dependencies {
+		dependencies = append(dependencies, name)
+	}
+	for name := range manifest.DevDependencies {
+		dependencies = append(dependencies, name)
+	}
+	sort.Strings(dependencies)
+	return dependencies, nil
+}
+
+// parseRequirements returns the packages of a pip requirements file
+func parseRequirements(data string) ([]string, error) {
+	var dependencies []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		dependencies = append(dependencies, requirementSeparator.Split(line, 2)[0])
+	}
+	return dependencies, nil
+}
+
+// parsePOM returns the groupId:artifactId of the dependencies in a Maven pom.xml
+func parsePOM(data string) ([]string, error) {
+	var dependencies []string
+	for _, match := range mavenPattern.FindAllStringSubmatch(data, -1) {
+		dependencies = append(dependencies, match[1]+":"+match[2])
+	}
+	return dependencies, nil
+}
+
+// parseGemfile returns the gems of a Gemfile
+func parseGemfile(data string) ([]string, error) {
+	var dependencies []string
+	for _, match := range gemPattern.FindAllStringSubmatch(data, -1) {
+		dependencies = append(dependencies, match[1])
+	}
+	return dependencies, nil
+}
+
+// parseGoMod returns the required modules of a go.mod
+func parseGoMod(data string) ([]string, error) {
+	var dependencies []string
+	for _, match := range goRequirePattern.FindAllStringSubmatch(data, -1) {
+		dependencies = append(dependencies, match[1])
+	}
+	for _, block := range goRequireBlock.FindAllStringSubmatch(data, -1) {
+		for _, match := range goRequireBlockDep.FindAllStringSubmatch(block[1], -1) {
+			dependencies = append(dependencies, match[1])
+		}
+	}
+	return dependencies, nil
+}
@@ -0,0 +1,177 @@
+// Package source inspects the files of generated code
+package source
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// skippedDirs are directories holding dependencies, build output or metadata
+var skippedDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+	"out":          true,
+	".next":        true,
+	".nuxt":        true,
+	".output":      true,
+	"coverage":     true,
+	"__pycache__":  true,
+	"venv":         true,
+	".venv":        true,
+}
+
+// SkipDir returns whether a directory holds dependencies, build output or metadata
+// rather than source code
+func SkipDir(name string) bool {
+	return skippedDirs[name]
+}
+
+// Kind is the kind of content of a file
+type Kind int
+
+const (
+	// KindOther is any file that is not code or markup
+	KindOther Kind = iota
+
+	// KindCode is a file containing program logic
+	KindCode
+
+	// KindMarkup is a template or style sheet
+	KindMarkup
+)
+
+// language is a language files can be written in
+type language struct {
+	name string
+	kind Kind
+}
+
+// languages maps lower-case file extensions to languages
+var languages = map[string]language{
+	".js":     {"JavaScript", KindCode},
+	".jsx":    {"JavaScript", KindCode},
+	".mjs":    {"JavaScript", KindCode},
+	".cjs":    {"JavaScript", KindCode},
+	".ts":     {"TypeScript", KindCode},
+	".tsx":    {"TypeScript", KindCode},
+	".vue":    {"Vue", KindCode},
+	".svelte": {"Svelte", KindCode},
+	".py":     {"Python", KindCode},
+	".rb":     {"Ruby", KindCode},
+	".java":   {"Java", KindCode},
+	".kt":     {"Kotlin", KindCode},
+	".go":     {"Go", KindCode},
+	".php":    {"PHP", KindCode},
+	".cs":     {"C#", KindCode},
+	".html":   {"HTML", KindMarkup},
+	".erb":    {"ERB", KindMarkup},
+	".css":    {"CSS", KindMarkup},
+	".scss":   {"SCSS", KindMarkup},
+	".sass":   {"Sass", KindMarkup},
+	".less":   {"Less", KindMarkup},
+	".json":   {"JSON", KindOther},
+	".yml":    {"YAML", KindOther},
+	".yaml":   {"YAML", KindOther},
+	".md":     {"Markdown", KindOther},
+}
+
+// Language returns the language and kind of a file based on its extension,
+// or an empty name for unknown extensions
+func Language(path string) (string, Kind) {
+	lang := languages[strings.ToLower(filepath.Ext(path))]
+	return lang.name, lang.kind
+}
+
+// Walk calls visit for every regular file below root, skipping directories for which
+// SkipDir returns true. Paths passed to visit are relative to root and use slashes.
+func Walk(ctx context.Context, root string, visit func(path, rel string) error) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() {
+			if path != root && SkipDir(entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return visit(path, filepath.ToSlash(rel))
+	})
+}
+
+// CountLines counts the non-blank lines of a file
+func CountLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			lines++
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// CopyTree copies the files below src to dst, skipping directories for which SkipDir
+// returns true
+func CopyTree(ctx context.Context, src, dst string) error {
+	return Walk(ctx, src, func(path, rel string) error {
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		return copyFile(path, target)
+	})
+}
+
+// copyFile copies a file, keeping its permissions
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return out.Close()
+}
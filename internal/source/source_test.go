@@ -0,0 +1,100 @@
+package source_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fr0g-66723067/cc/internal/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates files in a temporary directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestWalkAndCopyTree(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"src/App.js":                "app\n",
+		"README.md":                 "readme\n",
+		"node_modules/react/one.js": "skipped\n",
+		".git/HEAD":                 "skipped\n",
+	})
+
+	var files []string
+	require.NoError(t, source.Walk(context.Background(), dir, func(path, rel string) error {
+		files = append(files, rel)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{"src/App.js", "README.md"}, files)
+
+	// Copies leave out dependencies and metadata
+	target := filepath.Join(t.TempDir(), "copy")
+	require.NoError(t, source.CopyTree(context.Background(), dir, target))
+	data, err := os.ReadFile(filepath.Join(target, "src", "App.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "app\n", string(data))
+	assert.NoDirExists(t, filepath.Join(target, "node_modules"))
+	assert.NoDirExists(t, filepath.Join(target, ".git"))
+}
+
+func TestLanguage(t *testing.T) {
+	name, kind := source.Language("src/App.TSX")
+	assert.Equal(t, "TypeScript", name)
+	assert.Equal(t, source.KindCode, kind)
+
+	name, kind = source.Language("styles/main.scss")
+	assert.Equal(t, "SCSS", name)
+	assert.Equal(t, source.KindMarkup, kind)
+
+	name, kind = source.Language("logo.png")
+	assert.Empty(t, name)
+	assert.Equal(t, source.KindOther, kind)
+}
+
+func TestCountLines(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"app.py": "import os\n\n  \nprint(os.name)\n"})
+	lines, err := source.CountLines(filepath.Join(dir, "app.py"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, lines)
+}
+
+func TestReadManifests(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"package.json":     `{"dependencies": {"react": "^18", "react-dom": "^18"}, "devDependencies": {"vite": "^5"}}`,
+		"requirements.txt": "# comment\nflask==3.0\n-r base.txt\nrequests[security]>=2\npytest\n",
+		"pom.xml":          "<dependencies><dependency><groupId>org.springframework.boot</groupId><artifactId>spring-boot-starter-web</artifactId></dependency></dependencies>",
+		"Gemfile":          "source 'https://rubygems.org'\ngem 'rails', '~> 7.1'\n  gem \"puma\"\n",
+		"go.mod":           "module example\n\nrequire github.com/a/b v1.0.0\n\nrequire (\n\tgithub.com/c/d v1.2.0\n\tgithub.com/e/f v0.1.0 // indirect\n)\n",
+	})
+
+	manifests, err := source.ReadManifests(dir)
+	require.NoError(t, err)
+	require.Len(t, manifests, 5)
+
+	// Manifests are sorted by path
+	assert.Equal(t, source.Manifest{Path: "Gemfile", Dependencies: []string{"rails", "puma"}}, manifests[0])
+	assert.Equal(t, source.Manifest{Path: "go.mod", Dependencies: []string{"github.com/a/b", "github.com/c/d", "github.com/e/f"}}, manifests[1])
+	assert.Equal(t, source.Manifest{Path: "package.json", Dependencies: []string{"react", "react-dom", "vite"}}, manifests[2])
+	assert.Equal(t, source.Manifest{Path: "pom.xml", Dependencies: []string{"org.springframework.boot:spring-boot-starter-web"}}, manifests[3])
+	assert.Equal(t, source.Manifest{Path: "requirements.txt", Dependencies: []string{"flask", "requests", "pytest"}}, manifests[4])
+
+	// Invalid manifests are reported
+	dir = writeFiles(t, map[string]string{"package.json": "{"})
+	_, err = source.ReadManifests(dir)
+	assert.Error(t, err)
+
+	// Directories without manifests have none
+	manifests, err = source.ReadManifests(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, manifests)
+}
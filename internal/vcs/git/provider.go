@@ -1,10 +1,13 @@
 package git
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/fr0g-66723067/cc/internal/vcs"
@@ -34,8 +37,8 @@ func (p *Provider) Initialize(path string) error {
 	// Check if repo exists
 	_, err := os.Stat(filepath.Join(path, ".git"))
 	if err == nil {
-		// Repository exists, open it
-		repo, err := git.PlainOpen(path)
+		// Repository exists, open it (linked worktrees share the main repository's objects)
+		repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
 		if err != nil {
 			return fmt.Errorf("failed to open repository: %w", err)
 		}
@@ -210,6 +213,25 @@ func (p *Provider) ListBranches() ([]string, error) {
 	return branches, nil
 }
 
+// DeleteBranch deletes a branch, even if it is not merged
+func (p *Provider) DeleteBranch(name string) error {
+	if _, err := p.runGit("branch", "-D", name); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// GetBranchCommit returns the hash of the commit a branch points to
+func (p *Provider) GetBranchCommit(branch string) (string, error) {
+	ref, err := p.repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return "", fmt.Errorf("failed to get branch %s: %w", branch, err)
+	}
+
+	return ref.Hash().String(), nil
+}
+
 // AddFiles adds files to be committed
 func (p *Provider) AddFiles(paths []string) error {
 	// Get worktree
@@ -291,12 +313,29 @@ func (p *Provider) HasChanges() (bool, error) {
 	return !status.IsClean(), nil
 }
 
+// DiscardChanges discards uncommitted changes, removing untracked files. Ignored files
+// are kept.
+func (p *Provider) DiscardChanges() error {
+	if _, err := p.runGit("reset", "--hard", "HEAD"); err != nil {
+		return fmt.Errorf("failed to discard changes: %w", err)
+	}
+	if _, err := p.runGit("clean", "-fd"); err != nil {
+		return fmt.Errorf("failed to remove untracked files: %w", err)
+	}
+
+	return nil
+}
+
 // GetBranchMetadata gets metadata for a branch
 func (p *Provider) GetBranchMetadata(branch string) (map[string]string, error) {
-	metadataPath := filepath.Join(p.repoPath, ".git", "cc", "metadata", branch+".json")
+	gitDir, err := p.commonDir()
+	if err != nil {
+		return nil, err
+	}
+	metadataPath := filepath.Join(gitDir, "cc", "metadata", branch+".json")
 
 	// Check if metadata file exists
-	_, err := os.Stat(metadataPath)
+	_, err = os.Stat(metadataPath)
 	if os.IsNotExist(err) {
 		// No metadata yet
 		return make(map[string]string), nil
@@ -322,7 +361,11 @@ func (p *Provider) GetBranchMetadata(branch string) (map[string]string, error) {
 // SetBranchMetadata sets metadata for a branch
 func (p *Provider) SetBranchMetadata(branch string, metadata map[string]string) error {
 	// Create metadata directory if it doesn't exist
-	metadataDir := filepath.Join(p.repoPath, ".git", "cc", "metadata")
+	gitDir, err := p.commonDir()
+	if err != nil {
+		return err
+	}
+	metadataDir := filepath.Join(gitDir, "cc", "metadata")
 	if err := os.MkdirAll(metadataDir, 0755); err != nil {
 		return fmt.Errorf("failed to create metadata directory: %w", err)
 	}
@@ -382,6 +425,282 @@ func (p *Provider) ExportDiff(fromBranch, toBranch string) (string, error) {
 	return patch.String(), nil
 }
 
+// AddWorktree checks out a branch in a separate working tree at path.
+// If baseBranch is not empty, the branch is first created from baseBranch.
+func (p *Provider) AddWorktree(path string, branch string, baseBranch string) error {
+	if branch == "" {
+		return fmt.Errorf("branch name cannot be empty")
+	}
+
+	// Create parent directory if it doesn't exist
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+
+	// go-git has no support for linked worktrees, so use the git CLI
+	args := []string{"worktree", "add"}
+	if baseBranch != "" {
+		args = append(args, "-b", branch, path, baseBranch)
+	} else {
+		args = append(args, path, branch)
+	}
+	if _, err := p.runGit(args...); err != nil {
+		return fmt.Errorf("failed to add worktree: %w", err)
+	}
+
+	return nil
+}
+
+// ListWorktrees lists all working trees of the repository, including the main one
+func (p *Provider) ListWorktrees() ([]vcs.Worktree, error) {
+	output, err := p.runGit("worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+
+	// Entries are separated by blank lines, the first one is the main working tree
+	var worktrees []vcs.Worktree
+	for _, entry := range strings.Split(strings.TrimSpace(output), "\n\n") {
+		var worktree vcs.Worktree
+		for _, line := range strings.Split(entry, "\n") {
+			key, value, _ := strings.Cut(line, " ")
+			switch key {
+			case "worktree":
+				worktree.Path = value
+			case "HEAD":
+				worktree.Head = value
+			case "branch":
+				worktree.Branch = plumbing.ReferenceName(value).Short()
+			case "prunable":
+				worktree.Prunable = true
+			}
+		}
+		if worktree.Path == "" {
+			continue
+		}
+		worktree.Main = len(worktrees) == 0
+		worktrees = append(worktrees, worktree)
+	}
+
+	return worktrees, nil
+}
+
+// RemoveWorktree removes the working tree at path
+func (p *Provider) RemoveWorktree(path string) error {
+	if _, err := p.runGit("worktree", "remove", "--force", path); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w", err)
+	}
+
+	return nil
+}
+
+// PruneWorktrees removes administrative data of working trees whose directories no longer exist
+func (p *Provider) PruneWorktrees() error {
+	if _, err := p.runGit("worktree", "prune"); err != nil {
+		return fmt.Errorf("failed to prune worktrees: %w", err)
+	}
+
+	return nil
+}
+
+// Merge merges branch into the current branch using the given strategy.
+// If the merge stops on conflicts it is aborted and a *vcs.ConflictError is returned.
+func (p *Provider) Merge(branch string, strategy vcs.MergeStrategy, message string) error {
+	if message == "" {
+		message = fmt.Sprintf("Merge branch '%s'", branch)
+	}
+
+	var args []string
+	switch strategy {
+	case vcs.MergeFastForward:
+		args = []string{"merge", "--ff-only", branch}
+	case vcs.MergeCommit:
+		args = []string{"merge", "--no-ff", "-m", message, branch}
+	case vcs.MergeSquash:
+		args = []string{"merge", "--squash", branch}
+	default:
+		return fmt.Errorf("unknown merge strategy: %s", strategy)
+	}
+
+	if _, err := p.runGit(args...); err != nil {
+		return p.conflictError("merge", branch, err, "reset", "--merge")
+	}
+
+	// A squash merge only stages the changes, so commit them unless there are none
+	if strategy == vcs.MergeSquash {
+		if _, err := p.runGit("diff", "--cached", "--quiet"); err == nil {
+			return nil
+		}
+		if _, err := p.runGit("commit", "-m", message); err != nil {
+			return fmt.Errorf("failed to commit squash merge: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// StartMerge merges branch into the current branch without committing. Conflicts are
+// left in the working tree and returned as a *vcs.ConflictError.
+func (p *Provider) StartMerge(branch string) error {
+	if _, err := p.runGit("merge", "--no-ff", "--no-commit", branch); err != nil {
+		files, filesErr := p.conflictedFiles()
+		if filesErr != nil || len(files) == 0 {
+			return fmt.Errorf("failed to merge %s: %w", branch, err)
+		}
+		return &vcs.ConflictError{Operation: "merge", Branch: branch, Files: files}
+	}
+
+	return nil
+}
+
+// GetConflictVersions returns the common ancestor, current branch and merged branch
+// versions of a file with conflicts
+func (p *Provider) GetConflictVersions(path string) (string, string, string, error) {
+	// Unmerged index entries hold the ancestor in stage 1, ours in stage 2 and theirs in stage 3
+	var versions [3]string
+	found := false
+	for stage := range versions {
+		output, err := p.runGit("show", fmt.Sprintf(":%d:%s", stage+1, path))
+		if err != nil {
+			continue
+		}
+		versions[stage] = output
+		found = true
+	}
+
+	if !found {
+		return "", "", "", fmt.Errorf("no conflicts found in %s", path)
+	}
+
+	return versions[0], versions[1], versions[2], nil
+}
+
+// ContinueMerge stages all changes and concludes a merge started with StartMerge
+func (p *Provider) ContinueMerge(message string) error {
+	if _, err := p.runGit("add", "--all"); err != nil {
+		return fmt.Errorf("failed to stage resolved files: %w", err)
+	}
+
+	if _, err := p.runGit("commit", "--no-verify", "-m", message); err != nil {
+		return fmt.Errorf("failed to commit merge: %w", err)
+	}
+
+	return nil
+}
+
+// AbortMerge discards a merge started with StartMerge
+func (p *Provider) AbortMerge() error {
+	if _, err := p.runGit("merge", "--abort"); err != nil {
+		return fmt.Errorf("failed to abort merge: %w", err)
+	}
+
+	return nil
+}
+
+// Rebase replays the commits of the current branch on top of onto.
+// If the rebase stops on conflicts it is aborted and a *vcs.ConflictError is returned.
+func (p *Provider) Rebase(onto string) error {
+	if _, err := p.runGit("rebase", onto); err != nil {
+		return p.conflictError("rebase", onto, err, "rebase", "--abort")
+	}
+
+	return nil
+}
+
+// RebaseOnto replays the commits of the current branch that follow upstream on top of
+// onto. If the rebase stops on conflicts it is aborted and a *vcs.ConflictError is returned.
+func (p *Provider) RebaseOnto(onto string, upstream string) error {
+	if _, err := p.runGit("rebase", "--onto", onto, upstream); err != nil {
+		return p.conflictError("rebase", onto, err, "rebase", "--abort")
+	}
+
+	return nil
+}
+
+// conflictError turns a failed merge or rebase into a *vcs.ConflictError listing the
+// conflicted files, aborting the operation with abortArgs. Failures without conflicts
+// are returned as plain errors.
+func (p *Provider) conflictError(operation string, branch string, err error, abortArgs ...string) error {
+	files, filesErr := p.conflictedFiles()
+	if filesErr != nil || len(files) == 0 {
+		return fmt.Errorf("failed to %s %s: %w", operation, branch, err)
+	}
+
+	if _, abortErr := p.runGit(abortArgs...); abortErr != nil {
+		return fmt.Errorf("failed to abort %s: %w", operation, abortErr)
+	}
+
+	return &vcs.ConflictError{Operation: operation, Branch: branch, Files: files}
+}
+
+// conflictedFiles lists the files with unresolved conflicts
+func (p *Provider) conflictedFiles() ([]string, error) {
+	output, err := p.runGit("diff", "--name-only", "--diff-filter=U", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conflicted files: %w", err)
+	}
+
+	if output == "" {
+		return nil, nil
+	}
+
+	return strings.Split(strings.TrimRight(output, "\x00"), "\x00"), nil
+}
+
+// commonDir returns the git directory shared by all working trees of the repository
+func (p *Provider) commonDir() (string, error) {
+	dotGit := filepath.Join(p.repoPath, ".git")
+	if info, err := os.Stat(dotGit); err == nil && info.IsDir() {
+		return dotGit, nil
+	}
+
+	// In a linked worktree .git is a file pointing into the main repository
+	output, err := p.runGit("rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("failed to get git directory: %w", err)
+	}
+
+	dir := strings.TrimSpace(output)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(p.repoPath, dir)
+	}
+
+	return dir, nil
+}
+
+// runGit runs the git CLI in the repository for operations go-git does not support
+func (p *Provider) runGit(args ...string) (string, error) {
+	if p.repoPath == "" {
+		return "", fmt.Errorf("repository not initialized")
+	}
+
+	// Use the same identity as commits made through go-git
+	authorName := "Code Controller"
+	authorEmail := "cc@example.com"
+
+	if name, ok := p.config["user.name"]; ok && name != "" {
+		authorName = name
+	}
+
+	if email, ok := p.config["user.email"]; ok && email != "" {
+		authorEmail = email
+	}
+
+	gitArgs := append([]string{"-c", "user.name=" + authorName, "-c", "user.email=" + authorEmail}, args...)
+	cmd := exec.Command("git", gitArgs...)
+	cmd.Dir = p.repoPath
+
+	// Only stdout is returned, so warnings never end up in file contents or parsed output
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return string(output), fmt.Errorf("git %s: %w\n%s", args[0], err, stderr.String())
+	}
+
+	return string(output), nil
+}
+
 // Name returns the provider's name
 func (p *Provider) Name() string {
 	return "git"
@@ -1,11 +1,15 @@
 package git_test
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/fr0g-66723067/cc/internal/vcs"
 	"github.com/fr0g-66723067/cc/internal/vcs/git"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -62,4 +66,413 @@ func TestAddCommitFiles(t *testing.T) {
 // TestExportDiff tests exporting diffs between branches
 func TestExportDiff(t *testing.T) {
 	t.Skip("Skip export diff test - requires actual Git client")
-}
\ No newline at end of file
+}
+// TestWorktreeOperations tests adding and removing linked worktrees
+func TestWorktreeOperations(t *testing.T) {
+	// Create a temporary directory for the test
+	tempDir, err := os.MkdirTemp("", "git-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	
+	// Create and initialize the main repository
+	provider, err := git.NewProvider(nil)
+	require.NoError(t, err)
+	require.NoError(t, provider.Initialize(tempDir))
+	
+	baseBranch, err := provider.GetCurrentBranch()
+	require.NoError(t, err)
+	
+	// Add a worktree with a new branch
+	worktreePath := filepath.Join(tempDir, ".git", "cc", "worktrees", "impl-test")
+	err = provider.AddWorktree(worktreePath, "impl-test", baseBranch)
+	require.NoError(t, err)
+	
+	// The worktree has the base branch content checked out
+	_, err = os.Stat(filepath.Join(worktreePath, "README.md"))
+	assert.NoError(t, err)
+	
+	// Commit in the worktree without touching the main working copy
+	worktreeProvider, err := git.NewProvider(nil)
+	require.NoError(t, err)
+	require.NoError(t, worktreeProvider.Initialize(worktreePath))
+	
+	branch, err := worktreeProvider.GetCurrentBranch()
+	require.NoError(t, err)
+	assert.Equal(t, "impl-test", branch)
+	
+	newFile := filepath.Join(worktreePath, "app.js")
+	require.NoError(t, os.WriteFile(newFile, []byte("console.log('hi')\n"), 0644))
+	require.NoError(t, worktreeProvider.AddFiles([]string{newFile}))
+	require.NoError(t, worktreeProvider.CommitChanges("Add app"))
+	
+	currentBranch, err := provider.GetCurrentBranch()
+	require.NoError(t, err)
+	assert.Equal(t, baseBranch, currentBranch)
+	_, err = os.Stat(filepath.Join(tempDir, "app.js"))
+	assert.True(t, os.IsNotExist(err), "main working copy should not change")
+	
+	// Remove the worktree, the branch stays
+	require.NoError(t, provider.RemoveWorktree(worktreePath))
+	_, err = os.Stat(worktreePath)
+	assert.True(t, os.IsNotExist(err), "worktree directory should be removed")
+	
+	branches, err := provider.ListBranches()
+	require.NoError(t, err)
+	assert.Contains(t, branches, "impl-test")
+
+	// Delete the branch, which is not merged
+	require.NoError(t, provider.DeleteBranch("impl-test"))
+	branches, err = provider.ListBranches()
+	require.NoError(t, err)
+	assert.NotContains(t, branches, "impl-test")
+	assert.Error(t, provider.DeleteBranch("impl-test"))
+}
+
+// TestListAndPruneWorktrees tests listing worktrees and pruning stale ones
+func TestListAndPruneWorktrees(t *testing.T) {
+	// Create a temporary directory for the test
+	tempDir, err := os.MkdirTemp("", "git-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	
+	// Create and initialize the main repository
+	provider, err := git.NewProvider(nil)
+	require.NoError(t, err)
+	require.NoError(t, provider.Initialize(tempDir))
+	
+	baseBranch, err := provider.GetCurrentBranch()
+	require.NoError(t, err)
+	
+	// Add a worktree
+	worktreePath := filepath.Join(tempDir, ".git", "cc", "worktrees", "feat-test")
+	require.NoError(t, provider.AddWorktree(worktreePath, "feat-test", baseBranch))
+	
+	// Both the main and the linked worktree are listed
+	worktrees, err := provider.ListWorktrees()
+	require.NoError(t, err)
+	require.Len(t, worktrees, 2)
+	assert.True(t, worktrees[0].Main)
+	assert.Equal(t, baseBranch, worktrees[0].Branch)
+	assert.False(t, worktrees[1].Main)
+	assert.Equal(t, "feat-test", worktrees[1].Branch)
+	assert.NotEmpty(t, worktrees[1].Head)
+
+	// The branch commit is the head of its worktree
+	commit, err := provider.GetBranchCommit("feat-test")
+	require.NoError(t, err)
+	assert.Equal(t, worktrees[1].Head, commit)
+	_, err = provider.GetBranchCommit("missing")
+	assert.Error(t, err)
+	
+	// Metadata written from the worktree is shared with the main repository
+	worktreeProvider, err := git.NewProvider(nil)
+	require.NoError(t, err)
+	require.NoError(t, worktreeProvider.Initialize(worktreePath))
+	require.NoError(t, worktreeProvider.SetBranchMetadata("feat-test", map[string]string{"status": "in-progress"}))
+	
+	metadata, err := provider.GetBranchMetadata("feat-test")
+	require.NoError(t, err)
+	assert.Equal(t, "in-progress", metadata["status"])
+	
+	// Simulate an interrupted command that never removed its worktree
+	require.NoError(t, os.RemoveAll(worktreePath))
+	
+	worktrees, err = provider.ListWorktrees()
+	require.NoError(t, err)
+	require.Len(t, worktrees, 2)
+	assert.True(t, worktrees[1].Prunable)
+	
+	// Pruning removes the stale worktree
+	require.NoError(t, provider.PruneWorktrees())
+	
+	worktrees, err = provider.ListWorktrees()
+	require.NoError(t, err)
+	assert.Len(t, worktrees, 1)
+}
+
+
+// commitFile writes a file in the repository of provider and commits it
+func commitFile(t *testing.T, provider *git.Provider, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	require.NoError(t, provider.AddFiles([]string{path}))
+	require.NoError(t, provider.CommitChanges("Update "+name))
+}
+
+// runGit runs git in dir and returns its trimmed output
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return strings.TrimSpace(string(output))
+}
+
+// addBranch creates a branch from baseBranch in a new worktree and returns a provider for it
+func addBranch(t *testing.T, provider *git.Provider, repoDir, branch, baseBranch string) (*git.Provider, string) {
+	t.Helper()
+	path := filepath.Join(repoDir, ".git", "cc", "worktrees", branch)
+	require.NoError(t, provider.AddWorktree(path, branch, baseBranch))
+	
+	branchProvider, err := git.NewProvider(nil)
+	require.NoError(t, err)
+	require.NoError(t, branchProvider.Initialize(path))
+	return branchProvider, path
+}
+
+// TestMergeStrategies tests merging branches with each merge strategy
+func TestMergeStrategies(t *testing.T) {
+	// Create a temporary directory for the test
+	tempDir, err := os.MkdirTemp("", "git-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	
+	// Create and initialize the main repository
+	provider, err := git.NewProvider(nil)
+	require.NoError(t, err)
+	require.NoError(t, provider.Initialize(tempDir))
+	
+	baseBranch, err := provider.GetCurrentBranch()
+	require.NoError(t, err)
+	
+	// Fast-forward merge
+	ffProvider, ffPath := addBranch(t, provider, tempDir, "feat-ff", baseBranch)
+	commitFile(t, ffProvider, ffPath, "ff.txt", "fast-forward\n")
+	require.NoError(t, provider.Merge("feat-ff", vcs.MergeFastForward, ""))
+	assert.FileExists(t, filepath.Join(tempDir, "ff.txt"))
+	
+	// Fast-forward fails once the branches have diverged
+	mergeProvider, mergePath := addBranch(t, provider, tempDir, "feat-merge", baseBranch)
+	commitFile(t, mergeProvider, mergePath, "merge.txt", "merge commit\n")
+	commitFile(t, provider, tempDir, "main.txt", "main\n")
+	
+	err = provider.Merge("feat-merge", vcs.MergeFastForward, "")
+	require.Error(t, err)
+	var conflictErr *vcs.ConflictError
+	assert.False(t, errors.As(err, &conflictErr))
+	
+	// A merge commit joins diverged branches
+	require.NoError(t, provider.Merge("feat-merge", vcs.MergeCommit, "Merge feature"))
+	assert.FileExists(t, filepath.Join(tempDir, "merge.txt"))
+	
+	// Squash merge commits all changes of the branch at once
+	squashProvider, squashPath := addBranch(t, provider, tempDir, "feat-squash", baseBranch)
+	commitFile(t, squashProvider, squashPath, "squash1.txt", "one\n")
+	commitFile(t, squashProvider, squashPath, "squash2.txt", "two\n")
+	require.NoError(t, provider.Merge("feat-squash", vcs.MergeSquash, "Squash feature"))
+	assert.FileExists(t, filepath.Join(tempDir, "squash1.txt"))
+	assert.FileExists(t, filepath.Join(tempDir, "squash2.txt"))
+	
+	hasChanges, err := provider.HasChanges()
+	require.NoError(t, err)
+	assert.False(t, hasChanges, "Squash merge should be committed")
+}
+
+// TestMergeAndRebaseConflicts tests that conflicts are reported as a list of files
+func TestMergeAndRebaseConflicts(t *testing.T) {
+	// Create a temporary directory for the test
+	tempDir, err := os.MkdirTemp("", "git-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	
+	// Create and initialize the main repository
+	provider, err := git.NewProvider(nil)
+	require.NoError(t, err)
+	require.NoError(t, provider.Initialize(tempDir))
+	
+	baseBranch, err := provider.GetCurrentBranch()
+	require.NoError(t, err)
+	
+	// Change the same file on both branches
+	featureProvider, featurePath := addBranch(t, provider, tempDir, "feat-conflict", baseBranch)
+	commitFile(t, featureProvider, featurePath, "README.md", "# Feature\n")
+	commitFile(t, featureProvider, featurePath, "feature.txt", "feature\n")
+	commitFile(t, provider, tempDir, "README.md", "# Main\n")
+	
+	// Merging reports the conflicting file and leaves the working tree clean
+	err = provider.Merge("feat-conflict", vcs.MergeCommit, "")
+	var conflictErr *vcs.ConflictError
+	require.True(t, errors.As(err, &conflictErr), "expected a conflict error, got %v", err)
+	assert.Equal(t, "merge", conflictErr.Operation)
+	assert.Equal(t, "feat-conflict", conflictErr.Branch)
+	assert.Equal(t, []string{"README.md"}, conflictErr.Files)
+	
+	hasChanges, err := provider.HasChanges()
+	require.NoError(t, err)
+	assert.False(t, hasChanges, "Merge should be aborted")
+	
+	// Squash merges are aborted as well
+	err = provider.Merge("feat-conflict", vcs.MergeSquash, "")
+	require.True(t, errors.As(err, &conflictErr), "expected a conflict error, got %v", err)
+	assert.Equal(t, []string{"README.md"}, conflictErr.Files)
+	
+	hasChanges, err = provider.HasChanges()
+	require.NoError(t, err)
+	assert.False(t, hasChanges, "Squash merge should be aborted")
+	
+	// Rebasing the feature reports the same conflict
+	err = featureProvider.Rebase(baseBranch)
+	require.True(t, errors.As(err, &conflictErr), "expected a conflict error, got %v", err)
+	assert.Equal(t, "rebase", conflictErr.Operation)
+	assert.Equal(t, baseBranch, conflictErr.Branch)
+	assert.Equal(t, []string{"README.md"}, conflictErr.Files)
+	
+	branch, err := featureProvider.GetCurrentBranch()
+	require.NoError(t, err)
+	assert.Equal(t, "feat-conflict", branch, "Rebase should be aborted")
+	
+	// Rebasing a branch without conflicts replays its commits
+	cleanProvider, cleanPath := addBranch(t, provider, tempDir, "feat-clean", "HEAD~1")
+	commitFile(t, cleanProvider, cleanPath, "clean.txt", "clean\n")
+	require.NoError(t, cleanProvider.Rebase(baseBranch))
+	
+	content, err := os.ReadFile(filepath.Join(cleanPath, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Main\n", string(content))
+	assert.FileExists(t, filepath.Join(cleanPath, "clean.txt"))
+}
+
+// TestDiscardChanges tests discarding changes and untracked files in a worktree
+func TestDiscardChanges(t *testing.T) {
+	// Create a temporary directory for the test
+	tempDir, err := os.MkdirTemp("", "git-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	
+	// Create and initialize the main repository
+	provider, err := git.NewProvider(nil)
+	require.NoError(t, err)
+	require.NoError(t, provider.Initialize(tempDir))
+	
+	baseBranch, err := provider.GetCurrentBranch()
+	require.NoError(t, err)
+	
+	// Leave a changed file, a new file and a new directory behind
+	featureProvider, featurePath := addBranch(t, provider, tempDir, "feat-partial", baseBranch)
+	commitFile(t, featureProvider, featurePath, "app.js", "app\n")
+	require.NoError(t, os.WriteFile(filepath.Join(featurePath, "app.js"), []byte("partial\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(featurePath, "partial.js"), []byte("partial\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(featurePath, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(featurePath, "src", "index.js"), []byte("partial\n"), 0644))
+	
+	require.NoError(t, featureProvider.DiscardChanges())
+	
+	content, err := os.ReadFile(filepath.Join(featurePath, "app.js"))
+	require.NoError(t, err)
+	assert.Equal(t, "app\n", string(content))
+	assert.NoFileExists(t, filepath.Join(featurePath, "partial.js"))
+	assert.NoDirExists(t, filepath.Join(featurePath, "src"))
+	
+	hasChanges, err := featureProvider.HasChanges()
+	require.NoError(t, err)
+	assert.False(t, hasChanges)
+}
+
+// TestRebaseOnto tests rebasing a branch stacked on a parent that was rewritten
+func TestRebaseOnto(t *testing.T) {
+	// Create a temporary directory for the test
+	tempDir, err := os.MkdirTemp("", "git-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	
+	// Create and initialize the main repository
+	provider, err := git.NewProvider(nil)
+	require.NoError(t, err)
+	require.NoError(t, provider.Initialize(tempDir))
+	
+	baseBranch, err := provider.GetCurrentBranch()
+	require.NoError(t, err)
+	
+	// Stack a child on a parent
+	parentProvider, parentPath := addBranch(t, provider, tempDir, "feat-parent", baseBranch)
+	commitFile(t, parentProvider, parentPath, "parent.txt", "parent\n")
+	childProvider, childPath := addBranch(t, provider, tempDir, "feat-child", "feat-parent")
+	commitFile(t, childProvider, childPath, "child.txt", "child\n")
+	
+	// Rewrite the parent, its file changing in a new commit of the same change
+	oldTip, err := provider.GetBranchCommit("feat-parent")
+	require.NoError(t, err)
+	runGit(t, parentPath, "reset", "--hard", "HEAD~1")
+	commitFile(t, parentProvider, parentPath, "parent.txt", "amended parent\n")
+	
+	// Replaying the old parent commit conflicts with its rewrite
+	err = childProvider.Rebase("feat-parent")
+	var conflictErr *vcs.ConflictError
+	require.True(t, errors.As(err, &conflictErr), "expected a conflict error, got %v", err)
+	assert.Equal(t, []string{"parent.txt"}, conflictErr.Files)
+	
+	// Rebasing onto the parent from its old tip only replays the child commit
+	require.NoError(t, childProvider.RebaseOnto("feat-parent", oldTip))
+	content, err := os.ReadFile(filepath.Join(childPath, "parent.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "amended parent\n", string(content))
+	assert.FileExists(t, filepath.Join(childPath, "child.txt"))
+	
+	assert.Equal(t, "2", runGit(t, childPath, "rev-list", "--count", baseBranch+"..feat-child"))
+}
+
+// TestStartMergeWithConflicts tests resolving conflicts of a merge left in the working tree
+func TestStartMergeWithConflicts(t *testing.T) {
+	// Create a temporary directory for the test
+	tempDir, err := os.MkdirTemp("", "git-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	
+	// Create and initialize the main repository
+	provider, err := git.NewProvider(nil)
+	require.NoError(t, err)
+	require.NoError(t, provider.Initialize(tempDir))
+	
+	baseBranch, err := provider.GetCurrentBranch()
+	require.NoError(t, err)
+	
+	// Change the same file on both branches
+	featureProvider, featurePath := addBranch(t, provider, tempDir, "feat-conflict", baseBranch)
+	commitFile(t, featureProvider, featurePath, "README.md", "# Feature\n")
+	commitFile(t, featureProvider, featurePath, "feature.txt", "feature\n")
+	commitFile(t, provider, tempDir, "README.md", "# Main\n")
+	
+	// Conflicts are left in the working tree
+	err = provider.StartMerge("feat-conflict")
+	var conflictErr *vcs.ConflictError
+	require.True(t, errors.As(err, &conflictErr), "expected a conflict error, got %v", err)
+	assert.Equal(t, []string{"README.md"}, conflictErr.Files)
+	
+	// Git writing to stderr, like warnings or traces, leaves the versions as they are
+	t.Setenv("GIT_TRACE", "1")
+	base, ours, theirs, err := provider.GetConflictVersions("README.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# Project\n\nInitialized by Code Controller\n", base)
+	assert.Equal(t, "# Main\n", ours)
+	assert.Equal(t, "# Feature\n", theirs)
+	
+	// Errors report what git wrote to stderr
+	err = provider.DeleteBranch("feat-unknown")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "branch 'feat-unknown' not found")
+	
+	_, _, _, err = provider.GetConflictVersions("feature.txt")
+	assert.Error(t, err, "Files without conflicts have no conflict versions")
+	
+	// Aborting restores the current branch
+	require.NoError(t, provider.AbortMerge())
+	content, err := os.ReadFile(filepath.Join(tempDir, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Main\n", string(content))
+	
+	// Resolving and continuing commits the merge
+	err = provider.StartMerge("feat-conflict")
+	require.True(t, errors.As(err, &conflictErr), "expected a conflict error, got %v", err)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "README.md"), []byte("# Main and Feature\n"), 0644))
+	require.NoError(t, provider.ContinueMerge("Resolve conflicts"))
+	
+	hasChanges, err := provider.HasChanges()
+	require.NoError(t, err)
+	assert.False(t, hasChanges)
+	assert.FileExists(t, filepath.Join(tempDir, "feature.txt"))
+	
+	// The feature is now fully merged
+	require.NoError(t, provider.Merge("feat-conflict", vcs.MergeFastForward, ""))
+}
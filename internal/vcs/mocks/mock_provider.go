@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	vcs "github.com/fr0g-66723067/cc/internal/vcs"
 	gomock "go.uber.org/mock/gomock"
 	reflect "reflect"
 )
@@ -32,6 +33,20 @@ func (m *MockProvider) EXPECT() *MockProviderMockRecorder {
 	return m.recorder
 }
 
+// AbortMerge mocks base method
+func (m *MockProvider) AbortMerge() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbortMerge")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AbortMerge indicates an expected call of AbortMerge
+func (mr *MockProviderMockRecorder) AbortMerge() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbortMerge", reflect.TypeOf((*MockProvider)(nil).AbortMerge))
+}
+
 // AddFiles mocks base method
 func (m *MockProvider) AddFiles(paths []string) error {
 	m.ctrl.T.Helper()
@@ -46,6 +61,20 @@ func (mr *MockProviderMockRecorder) AddFiles(paths interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFiles", reflect.TypeOf((*MockProvider)(nil).AddFiles), paths)
 }
 
+// AddWorktree mocks base method
+func (m *MockProvider) AddWorktree(path, branch, baseBranch string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddWorktree", path, branch, baseBranch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddWorktree indicates an expected call of AddWorktree
+func (mr *MockProviderMockRecorder) AddWorktree(path, branch, baseBranch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddWorktree", reflect.TypeOf((*MockProvider)(nil).AddWorktree), path, branch, baseBranch)
+}
+
 // CommitChanges mocks base method
 func (m *MockProvider) CommitChanges(message string) error {
 	m.ctrl.T.Helper()
@@ -60,6 +89,20 @@ func (mr *MockProviderMockRecorder) CommitChanges(message interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitChanges", reflect.TypeOf((*MockProvider)(nil).CommitChanges), message)
 }
 
+// ContinueMerge mocks base method
+func (m *MockProvider) ContinueMerge(message string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContinueMerge", message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ContinueMerge indicates an expected call of ContinueMerge
+func (mr *MockProviderMockRecorder) ContinueMerge(message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContinueMerge", reflect.TypeOf((*MockProvider)(nil).ContinueMerge), message)
+}
+
 // CreateBranch mocks base method
 func (m *MockProvider) CreateBranch(name, baseBranch string) error {
 	m.ctrl.T.Helper()
@@ -74,6 +117,20 @@ func (mr *MockProviderMockRecorder) CreateBranch(name, baseBranch interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBranch", reflect.TypeOf((*MockProvider)(nil).CreateBranch), name, baseBranch)
 }
 
+// DiscardChanges mocks base method
+func (m *MockProvider) DiscardChanges() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DiscardChanges")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DiscardChanges indicates an expected call of DiscardChanges
+func (mr *MockProviderMockRecorder) DiscardChanges() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiscardChanges", reflect.TypeOf((*MockProvider)(nil).DiscardChanges))
+}
+
 // ExportDiff mocks base method
 func (m *MockProvider) ExportDiff(fromBranch, toBranch string) (string, error) {
 	m.ctrl.T.Helper()
@@ -104,6 +161,38 @@ func (mr *MockProviderMockRecorder) GetBranchMetadata(branch interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBranchMetadata", reflect.TypeOf((*MockProvider)(nil).GetBranchMetadata), branch)
 }
 
+// GetBranchCommit mocks base method
+func (m *MockProvider) GetBranchCommit(branch string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBranchCommit", branch)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBranchCommit indicates an expected call of GetBranchCommit
+func (mr *MockProviderMockRecorder) GetBranchCommit(branch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBranchCommit", reflect.TypeOf((*MockProvider)(nil).GetBranchCommit), branch)
+}
+
+// GetConflictVersions mocks base method
+func (m *MockProvider) GetConflictVersions(path string) (string, string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConflictVersions", path)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(string)
+	ret3, _ := ret[3].(error)
+	return ret0, ret1, ret2, ret3
+}
+
+// GetConflictVersions indicates an expected call of GetConflictVersions
+func (mr *MockProviderMockRecorder) GetConflictVersions(path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConflictVersions", reflect.TypeOf((*MockProvider)(nil).GetConflictVersions), path)
+}
+
 // GetCurrentBranch mocks base method
 func (m *MockProvider) GetCurrentBranch() (string, error) {
 	m.ctrl.T.Helper()
@@ -148,6 +237,20 @@ func (mr *MockProviderMockRecorder) Initialize(path interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Initialize", reflect.TypeOf((*MockProvider)(nil).Initialize), path)
 }
 
+// DeleteBranch mocks base method
+func (m *MockProvider) DeleteBranch(name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBranch", name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBranch indicates an expected call of DeleteBranch
+func (mr *MockProviderMockRecorder) DeleteBranch(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBranch", reflect.TypeOf((*MockProvider)(nil).DeleteBranch), name)
+}
+
 // ListBranches mocks base method
 func (m *MockProvider) ListBranches() ([]string, error) {
 	m.ctrl.T.Helper()
@@ -163,6 +266,35 @@ func (mr *MockProviderMockRecorder) ListBranches() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBranches", reflect.TypeOf((*MockProvider)(nil).ListBranches))
 }
 
+// ListWorktrees mocks base method
+func (m *MockProvider) ListWorktrees() ([]vcs.Worktree, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWorktrees")
+	ret0, _ := ret[0].([]vcs.Worktree)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWorktrees indicates an expected call of ListWorktrees
+func (mr *MockProviderMockRecorder) ListWorktrees() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWorktrees", reflect.TypeOf((*MockProvider)(nil).ListWorktrees))
+}
+
+// Merge mocks base method
+func (m *MockProvider) Merge(branch string, strategy vcs.MergeStrategy, message string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", branch, strategy, message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Merge indicates an expected call of Merge
+func (mr *MockProviderMockRecorder) Merge(branch, strategy, message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockProvider)(nil).Merge), branch, strategy, message)
+}
+
 // Name mocks base method
 func (m *MockProvider) Name() string {
 	m.ctrl.T.Helper()
@@ -177,6 +309,62 @@ func (mr *MockProviderMockRecorder) Name() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockProvider)(nil).Name))
 }
 
+// PruneWorktrees mocks base method
+func (m *MockProvider) PruneWorktrees() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneWorktrees")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PruneWorktrees indicates an expected call of PruneWorktrees
+func (mr *MockProviderMockRecorder) PruneWorktrees() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneWorktrees", reflect.TypeOf((*MockProvider)(nil).PruneWorktrees))
+}
+
+// Rebase mocks base method
+func (m *MockProvider) Rebase(onto string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rebase", onto)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rebase indicates an expected call of Rebase
+func (mr *MockProviderMockRecorder) Rebase(onto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rebase", reflect.TypeOf((*MockProvider)(nil).Rebase), onto)
+}
+
+// RebaseOnto mocks base method
+func (m *MockProvider) RebaseOnto(onto, upstream string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RebaseOnto", onto, upstream)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RebaseOnto indicates an expected call of RebaseOnto
+func (mr *MockProviderMockRecorder) RebaseOnto(onto, upstream interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RebaseOnto", reflect.TypeOf((*MockProvider)(nil).RebaseOnto), onto, upstream)
+}
+
+// RemoveWorktree mocks base method
+func (m *MockProvider) RemoveWorktree(path string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveWorktree", path)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveWorktree indicates an expected call of RemoveWorktree
+func (mr *MockProviderMockRecorder) RemoveWorktree(path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveWorktree", reflect.TypeOf((*MockProvider)(nil).RemoveWorktree), path)
+}
+
 // SetBranchMetadata mocks base method
 func (m *MockProvider) SetBranchMetadata(branch string, metadata map[string]string) error {
 	m.ctrl.T.Helper()
@@ -191,6 +379,20 @@ func (mr *MockProviderMockRecorder) SetBranchMetadata(branch, metadata interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBranchMetadata", reflect.TypeOf((*MockProvider)(nil).SetBranchMetadata), branch, metadata)
 }
 
+// StartMerge mocks base method
+func (m *MockProvider) StartMerge(branch string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartMerge", branch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StartMerge indicates an expected call of StartMerge
+func (mr *MockProviderMockRecorder) StartMerge(branch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartMerge", reflect.TypeOf((*MockProvider)(nil).StartMerge), branch)
+}
+
 // SwitchBranch mocks base method
 func (m *MockProvider) SwitchBranch(name string) error {
 	m.ctrl.T.Helper()
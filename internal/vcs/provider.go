@@ -2,6 +2,7 @@ package vcs
 
 import (
 	"fmt"
+	"strings"
 )
 
 // Provider defines the interface for version control systems
@@ -21,6 +22,12 @@ type Provider interface {
 	// ListBranches lists all branches
 	ListBranches() ([]string, error)
 
+	// DeleteBranch deletes a branch, even if it is not merged
+	DeleteBranch(name string) error
+
+	// GetBranchCommit returns the hash of the commit a branch points to
+	GetBranchCommit(branch string) (string, error)
+
 	// AddFiles adds files to be committed
 	AddFiles(paths []string) error
 
@@ -30,6 +37,9 @@ type Provider interface {
 	// HasChanges returns whether there are uncommitted changes
 	HasChanges() (bool, error)
 
+	// DiscardChanges discards uncommitted changes, removing untracked files
+	DiscardChanges() error
+
 	// GetBranchMetadata gets metadata for a branch
 	GetBranchMetadata(branch string) (map[string]string, error)
 
@@ -39,10 +49,110 @@ type Provider interface {
 	// ExportDiff exports a diff between branches
 	ExportDiff(fromBranch, toBranch string) (string, error)
 
+	// AddWorktree checks out a branch in a separate working tree at path.
+	// If baseBranch is not empty, the branch is first created from baseBranch.
+	AddWorktree(path string, branch string, baseBranch string) error
+
+	// ListWorktrees lists all working trees of the repository, including the main one
+	ListWorktrees() ([]Worktree, error)
+
+	// RemoveWorktree removes the working tree at path
+	RemoveWorktree(path string) error
+
+	// PruneWorktrees removes administrative data of working trees whose directories no longer exist
+	PruneWorktrees() error
+
+	// Merge merges branch into the current branch using the given strategy.
+	// If the merge stops on conflicts it is aborted and a *ConflictError is returned.
+	Merge(branch string, strategy MergeStrategy, message string) error
+
+	// StartMerge merges branch into the current branch without committing. Conflicts are
+	// left in the working tree and returned as a *ConflictError so they can be resolved
+	// before calling ContinueMerge, or discarded with AbortMerge.
+	StartMerge(branch string) error
+
+	// GetConflictVersions returns the common ancestor, current branch and merged branch
+	// versions of a file with conflicts. Versions missing on a side are empty.
+	GetConflictVersions(path string) (base string, ours string, theirs string, err error)
+
+	// ContinueMerge stages all changes and concludes a merge started with StartMerge
+	ContinueMerge(message string) error
+
+	// AbortMerge discards a merge started with StartMerge
+	AbortMerge() error
+
+	// Rebase replays the commits of the current branch on top of onto.
+	// If the rebase stops on conflicts it is aborted and a *ConflictError is returned.
+	Rebase(onto string) error
+
+	// RebaseOnto replays the commits of the current branch that follow upstream on top of
+	// onto, leaving out the commits of upstream, like the old tip of a rewritten parent.
+	// If the rebase stops on conflicts it is aborted and a *ConflictError is returned.
+	RebaseOnto(onto string, upstream string) error
+
 	// Name returns the provider's name
 	Name() string
 }
 
+// MergeStrategy defines how a branch is merged into another
+type MergeStrategy string
+
+const (
+	// MergeFastForward only moves the current branch forward, failing if it has diverged
+	MergeFastForward MergeStrategy = "ff"
+
+	// MergeCommit always records a merge commit
+	MergeCommit MergeStrategy = "merge"
+
+	// MergeSquash combines all changes of the branch into a single commit
+	MergeSquash MergeStrategy = "squash"
+)
+
+// ParseMergeStrategy returns the merge strategy with the given name
+func ParseMergeStrategy(name string) (MergeStrategy, error) {
+	switch strategy := MergeStrategy(name); strategy {
+	case MergeFastForward, MergeCommit, MergeSquash:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown merge strategy: %s (valid strategies: ff, merge, squash)", name)
+	}
+}
+
+// ConflictError is returned when a merge or rebase stops on conflicting files
+type ConflictError struct {
+	// Operation that caused the conflicts (e.g. "merge", "rebase")
+	Operation string
+
+	// Branch being merged or rebased onto
+	Branch string
+
+	// Files with conflicts, relative to the repository root
+	Files []string
+}
+
+// Error implements the error interface
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s of %s has conflicts in %d file(s): %s", e.Operation, e.Branch, len(e.Files), strings.Join(e.Files, ", "))
+}
+
+// Worktree describes a working tree of a repository
+type Worktree struct {
+	// Path to the working tree directory
+	Path string `json:"path"`
+
+	// Branch checked out in the working tree (empty if HEAD is detached)
+	Branch string `json:"branch"`
+
+	// Commit hash of HEAD
+	Head string `json:"head"`
+
+	// Whether this is the main working tree of the repository
+	Main bool `json:"main"`
+
+	// Whether the working tree directory is missing and can be pruned
+	Prunable bool `json:"prunable"`
+}
+
 // Factory creates a provider based on name
 type Factory func(config map[string]string) (Provider, error)
 
@@ -0,0 +1,131 @@
+package verify
+
+import (
+	"strings"
+	"sync"
+)
+
+// Names of the standard verification steps
+const (
+	StepInstall = "install"
+	StepBuild   = "build"
+	StepTest    = "test"
+	StepLint    = "lint"
+)
+
+// Step is a single stage of a verification recipe
+type Step struct {
+	// Name of the step (install, build, test, lint)
+	Name string `json:"name"`
+
+	// Shell command run in the workspace directory of the container
+	Command string `json:"command"`
+
+	// Whether later steps still run if this step fails
+	Optional bool `json:"optional,omitempty"`
+}
+
+// Recipe describes how to verify code written with a framework
+type Recipe struct {
+	// Framework the recipe applies to
+	Framework string `json:"framework"`
+
+	// Container image providing the framework's toolchain
+	Image string `json:"image"`
+
+	// Steps run in order
+	Steps []Step `json:"steps"`
+
+	// Paths (relative to the code, may contain wildcards) holding the build output,
+	// whose size is measured after a successful build
+	BundlePaths []string `json:"bundlePaths,omitempty"`
+}
+
+// Container images for the built-in recipes
+const (
+	nodeImage   = "node:20"
+	pythonImage = "python:3.12"
+	mavenImage  = "maven:3-eclipse-temurin-17"
+	rubyImage   = "ruby:3.3"
+)
+
+// nodeRecipe verifies a JavaScript project through its package.json scripts
+func nodeRecipe(framework string) Recipe {
+	return Recipe{
+		Framework: framework,
+		Image:     nodeImage,
+		Steps: []Step{
+			{Name: StepInstall, Command: "npm install --no-audit --no-fund"},
+			{Name: StepBuild, Command: "npm run build --if-present"},
+			{Name: StepTest, Command: "CI=true npm run test --if-present"},
+			{Name: StepLint, Command: "npm run lint --if-present", Optional: true},
+		},
+		BundlePaths: []string{"dist", "build", ".next", ".output", "out"},
+	}
+}
+
+// pythonRecipe verifies a Python project with the given test command
+func pythonRecipe(framework string, testCommand string) Recipe {
+	return Recipe{
+		Framework: framework,
+		Image:     pythonImage,
+		Steps: []Step{
+			{Name: StepInstall, Command: "pip install -q -r requirements.txt"},
+			{Name: StepBuild, Command: "python -m compileall -q ."},
+			{Name: StepTest, Command: testCommand},
+			{Name: StepLint, Command: "pip install -q flake8 && python -m flake8 --exclude=.git,venv .", Optional: true},
+		},
+	}
+}
+
+var (
+	recipesMutex sync.RWMutex
+
+	recipes = map[string]Recipe{
+		"react":   nodeRecipe("react"),
+		"vue":     nodeRecipe("vue"),
+		"svelte":  nodeRecipe("svelte"),
+		"angular": nodeRecipe("angular"),
+		"nextjs":  nodeRecipe("nextjs"),
+		"nuxt":    nodeRecipe("nuxt"),
+		"express": nodeRecipe("express"),
+		"fastify": nodeRecipe("fastify"),
+		"django":  pythonRecipe("django", "python manage.py test"),
+		"flask":   pythonRecipe("flask", "pip install -q pytest && python -m pytest"),
+		"spring": {
+			Framework: "spring",
+			Image:     mavenImage,
+			Steps: []Step{
+				{Name: StepInstall, Command: "mvn -q -B dependency:resolve"},
+				{Name: StepBuild, Command: "mvn -q -B -DskipTests package"},
+				{Name: StepTest, Command: "mvn -B test"},
+			},
+			BundlePaths: []string{"target/*.jar"},
+		},
+		"rails": {
+			Framework: "rails",
+			Image:     rubyImage,
+			Steps: []Step{
+				{Name: StepInstall, Command: "bundle install"},
+				{Name: StepBuild, Command: "bundle exec rails zeitwerk:check"},
+				{Name: StepTest, Command: "bundle exec rails test"},
+				{Name: StepLint, Command: "bundle exec rubocop", Optional: true},
+			},
+		},
+	}
+)
+
+// RegisterRecipe registers a recipe, replacing any recipe for the same framework
+func RegisterRecipe(recipe Recipe) {
+	recipesMutex.Lock()
+	defer recipesMutex.Unlock()
+	recipes[strings.ToLower(recipe.Framework)] = recipe
+}
+
+// GetRecipe returns the recipe for a framework
+func GetRecipe(framework string) (Recipe, bool) {
+	recipesMutex.RLock()
+	defer recipesMutex.RUnlock()
+	recipe, exists := recipes[strings.ToLower(framework)]
+	return recipe, exists
+}
@@ -0,0 +1,364 @@
+// Package verify builds and tests generated code inside containers
+package verify
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fr0g-66723067/cc/internal/container"
+)
+
+// workspaceDir is where the code is copied to in the verification container
+const workspaceDir = "/cc-verify"
+
+// maxOutputLength is how much of the end of a step's output is kept
+const maxOutputLength = 4000
+
+// StepResult is the outcome of a single verification step
+type StepResult struct {
+	// Name of the step
+	Name string
+
+	// Whether the step succeeded
+	Passed bool
+
+	// Whether the step may fail without failing the verification
+	Optional bool
+
+	// How long the step took
+	Duration time.Duration
+
+	// End of the step's combined output
+	Output string
+}
+
+// Result is the outcome of verifying code with a recipe
+type Result struct {
+	// Framework of the recipe used
+	Framework string
+
+	// Whether all required steps succeeded
+	Passed bool
+
+	// Results of the steps that were run, in order
+	Steps []StepResult
+
+	// Number of passing and failing tests reported by the test step
+	TestsPassed int
+	TestsFailed int
+
+	// Whether test counts could be read from the test output
+	HasTestCounts bool
+
+	// Size of the build output in bytes (0 if unknown)
+	BundleBytes int64
+
+	// Total duration of the verification
+	Duration time.Duration
+
+	// Steps of the recipe, including those that did not run
+	recipe []Step
+}
+
+// Verifier runs verification recipes in containers
+type Verifier struct {
+	provider container.Provider
+	options  container.RunOptions
+	egress   container.EgressPolicy
+}
+
+// NewVerifier creates a verifier that runs containers with the given provider, limited and
+// hardened as the options say, with the network access of the egress policy
+func NewVerifier(provider container.Provider, options container.RunOptions, egress container.EgressPolicy) *Verifier {
+	return &Verifier{provider: provider, options: options, egress: egress}
+}
+
+// Verify copies codeDir into a new container running the recipe's image and runs the
+// recipe's steps, stopping at the first required step that fails
+func (v *Verifier) Verify(ctx context.Context, recipe Recipe, codeDir string) (*Result, error) {
+	absCodeDir, err := filepath.Abs(codeDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for code directory: %w", err)
+	}
+
+	// Generated code is built and tested with no more network access than it was generated with
+	options := v.options
+	options.CapAdd = append([]string(nil), v.options.CapAdd...)
+	if err := v.egress.Apply(&options); err != nil {
+		return nil, fmt.Errorf("invalid egress policy: %w", err)
+	}
+
+	// Start a container with the framework's toolchain
+	containerID, err := v.provider.RunContainer(ctx, recipe.Image, nil, nil, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run verification container: %w", err)
+	}
+	defer func() {
+		// Clean up even if the verification was cancelled
+		cleanupCtx := context.Background()
+		if err := v.provider.StopContainer(cleanupCtx, containerID); err != nil {
+			fmt.Printf("Warning: Failed to stop verification container: %v\n", err)
+		}
+		if err := v.provider.RemoveContainer(cleanupCtx, containerID); err != nil {
+			fmt.Printf("Warning: Failed to remove verification container: %v\n", err)
+		}
+	}()
+
+	// Never run the code in a container whose firewall failed
+	if err := v.egress.Enforce(ctx, v.provider, containerID); err != nil {
+		return nil, err
+	}
+
+	// Copy the code instead of mounting it so build artifacts stay in the container
+	if err := v.provider.CopyFilesToContainer(ctx, containerID, absCodeDir, workspaceDir); err != nil {
+		return nil, fmt.Errorf("failed to copy code to verification container: %w", err)
+	}
+
+	result := &Result{Framework: recipe.Framework, Passed: true, recipe: recipe.Steps}
+	start := time.Now()
+
+	for _, step := range recipe.Steps {
+		fmt.Printf("Verifying %s: %s...\n", recipe.Framework, step.Name)
+		stepStart := time.Now()
+		command := []string{"sh", "-c", fmt.Sprintf("cd %s && %s", workspaceDir, step.Command)}
+		output, err := v.provider.ExecuteCommand(ctx, containerID, command)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("verification cancelled: %w", ctx.Err())
+		}
+
+		// Failed commands report their output in the error
+		if err != nil {
+			output = err.Error()
+		}
+
+		stepResult := StepResult{
+			Name:     step.Name,
+			Passed:   err == nil,
+			Optional: step.Optional,
+			Duration: time.Since(stepStart),
+			Output:   tail(output, maxOutputLength),
+		}
+		result.Steps = append(result.Steps, stepResult)
+
+		if step.Name == StepTest {
+			result.TestsPassed, result.TestsFailed, result.HasTestCounts = ParseTestCounts(output)
+		}
+
+		if !stepResult.Passed && !step.Optional {
+			result.Passed = false
+			break
+		}
+	}
+
+	if build := result.Step(StepBuild); build != nil && build.Passed && len(recipe.BundlePaths) > 0 {
+		result.BundleBytes = v.bundleSize(ctx, containerID, recipe.BundlePaths)
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// bundleSize returns the total size of the paths in the container, or 0 if none exist
+func (v *Verifier) bundleSize(ctx context.Context, containerID string, paths []string) int64 {
+	// du prints the total last, even if some of the paths do not exist
+	command := []string{"sh", "-c", fmt.Sprintf("cd %s && du -skc %s 2>/dev/null | tail -n 1", workspaceDir, strings.Join(paths, " "))}
+	output, err := v.provider.ExecuteCommand(ctx, containerID, command)
+	if err != nil {
+		return 0
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return 0
+	}
+	kilobytes, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return kilobytes * 1024
+}
+
+// Step returns the result of the named step, or nil if it did not run
+func (r *Result) Step(name string) *StepResult {
+	for i := range r.Steps {
+		if r.Steps[i].Name == name {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+// Metrics returns the result as implementation metrics: whether the verification and
+// each step passed (1 or 0), step durations in seconds, the score, test counts and
+// the bundle size
+func (r *Result) Metrics() map[string]float64 {
+	metrics := map[string]float64{
+		"verify_passed":  boolMetric(r.Passed),
+		"verify_seconds": r.Duration.Seconds(),
+		"verify_score":   float64(r.Score()),
+	}
+
+	// Steps that did not run because an earlier step failed count as failed
+	for _, step := range r.recipe {
+		metrics[step.Name+"_passed"] = 0
+	}
+	for _, step := range r.Steps {
+		metrics[step.Name+"_passed"] = boolMetric(step.Passed)
+		metrics[step.Name+"_seconds"] = step.Duration.Seconds()
+	}
+
+	if r.HasTestCounts {
+		metrics["tests_passed"] = float64(r.TestsPassed)
+		metrics["tests_failed"] = float64(r.TestsFailed)
+	}
+
+	if r.BundleBytes > 0 {
+		metrics["bundle_bytes"] = float64(r.BundleBytes)
+	}
+
+	return metrics
+}
+
+// stepWeights is how much each standard step contributes to the score
+var stepWeights = map[string]float64{
+	StepInstall: 10,
+	StepBuild:   40,
+	StepTest:    40,
+	StepLint:    10,
+}
+
+// Score rates the result from 0 to 100. Installing, building, testing and linting
+// contribute 10, 40, 40 and 10 points respectively, scaled to the steps in the recipe.
+// Tests earn points in proportion to the share of passing tests when counts are known.
+func (r *Result) Score() int {
+	var available, earned float64
+	for _, step := range r.recipe {
+		weight, ok := stepWeights[step.Name]
+		if !ok {
+			continue
+		}
+		available += weight
+
+		stepResult := r.Step(step.Name)
+		if stepResult == nil {
+			continue
+		}
+
+		switch {
+		case step.Name == StepTest && r.HasTestCounts && r.TestsPassed+r.TestsFailed > 0:
+			earned += weight * float64(r.TestsPassed) / float64(r.TestsPassed+r.TestsFailed)
+		case stepResult.Passed:
+			earned += weight
+		}
+	}
+
+	if available == 0 {
+		return 0
+	}
+	return int(earned/available*100 + 0.5)
+}
+
+// Summary returns a one-line description of the result
+func (r *Result) Summary() string {
+	var parts []string
+	for _, step := range r.Steps {
+		status := "ok"
+		if !step.Passed {
+			status = "failed"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s (%.1fs)", step.Name, status, step.Duration.Seconds()))
+	}
+	if r.HasTestCounts {
+		parts = append(parts, fmt.Sprintf("%d/%d tests passed", r.TestsPassed, r.TestsPassed+r.TestsFailed))
+	}
+	return strings.Join(parts, ", ")
+}
+
+var (
+	// Maven Surefire: "Tests run: 5, Failures: 1, Errors: 0, Skipped: 1"
+	junitPattern = regexp.MustCompile(`Tests run: (\d+), Failures: (\d+), Errors: (\d+)(?:, Skipped: (\d+))?`)
+
+	// Minitest: "5 runs, 9 assertions, 1 failures, 0 errors, 0 skips"
+	minitestPattern = regexp.MustCompile(`(\d+) runs, \d+ assertions, (\d+) failures, (\d+) errors`)
+
+	// Python unittest (used by Django): "Ran 5 tests in 0.010s" followed by "FAILED (failures=1, errors=1)"
+	unittestRanPattern    = regexp.MustCompile(`Ran (\d+) tests? in`)
+	unittestFailedPattern = regexp.MustCompile(`FAILED \((.*)\)`)
+	unittestCountPattern  = regexp.MustCompile(`(?:failures|errors)=(\d+)`)
+
+	// Jest, Vitest and pytest summaries: "Tests: 1 failed, 4 passed, 5 total", "3 passed, 1 failed in 0.12s"
+	passedPattern = regexp.MustCompile(`(\d+) passed`)
+	failedPattern = regexp.MustCompile(`(\d+) failed`)
+)
+
+// ParseTestCounts reads the number of passing and failing tests from the output of
+// common test runners, returning false if no summary was found
+func ParseTestCounts(output string) (passed int, failed int, found bool) {
+	if matches := junitPattern.FindAllStringSubmatch(output, -1); len(matches) > 0 {
+		// The last match is the summary of all test classes
+		last := matches[len(matches)-1]
+		run, failures, errors, skipped := atoi(last[1]), atoi(last[2]), atoi(last[3]), atoi(last[4])
+		return run - failures - errors - skipped, failures + errors, true
+	}
+
+	if match := minitestPattern.FindStringSubmatch(output); match != nil {
+		runs, failures, errors := atoi(match[1]), atoi(match[2]), atoi(match[3])
+		return runs - failures - errors, failures + errors, true
+	}
+
+	if match := unittestRanPattern.FindStringSubmatch(output); match != nil {
+		failed := 0
+		if failedMatch := unittestFailedPattern.FindStringSubmatch(output); failedMatch != nil {
+			for _, count := range unittestCountPattern.FindAllStringSubmatch(failedMatch[1], -1) {
+				failed += atoi(count[1])
+			}
+		}
+		return atoi(match[1]) - failed, failed, true
+	}
+
+	// Summaries are printed last, after the per-suite lines
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		passedMatch := passedPattern.FindStringSubmatch(lines[i])
+		failedMatch := failedPattern.FindStringSubmatch(lines[i])
+		if passedMatch == nil && failedMatch == nil {
+			continue
+		}
+		if passedMatch != nil {
+			passed = atoi(passedMatch[1])
+		}
+		if failedMatch != nil {
+			failed = atoi(failedMatch[1])
+		}
+		return passed, failed, true
+	}
+
+	return 0, 0, false
+}
+
+// atoi converts a matched number, treating unmatched groups as zero
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// boolMetric converts a boolean to a metric value
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// tail returns the last maxLen bytes of s
+func tail(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return "..." + s[len(s)-maxLen:]
+}
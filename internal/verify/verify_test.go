@@ -0,0 +1,217 @@
+package verify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fr0g-66723067/cc/internal/container"
+	"github.com/fr0g-66723067/cc/internal/container/mocks"
+	"github.com/fr0g-66723067/cc/internal/verify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// testRecipe is a recipe with all standard steps
+var testRecipe = verify.Recipe{
+	Framework: "test",
+	Image:     "test-image",
+	Steps: []verify.Step{
+		{Name: verify.StepInstall, Command: "install"},
+		{Name: verify.StepBuild, Command: "build"},
+		{Name: verify.StepTest, Command: "test"},
+		{Name: verify.StepLint, Command: "lint", Optional: true},
+	},
+}
+
+// newMockProvider creates a container provider running the given recipe commands
+func newMockProvider() *mocks.MockProvider {
+	mockProvider := new(mocks.MockProvider)
+	mockProvider.On("RunContainer", mock.Anything, "test-image", mock.Anything, mock.Anything, mock.Anything).Return("verify-container", nil)
+	mockProvider.On("CopyFilesToContainer", mock.Anything, "verify-container", mock.Anything, mock.Anything).Return(nil)
+	mockProvider.On("StopContainer", mock.Anything, "verify-container").Return(nil)
+	mockProvider.On("RemoveContainer", mock.Anything, "verify-container").Return(nil)
+	return mockProvider
+}
+
+// command returns the shell command a recipe command is run with
+func command(recipeCommand string) []string {
+	return []string{"sh", "-c", "cd /cc-verify && " + recipeCommand}
+}
+
+func TestVerifyPassing(t *testing.T) {
+	mockProvider := newMockProvider()
+	mockProvider.On("ExecuteCommand", mock.Anything, "verify-container", command("test")).Return("Tests: 1 failed, 3 passed, 4 total", nil)
+	mockProvider.On("ExecuteCommand", mock.Anything, "verify-container", command("lint")).Return("", errors.New("lint errors"))
+	mockProvider.On("ExecuteCommand", mock.Anything, "verify-container", mock.Anything).Return("ok", nil)
+
+	options := container.RunOptions{Memory: 1 << 30, Network: "none"}
+	result, err := verify.NewVerifier(mockProvider, options, container.EgressPolicy{}).Verify(context.Background(), testRecipe, t.TempDir())
+	require.NoError(t, err)
+	mockProvider.AssertCalled(t, "RunContainer", mock.Anything, "test-image", mock.Anything, mock.Anything, options)
+
+	// A failing optional step does not fail the verification
+	assert.True(t, result.Passed)
+	require.Len(t, result.Steps, 4)
+	assert.False(t, result.Step(verify.StepLint).Passed)
+	assert.Equal(t, "lint errors", result.Step(verify.StepLint).Output)
+	assert.True(t, result.HasTestCounts)
+	assert.Equal(t, 3, result.TestsPassed)
+	assert.Equal(t, 1, result.TestsFailed)
+
+	metrics := result.Metrics()
+	assert.Equal(t, 1.0, metrics["verify_passed"])
+	assert.Equal(t, 1.0, metrics["build_passed"])
+	assert.Equal(t, 0.0, metrics["lint_passed"])
+	assert.Equal(t, 3.0, metrics["tests_passed"])
+	assert.Equal(t, 1.0, metrics["tests_failed"])
+	assert.Contains(t, metrics, "test_seconds")
+
+	// 10 for installing, 40 for building, 30 for 3 of 4 tests, nothing for linting
+	assert.Equal(t, 80, result.Score())
+
+	// The container is always cleaned up
+	mockProvider.AssertCalled(t, "StopContainer", mock.Anything, "verify-container")
+	mockProvider.AssertCalled(t, "RemoveContainer", mock.Anything, "verify-container")
+}
+
+func TestVerifyFailingBuild(t *testing.T) {
+	mockProvider := newMockProvider()
+	mockProvider.On("ExecuteCommand", mock.Anything, "verify-container", command("install")).Return("ok", nil)
+	mockProvider.On("ExecuteCommand", mock.Anything, "verify-container", command("build")).Return("", errors.New("syntax error"))
+
+	result, err := verify.NewVerifier(mockProvider, container.RunOptions{}, container.EgressPolicy{}).Verify(context.Background(), testRecipe, t.TempDir())
+	require.NoError(t, err)
+
+	// Steps after a failing required step are not run
+	assert.False(t, result.Passed)
+	require.Len(t, result.Steps, 2)
+	assert.Nil(t, result.Step(verify.StepTest))
+	mockProvider.AssertNotCalled(t, "ExecuteCommand", mock.Anything, "verify-container", command("test"))
+
+	metrics := result.Metrics()
+	assert.Equal(t, 0.0, metrics["verify_passed"])
+	assert.Equal(t, 1.0, metrics["install_passed"])
+	assert.Equal(t, 0.0, metrics["build_passed"])
+	assert.Equal(t, 0.0, metrics["test_passed"])
+	assert.NotContains(t, metrics, "tests_passed")
+	assert.Equal(t, 10, result.Score())
+}
+
+func TestVerifyContainerError(t *testing.T) {
+	mockProvider := new(mocks.MockProvider)
+	mockProvider.On("RunContainer", mock.Anything, "test-image", mock.Anything, mock.Anything, mock.Anything).Return("", errors.New("no docker"))
+
+	_, err := verify.NewVerifier(mockProvider, container.RunOptions{}, container.EgressPolicy{}).Verify(context.Background(), testRecipe, t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestVerifyEgress(t *testing.T) {
+	policy := container.EgressPolicy{Mode: container.EgressAllowlist, Allow: []string{"registry.npmjs.org"}}
+	firewallCmd := mock.MatchedBy(func(command []string) bool {
+		return len(command) == 5 && command[3] == container.FirewallScript && command[4] == "registry.npmjs.org"
+	})
+
+	// The firewall is set up before the code is copied in
+	mockProvider := newMockProvider()
+	mockProvider.On("ExecuteCommand", mock.Anything, "verify-container", firewallCmd).Return("Firewall configuration complete", nil).Once()
+	mockProvider.On("ExecuteCommand", mock.Anything, "verify-container", mock.Anything).Return("ok", nil)
+	options := container.RunOptions{CapDrop: []string{"MKNOD"}}
+	_, err := verify.NewVerifier(mockProvider, options, policy).Verify(context.Background(), testRecipe, t.TempDir())
+	require.NoError(t, err)
+	mockProvider.AssertCalled(t, "RunContainer", mock.Anything, "test-image", mock.Anything, mock.Anything,
+		container.RunOptions{CapDrop: []string{"MKNOD"}, CapAdd: []string{"NET_ADMIN", "NET_RAW"}})
+	mockProvider.AssertExpectations(t)
+
+	// Code is never run in a container whose firewall failed
+	mockProvider = newMockProvider()
+	mockProvider.On("ExecuteCommand", mock.Anything, "verify-container", firewallCmd).Return("ipset: command not found", errors.New("exit status 127"))
+	_, err = verify.NewVerifier(mockProvider, options, policy).Verify(context.Background(), testRecipe, t.TempDir())
+	assert.ErrorContains(t, err, "failed to apply the egress allowlist")
+	mockProvider.AssertNotCalled(t, "CopyFilesToContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockProvider.AssertCalled(t, "RemoveContainer", mock.Anything, "verify-container")
+
+	// No container is started when the allowlist cannot be enforced
+	unused := new(mocks.MockProvider)
+	_, err = verify.NewVerifier(unused, container.RunOptions{CapDrop: []string{"ALL"}}, policy).Verify(context.Background(), testRecipe, t.TempDir())
+	assert.ErrorContains(t, err, "invalid egress policy")
+	unused.AssertNotCalled(t, "RunContainer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// Verifications without network run in containers without network
+	mockProvider = newMockProvider()
+	mockProvider.On("ExecuteCommand", mock.Anything, "verify-container", mock.Anything).Return("ok", nil)
+	_, err = verify.NewVerifier(mockProvider, container.RunOptions{}, container.EgressPolicy{Mode: container.EgressNone}).Verify(context.Background(), testRecipe, t.TempDir())
+	require.NoError(t, err)
+	mockProvider.AssertCalled(t, "RunContainer", mock.Anything, "test-image", mock.Anything, mock.Anything, container.RunOptions{Network: "none"})
+}
+
+func TestScoreWithoutLintStep(t *testing.T) {
+	mockProvider := newMockProvider()
+	mockProvider.On("ExecuteCommand", mock.Anything, "verify-container", mock.Anything).Return("ok", nil)
+
+	// Scores are scaled to the steps in the recipe
+	recipe := verify.Recipe{Framework: "test", Image: "test-image", Steps: testRecipe.Steps[:3]}
+	result, err := verify.NewVerifier(mockProvider, container.RunOptions{}, container.EgressPolicy{}).Verify(context.Background(), recipe, t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, 100, result.Score())
+}
+
+func TestParseTestCounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		passed int
+		failed int
+		found  bool
+	}{
+		{"jest", "Test Suites: 1 failed, 2 passed, 3 total\nTests:       2 failed, 10 passed, 12 total\nSnapshots:   0 total\nTime:        1.2 s", 10, 2, true},
+		{"vitest", " Test Files  2 passed (2)\n      Tests  7 passed (7)\n   Duration  1.1s", 7, 0, true},
+		{"pytest", "collected 5 items\n\ntest_app.py ..F..\n\n===== 1 failed, 4 passed in 0.12s =====", 4, 1, true},
+		{"maven", "Tests run: 2, Failures: 0, Errors: 0, Skipped: 0\nResults:\nTests run: 6, Failures: 1, Errors: 1, Skipped: 1", 3, 2, true},
+		{"minitest", "Finished in 0.5s\n8 runs, 12 assertions, 1 failures, 0 errors, 0 skips", 7, 1, true},
+		{"django", "Ran 5 tests in 0.010s\n\nFAILED (failures=1, errors=1)", 3, 2, true},
+		{"django passing", "Ran 5 tests in 0.010s\n\nOK", 5, 0, true},
+		{"no tests", "> echo \"no test specified\"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passed, failed, found := verify.ParseTestCounts(tt.output)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.passed, passed)
+			assert.Equal(t, tt.failed, failed)
+		})
+	}
+}
+
+func TestRegisterRecipe(t *testing.T) {
+	// Built-in recipes are available
+	recipe, ok := verify.GetRecipe("React")
+	require.True(t, ok)
+	assert.Equal(t, "react", recipe.Framework)
+
+	// Registered recipes replace built-in ones
+	verify.RegisterRecipe(verify.Recipe{Framework: "Elixir", Image: "elixir:1.16"})
+	recipe, ok = verify.GetRecipe("elixir")
+	require.True(t, ok)
+	assert.Equal(t, "elixir:1.16", recipe.Image)
+
+	_, ok = verify.GetRecipe("unknown")
+	assert.False(t, ok)
+}
+
+func TestVerifyBundleSize(t *testing.T) {
+	mockProvider := newMockProvider()
+	mockProvider.On("ExecuteCommand", mock.Anything, "verify-container", command("du -skc dist build 2>/dev/null | tail -n 1")).Return("120\ttotal\n", nil)
+	mockProvider.On("ExecuteCommand", mock.Anything, "verify-container", mock.Anything).Return("ok", nil)
+
+	// The build output is measured after a successful build
+	recipe := testRecipe
+	recipe.BundlePaths = []string{"dist", "build"}
+	result, err := verify.NewVerifier(mockProvider, container.RunOptions{}, container.EgressPolicy{}).Verify(context.Background(), recipe, t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, int64(120*1024), result.BundleBytes)
+	assert.Equal(t, float64(120*1024), result.Metrics()["bundle_bytes"])
+	assert.Equal(t, 100.0, result.Metrics()["verify_score"])
+}
@@ -30,6 +30,36 @@ type Config struct {
 
 		// Provider-specific configuration
 		Config map[string]string `json:"config"`
+
+		// Caps on the usage of AI calls (0 for no limit)
+		Budget struct {
+			// Spend in US dollars per project
+			ProjectCostUSD float64 `json:"projectCostUSD"`
+
+			// Input and output tokens per project
+			ProjectTokens int `json:"projectTokens"`
+
+			// Spend in US dollars per day, over all projects
+			DailyCostUSD float64 `json:"dailyCostUSD"`
+
+			// Input and output tokens per day, over all projects
+			DailyTokens int `json:"dailyTokens"`
+
+			// Ledger file recording the usage of AI calls (next to the config file if empty)
+			Ledger string `json:"ledger"`
+		} `json:"budget"`
+
+		// Retrying AI calls that fail to generate an implementation or add a feature
+		Retry struct {
+			// Attempts in total, including the first (1 or less for no retries)
+			Attempts int `json:"attempts"`
+
+			// Seconds to wait before the first retry, doubled before every further retry
+			Backoff int `json:"backoff"`
+
+			// Longest wait between attempts in seconds (0 for no maximum)
+			MaxBackoff int `json:"maxBackoff"`
+		} `json:"retry"`
 	} `json:"ai"`
 
 	// Version control configuration
@@ -56,6 +86,36 @@ type Config struct {
 		Timeout int `json:"timeout"`
 	} `json:"jobs"`
 
+	// Verification of generated code
+	Verify struct {
+		// Skip building and testing generated code
+		Disabled bool `json:"disabled"`
+
+		// Verification timeout in seconds (0 for no timeout)
+		Timeout int `json:"timeout"`
+	} `json:"verify"`
+
+	// Framework profiles, replacing the fields they set of the built-in profiles and of the
+	// profiles in the frameworks directory of the plugins directory. They are kept as JSON
+	// and parsed by the command that loads them.
+	Frameworks []json.RawMessage `json:"frameworks"`
+
+	// Scoring of implementations
+	Scoring struct {
+		// Weights of the scorers, overriding the default weights (0 disables a scorer)
+		Weights map[string]float64 `json:"weights"`
+
+		// Scorer-specific configuration, prefixed with the scorer name (e.g. "lines_of_code_good")
+		Config map[string]string `json:"config"`
+	} `json:"scoring"`
+
+	// Code analysis of implementations
+	Analysis struct {
+		// Refuse to select implementations with findings of at least this severity
+		// (critical, high, medium, low or info; empty to never refuse)
+		BlockSeverity string `json:"blockSeverity"`
+	} `json:"analysis"`
+
 	// Plugin configuration
 	Plugins struct {
 		// Directory to load plugins from
@@ -98,6 +158,13 @@ func DefaultConfig() *Config {
 	config.Jobs.MaxConcurrent = 4
 	config.Jobs.Timeout = 3600 // 1 hour
 
+	// Default verify config
+	config.Verify.Timeout = 900 // 15 minutes
+
+	// Default scoring config
+	config.Scoring.Weights = make(map[string]float64)
+	config.Scoring.Config = make(map[string]string)
+
 	// Default plugins config
 	config.Plugins.Dir = filepath.Join(homeDir, ".cc", "plugins")
 	config.Plugins.Enabled = []string{}
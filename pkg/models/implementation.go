@@ -28,6 +28,13 @@ type Implementation struct {
 	// Evaluation score (0-100)
 	Score int `json:"score"`
 
+	// Status of the implementation ("completed" or "failed", empty for older implementations)
+	Status string `json:"status,omitempty"`
+
+	// Egress policy of the containers the implementation was generated in, like
+	// "allowlist: api.anthropic.com" (empty if not generated in containers)
+	Egress string `json:"egress,omitempty"`
+
 	// Features implemented in this branch
 	Features []Feature `json:"features"`
 }
@@ -46,15 +53,69 @@ type Feature struct {
 	// Creation timestamp
 	CreatedAt time.Time `json:"createdAt"`
 
-	// Name of branch this feature is based on
+	// Name of branch this feature is based on (the implementation or the parent feature)
 	BaseBranch string `json:"baseBranch"`
 
+	// Branch of the feature this feature is stacked on (empty if based on the implementation)
+	Parent string `json:"parent,omitempty"`
+
+	// Branches of the features stacked on this feature
+	Children []string `json:"children,omitempty"`
+
 	// AI provider that generated this feature
 	Provider string `json:"provider"`
 
-	// Status of the feature (e.g. "completed", "in-progress", "failed")
+	// Status of the feature (e.g. "completed", "in-progress", "failed", "merged")
 	Status string `json:"status"`
 
+	// Egress policy of the containers the feature was generated in (empty if not generated
+	// in containers)
+	Egress string `json:"egress,omitempty"`
+
 	// Tags for categorizing features
 	Tags []string `json:"tags"`
-}
\ No newline at end of file
+
+	// Metrics from verifying the feature branch
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+
+	// Evaluation score (0-100)
+	Score int `json:"score,omitempty"`
+
+	// Branch with a proposed resolution of conflicts with the base branch (if any)
+	ResolveBranch string `json:"resolveBranch,omitempty"`
+}
+
+// AddFeature adds a feature to the implementation, linking it to its parent feature
+func (i *Implementation) AddFeature(feature Feature) {
+	if parent := i.GetFeature(feature.Parent); parent != nil {
+		parent.Children = append(parent.Children, feature.BranchName)
+	}
+	i.Features = append(i.Features, feature)
+}
+
+// GetFeature returns a feature by branch name
+func (i *Implementation) GetFeature(branchName string) *Feature {
+	if branchName == "" {
+		return nil
+	}
+	for j := range i.Features {
+		if i.Features[j].BranchName == branchName {
+			return &i.Features[j]
+		}
+	}
+	return nil
+}
+
+// GetStack returns a feature followed by all features stacked on it, parents before their children
+func (i *Implementation) GetStack(branchName string) []*Feature {
+	feature := i.GetFeature(branchName)
+	if feature == nil {
+		return nil
+	}
+
+	stack := []*Feature{feature}
+	for _, child := range feature.Children {
+		stack = append(stack, i.GetStack(child)...)
+	}
+	return stack
+}
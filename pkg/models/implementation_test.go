@@ -0,0 +1,57 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/fr0g-66723067/cc/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddStackedFeatures(t *testing.T) {
+	// Create a test implementation
+	impl := models.Implementation{
+		Framework:  "react",
+		BranchName: "implementation/react",
+	}
+
+	// Add a feature based on the implementation and two features stacked on it
+	impl.AddFeature(models.Feature{BranchName: "feat-auth", BaseBranch: "implementation/react"})
+	impl.AddFeature(models.Feature{BranchName: "feat-profile", BaseBranch: "feat-auth", Parent: "feat-auth"})
+	impl.AddFeature(models.Feature{BranchName: "feat-avatar", BaseBranch: "feat-profile", Parent: "feat-profile"})
+	impl.AddFeature(models.Feature{BranchName: "feat-logout", BaseBranch: "feat-auth", Parent: "feat-auth"})
+
+	// Verify the parent/children relation
+	assert.Len(t, impl.Features, 4)
+	assert.Equal(t, []string{"feat-profile", "feat-logout"}, impl.GetFeature("feat-auth").Children)
+	assert.Equal(t, []string{"feat-avatar"}, impl.GetFeature("feat-profile").Children)
+	assert.Empty(t, impl.GetFeature("feat-avatar").Children)
+
+	// Test getting a non-existent feature
+	assert.Nil(t, impl.GetFeature("feat-unknown"))
+	assert.Nil(t, impl.GetFeature(""))
+}
+
+func TestGetStack(t *testing.T) {
+	// Create a test implementation with a stack of features
+	impl := models.Implementation{BranchName: "implementation/react"}
+	impl.AddFeature(models.Feature{BranchName: "feat-auth", BaseBranch: "implementation/react"})
+	impl.AddFeature(models.Feature{BranchName: "feat-profile", BaseBranch: "feat-auth", Parent: "feat-auth"})
+	impl.AddFeature(models.Feature{BranchName: "feat-avatar", BaseBranch: "feat-profile", Parent: "feat-profile"})
+	impl.AddFeature(models.Feature{BranchName: "feat-logout", BaseBranch: "feat-auth", Parent: "feat-auth"})
+
+	// Parents come before their children
+	var branches []string
+	for _, feature := range impl.GetStack("feat-auth") {
+		branches = append(branches, feature.BranchName)
+	}
+	assert.Equal(t, []string{"feat-auth", "feat-profile", "feat-avatar", "feat-logout"}, branches)
+
+	// A stack can start in the middle
+	stack := impl.GetStack("feat-profile")
+	require.Len(t, stack, 2)
+	assert.Equal(t, "feat-avatar", stack[1].BranchName)
+
+	// Unknown features have no stack
+	assert.Empty(t, impl.GetStack("feat-unknown"))
+}
@@ -87,6 +87,19 @@ func (p *Project) GetImplementation(branchName string) *Implementation {
 	return nil
 }
 
+// GetFeature returns a feature by branch name along with the implementation it belongs to
+func (p *Project) GetFeature(branchName string) (*Implementation, *Feature) {
+	for i := range p.Implementations {
+		impl := &p.Implementations[i]
+		for j := range impl.Features {
+			if impl.Features[j].BranchName == branchName {
+				return impl, &impl.Features[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
 // SetSelectedImplementation sets the selected implementation
 func (p *Project) SetSelectedImplementation(branchName string) {
 	p.SelectedImplementation = branchName
@@ -140,4 +140,42 @@ func TestSelectedImplementation(t *testing.T) {
 	project.SetSelectedImplementation("implementation/angular")
 	selected = project.GetSelectedImplementation()
 	assert.Nil(t, selected)
-}
\ No newline at end of file
+}
+
+func TestGetFeature(t *testing.T) {
+	// Create a test project
+	project := models.NewProject("test", "/path/to/test", "Test project")
+
+	// Add implementations with features
+	project.AddImplementation(models.Implementation{
+		Framework:  "react",
+		BranchName: "implementation/react",
+		Features: []models.Feature{
+			{Name: "dark-mode", BranchName: "feat-dark-mode", BaseBranch: "implementation/react"},
+		},
+	})
+
+	project.AddImplementation(models.Implementation{
+		Framework:  "vue",
+		BranchName: "implementation/vue",
+		Features: []models.Feature{
+			{Name: "search", BranchName: "feat-search", BaseBranch: "implementation/vue"},
+		},
+	})
+
+	// Test getting a feature of the second implementation
+	impl, feature := project.GetFeature("feat-search")
+	assert.NotNil(t, impl)
+	assert.NotNil(t, feature)
+	assert.Equal(t, "vue", impl.Framework)
+	assert.Equal(t, "search", feature.Name)
+
+	// Changes through the returned pointer are stored in the project
+	feature.Status = "merged"
+	assert.Equal(t, "merged", project.Implementations[1].Features[0].Status)
+
+	// Test getting a non-existent feature
+	impl, feature = project.GetFeature("feat-unknown")
+	assert.Nil(t, impl)
+	assert.Nil(t, feature)
+}
@@ -0,0 +1,38 @@
+import { useState } from 'react';
+
+// App keeps the list of todos and renders a form to add new ones
+export default function App() {
+  const [todos, setTodos] = useState([]);
+  const [text, setText] = useState('');
+
+  const addTodo = (event) => {
+    event.preventDefault();
+    if (!text.trim()) return;
+    setTodos([...todos, { id: Date.now(), text, done: false }]);
+    setText('');
+  };
+
+  const toggleTodo = (id) =>
+    setTodos(todos.map((todo) => (todo.id === id ? { ...todo, done: !todo.done } : todo)));
+
+  const removeTodo = (id) => setTodos(todos.filter((todo) => todo.id !== id));
+
+  return (
+    <main>
+      <h1>Todos</h1>
+      <form onSubmit={addTodo}>
+        <input value={text} onChange={(event) => setText(event.target.value)} />
+        <button type="submit">Add</button>
+      </form>
+      <ul>
+        {todos.map((todo) => (
+          <li key={todo.id}>
+            <input type="checkbox" checked={todo.done} onChange={() => toggleTodo(todo.id)} />
+            {todo.text}
+            <button onClick={() => removeTodo(todo.id)}>Remove</button>
+          </li>
+        ))}
+      </ul>
+    </main>
+  );
+}
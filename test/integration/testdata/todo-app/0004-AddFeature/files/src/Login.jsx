@@ -0,0 +1,20 @@
+import { useState } from 'react';
+
+// Login asks for credentials and reports the signed in user
+export default function Login({ onLogin }) {
+  const [username, setUsername] = useState('');
+  const [password, setPassword] = useState('');
+
+  const submit = (event) => {
+    event.preventDefault();
+    if (username && password) onLogin({ username });
+  };
+
+  return (
+    <form onSubmit={submit}>
+      <input placeholder="Username" value={username} onChange={(event) => setUsername(event.target.value)} />
+      <input placeholder="Password" type="password" value={password} onChange={(event) => setPassword(event.target.value)} />
+      <button type="submit">Sign in</button>
+    </form>
+  );
+}
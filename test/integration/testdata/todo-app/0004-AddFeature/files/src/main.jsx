@@ -0,0 +1,12 @@
+import React, { useState } from 'react';
+import ReactDOM from 'react-dom/client';
+import App from './App';
+import Login from './Login';
+
+// Root shows the todo list once a user signed in
+function Root() {
+  const [user, setUser] = useState(null);
+  return user ? <App /> : <Login onLogin={setUser} />;
+}
+
+ReactDOM.createRoot(document.getElementById('root')).render(<Root />);